@@ -5,6 +5,9 @@ echo "Starting database & seeding data..."
 
 docker-compose up -d db
 
+echo "📜 Running migrations..."
+docker-compose run --rm migrate
+
 echo "🌱 Running Go seeder..."
 docker-compose run --rm seed
 
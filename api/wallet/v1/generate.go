@@ -0,0 +1,5 @@
+// Package walletv1 holds the wallet.v1 gRPC API generated from wallet.proto.
+// Other Go services import it to get a typed client.
+package walletv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/wallet/v1/wallet.proto
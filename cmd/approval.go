@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"gorm.io/gorm"
+)
+
+const approvalUsage = `usage: app approval <command>
+
+commands:
+  list [-status pending] [-kind <kind>] [-limit 50]   list admin operations, newest first
+  show [-audit 20] <operation-id>                     show an operation and its audit trail
+  approve [-note <text>] <operation-id>               approve another admin's operation and execute it
+  reject [-note <text>] <operation-id>                reject an operation, or withdraw your own
+
+approve and reject act as the admin whose key from auth.admin_keys is in $ADMIN_KEY.`
+
+type operationDetails struct {
+	Operation repository.PendingOperation     `json:"operation"`
+	Audit     []repository.ApprovalAuditEntry `json:"audit"`
+}
+
+func runApproval(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", approvalUsage)
+	}
+	operations := repository.NewApprovalRepository(db.GetDB())
+	switch args[0] {
+	case "list":
+		return listOperations(ctx, operations, args[1:])
+	case "show":
+		return showOperation(ctx, operations, args[1:])
+	case "approve", "reject":
+		return decideOperation(ctx, operations, args[0], args[1:])
+	}
+	return fmt.Errorf("unknown approval command %q\n%s", args[0], approvalUsage)
+}
+
+func listOperations(ctx context.Context, operations repository.ApprovalRepository, args []string) error {
+	flags := flag.NewFlagSet("approval list", flag.ContinueOnError)
+	output := outputFlag(flags)
+	status := flags.String("status", "pending", "pending, approved, executed, failed, rejected or expired; empty lists all")
+	kind := flags.String("kind", "", "mint, burn, adjustment or transfer; empty lists all")
+	limit := flags.Int("limit", 50, "maximum number of operations")
+	if err := parseFlags(flags, args, 0, "approval list [flags]"); err != nil {
+		return err
+	}
+	ops, err := operations.ListOperations(ctx, repository.PendingOperationFilter{Status: *status, Kind: *kind}, *limit)
+	if err != nil {
+		return err
+	}
+	return render(*output, ops, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tKIND\tAMOUNT\tCURRENCY\tSTATUS\tPROPOSED BY\tDECIDED BY\tEXPIRES AT")
+		for _, op := range ops {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", op.ID, op.Kind, op.Amount, op.CurrencyTypeID,
+				op.Status, op.ProposedBy, op.DecidedBy, formatTime(op.ExpiresAt))
+		}
+	})
+}
+
+func showOperation(ctx context.Context, operations repository.ApprovalRepository, args []string) error {
+	flags := flag.NewFlagSet("approval show", flag.ContinueOnError)
+	output := outputFlag(flags)
+	limit := flags.Int("audit", 20, "number of recent audit entries to show")
+	if err := parseFlags(flags, args, 1, "approval show [flags] <operation-id>"); err != nil {
+		return err
+	}
+	op, err := operations.GetOperation(ctx, flags.Arg(0))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("no operation with ID %s", flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := operations.ListAudit(ctx, op.ID.String(), *limit)
+	if err != nil {
+		return err
+	}
+	details := operationDetails{Operation: *op, Audit: entries}
+	return render(*output, details, func(w io.Writer) {
+		printOperation(w, op)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "AT\tACTION\tACTOR\tDETAIL")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatTime(e.CreatedAt), e.Action, e.Actor, e.Detail)
+		}
+	})
+}
+
+func decideOperation(ctx context.Context, operations repository.ApprovalRepository, name string, args []string) error {
+	flags := flag.NewFlagSet("approval "+name, flag.ContinueOnError)
+	output := outputFlag(flags)
+	note := flags.String("note", "", "reason for the decision")
+	if err := parseFlags(flags, args, 1, "approval "+name+" [flags] <operation-id>"); err != nil {
+		return err
+	}
+	admin, err := adminFromEnv()
+	if err != nil {
+		return err
+	}
+	var op *repository.PendingOperation
+	if name == "approve" {
+		wallets := repository.NewWalletRepository(db.GetDB())
+		op, err = operations.Approve(ctx, flags.Arg(0), admin, *note, approval.Executor(wallets, openWallet(wallets)))
+	} else {
+		op, err = operations.Reject(ctx, flags.Arg(0), admin, *note)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("no operation with ID %s", flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	return render(*output, op, func(w io.Writer) { printOperation(w, op) })
+}
+
+// adminFromEnv returns the admin whose key is in $ADMIN_KEY, so that the CLI
+// records the same admins as the admin API does.
+func adminFromEnv() (string, error) {
+	admin, ok := auth.NewAdminKeys(appEnv.AuthConfig.Admins()).Admin(os.Getenv("ADMIN_KEY"))
+	if !ok {
+		return "", errors.New("set ADMIN_KEY to your key from auth.admin_keys; it names the admin in the audit trail")
+	}
+	return admin, nil
+}
+
+func printOperation(w io.Writer, op *repository.PendingOperation) {
+	fmt.Fprintln(w, "ID\tKIND\tAMOUNT\tCURRENCY\tSTATUS\tPROPOSED BY\tDECIDED BY\tREFERENCE")
+	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", op.ID, op.Kind, op.Amount, op.CurrencyTypeID,
+		op.Status, op.ProposedBy, op.DecidedBy, op.ReferenceID)
+}
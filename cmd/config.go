@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+)
+
+const configUsage = `usage: app [global flags] config <command>
+
+commands:
+  print [-o table|json|yaml]   show every setting, its source and its environment variable`
+
+// runConfig works without a database, so it can diagnose a configuration
+// that stops every other command from starting.
+func runConfig(loadOptions config_env.LoadOptions, args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	output := flags.String("o", "table", "output format: table, json or yaml")
+	if err := parseFlags(flags, args[1:], 0, "config print [-o table|json|yaml]"); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return 2
+	}
+
+	cfg, err := config_env.Load(loadOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := printConfig(*output, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return 1
+	}
+	return 0
+}
+
+func printConfig(format string, cfg *config_env.AppEnv) error {
+	settings := cfg.Settings()
+	if format != "yaml" {
+		return render(format, settings, func(w io.Writer) {
+			if cfg.File != "" {
+				fmt.Fprintf(w, "# config file: %s\n", cfg.File)
+			}
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+			for _, s := range settings {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, s.Value, s.Source, s.Env)
+			}
+		})
+	}
+
+	// nest the keys again, so the output has the layout of a config file
+	tree := yaml.MapSlice{}
+	for _, s := range settings {
+		section, key, nested := strings.Cut(s.Key, ".")
+		if !nested {
+			tree = append(tree, yaml.MapItem{Key: s.Key, Value: scalar(s.Value)})
+			continue
+		}
+		last := len(tree) - 1
+		if last < 0 || tree[last].Key != section {
+			tree = append(tree, yaml.MapItem{Key: section, Value: yaml.MapSlice{}})
+			last++
+		}
+		tree[last].Value = append(tree[last].Value.(yaml.MapSlice), yaml.MapItem{Key: key, Value: scalar(s.Value)})
+	}
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// scalar turns a printed value back into a number or boolean where it is
+// one, so the YAML output does not quote them.
+func scalar(value string) any {
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"github.com/jay6909/dino-internal-wallet-service/internal/seed"
+	"gorm.io/gorm"
+)
+
+const currencyUsage = `usage: app currency <command>
+
+commands:
+  create -name <name> [-supply 0]   create a currency with a treasury holding -supply
+  list                              list currencies and their treasury balances`
+
+type currencySummary struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	TreasuryID *uuid.UUID `json:"treasury_wallet_id"`
+	Treasury   int64      `json:"treasury_balance"`
+}
+
+func runCurrency(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", currencyUsage)
+	}
+	switch args[0] {
+	case "create":
+		return createCurrency(ctx, args[1:])
+	case "list":
+		return listCurrencies(ctx, args[1:])
+	}
+	return fmt.Errorf("unknown currency command %q\n%s", args[0], currencyUsage)
+}
+
+func createCurrency(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("currency create", flag.ContinueOnError)
+	output := outputFlag(flags)
+	name := flags.String("name", "", "currency name (required)")
+	supply := flags.Int64("supply", 0, "amount to mint into the new treasury")
+	if err := parseFlags(flags, args, 0, "currency create -name <name> [flags]"); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	if *supply < 0 {
+		return fmt.Errorf("-supply must not be negative")
+	}
+
+	database := db.GetDB()
+	wallets := repository.NewWalletRepository(database)
+	systemUser, err := seed.SystemUser(ctx, repository.NewUserRepository(database))
+	if err != nil {
+		return err
+	}
+	currency := repository.CurrencyType{ID: uuid.New(), Name: *name}
+	if err := repository.NewCurrencyTypeRepository(database).CreateCurrencyType(ctx, &currency); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("currency %q already exists", *name)
+		}
+		return err
+	}
+	treasury := repository.Wallet{
+		ID:             uuid.New(),
+		OwnerType:      "system",
+		OwnerID:        systemUser.ID,
+		CurrencyTypeID: currency.ID,
+	}
+	if err := wallets.CreateWallet(ctx, &treasury); err != nil {
+		return err
+	}
+	if *supply > 0 {
+		// the initial supply is minted so that it shows up in the ledger
+		if err := wallets.Mint(ctx, currency.ID.String(), "currency-create-"+currency.ID.String(), *supply); err != nil {
+			return err
+		}
+	}
+	return printCurrencies(*output, []currencySummary{{
+		ID: currency.ID, Name: currency.Name, TreasuryID: &treasury.ID, Treasury: *supply,
+	}})
+}
+
+func listCurrencies(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("currency list", flag.ContinueOnError)
+	output := outputFlag(flags)
+	if err := parseFlags(flags, args, 0, "currency list [flags]"); err != nil {
+		return err
+	}
+	database := db.GetDB()
+	currencies, err := repository.NewCurrencyTypeRepository(database).ListCurrencyTypes(ctx)
+	if err != nil {
+		return err
+	}
+	treasuries, err := repository.NewWalletRepository(database).ListSystemWallets(ctx)
+	if err != nil {
+		return err
+	}
+	summaries := make([]currencySummary, 0, len(currencies))
+	for _, currency := range currencies {
+		summary := currencySummary{ID: currency.ID, Name: currency.Name}
+		for _, treasury := range treasuries {
+			if treasury.CurrencyTypeID == currency.ID {
+				summary.TreasuryID, summary.Treasury = &treasury.ID, treasury.Balance
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return printCurrencies(*output, summaries)
+}
+
+func printCurrencies(format string, currencies []currencySummary) error {
+	return render(format, currencies, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tTREASURY WALLET\tTREASURY BALANCE")
+		for _, c := range currencies {
+			treasury := "-"
+			if c.TreasuryID != nil {
+				treasury = c.TreasuryID.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", c.ID, c.Name, treasury, c.Treasury)
+		}
+	})
+}
+
+// resolveCurrency finds a currency by name or ID.
+func resolveCurrency(ctx context.Context, nameOrID string) (*repository.CurrencyType, error) {
+	currencies, err := repository.NewCurrencyTypeRepository(db.GetDB()).ListCurrencyTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range currencies {
+		if currency.Name == nameOrID || currency.ID.String() == nameOrID {
+			return &currency, nil
+		}
+	}
+	return nil, fmt.Errorf("no currency named %q", nameOrID)
+}
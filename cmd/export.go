@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+func runExport(ctx context.Context, _ *migrations.Migrator, args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "json", "json (one object per line) or csv")
+	out := flags.String("out", "", "file to write; stdout when empty")
+	if err := parseFlags(flags, args, 1, "export [flags] users|currencies|wallets|transactions"); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = file
+	}
+	count, err := admin.Export(ctx, db.GetDB(), flags.Arg(0), *format, w)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d %s\n", count, flags.Arg(0))
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+)
+
+// healthcheck probes the local API for container health checks, where the
+// distroless image has no curl. It takes an optional path, /healthz by default.
+func healthcheck(loadOptions config_env.LoadOptions, args []string) int {
+	path := "/healthz"
+	if len(args) > 0 {
+		path = args[0]
+	}
+	// a configuration the server would reject leaves nothing to probe on
+	// its port, so fall back to the default and let the request fail
+	port := "8080"
+	if cfg, err := config_env.Load(loadOptions); err == nil {
+		port = cfg.Port
+	}
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%s%s", port, path))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "%s returned %d\n", path, resp.StatusCode)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/loadgen"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+// runLoadgen creates a fresh set of users and currencies in the database,
+// drives the HTTP API at -target with them and checks the ledger afterwards.
+func runLoadgen(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	flags := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	cfg := loadgen.Config{}
+	mix := flags.String("mix", "top-up=40,spend=30,bonus=10,transfer=20", "relative weight of each operation")
+	output := outputFlag(flags)
+	check := flags.Bool("check", true, "verify ledger conservation after the run")
+	flags.StringVar(&cfg.Target, "target", "http://127.0.0.1:"+appEnv.Port, "base URL of the API")
+	flags.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to run; 0 runs until -requests are sent")
+	flags.IntVar(&cfg.Requests, "requests", 0, "stop after this many requests; 0 is unlimited")
+	flags.IntVar(&cfg.Concurrency, "concurrency", 32, "number of concurrent requests")
+	flags.Float64Var(&cfg.Rate, "rate", 0, "requests per second across all workers; 0 is unlimited")
+	flags.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "per-request timeout")
+	flags.IntVar(&cfg.Users, "users", 100, "number of users to create")
+	flags.IntVar(&cfg.Currencies, "currencies", 2, "number of currencies to create")
+	flags.Int64Var(&cfg.Supply, "supply", 1_000_000_000, "treasury supply per currency")
+	flags.Int64Var(&cfg.InitialBalance, "initial-balance", 1_000, "balance each user wallet starts with")
+	flags.Int64Var(&cfg.MaxAmount, "max-amount", 100, "largest amount per operation")
+	flags.Float64Var(&cfg.Skew, "skew", 0, "Zipf exponent for picking users; >1 concentrates load on hot wallets, 0 is uniform")
+	flags.Float64Var(&cfg.DuplicateRate, "duplicate-rate", 0.05, "fraction of requests that resend an earlier idempotency key")
+	flags.Int64Var(&cfg.Seed, "seed", time.Now().UnixNano(), "random seed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("output format must be table or json, got %q", *output)
+	}
+	var err error
+	if cfg.Mix, err = loadgen.ParseMix(*mix); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "creating %d users and %d currencies\n", cfg.Users, cfg.Currencies)
+	database := db.GetDB()
+	fixture, err := loadgen.Setup(ctx, database, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "run %s: sending load to %s\n", fixture.RunID, cfg.Target)
+	report, err := loadgen.Run(ctx, cfg, fixture)
+	if err != nil {
+		return err
+	}
+	if *check {
+		// the check must run even when the load was interrupted
+		if report.Conservation, err = loadgen.Check(consistency.WithPrimary(context.Background()), database, fixture); err != nil {
+			return fmt.Errorf("conservation check: %w", err)
+		}
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Conservation != nil && !report.Conservation.OK {
+		return fmt.Errorf("ledger conservation check failed for run %s", fixture.RunID)
+	}
+	return nil
+}
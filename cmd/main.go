@@ -1,56 +1,148 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 
-	"github.com/gin-gonic/gin"
 	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
 	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
-	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
-	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
-	"github.com/jay6909/dino-internal-wallet-service/internal/seed"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
 )
 
 var appEnv *config_env.AppEnv
 var db config_db.DB
 
-func main() {
-	var err error
-	appEnv, err = config_env.LoadAppEnv()
-	if err != nil {
-		panic(err)
-	}
+const usage = `usage: app [global flags] <command> [flags] [arguments]
 
-	db, err = config_db.NewGormDB(appEnv.DatabaseConfig.DSN)
-	if err != nil {
-		panic(err)
-	}
+commands:
+  serve                          run the HTTP API (the default without a command)
+  migrate up|down|to|status      manage the database schema
+  seed                           load the demo fixture, a fixture file or a synthetic dataset
+  user create|list               manage users
+  wallet show|freeze|unfreeze    inspect wallets and stop them from moving funds
+  currency create|list           manage currencies and their treasuries
+  mint, burn                     add to or remove from a treasury's supply
+  approval list|approve|reject   review admin operations waiting for a second admin
+  reconcile                      check every balance against the ledger
+  export <table>                 dump users, currencies, wallets or transactions
+  loadgen                        drive the API with synthetic traffic
+  config print                   show the effective configuration with secrets redacted
+  healthcheck [path]             probe the local API, for container health checks
 
-	//migrate db
-	db.Migrate()
+Settings come from defaults, a YAML or TOML file (-config or CONFIG_FILE), the
+environment (DATABASE_DSN, ...) and global flags (-database.dsn, ...), each
+overriding the one before; "app -h" lists them all.
+Most commands accept -o json for machine-readable output; "app <command> -h" lists flags.`
 
-	if appEnv.Seed {
-		seed.SeedDb()
-		fmt.Println("✅ Seeding complete")
+type command func(ctx context.Context, migrator *migrations.Migrator, args []string) error
 
+var commands = map[string]command{
+	"serve":     runServe,
+	"migrate":   runMigrate,
+	"seed":      runSeed,
+	"user":      runUser,
+	"wallet":    runWallet,
+	"currency":  runCurrency,
+	"mint":      runMint,
+	"burn":      runBurn,
+	"approval":  runApproval,
+	"reconcile": runReconcile,
+	"export":    runExport,
+	"loadgen":   runLoadgen,
+}
+
+func main() {
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	loadOptions := config_env.RegisterFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "%s\n\nglobal flags:\n", usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	name, args := "serve", []string{}
+	if flags.NArg() > 0 {
+		name, args = flags.Arg(0), flags.Args()[1:]
+	}
+	switch name {
+	case "healthcheck":
+		os.Exit(healthcheck(*loadOptions, args))
+	case "config":
+		os.Exit(runConfig(*loadOptions, args))
+	case "help":
+		flags.SetOutput(os.Stdout)
+		flags.Usage()
 		return
 	}
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+		os.Exit(2)
+	}
+	os.Exit(execute(name, run, args, *loadOptions))
+}
 
-	r := gin.Default()
+func execute(name string, run command, args []string, loadOptions config_env.LoadOptions) int {
+	var err error
+	appEnv, err = config_env.Load(loadOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 
-	//init repositories
-	userRepository := repository.NewUserRepository(db.GetDB())
-	walletRepository := repository.NewWalletRepository(db.GetDB())
+	// only the server logs to stdout; the other commands print their results there
+	var logOutput io.Writer = os.Stderr
+	if name == "serve" {
+		logOutput = os.Stdout
+	}
+	log := logger.New(appEnv.LogConfig, logOutput)
+	slog.SetDefault(log)
 
-	//init handlers
-	userHandler := handler.NewUserHandler(userRepository)
-	walletHandler := handler.NewWalletHandler(walletRepository, userRepository)
-	apiV1 := r.Group("/api/v1")
-	{
-		userHandler.RegisterRoutes(apiV1)
-		walletHandler.RegisterRoutes(apiV1)
+	db, err = config_db.NewGormDB(appEnv.DatabaseConfig, log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "database:", err)
+		return 1
+	}
+	migrator, err := migrations.New(db.GetDB(), log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrations:", err)
+		return 1
 	}
 
-	r.Run(fmt.Sprintf(":%s", appEnv.Port))
+	// commands other than serve act on what they just read, as do serve's
+	// migrations and startup; API requests choose per request
+	ctx := consistency.WithPrimary(context.Background())
+	// serve migrates first when asked to and migrate fixes the schema itself
+	if name != "serve" && name != "migrate" {
+		if err := migrator.CheckCurrent(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return 1
+		}
+	}
 
+	err = run(ctx, migrator, args)
+	if name != "serve" {
+		// serve closes the pool itself as the last step of its shutdown
+		db.Close()
+	}
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+const migrateUsage = `usage: app migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [n]           revert the last n applied migrations (default 1)
+  status [-o json]   list migrations and whether they are applied
+  to <version>       migrate up or down to exactly <version>`
+
+func runMigrate(ctx context.Context, migrator *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	var applied []migrations.Migration
+	var err error
+	switch args[0] {
+	case "up":
+		applied, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		applied, err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		applied, err = migrator.To(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, migrator, args[1:])
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := migrator.Current(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d migration(s) run, schema at version %d (latest %d)\n", len(applied), current, migrator.Latest())
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator, args []string) error {
+	flags := flag.NewFlagSet("migrate status", flag.ContinueOnError)
+	output := outputFlag(flags)
+	if err := parseFlags(flags, args, 0, "migrate status [-o json]"); err != nil {
+		return err
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return render(*output, statuses, func(w io.Writer) {
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = formatTime(*status.AppliedAt)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	})
+}
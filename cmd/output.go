@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFlag registers the -o flag shared by every command that prints records.
+func outputFlag(flags *flag.FlagSet) *string {
+	return flags.String("o", "table", "output format: table or json")
+}
+
+// render prints v as indented JSON, or hands a tab-aligned writer on stdout
+// to table. Tables use upper-case headers, like "migrate status".
+func render(format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+	return fmt.Errorf("output format must be table or json, got %q", format)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// parseFlags parses args and requires exactly wantArgs positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string, wantArgs int, usage string) error {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: app %s\n", usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != wantArgs {
+		flags.Usage()
+		return fmt.Errorf("expected %d argument(s), got %d", wantArgs, flags.NArg())
+	}
+	return nil
+}
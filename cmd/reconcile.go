@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+// runReconcile prints the reconciliation report and fails when the ledger
+// does not add up, so it can run as a scheduled check.
+func runReconcile(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	output := outputFlag(flags)
+	if err := parseFlags(flags, args, 0, "reconcile [flags]"); err != nil {
+		return err
+	}
+	report, err := admin.Reconcile(ctx, db.GetDB())
+	if err != nil {
+		return err
+	}
+	err = render(*output, report, func(w io.Writer) {
+		fmt.Fprintln(w, "CURRENCY\tWALLETS\tTOTAL BALANCE\tMINTED\tBURNED")
+		for _, c := range report.Currencies {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", c.Name, c.Wallets, c.TotalBalance, c.Minted, c.Burned)
+		}
+		if len(report.Drift) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "DRIFTED WALLET\tOWNER TYPE\tOWNER\tBALANCE\tLEDGER SUM")
+			for _, d := range report.Drift {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", d.ID, d.OwnerType, d.OwnerID, d.Balance, d.LedgerSum)
+			}
+		}
+		if len(report.Unbalanced) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "UNBALANCED REFERENCE\tTOTAL")
+			for _, u := range report.Unbalanced {
+				fmt.Fprintf(w, "%s\t%d\n", u.ReferenceID, u.Total)
+			}
+		}
+		if len(report.ReusedKeys) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "REUSED KEY\tREFERENCES")
+			for _, k := range report.ReusedKeys {
+				fmt.Fprintf(w, "%s\t%d\n", k.IdempotencyKey, k.References)
+			}
+		}
+		fmt.Fprintln(w)
+		if report.OK {
+			fmt.Fprintln(w, "ledger reconciles")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !report.OK {
+		return errors.New("ledger does not reconcile")
+	}
+	return nil
+}
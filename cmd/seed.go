@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"github.com/jay6909/dino-internal-wallet-service/internal/seed"
+)
+
+// runSeed loads the demo fixture, a fixture file or a generated synthetic
+// dataset. Seeding is idempotent, so it is safe to rerun after a failure.
+func runSeed(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	output := outputFlag(flags)
+	file := flags.String("file", "", "YAML or JSON fixture to load instead of a profile")
+	profile := flags.String("profile", "demo", "demo or synthetic")
+	users := flags.Int("users", 10_000, "synthetic: number of users")
+	currencies := flags.Int("currencies", 3, "synthetic: number of currencies")
+	balance := flags.Int64("balance", 1_000, "synthetic: initial balance of every wallet")
+	opts := seed.Options{}
+	flags.IntVar(&opts.Concurrency, "concurrency", 8, "wallets opened and funded in parallel")
+	flags.IntVar(&opts.BatchSize, "batch-size", 500, "users inserted per statement")
+	if err := parseFlags(flags, args, 0, "seed [-file fixture.yaml | -profile demo|synthetic] [flags]"); err != nil {
+		return err
+	}
+
+	var fixture *seed.Fixture
+	var err error
+	switch {
+	case *file != "":
+		if fixture, err = seed.Load(*file); err != nil {
+			return err
+		}
+	case *profile == "demo":
+		fixture = seed.Demo()
+	case *profile == "synthetic":
+		if *users < 1 || *currencies < 1 || *balance < 0 {
+			return fmt.Errorf("-users and -currencies must be positive and -balance not negative")
+		}
+		fixture = seed.Synthetic(*users, *currencies, *balance)
+	default:
+		return fmt.Errorf("-profile must be demo or synthetic, got %q", *profile)
+	}
+
+	result, err := seed.Apply(ctx, db.GetDB(), fixture, opts)
+	if err != nil {
+		return err
+	}
+	return render(*output, result, func(w io.Writer) {
+		fmt.Fprintln(w, "CURRENCIES\tMINTED\tUSERS\tWALLETS\tFUNDED")
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\n", result.Currencies, result.Minted, result.Users, result.Wallets, result.Funded)
+	})
+}
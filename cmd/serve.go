@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/bulk"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/escrow"
+	"github.com/jay6909/dino-internal-wallet-service/internal/events"
+	"github.com/jay6909/dino-internal-wallet-service/internal/grpcapi"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/health"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+	"github.com/jay6909/dino-internal-wallet-service/internal/limits"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"github.com/jay6909/dino-internal-wallet-service/internal/openapi"
+	"github.com/jay6909/dino-internal-wallet-service/internal/referral"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+	"github.com/jay6909/dino-internal-wallet-service/internal/schedule"
+	"github.com/jay6909/dino-internal-wallet-service/internal/seed"
+	"github.com/jay6909/dino-internal-wallet-service/internal/tracing"
+	"google.golang.org/grpc"
+	health_grpc "google.golang.org/grpc/health"
+)
+
+// streamBuffer is how many events a slow stream client may fall behind
+// before it is dropped and has to resume.
+const streamBuffer = 256
+
+// runServe runs the HTTP and gRPC APIs until SIGINT or SIGTERM, then drains it.
+func runServe(ctx context.Context, migrator *migrations.Migrator, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	log := slog.Default()
+
+	if appEnv.DatabaseConfig.AutoMigrate {
+		if _, err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("auto-migrate: %w", err)
+		}
+	}
+	// refuse to run against a schema older than this binary expects
+	if err := migrator.CheckCurrent(ctx); err != nil {
+		return fmt.Errorf("refusing to start: %w", err)
+	}
+
+	if appEnv.Seed || appEnv.SeedOnStart {
+		result, err := seed.Apply(ctx, db.GetDB(), seed.Demo(), seed.Options{})
+		if err != nil {
+			return fmt.Errorf("seed: %w", err)
+		}
+		log.Info("seeding complete", "result", result)
+		if !appEnv.SeedOnStart {
+			return nil
+		}
+	}
+
+	shutdownTracing, err := tracing.Init(ctx, appEnv.TracingConfig)
+	if err != nil {
+		return err
+	}
+	if err := tracing.InstrumentGorm(db.GetDB()); err != nil {
+		return err
+	}
+
+	r := gin.New()
+	r.Use(tracing.GinMiddleware(appEnv.TracingConfig.ServiceName))
+	r.Use(logger.GinMiddleware(log), logger.Recovery())
+	r.Use(metrics.GinMiddleware())
+	httpConfig := appEnv.HTTPConfig
+
+	//init repositories
+	userRepository := repository.NewUserRepository(db.GetDB())
+	walletRepository := repository.NewWalletRepository(db.GetDB())
+	currencyTypeRepository := repository.NewCurrencyTypeRepository(db.GetDB())
+	bulkJobRepository := repository.NewBulkJobRepository(db.GetDB())
+	scheduleRepository := repository.NewScheduleRepository(db.GetDB())
+	tradeRepository := repository.NewTradeRepository(db.GetDB())
+	campaignRepository := repository.NewCampaignRepository(db.GetDB())
+	referralRepository := repository.NewReferralRepository(db.GetDB())
+	riskRepository := repository.NewRiskRepository(db.GetDB())
+	approvalRepository := repository.NewApprovalRepository(db.GetDB())
+	var referralTerms *repository.ReferralTerms
+	if referrals := appEnv.ReferralConfig; referrals.Currency != "" {
+		currency, err := resolveCurrency(ctx, referrals.Currency)
+		if err != nil {
+			return fmt.Errorf("referrals: %w", err)
+		}
+		referralTerms = &repository.ReferralTerms{
+			CurrencyTypeID: currency.ID,
+			MinTopUp:       referrals.MinTopUp,
+			ReferrerReward: referrals.ReferrerReward,
+			RefereeReward:  referrals.RefereeReward,
+		}
+	}
+
+	var riskEvaluator risk.Evaluator
+	if appEnv.RiskConfig.Enabled {
+		riskEvaluator = risk.NewEngine(riskRepository, risk.Rules(appEnv.RiskConfig)...)
+	}
+
+	//init metrics
+	sqlDB, err := db.GetDB().DB()
+	if err != nil {
+		return err
+	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		return err
+	}
+	for i, replica := range db.Replicas() {
+		if err := metrics.RegisterReplicaDBStats(i+1, replica); err != nil {
+			return err
+		}
+	}
+	if err := metrics.RegisterTreasury(func() (map[string]int64, error) {
+		wallets, err := walletRepository.ListSystemWallets(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		balances := make(map[string]int64, len(wallets))
+		for _, wallet := range wallets {
+			balances[wallet.CurrencyTypeID.String()] = wallet.Balance
+		}
+		return balances, nil
+	}); err != nil {
+		return err
+	}
+	if appEnv.FeatureConfig.Metrics {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
+	lc := lifecycle.New()
+
+	//init handlers
+	checks := []health.Check{
+		health.DatabaseCheck(sqlDB),
+		health.MigrationCheck(migrator),
+		health.TreasuryCheck(currencyTypeRepository, walletRepository),
+		health.WorkersCheck(lc),
+	}
+	for i, replica := range db.Replicas() {
+		checks = append(checks, health.ReplicaCheck(i+1, replica))
+	}
+	healthHandler := handler.NewHealthHandler(lc, health.NewChecker(httpConfig.HealthCheckTimeout, checks...))
+	healthHandler.RegisterRoutes(r)
+	spec, err := openapi.Load()
+	if err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+	docsHandler, err := openapi.NewHandler(spec)
+	if err != nil {
+		return err
+	}
+	docsHandler.RegisterRoutes(r)
+	validateRequests, err := openapi.GinMiddleware(spec)
+	if err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+	userHandler := handler.NewUserHandler(userRepository)
+	walletHandler := handler.NewWalletHandler(walletRepository, userRepository).WithOptions(handler.WalletOptions{
+		MaxAmount: appEnv.LimitsConfig.MaxAmount,
+		Transfers: appEnv.FeatureConfig.Transfers,
+		Risk:      riskEvaluator,
+	})
+	apiV1 := r.Group("/api/v1",
+		limits.GinMiddleware(appEnv.LimitsConfig.MaxBodyBytes, httpConfig.RequestTimeout),
+		auth.GinMiddleware(auth.NewKeys(appEnv.AuthConfig.APIKeys)),
+		validateRequests,
+		consistency.GinMiddleware())
+	{
+		userHandler.RegisterRoutes(apiV1)
+		walletHandler.RegisterRoutes(apiV1)
+		handler.NewScheduleHandler(scheduleRepository, userRepository).WithMaxAmount(appEnv.LimitsConfig.MaxAmount).RegisterRoutes(apiV1)
+		handler.NewTradeHandler(tradeRepository, walletHandler.CheckUserWalletIfNotCreate).WithMaxAmount(appEnv.LimitsConfig.MaxAmount).RegisterRoutes(apiV1)
+		handler.NewCampaignHandler(campaignRepository, walletHandler.CheckUserWalletIfNotCreate).WithMaxAmount(appEnv.LimitsConfig.MaxAmount).RegisterRoutes(apiV1)
+		handler.NewReferralHandler(referralRepository).RegisterRoutes(apiV1)
+	}
+	// admin endpoints take the admins' own keys instead of the client keys,
+	// so that each decision is recorded under the admin who made it
+	if len(appEnv.AuthConfig.AdminKeys) > 0 {
+		adminV1 := r.Group("/api/v1/admin",
+			limits.GinMiddleware(appEnv.LimitsConfig.MaxBodyBytes, httpConfig.RequestTimeout),
+			auth.AdminGinMiddleware(auth.NewAdminKeys(appEnv.AuthConfig.Admins())),
+			validateRequests,
+			consistency.GinMiddleware())
+		handler.NewRiskHandler(riskRepository, walletHandler.Release).RegisterRoutes(adminV1)
+		handler.NewApprovalHandler(approvalRepository, walletRepository, walletHandler.CheckUserWalletIfNotCreate,
+			approval.NewPolicy(appEnv.ApprovalConfig)).RegisterRoutes(adminV1)
+	} else {
+		log.Warn("auth.admin_keys is empty, so /api/v1/admin is not served; risk reviews and approvals stay pending until an admin key is configured")
+	}
+	// uploads get their own body limit
+	uploads := r.Group("/api/v1",
+		limits.GinMiddleware(appEnv.LimitsConfig.MaxUploadBytes, httpConfig.RequestTimeout),
+		auth.GinMiddleware(auth.NewKeys(appEnv.AuthConfig.APIKeys)),
+		validateRequests,
+		consistency.GinMiddleware())
+	handler.NewBulkJobHandler(bulkJobRepository).WithMaxAmount(appEnv.LimitsConfig.MaxAmount).RegisterRoutes(uploads)
+	eventRepository := repository.NewWalletEventRepository(db.GetDB())
+	var poller *events.Poller
+	var broker *events.Broker
+	if interval := appEnv.WorkersConfig.EventPollInterval; interval > 0 {
+		broker = events.NewBroker(streamBuffer)
+		poller = events.NewPoller(eventRepository, broker, events.PollerOptions{Interval: interval})
+		// streams outlive the request timeout, so they skip the limits
+		streams := r.Group("/api/v1", auth.GinMiddleware(auth.NewKeys(appEnv.AuthConfig.APIKeys)), validateRequests)
+		handler.NewStreamHandler(broker, walletRepository, userRepository, eventRepository).RegisterRoutes(streams)
+	}
+
+	var grpcServer *grpc.Server
+	var grpcHealth *health_grpc.Server
+	if appEnv.GRPCConfig.Port != "" {
+		walletServer := grpcapi.NewWalletServer(walletRepository, userRepository).WithOptions(handler.WalletOptions{
+			MaxAmount: appEnv.LimitsConfig.MaxAmount,
+			Transfers: appEnv.FeatureConfig.Transfers,
+			Risk:      riskEvaluator,
+		})
+		grpcServer, grpcHealth = grpcapi.NewServer(walletServer, grpcapi.ServerOptions{
+			Logger:          log,
+			Keys:            auth.NewKeys(appEnv.AuthConfig.APIKeys),
+			RequestTimeout:  httpConfig.RequestTimeout,
+			MaxMessageBytes: appEnv.LimitsConfig.MaxBodyBytes,
+			Reflection:      appEnv.GRPCConfig.Reflection,
+		})
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%s", appEnv.Port),
+		Handler:           r,
+		ReadHeaderTimeout: httpConfig.ReadHeaderTimeout,
+		ReadTimeout:       httpConfig.ReadTimeout,
+		WriteTimeout:      httpConfig.WriteTimeout,
+		IdleTimeout:       httpConfig.IdleTimeout,
+	}
+	if broker != nil {
+		// open streams would otherwise keep Shutdown waiting for the timeout
+		srv.RegisterOnShutdown(broker.Close)
+	}
+	// claim the gRPC port before serving HTTP, so that a taken port stops
+	// startup with nothing serving yet
+	var grpcListener net.Listener
+	if grpcServer != nil {
+		if grpcListener, err = net.Listen("tcp", fmt.Sprintf(":%s", appEnv.GRPCConfig.Port)); err != nil {
+			err = fmt.Errorf("grpc: %w", err)
+			return errors.Join(err, shutdown(srv, nil, lc, shutdownTracing, appEnv.ShutdownConfig.Timeout))
+		}
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info("starting api", "port", appEnv.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	if grpcServer != nil {
+		go func() {
+			log.Info("starting grpc api", "port", appEnv.GRPCConfig.Port)
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serveErr <- fmt.Errorf("grpc: %w", err)
+			}
+		}()
+		grpcapi.SetServing(grpcHealth, true)
+	}
+	if interval := appEnv.WorkersConfig.ReconcileInterval; interval > 0 {
+		lc.Go("reconcile", admin.Reconciler(db.GetDB(), interval))
+	}
+	if poller != nil {
+		lc.Go("wallet_events", poller.Run)
+	}
+	// the outbox fills up with streaming off too
+	if retention := appEnv.WorkersConfig.EventRetention; retention > 0 {
+		lc.Go("wallet_event_retention", events.Pruner(eventRepository, retention))
+	}
+	if interval := appEnv.WorkersConfig.BulkPollInterval; interval > 0 {
+		processor := bulk.NewProcessor(bulkJobRepository, walletRepository, walletHandler.CheckUserWalletIfNotCreate, bulk.ProcessorOptions{
+			Interval:  interval,
+			ChunkSize: appEnv.WorkersConfig.BulkChunkSize,
+		})
+		lc.Go("bulk_jobs", processor.Run)
+	}
+	if interval := appEnv.WorkersConfig.SchedulePollInterval; interval > 0 {
+		runner := schedule.NewRunner(scheduleRepository, walletRepository, walletHandler.CheckUserWalletIfNotCreate, schedule.RunnerOptions{
+			Interval: interval,
+		})
+		lc.Go("schedules", runner.Run)
+	}
+	if interval := appEnv.WorkersConfig.TradeExpiryInterval; interval > 0 {
+		lc.Go("trade_expiry", escrow.Expirer(tradeRepository, interval))
+	}
+	if interval := appEnv.WorkersConfig.ReferralPollInterval; interval > 0 && referralTerms != nil {
+		lc.Go("referrals", referral.Qualifier(referralRepository, walletHandler.CheckUserWalletIfNotCreate, *referralTerms, interval))
+	}
+	if interval := appEnv.WorkersConfig.ApprovalExpiryInterval; interval > 0 {
+		lc.Go("approval_expiry", approval.Expirer(approvalRepository,
+			approval.Executor(walletRepository, walletHandler.CheckUserWalletIfNotCreate), interval))
+	}
+	lc.SetReady(true)
+
+	var serveFailed error
+	select {
+	case <-signalCtx.Done():
+		log.Info("shutdown signal received, draining", "drain_delay", appEnv.ShutdownConfig.DrainDelay)
+		// flip readiness first and keep serving while the load balancer notices
+		lc.SetReady(false)
+		if grpcHealth != nil {
+			grpcapi.SetServing(grpcHealth, false)
+		}
+		time.Sleep(appEnv.ShutdownConfig.DrainDelay)
+	case serveFailed = <-serveErr:
+		log.Error("api stopped", "error", serveFailed)
+	}
+
+	if err := shutdown(srv, grpcServer, lc, shutdownTracing, appEnv.ShutdownConfig.Timeout); err != nil {
+		log.Error("graceful shutdown incomplete", "error", err)
+		return errors.Join(serveFailed, err)
+	}
+	log.Info("shutdown complete")
+	return serveFailed
+}
+
+// shutdown stops accepting requests, waits for in-flight requests and
+// background workers, then flushes traces and closes the database pool.
+func shutdown(srv *http.Server, grpcServer *grpc.Server, lc *lifecycle.Manager, shutdownTracing func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	lc.SetReady(false)
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+		srv.Close()
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("grpc server: %w", ctx.Err()))
+			grpcServer.Stop()
+		}
+	}
+	if err := lc.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("background workers: %w", err))
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+	if err := db.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("database: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+func runMint(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	return changeSupply(ctx, enums.ApprovalKindMint, args)
+}
+
+func runBurn(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	return changeSupply(ctx, enums.ApprovalKindBurn, args)
+}
+
+// changeSupply proposes a mint into or burn from a currency's treasury and
+// applies it unless approvals.privileged or approvals.threshold make it
+// wait for another admin's "app approval approve". It is proposed by the
+// admin whose key is in $ADMIN_KEY. Rerunning it with the same -key does
+// nothing, so a failed call can safely be retried.
+func changeSupply(ctx context.Context, name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	currencyFlag := flags.String("currency", "", "currency name or ID (required)")
+	amount := flags.Int64("amount", 0, "amount to "+name+" (required)")
+	key := flags.String("key", "", "idempotency key; generated and printed when empty")
+	note := flags.String("note", "", "reason for the "+name+", kept with the proposal")
+	if err := parseFlags(flags, args, 0, name+" -currency <name|id> -amount <n> [-key <key>] [-note <text>]"); err != nil {
+		return err
+	}
+	if *currencyFlag == "" || *amount <= 0 {
+		return fmt.Errorf("-currency and a positive -amount are required")
+	}
+	admin, err := adminFromEnv()
+	if err != nil {
+		return err
+	}
+	currency, err := resolveCurrency(ctx, *currencyFlag)
+	if err != nil {
+		return err
+	}
+	if *key == "" {
+		*key = name + "-" + uuid.NewString()
+	}
+
+	wallets := repository.NewWalletRepository(db.GetDB())
+	policy := approval.NewPolicy(appEnv.ApprovalConfig)
+	var execute func(ctx context.Context, op *repository.PendingOperation) (string, error)
+	if !policy.NeedsApproval(name, *amount) {
+		execute = approval.Executor(wallets, openWallet(wallets))
+	}
+	op, _, err := repository.NewApprovalRepository(db.GetDB()).Propose(ctx, &repository.PendingOperation{
+		Kind:           name,
+		IdempotencyKey: *key,
+		CurrencyTypeID: currency.ID,
+		Amount:         *amount,
+		Note:           *note,
+		ProposedBy:     admin,
+		ExpiresAt:      time.Now().UTC().Add(policy.Expiry),
+	}, execute)
+	if err != nil {
+		return err
+	}
+	if op.Status != enums.ApprovalExecuted {
+		fmt.Printf("%s %d %s (key %s) is %s as operation %s; another admin approves it with\n  app approval approve %s\n",
+			name, *amount, currency.Name, *key, op.Status, op.ID, op.ID)
+		return nil
+	}
+	treasury, err := wallets.GetSystemWalletByCurrencyType(ctx, currency.ID.String())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %d %s (key %s); treasury balance is now %d\n", name, *amount, currency.Name, *key, treasury.Balance)
+	return nil
+}
+
+// openWallet finds or opens a user's wallet as the API does.
+func openWallet(wallets repository.WalletRepository) func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error) {
+	return handler.NewWalletHandler(wallets, repository.NewUserRepository(db.GetDB())).CheckUserWalletIfNotCreate
+}
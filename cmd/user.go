@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+const userUsage = `usage: app user <command>
+
+commands:
+  create -name <name> [-role user] [-id <uuid>]   create a user
+  list [-role <role>] [-limit 50] [-offset 0]     list users ordered by name`
+
+func runUser(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", userUsage)
+	}
+	users := repository.NewUserRepository(db.GetDB())
+	switch args[0] {
+	case "create":
+		return createUser(ctx, users, args[1:])
+	case "list":
+		return listUsers(ctx, users, args[1:])
+	}
+	return fmt.Errorf("unknown user command %q\n%s", args[0], userUsage)
+}
+
+func createUser(ctx context.Context, users repository.UserRepository, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	output := outputFlag(flags)
+	name := flags.String("name", "", "display name (required)")
+	role := flags.String("role", "user", "user or system")
+	id := flags.String("id", "", "user ID; generated when empty")
+	if err := parseFlags(flags, args, 0, "user create -name <name> [flags]"); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	if *role != "user" && *role != "system" {
+		return fmt.Errorf("-role must be user or system, got %q", *role)
+	}
+	user := repository.User{ID: uuid.New(), Name: *name, Role: *role}
+	if *id != "" {
+		parsed, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("-id must be a UUID: %w", err)
+		}
+		user.ID = parsed
+	}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		return err
+	}
+	return printUsers(*output, user, []repository.User{user})
+}
+
+func listUsers(ctx context.Context, users repository.UserRepository, args []string) error {
+	flags := flag.NewFlagSet("user list", flag.ContinueOnError)
+	output := outputFlag(flags)
+	role := flags.String("role", "", "only list users with this role")
+	limit := flags.Int("limit", 50, "maximum number of users")
+	offset := flags.Int("offset", 0, "number of users to skip")
+	if err := parseFlags(flags, args, 0, "user list [flags]"); err != nil {
+		return err
+	}
+	if *limit < 1 || *offset < 0 {
+		return fmt.Errorf("-limit must be positive and -offset not negative")
+	}
+	list, err := users.ListUsers(ctx, *role, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	return printUsers(*output, list, list)
+}
+
+func printUsers(format string, v any, users []repository.User) error {
+	return render(format, v, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tCREATED AT")
+		for _, user := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", user.ID, user.Name, user.Role, formatTime(user.CreatedAt))
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"gorm.io/gorm"
+)
+
+const walletUsage = `usage: app wallet <command>
+
+commands:
+  show [-transactions 10] <wallet-id|owner-id>   show a wallet, or every wallet of an owner
+  freeze <wallet-id>                             stop a wallet from sending or receiving funds
+  unfreeze <wallet-id>                           allow a frozen wallet to move funds again`
+
+type walletDetails struct {
+	Wallet       repository.Wallet              `json:"wallet"`
+	Transactions []repository.WalletTransaction `json:"transactions"`
+}
+
+func runWallet(ctx context.Context, _ *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", walletUsage)
+	}
+	wallets := repository.NewWalletRepository(db.GetDB())
+	switch args[0] {
+	case "show":
+		return showWallet(ctx, wallets, args[1:])
+	case "freeze":
+		return freezeWallet(ctx, wallets, args[1:], true)
+	case "unfreeze":
+		return freezeWallet(ctx, wallets, args[1:], false)
+	}
+	return fmt.Errorf("unknown wallet command %q\n%s", args[0], walletUsage)
+}
+
+func showWallet(ctx context.Context, wallets repository.WalletRepository, args []string) error {
+	flags := flag.NewFlagSet("wallet show", flag.ContinueOnError)
+	output := outputFlag(flags)
+	limit := flags.Int("transactions", 10, "number of recent transactions to show per wallet")
+	if err := parseFlags(flags, args, 1, "wallet show [flags] <wallet-id|owner-id>"); err != nil {
+		return err
+	}
+	id := flags.Arg(0)
+
+	var found []repository.Wallet
+	wallet, err := wallets.GetWalletByID(ctx, id)
+	switch {
+	case err == nil:
+		found = []repository.Wallet{*wallet}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if found, err = wallets.ListWalletsByOwner(ctx, id); err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("no wallet or owner with ID %s", id)
+		}
+	default:
+		return err
+	}
+
+	details := make([]walletDetails, 0, len(found))
+	for _, wallet := range found {
+		transactions, err := wallets.ListTransactions(ctx, wallet.ID.String(), *limit)
+		if err != nil {
+			return err
+		}
+		details = append(details, walletDetails{Wallet: wallet, Transactions: transactions})
+	}
+	return render(*output, details, func(w io.Writer) {
+		for i, d := range details {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "WALLET\tOWNER TYPE\tOWNER\tCURRENCY\tBALANCE\tFROZEN")
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\n", d.Wallet.ID, d.Wallet.OwnerType, d.Wallet.OwnerID,
+				d.Wallet.CurrencyTypeID, d.Wallet.Balance, d.Wallet.Frozen)
+			if len(d.Transactions) == 0 {
+				continue
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "CREATED AT\tTYPE\tAMOUNT\tBALANCE AFTER\tREFERENCE\tIDEMPOTENCY KEY")
+			for _, t := range d.Transactions {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", formatTime(t.CreatedAt), t.TransactionType,
+					t.Amount, t.BalanceAfter, t.ReferenceID, t.IdempotencyKey)
+			}
+		}
+	})
+}
+
+func freezeWallet(ctx context.Context, wallets repository.WalletRepository, args []string, frozen bool) error {
+	name, done := "unfreeze", "unfrozen"
+	if frozen {
+		name, done = "freeze", "frozen"
+	}
+	flags := flag.NewFlagSet("wallet "+name, flag.ContinueOnError)
+	if err := parseFlags(flags, args, 1, "wallet "+name+" <wallet-id>"); err != nil {
+		return err
+	}
+	if err := wallets.SetFrozen(ctx, flags.Arg(0), frozen); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no wallet with ID %s", flags.Arg(0))
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "wallet %s %s\n", flags.Arg(0), done)
+	return nil
+}
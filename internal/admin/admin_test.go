@@ -0,0 +1,117 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	db       *gorm.DB
+	wallets  repository.WalletRepository
+	currency uuid.UUID
+	treasury repository.Wallet
+	user     repository.Wallet
+}
+
+// newFixture mints 1000, tops a user up with 300 of it and burns 200 of the
+// rest.
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	f := fixture{db: db, wallets: repository.NewWalletRepository(db)}
+	f.currency, f.treasury = dbtest.Currency(t, db, "gold", 1000)
+	f.user = repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: uuid.New(), CurrencyTypeID: f.currency}
+	if err := f.wallets.CreateWallet(ctx, &f.user); err != nil {
+		t.Fatal(err)
+	}
+	err := f.wallets.Transfer(ctx, f.treasury.ID.String(), f.user.ID.String(), f.currency.String(), "top-up-1", 300, enums.TransactionTypeTopUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Burn(ctx, f.currency.String(), "burn-1", 200); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	report, err := admin.Reconcile(ctx, f.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Fatalf("clean ledger does not reconcile: %+v", report)
+	}
+	if len(report.Currencies) != 1 {
+		t.Fatalf("got %d currencies, want 1", len(report.Currencies))
+	}
+	supply := report.Currencies[0]
+	if supply.TotalBalance != 800 || supply.Minted != 1000 || supply.Burned != 200 || supply.Wallets != 2 {
+		t.Errorf("supply = %+v, want 2 wallets holding 800 with 1000 minted and 200 burned", supply)
+	}
+
+	// a balance changed outside the ledger is reported as drift
+	if err := f.db.Exec("UPDATE wallets SET balance = balance + 5 WHERE id = ?", f.user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if report, err = admin.Reconcile(ctx, f.db); err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || len(report.Drift) != 1 {
+		t.Fatalf("report = %+v, want one drifted wallet", report)
+	}
+	if drift := report.Drift[0]; drift.ID != f.user.ID || drift.Balance != 305 || drift.LedgerSum != 300 {
+		t.Errorf("drift = %+v, want wallet %s at 305 against a ledger sum of 300", drift, f.user.ID)
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	var out bytes.Buffer
+	count, err := admin.Export(ctx, f.db, "transactions", "csv", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if count != 4 || len(lines) != 5 {
+		t.Fatalf("exported %d rows in %d lines, want 4 rows and a header", count, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "id,wallet_id,transaction_type,amount") {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+
+	out.Reset()
+	if count, err = admin.Export(ctx, f.db, "wallets", "json", &out); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("exported %d wallets, want 2", count)
+	}
+	var wallet map[string]any
+	if err := json.NewDecoder(&out).Decode(&wallet); err != nil {
+		t.Fatal(err)
+	}
+	if wallet["currency_type_id"] != f.currency.String() {
+		t.Errorf("currency_type_id = %v, want %s", wallet["currency_type_id"], f.currency)
+	}
+
+	if _, err := admin.Export(ctx, f.db, "schema_migrations", "json", &out); err == nil {
+		t.Error("exporting an unknown table succeeded")
+	}
+}
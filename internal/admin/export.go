@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExportTables maps the names accepted by Export to their tables.
+var ExportTables = map[string]string{
+	"users":        "users",
+	"currencies":   "currency_types",
+	"wallets":      "wallets",
+	"transactions": "wallet_transactions",
+}
+
+// Export streams every row of the named table to w, ordered by creation
+// time, as JSON lines ("json") or CSV with a header row ("csv"). It returns
+// the number of rows written.
+func Export(ctx context.Context, db *gorm.DB, name, format string, w io.Writer) (int, error) {
+	table, ok := ExportTables[name]
+	if !ok {
+		return 0, fmt.Errorf("cannot export %q, want users, currencies, wallets or transactions", name)
+	}
+	if format != "json" && format != "csv" {
+		return 0, fmt.Errorf("export format must be json or csv, got %q", format)
+	}
+
+	rows, err := db.WithContext(ctx).Table(table).Order("created_at").Order("id").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	var csvWriter *csv.Writer
+	encoder := json.NewEncoder(w)
+	if format == "csv" {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		if csvWriter != nil {
+			record := make([]string, len(columns))
+			for i, value := range values {
+				record[i] = formatValue(value)
+			}
+			err = csvWriter.Write(record)
+		} else {
+			record := make(map[string]any, len(columns))
+			for i, column := range columns {
+				record[column] = normalizeValue(values[i])
+			}
+			err = encoder.Encode(record)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return count, csvWriter.Error()
+	}
+	return count, nil
+}
+
+// normalizeValue turns driver values into JSON-friendly ones; MySQL returns
+// text columns as bytes.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+func formatValue(value any) string {
+	switch v := normalizeValue(value).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
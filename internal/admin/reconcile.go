@@ -0,0 +1,114 @@
+// Package admin holds the operator tasks behind the CLI that work on the
+// ledger as a whole rather than through one wallet at a time.
+package admin
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+)
+
+// ReconcileReport lists every way the stored balances and the ledger disagree.
+type ReconcileReport struct {
+	OK         bool             `json:"ok"`
+	Currencies []CurrencySupply `json:"currencies"`
+	// Drift lists wallets whose balance is not the sum of their ledger legs,
+	// or is negative.
+	Drift []WalletDrift `json:"drift"`
+	// Unbalanced lists transfers whose legs do not net to zero.
+	Unbalanced []UnbalancedReference `json:"unbalanced_references"`
+	// ReusedKeys lists idempotency keys that were applied more than once.
+	ReusedKeys []ReusedKey `json:"reused_keys"`
+}
+
+type CurrencySupply struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Wallets      int64     `json:"wallets"`
+	TotalBalance int64     `json:"total_balance"`
+	Minted       int64     `json:"minted"`
+	Burned       int64     `json:"burned"`
+}
+
+type WalletDrift struct {
+	ID             uuid.UUID `json:"wallet_id"`
+	OwnerType      string    `json:"owner_type"`
+	OwnerID        uuid.UUID `json:"owner_id"`
+	CurrencyTypeID uuid.UUID `json:"currency_type_id"`
+	Balance        int64     `json:"balance"`
+	LedgerSum      int64     `json:"ledger_sum"`
+}
+
+type UnbalancedReference struct {
+	ReferenceID string `json:"reference_id"`
+	Total       int64  `json:"total"`
+}
+
+type ReusedKey struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	References     int64  `json:"references" gorm:"column:references_count"`
+}
+
+// Reconcile checks the whole ledger. A wallet must equal the sum of its
+// ledger legs, so balances written outside the ledger show up as drift.
+func Reconcile(ctx context.Context, db *gorm.DB) (*ReconcileReport, error) {
+	db = db.WithContext(ctx)
+	report := &ReconcileReport{}
+
+	err := db.Raw(`SELECT c.id AS id, c.name AS name, COUNT(w.id) AS wallets, COALESCE(SUM(w.balance), 0) AS total_balance
+		FROM currency_types c LEFT JOIN wallets w ON w.currency_type_id = c.id
+		GROUP BY c.id, c.name ORDER BY c.name`).Scan(&report.Currencies).Error
+	if err != nil {
+		return nil, err
+	}
+	var supply []struct {
+		CurrencyTypeID uuid.UUID
+		Minted         int64
+		Burned         int64
+	}
+	err = db.Raw(`SELECT w.currency_type_id AS currency_type_id,
+			COALESCE(SUM(CASE WHEN t.transaction_type = ? THEN t.amount ELSE 0 END), 0) AS minted,
+			COALESCE(SUM(CASE WHEN t.transaction_type = ? THEN -t.amount ELSE 0 END), 0) AS burned
+		FROM wallet_transactions t JOIN wallets w ON w.id = t.wallet_id
+		GROUP BY w.currency_type_id`, enums.TransactionTypeMint, enums.TransactionTypeBurn).Scan(&supply).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range supply {
+		for i := range report.Currencies {
+			if report.Currencies[i].ID == s.CurrencyTypeID {
+				report.Currencies[i].Minted, report.Currencies[i].Burned = s.Minted, s.Burned
+			}
+		}
+	}
+
+	err = db.Raw(`SELECT w.id AS id, w.owner_type AS owner_type, w.owner_id AS owner_id, w.currency_type_id AS currency_type_id,
+			w.balance AS balance, COALESCE(SUM(t.amount), 0) AS ledger_sum
+		FROM wallets w LEFT JOIN wallet_transactions t ON t.wallet_id = w.id
+		GROUP BY w.id, w.owner_type, w.owner_id, w.currency_type_id, w.balance
+		HAVING w.balance <> COALESCE(SUM(t.amount), 0) OR w.balance < 0
+		ORDER BY w.id`).Scan(&report.Drift).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Raw(`SELECT reference_id, SUM(amount) AS total FROM wallet_transactions
+		WHERE transaction_type NOT IN (?, ?)
+		GROUP BY reference_id HAVING SUM(amount) <> 0
+		ORDER BY reference_id`, enums.TransactionTypeMint, enums.TransactionTypeBurn).Scan(&report.Unbalanced).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Raw(`SELECT idempotency_key, COUNT(DISTINCT reference_id) AS references_count FROM wallet_transactions
+		GROUP BY idempotency_key HAVING COUNT(DISTINCT reference_id) > 1
+		ORDER BY idempotency_key`).Scan(&report.ReusedKeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report.OK = len(report.Drift) == 0 && len(report.Unbalanced) == 0 && len(report.ReusedKeys) == 0
+	return report, nil
+}
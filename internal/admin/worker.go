@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// Reconciler returns a background worker that reconciles the ledger every
+// interval, exports the result as metrics and logs when it does not add up.
+// A failed run is logged and retried on the next tick rather than stopping
+// the worker.
+func Reconciler(db *gorm.DB, interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			report, err := Reconcile(ctx, db)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.ErrorContext(ctx, "ledger reconciliation failed", "error", err)
+				}
+				continue
+			}
+			metrics.ObserveReconcile(len(report.Drift), len(report.Unbalanced), len(report.ReusedKeys))
+			if !report.OK {
+				slog.ErrorContext(ctx, "ledger does not reconcile",
+					"drifted_wallets", len(report.Drift),
+					"unbalanced_references", len(report.Unbalanced),
+					"reused_keys", len(report.ReusedKeys))
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package approval decides which admin operations need a second admin's
+// approval and applies operations to the ledger once approved.
+package approval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+// Policy says which operations need approval and how long they wait for
+// it.
+type Policy struct {
+	// Threshold makes every operation over this amount need approval; 0
+	// leaves it to Privileged.
+	Threshold int64
+	// Privileged kinds need approval whatever the amount.
+	Privileged []string
+	Expiry     time.Duration
+}
+
+func NewPolicy(cfg config_env.ApprovalConfig) Policy {
+	return Policy{Threshold: cfg.Threshold, Privileged: cfg.Privileged, Expiry: cfg.Expiry}
+}
+
+// NeedsApproval reports whether an operation of kind moving amount needs a
+// second admin.
+func (p Policy) NeedsApproval(kind string, amount int64) bool {
+	return slices.Contains(p.Privileged, kind) || p.Threshold > 0 && amount > p.Threshold
+}
+
+// Executor returns the function that applies an approved operation to the
+// ledger under its idempotency key and returns the reference ID of the
+// entry, as ApprovalRepository.Approve expects. openWallet finds or opens
+// the wallet of an adjusted user, such as
+// WalletHandler.CheckUserWalletIfNotCreate.
+func Executor(wallets repository.WalletRepository,
+	openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)) func(ctx context.Context, op *repository.PendingOperation) (string, error) {
+	return func(ctx context.Context, op *repository.PendingOperation) (string, error) {
+		currency := op.CurrencyTypeID.String()
+		var err error
+		switch op.Kind {
+		case enums.ApprovalKindMint:
+			err = wallets.Mint(ctx, currency, op.IdempotencyKey, op.Amount)
+		case enums.ApprovalKindBurn:
+			err = wallets.Burn(ctx, currency, op.IdempotencyKey, op.Amount)
+		case enums.ApprovalKindAdjustment:
+			treasury, err := wallets.GetSystemWalletByCurrencyType(ctx, currency)
+			if err != nil {
+				return "", err
+			}
+			wallet, err := openWallet(ctx, *op.OwnerID, op.CurrencyTypeID)
+			if err != nil {
+				return "", err
+			}
+			from, to := treasury.ID.String(), wallet.ID.String()
+			if op.Direction == enums.AdjustmentDebit {
+				from, to = to, from
+			}
+			if err := wallets.Transfer(ctx, from, to, currency, op.IdempotencyKey, op.Amount, enums.TransactionTypeAdjustment); err != nil {
+				return "", err
+			}
+		case enums.ApprovalKindTransfer:
+			err = wallets.Transfer(ctx, op.FromWalletID.String(), op.ToWalletID.String(), currency,
+				op.IdempotencyKey, op.Amount, enums.TransactionTypeAdjustment)
+		default:
+			return "", fmt.Errorf("cannot execute a %q operation", op.Kind)
+		}
+		if err != nil {
+			return "", err
+		}
+		transaction, err := wallets.GetTransactionByIdempotencyKey(ctx, op.IdempotencyKey)
+		if err != nil {
+			return "", err
+		}
+		return transaction.ReferenceID, nil
+	}
+}
@@ -0,0 +1,348 @@
+package approval_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fixture is a migrated SQLite database with a gold treasury of 1000 and a
+// player without a wallet yet.
+type fixture struct {
+	db         *gorm.DB
+	operations repository.ApprovalRepository
+	wallets    repository.WalletRepository
+	execute    func(ctx context.Context, op *repository.PendingOperation) (string, error)
+	currency   uuid.UUID
+	treasury   uuid.UUID
+	player     uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	f := &fixture{
+		db:         db,
+		operations: repository.NewApprovalRepository(db),
+		wallets:    repository.NewWalletRepository(db),
+		player:     uuid.New(),
+	}
+	f.execute = approval.Executor(f.wallets, handler.NewWalletHandler(f.wallets, repository.NewUserRepository(db)).CheckUserWalletIfNotCreate)
+	dbtest.Users(t, db, f.player)
+	var treasury repository.Wallet
+	f.currency, treasury = dbtest.Currency(t, db, "gold", 1000)
+	f.treasury = treasury.ID
+	return f
+}
+
+// adjustment credits or debits the player amount, proposed by ops-a.
+func (f *fixture) adjustment(direction string, amount int64) *repository.PendingOperation {
+	return &repository.PendingOperation{Kind: enums.ApprovalKindAdjustment, IdempotencyKey: uuid.NewString(),
+		CurrencyTypeID: f.currency, OwnerID: &f.player, Direction: direction, Amount: amount,
+		ProposedBy: "ops-a", ExpiresAt: time.Now().UTC().Add(time.Hour)}
+}
+
+func (f *fixture) actions(t *testing.T, op *repository.PendingOperation) []string {
+	t.Helper()
+	entries, err := f.operations.ListAudit(context.Background(), op.ID.String(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := make([]string, len(entries))
+	for i, entry := range entries {
+		actions[len(entries)-1-i] = entry.Action + " by " + entry.Actor
+	}
+	return actions
+}
+
+func (f *fixture) balance(t *testing.T) int64 {
+	t.Helper()
+	wallet, err := f.wallets.GetWalletByOwner(context.Background(), "user", f.player.String(), f.currency.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+func TestPolicy(t *testing.T) {
+	policy := approval.NewPolicy(config_env.ApprovalConfig{Threshold: 100, Privileged: []string{enums.ApprovalKindMint}})
+	for _, tt := range []struct {
+		kind   string
+		amount int64
+		want   bool
+	}{
+		{enums.ApprovalKindMint, 1, true},
+		{enums.ApprovalKindBurn, 100, false},
+		{enums.ApprovalKindBurn, 101, true},
+		{enums.ApprovalKindTransfer, 5000, true},
+	} {
+		if got := policy.NeedsApproval(tt.kind, tt.amount); got != tt.want {
+			t.Errorf("NeedsApproval(%s, %d) = %v, want %v", tt.kind, tt.amount, got, tt.want)
+		}
+	}
+	if approval.NewPolicy(config_env.ApprovalConfig{}).NeedsApproval(enums.ApprovalKindTransfer, 1<<40) {
+		t.Error("NeedsApproval without threshold or privileged kinds = true, want false")
+	}
+}
+
+func TestApprove(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	proposed, existing, err := f.operations.Propose(ctx, f.adjustment(enums.AdjustmentCredit, 300), nil)
+	if err != nil || existing || proposed.Status != enums.ApprovalPending {
+		t.Fatalf("Propose = %+v, %v, %v; want a new pending operation", proposed, existing, err)
+	}
+	if f.balance(t) != 0 {
+		t.Fatal("a pending adjustment moved funds")
+	}
+	if _, err := f.operations.Approve(ctx, proposed.ID.String(), "OPS-A ", "", f.execute); !errors.Is(err, repository.ErrSameAdmin) {
+		t.Errorf("Approve by the proposer = %v, want ErrSameAdmin", err)
+	}
+	approved, err := f.operations.Approve(ctx, proposed.ID.String(), "ops-b", "ticket 42", f.execute)
+	if err != nil || approved.Status != enums.ApprovalExecuted || approved.DecidedBy != "ops-b" || approved.ReferenceID == "" {
+		t.Fatalf("Approve = %+v, %v; want it executed", approved, err)
+	}
+	if f.balance(t) != 300 {
+		t.Errorf("balance %d, want the 300 credited", f.balance(t))
+	}
+	if _, err := f.operations.Approve(ctx, proposed.ID.String(), "ops-c", "", f.execute); !errors.Is(err, repository.ErrNotPending) {
+		t.Errorf("second approval = %v, want ErrNotPending", err)
+	}
+	retry, existing, err := f.operations.Propose(ctx, &repository.PendingOperation{Kind: proposed.Kind, IdempotencyKey: proposed.IdempotencyKey,
+		CurrencyTypeID: f.currency, OwnerID: &f.player, Direction: enums.AdjustmentCredit, Amount: 300, ProposedBy: "ops-a"}, f.execute)
+	if err != nil || !existing || retry.ID != proposed.ID || f.balance(t) != 300 {
+		t.Errorf("retried proposal = %+v, %v, %v; want the executed operation, applied once", retry, existing, err)
+	}
+	changed := f.adjustment(enums.AdjustmentCredit, 301)
+	changed.IdempotencyKey = proposed.IdempotencyKey
+	if _, _, err := f.operations.Propose(ctx, changed, nil); !errors.Is(err, repository.ErrIdempotencyKeyReused) {
+		t.Errorf("proposal reusing the key = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if got, want := f.actions(t, proposed), []string{"proposed by ops-a", "approved by ops-b", "executed by ops-b"}; !slices.Equal(got, want) {
+		t.Errorf("audit trail %q, want %q", got, want)
+	}
+}
+
+func TestFailedExecutionStaysPending(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	debit, _, err := f.operations.Propose(ctx, f.adjustment(enums.AdjustmentDebit, 50), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.operations.Approve(ctx, debit.ID.String(), "ops-b", "", f.execute); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("Approve of a debit beyond the balance = %v, want ErrInsufficientBalance", err)
+	}
+	if got, err := f.operations.GetOperation(ctx, debit.ID.String()); err != nil || got.Status != enums.ApprovalPending || got.DecidedBy != "" {
+		t.Errorf("operation after a failed execution %+v, %v; want it pending again", got, err)
+	}
+
+	// operations the policy lets through execute right away
+	credit, _, err := f.operations.Propose(ctx, f.adjustment(enums.AdjustmentCredit, 80), f.execute)
+	if err != nil || credit.Status != enums.ApprovalExecuted {
+		t.Fatalf("Propose without approval = %+v, %v; want it executed", credit, err)
+	}
+	executed, err := f.operations.Approve(ctx, debit.ID.String(), "ops-b", "", f.execute)
+	if err != nil || executed.Status != enums.ApprovalExecuted || f.balance(t) != 30 {
+		t.Errorf("Approve after the credit = %+v, %v with balance %d; want 30 left", executed, err, f.balance(t))
+	}
+	if got, want := f.actions(t, debit), []string{"proposed by ops-a", "approved by ops-b", "failed by ops-b",
+		"approved by ops-b", "executed by ops-b"}; !slices.Equal(got, want) {
+		t.Errorf("audit trail %q, want %q", got, want)
+	}
+}
+
+func TestFailedProposalIsFailed(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	debit := f.adjustment(enums.AdjustmentDebit, 50)
+	if _, _, err := f.operations.Propose(ctx, debit, f.execute); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("Propose of a debit beyond the balance = %v, want ErrInsufficientBalance", err)
+	}
+	failed, existing, err := f.operations.Propose(ctx, debit, f.execute)
+	if err != nil || !existing || failed.Status != enums.ApprovalFailed {
+		t.Fatalf("retried proposal = %+v, %v, %v; want the failed operation", failed, existing, err)
+	}
+	if pending, err := f.operations.ListOperations(ctx, repository.PendingOperationFilter{Status: enums.ApprovalPending}, 10); err != nil || len(pending) != 0 {
+		t.Errorf("pending operations %+v, %v; want none waiting for an approval it never needed", pending, err)
+	}
+	if _, err := f.operations.Approve(ctx, failed.ID.String(), "ops-b", "", f.execute); !errors.Is(err, repository.ErrNotPending) {
+		t.Errorf("Approve of a failed operation = %v, want ErrNotPending", err)
+	}
+	if got, want := f.actions(t, failed), []string{"proposed by ops-a", "failed by ops-a"}; !slices.Equal(got, want) {
+		t.Errorf("audit trail %q, want %q", got, want)
+	}
+}
+
+func TestResume(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	// a stopped process left these approved without executing them
+	stalled := map[string]*repository.PendingOperation{}
+	for _, tt := range []struct {
+		name, direction, decidedBy string
+		amount                     int64
+	}{
+		{"approved", enums.AdjustmentCredit, "ops-b", 70},
+		{"unapproved", enums.AdjustmentCredit, "", 30},
+		{"short", enums.AdjustmentDebit, "ops-b", 500},
+		{"recent", enums.AdjustmentCredit, "ops-b", 1},
+	} {
+		op, _, err := f.operations.Propose(ctx, f.adjustment(tt.direction, tt.amount), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		updatedAt := time.Now().UTC().Add(-time.Hour)
+		if tt.name == "recent" {
+			updatedAt = time.Now().UTC()
+		}
+		if err := f.db.Model(op).Updates(map[string]any{"status": enums.ApprovalApproved, "decided_by": tt.decidedBy,
+			"updated_at": updatedAt}).Error; err != nil {
+			t.Fatal(err)
+		}
+		stalled[tt.name] = op
+	}
+
+	resumed, err := f.operations.Resume(ctx, time.Now().UTC().Add(-time.Minute), 10, f.execute)
+	if err != nil || resumed != 2 {
+		t.Fatalf("Resume = %d, %v; want the two that can execute", resumed, err)
+	}
+	if f.balance(t) != 100 {
+		t.Errorf("balance %d, want both credits applied", f.balance(t))
+	}
+	for name, want := range map[string]string{"approved": enums.ApprovalExecuted, "unapproved": enums.ApprovalExecuted,
+		"short": enums.ApprovalPending, "recent": enums.ApprovalApproved} {
+		if got, err := f.operations.GetOperation(ctx, stalled[name].ID.String()); err != nil || got.Status != want {
+			t.Errorf("%s operation %+v, %v; want it %s", name, got, err, want)
+		}
+	}
+	for name, want := range map[string][]string{
+		"approved":   {"proposed by ops-a", "executed by ops-b"},
+		"unapproved": {"proposed by ops-a", "executed by ops-a"},
+		"short":      {"proposed by ops-a", "failed by ops-b"},
+	} {
+		if got := f.actions(t, stalled[name]); !slices.Equal(got, want) {
+			t.Errorf("%s audit trail %q, want %q", name, got, want)
+		}
+	}
+	if again, err := f.operations.Resume(ctx, time.Now().UTC().Add(-time.Minute), 10, f.execute); err != nil || again != 0 || f.balance(t) != 100 {
+		t.Errorf("second Resume = %d, %v with balance %d; want nothing left to execute", again, err, f.balance(t))
+	}
+}
+
+func TestRejectAndExpire(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	withdrawn, _, err := f.operations.Propose(ctx, f.adjustment(enums.AdjustmentCredit, 10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected, err := f.operations.Reject(ctx, withdrawn.ID.String(), "ops-a", "typo")
+	if err != nil || rejected.Status != enums.ApprovalRejected {
+		t.Errorf("Reject by the proposer = %+v, %v; want it withdrawn", rejected, err)
+	}
+	if _, err := f.operations.Approve(ctx, withdrawn.ID.String(), "ops-b", "", f.execute); !errors.Is(err, repository.ErrNotPending) {
+		t.Errorf("Approve of a rejected operation = %v, want ErrNotPending", err)
+	}
+
+	overdue := f.adjustment(enums.AdjustmentCredit, 10)
+	overdue.ExpiresAt = time.Now().UTC().Add(-time.Second)
+	late, _, err := f.operations.Propose(ctx, overdue, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.operations.Approve(ctx, late.ID.String(), "ops-b", "", f.execute); !errors.Is(err, repository.ErrOperationExpired) {
+		t.Errorf("Approve past the expiry = %v, want ErrOperationExpired", err)
+	}
+	stale := f.adjustment(enums.AdjustmentCredit, 10)
+	stale.ExpiresAt = time.Now().UTC().Add(time.Minute)
+	stale, _, err = f.operations.Propose(ctx, stale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := f.operations.Expire(ctx, time.Now().UTC(), 10); err != nil || expired != 0 {
+		t.Errorf("Expire before the expiry = %d, %v; want none", expired, err)
+	}
+	if expired, err := f.operations.Expire(ctx, time.Now().UTC().Add(2*time.Minute), 10); err != nil || expired != 1 {
+		t.Errorf("Expire after the expiry = %d, %v; want 1", expired, err)
+	}
+	for op, want := range map[*repository.PendingOperation][]string{
+		withdrawn: {"proposed by ops-a", "rejected by ops-a"},
+		late:      {"proposed by ops-a", "expired by "},
+		stale:     {"proposed by ops-a", "expired by "},
+	} {
+		if got := f.actions(t, op); !slices.Equal(got, want) {
+			t.Errorf("audit trail %q, want %q", got, want)
+		}
+	}
+	if f.balance(t) != 0 {
+		t.Errorf("balance %d, want nothing credited", f.balance(t))
+	}
+}
+
+func TestAuditIsAppendOnly(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	if _, _, err := f.operations.Propose(ctx, f.adjustment(enums.AdjustmentCredit, 10), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Exec("UPDATE approval_audit_entries SET actor = 'someone else'").Error; err == nil {
+		t.Error("updating an audit entry succeeded")
+	}
+	if err := f.db.Exec("DELETE FROM approval_audit_entries").Error; err == nil {
+		t.Error("deleting an audit entry succeeded")
+	}
+	if entries, err := f.operations.ListAudit(ctx, "", 10); err != nil || len(entries) != 1 || entries[0].Actor != "ops-a" {
+		t.Errorf("ListAudit = %+v, %v; want the proposal untouched", entries, err)
+	}
+}
+
+func TestExecutor(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	campaign := repository.Wallet{ID: uuid.New(), OwnerType: "campaign", OwnerID: uuid.New(), CurrencyTypeID: f.currency}
+	if err := f.wallets.CreateWallet(ctx, &campaign); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []*repository.PendingOperation{
+		{Kind: enums.ApprovalKindMint, CurrencyTypeID: f.currency, Amount: 500},
+		{Kind: enums.ApprovalKindBurn, CurrencyTypeID: f.currency, Amount: 200},
+		{Kind: enums.ApprovalKindTransfer, CurrencyTypeID: f.currency, FromWalletID: &f.treasury, ToWalletID: &campaign.ID, Amount: 300},
+	} {
+		op.IdempotencyKey, op.ProposedBy, op.ExpiresAt = uuid.NewString(), "ops-a", time.Now().UTC().Add(time.Hour)
+		if _, _, err := f.operations.Propose(ctx, op, f.execute); err != nil {
+			t.Fatalf("%s: %v", op.Kind, err)
+		}
+	}
+	treasury, err := f.wallets.GetWalletByID(ctx, f.treasury.String())
+	if err != nil || treasury.Balance != 1000 {
+		t.Errorf("treasury %+v, %v; want 1000 after +500, -200 and -300", treasury, err)
+	}
+	funded, err := f.wallets.GetWalletByID(ctx, campaign.ID.String())
+	if err != nil || funded.Balance != 300 {
+		t.Errorf("campaign wallet %+v, %v; want the 300 transferred", funded, err)
+	}
+}
@@ -0,0 +1,50 @@
+package approval
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+const batchSize = 100
+
+// stalledAfter is how long an operation stays approved before the worker
+// takes it for left behind by a stopped process; executing one takes a
+// single request.
+const stalledAfter = time.Minute
+
+// Expirer returns a background worker that every interval expires
+// operations left pending past their expiry and resumes, with execute,
+// those left approved by a process that stopped before executing them.
+// Approving or rejecting an overdue operation expires it too, so expiry
+// only keeps the queue tidy.
+func Expirer(operations repository.ApprovalRepository,
+	execute func(ctx context.Context, op *repository.PendingOperation) (string, error), interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx = consistency.WithPrimary(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			now := time.Now().UTC()
+			expired, err := operations.Expire(ctx, now, batchSize)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "expiring admin operations failed", "error", err)
+			}
+			resumed, err := operations.Resume(ctx, now.Add(-stalledAfter), batchSize, execute)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "resuming approved admin operations failed", "error", err)
+			}
+			if expired == batchSize || resumed == batchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
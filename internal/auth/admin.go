@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminContextKey holds the admin a request authenticated as.
+const adminContextKey = "auth.admin"
+
+// AdminKeys maps the keys of the admin API to the admins they belong to.
+// Unlike Keys, an empty set accepts nothing.
+type AdminKeys struct {
+	hashes [][sha256.Size]byte
+	admins []string
+}
+
+// NewAdminKeys takes each admin's key, keyed by admin.
+func NewAdminKeys(admins map[string]string) *AdminKeys {
+	k := &AdminKeys{}
+	for admin, key := range admins {
+		k.hashes = append(k.hashes, sha256.Sum256([]byte(key)))
+		k.admins = append(k.admins, admin)
+	}
+	return k
+}
+
+// Admin returns the admin key belongs to. Keys are compared as Keys.Valid
+// compares them, every one of them, in constant time.
+func (k *AdminKeys) Admin(key string) (string, bool) {
+	hash := sha256.Sum256([]byte(key))
+	match := -1
+	for i, h := range k.hashes {
+		if subtle.ConstantTimeCompare(hash[:], h[:]) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return "", false
+	}
+	return k.admins[match], true
+}
+
+// AdminGinMiddleware rejects requests without a valid admin key with 401
+// and otherwise makes the admin available to handlers through AdminFrom.
+func AdminGinMiddleware(keys *AdminKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, ok := keys.Admin(FromHeaders(c.Request.Header))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "missing or invalid admin key",
+			})
+			return
+		}
+		c.Set(adminContextKey, admin)
+		c.Next()
+	}
+}
+
+// AdminFrom returns the admin AdminGinMiddleware authenticated, or "" for
+// requests it did not see.
+func AdminFrom(c *gin.Context) string {
+	return c.GetString(adminContextKey)
+}
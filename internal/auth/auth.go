@@ -0,0 +1,64 @@
+// Package auth checks the static API keys configured in auth.api_keys and
+// the named admin keys in auth.admin_keys.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys is a set of accepted API keys. The zero value accepts every request.
+type Keys struct {
+	hashes [][sha256.Size]byte
+}
+
+func NewKeys(keys []string) *Keys {
+	k := &Keys{}
+	for _, key := range keys {
+		k.hashes = append(k.hashes, sha256.Sum256([]byte(key)))
+	}
+	return k
+}
+
+// Enabled reports whether any key is configured.
+func (k *Keys) Enabled() bool {
+	return len(k.hashes) > 0
+}
+
+// Valid reports whether key is one of the configured keys. Keys are compared
+// as hashes in constant time, so timing reveals neither content nor length.
+func (k *Keys) Valid(key string) bool {
+	hash := sha256.Sum256([]byte(key))
+	valid := 0
+	for _, h := range k.hashes {
+		valid |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	return valid == 1
+}
+
+// FromHeaders extracts the key from "Authorization: Bearer <key>" or
+// "X-API-Key: <key>".
+func FromHeaders(header http.Header) string {
+	if bearer, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(bearer)
+	}
+	return header.Get("X-API-Key")
+}
+
+// GinMiddleware rejects requests without a valid key with 401 when keys are
+// configured, and lets everything through otherwise.
+func GinMiddleware(keys *Keys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keys.Enabled() && !keys.Valid(FromHeaders(c.Request.Header)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "missing or invalid API key",
+			})
+			return
+		}
+		c.Next()
+	}
+}
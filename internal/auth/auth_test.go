@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const key = "0123456789abcdef"
+	tests := []struct {
+		name       string
+		keys       []string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{name: "no keys configured", wantStatus: http.StatusOK},
+		{name: "bearer", keys: []string{"other-key-0000000", key}, header: "Authorization", value: "Bearer " + key, wantStatus: http.StatusOK},
+		{name: "x-api-key", keys: []string{key}, header: "X-API-Key", value: key, wantStatus: http.StatusOK},
+		{name: "missing", keys: []string{key}, wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", keys: []string{key}, header: "X-API-Key", value: key + "x", wantStatus: http.StatusUnauthorized},
+		{name: "not bearer", keys: []string{key}, header: "Authorization", value: "Basic " + key, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(auth.GinMiddleware(auth.NewKeys(tt.keys)))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAdminGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := auth.NewAdminKeys(map[string]string{"dana": "dana-key-0123456789", "erin": "erin-key-0123456789"})
+	tests := []struct {
+		name       string
+		keys       *auth.AdminKeys
+		header     string
+		value      string
+		wantStatus int
+		wantAdmin  string
+	}{
+		{name: "bearer", keys: keys, header: "Authorization", value: "Bearer erin-key-0123456789", wantStatus: http.StatusOK, wantAdmin: "erin"},
+		{name: "x-api-key", keys: keys, header: "X-API-Key", value: "dana-key-0123456789", wantStatus: http.StatusOK, wantAdmin: "dana"},
+		{name: "missing", keys: keys, wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", keys: keys, header: "X-API-Key", value: "dana-key-01234567890", wantStatus: http.StatusUnauthorized},
+		{name: "no keys configured", keys: auth.NewAdminKeys(nil), header: "X-API-Key", value: "", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(auth.AdminGinMiddleware(tt.keys))
+			router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, auth.AdminFrom(c)) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantAdmin != "" && rec.Body.String() != tt.wantAdmin {
+				t.Errorf("admin = %q, want %q", rec.Body, tt.wantAdmin)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor is GinMiddleware for gRPC: it reads the key from the
+// "authorization" or "x-api-key" metadata and answers Unauthenticated. The
+// health service stays open, like /healthz and /readyz.
+func UnaryServerInterceptor(keys *Keys) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if keys.Enabled() && !strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			md, _ := metadata.FromIncomingContext(ctx)
+			header := make(http.Header, len(md))
+			for key, values := range md {
+				header[http.CanonicalHeaderKey(key)] = values
+			}
+			if !keys.Valid(FromHeaders(header)) {
+				return nil, status.Error(codes.Unauthenticated, "missing or invalid API key")
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies the same check to streams, which server
+// reflection uses.
+func StreamServerInterceptor(keys *Keys) grpc.StreamServerInterceptor {
+	unary := UnaryServerInterceptor(keys)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		_, err := unary(ss.Context(), nil, &grpc.UnaryServerInfo{FullMethod: info.FullMethod},
+			func(context.Context, any) (any, error) { return nil, handler(srv, ss) })
+		return err
+	}
+}
@@ -0,0 +1,416 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/bulk"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func TestParse(t *testing.T) {
+	owner, currency := uuid.NewString(), uuid.NewString()
+	tests := []struct {
+		name    string
+		format  string
+		upload  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "csv with reordered columns",
+			format: bulk.FormatCSV,
+			upload: "\ufeffamount,Owner_ID,idempotency_key,currency_type_id,note\n" +
+				"10," + owner + ",k1," + currency + ",welcome\n" +
+				"0," + owner + ",k2," + currency + "\n" +
+				"5,alice,k3," + currency + "\n" +
+				"7," + owner + ",k1," + currency + "\n" +
+				"2000," + owner + ",k4," + currency + "\n",
+			want: []string{
+				"2 pending",
+				"3 invalid: amount must be a positive whole number",
+				"4 invalid: owner_id must be a valid UUID",
+				"5 invalid: idempotency_key repeats line 2",
+				"6 invalid: amount exceeds the limit of 1000",
+			},
+		},
+		{
+			name:   "jsonl",
+			format: bulk.FormatJSONL,
+			upload: `{"owner_id":"` + owner + `","currency_type_id":"` + currency + `","amount":10,"idempotency_key":"k1"}` + "\n\n" +
+				`{"owner_id":"` + owner + `","currency_type_id":"` + currency + `","amount":1.5,"idempotency_key":"k2"}` + "\n" +
+				"owner_id,amount\n" +
+				`{"owner_id":"` + owner + `","currency_type_id":"` + currency + `","amount":"3"}`,
+			want: []string{
+				"1 pending",
+				"3 invalid: amount must be a positive whole number",
+				"4 invalid: line is not a JSON object with string ids and a numeric amount",
+				"5 invalid: idempotency_key is required",
+			},
+		},
+		{name: "csv without a required column", format: bulk.FormatCSV, upload: "owner_id,amount\n", wantErr: "header must name"},
+		{name: "csv with a malformed quote", format: bulk.FormatCSV, upload: "owner_id,currency_type_id,amount,idempotency_key\n\"a,b\n", wantErr: "quote"},
+		{name: "empty csv", format: bulk.FormatCSV, upload: "", wantErr: bulk.ErrNoRows.Error()},
+		{name: "header only", format: bulk.FormatCSV, upload: "owner_id,currency_type_id,amount,idempotency_key\n", wantErr: bulk.ErrNoRows.Error()},
+		{name: "blank jsonl", format: bulk.FormatJSONL, upload: "\n\n", wantErr: bulk.ErrNoRows.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := bulk.Parse(strings.NewReader(tt.upload), tt.format, 1000)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, row := range rows {
+				status := row.Status
+				if status == "" {
+					status = enums.BulkRowStatusPending
+				}
+				summary := strconv.Itoa(row.Line) + " " + status
+				if row.Error != "" {
+					summary += ": " + row.Error
+				}
+				got = append(got, summary)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// fixture is a migrated SQLite database with two users, a currency with a
+// treasury of 100 and a processor applying one row per chunk.
+type fixture struct {
+	db        *gorm.DB
+	jobs      repository.BulkJobRepository
+	wallets   repository.WalletRepository
+	users     repository.UserRepository
+	currency  uuid.UUID
+	alice     uuid.UUID
+	bob       uuid.UUID
+	processor *bulk.Processor
+	// onOpen runs before each wallet is opened
+	onOpen func(ownerID uuid.UUID)
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	f := &fixture{
+		db:      db,
+		jobs:    repository.NewBulkJobRepository(db),
+		wallets: repository.NewWalletRepository(db),
+		users:   repository.NewUserRepository(db),
+		alice:   uuid.New(),
+		bob:     uuid.New(),
+		onOpen:  func(uuid.UUID) {},
+	}
+	f.currency, _ = dbtest.Currency(t, db, "gold", 100)
+	dbtest.Users(t, db, f.alice, f.bob)
+	wallets := handler.NewWalletHandler(f.wallets, f.users)
+	f.processor = bulk.NewProcessor(f.jobs, f.wallets, func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error) {
+		f.onOpen(ownerID)
+		return wallets.CheckUserWalletIfNotCreate(ctx, ownerID, currencyTypeID)
+	}, bulk.ProcessorOptions{Interval: time.Hour, ChunkSize: 1})
+	return f
+}
+
+type credit struct {
+	owner  uuid.UUID
+	amount int64
+	key    string
+}
+
+func (f *fixture) upload(t *testing.T, credits ...credit) *repository.BulkJob {
+	t.Helper()
+	rows := make([]repository.BulkJobRow, len(credits))
+	for i, c := range credits {
+		rows[i] = repository.BulkJobRow{Line: i + 2, OwnerID: c.owner.String(), CurrencyTypeID: f.currency.String(),
+			Amount: c.amount, IdempotencyKey: c.key}
+	}
+	job := &repository.BulkJob{ID: uuid.New(), TransactionType: enums.TransactionTypeBonus}
+	if err := f.jobs.CreateJob(context.Background(), job, rows); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func (f *fixture) run(t *testing.T) {
+	t.Helper()
+	ran, err := f.processor.RunNext(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("RunNext = %v, %v; want a job run", ran, err)
+	}
+}
+
+func (f *fixture) job(t *testing.T, id uuid.UUID) *repository.BulkJob {
+	t.Helper()
+	job, err := f.jobs.GetJob(context.Background(), id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func (f *fixture) balance(t *testing.T, owner uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := f.wallets.GetWalletByOwner(context.Background(), "user", owner.String(), f.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+func (f *fixture) rows(t *testing.T, id uuid.UUID) map[string]repository.BulkJobRow {
+	t.Helper()
+	rows, err := f.jobs.ListRows(context.Background(), id.String(), "", 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := make(map[string]repository.BulkJobRow, len(rows))
+	for _, row := range rows {
+		byKey[row.IdempotencyKey] = row
+	}
+	return byKey
+}
+
+func TestProcessorRetriesWithoutDoubleCrediting(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	carol := uuid.New()
+	// applied before the upload, so its row is a replay
+	wallet, err := handler.NewWalletHandler(f.wallets, f.users).CheckUserWalletIfNotCreate(ctx, f.bob, f.currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury, err := f.wallets.GetSystemWalletByCurrencyType(ctx, f.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(), f.currency.String(), "early", 5, enums.TransactionTypeBonus); err != nil {
+		t.Fatal(err)
+	}
+
+	job := f.upload(t,
+		credit{f.alice, 10, "a"},
+		credit{carol, 20, "c"},
+		credit{f.bob, 5, "early"},
+		credit{f.alice, 500, "too-much"},
+	)
+	f.run(t)
+	got := f.job(t, job.ID)
+	if got.Status != enums.BulkJobStatusCompleted || got.SucceededRows != 2 || got.FailedRows != 2 || got.PendingRows != 0 {
+		t.Fatalf("job %+v, want completed with 2 succeeded and 2 failed", got)
+	}
+	rows := f.rows(t, job.ID)
+	if row := rows["early"]; !row.Replayed || row.ReferenceID == "" {
+		t.Errorf("pre-applied row %+v, want a replay with its reference", row)
+	}
+	if row := rows["c"]; row.Status != enums.BulkRowStatusFailed || row.Error != "owner not found" {
+		t.Errorf("unknown owner row %+v, want failed with owner not found", row)
+	}
+	if row := rows["too-much"]; row.Status != enums.BulkRowStatusFailed || row.Error != repository.ErrInsufficientBalance.Error() {
+		t.Errorf("overdraft row %+v, want failed with insufficient balance", row)
+	}
+
+	if _, err := f.jobs.Retry(ctx, uuid.NewString()); err == nil {
+		t.Error("retried a job that does not exist")
+	}
+	if err := f.users.CreateUser(ctx, &repository.User{ID: carol, Name: "Carol", Role: "user"}); err != nil {
+		t.Fatal(err)
+	}
+	retried, err := f.jobs.Retry(ctx, job.ID.String())
+	if err != nil || retried.Status != enums.BulkJobStatusPending || retried.PendingRows != 2 {
+		t.Fatalf("Retry = %+v, %v; want 2 rows pending", retried, err)
+	}
+	if _, err := f.jobs.Retry(ctx, job.ID.String()); !errors.Is(err, repository.ErrBulkJobActive) {
+		t.Errorf("second Retry = %v, want ErrBulkJobActive", err)
+	}
+	f.run(t)
+	got = f.job(t, job.ID)
+	if got.SucceededRows != 3 || got.FailedRows != 1 {
+		t.Errorf("after retry %+v, want 3 succeeded and 1 failed", got)
+	}
+	if row := f.rows(t, job.ID)["c"]; row.Status != enums.BulkRowStatusSucceeded || row.Attempts != 2 {
+		t.Errorf("retried row %+v, want succeeded on the second attempt", row)
+	}
+	for owner, want := range map[uuid.UUID]int64{f.alice: 10, f.bob: 5, carol: 20} {
+		if got := f.balance(t, owner); got != want {
+			t.Errorf("balance %d, want %d", got, want)
+		}
+	}
+
+	// a row that credited but was not recorded, as after a crash, is replayed
+	if err := f.jobs.SaveResults(ctx, []repository.BulkJobRow{{JobID: job.ID, Line: 2, Status: enums.BulkRowStatusFailed}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.jobs.Retry(ctx, job.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	f.run(t)
+	if row := f.rows(t, job.ID)["a"]; row.Status != enums.BulkRowStatusSucceeded || !row.Replayed {
+		t.Errorf("re-run row %+v, want a replay", row)
+	}
+	if got := f.balance(t, f.alice); got != 10 {
+		t.Errorf("balance after the re-run %d, want 10", got)
+	}
+}
+
+// TestProcessorRejectsReusedKeys uploads rows whose keys the ledger already
+// holds for other credits; they must fail rather than count as replays.
+func TestProcessorRejectsReusedKeys(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	wallets := handler.NewWalletHandler(f.wallets, f.users)
+	silver, _ := dbtest.Currency(t, f.db, "silver", 100)
+	for _, currency := range []uuid.UUID{f.currency, silver} {
+		wallet, err := wallets.CheckUserWalletIfNotCreate(ctx, f.alice, currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		treasury, err := f.wallets.GetSystemWalletByCurrencyType(ctx, currency.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{"same", "other-owner", "other-amount"}
+		if currency == silver {
+			keys = []string{"other-currency"}
+		}
+		for _, key := range keys {
+			if err := f.wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(), currency.String(), key, 7, enums.TransactionTypeBonus); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	job := f.upload(t,
+		credit{f.alice, 7, "same"},
+		credit{f.bob, 7, "other-owner"},
+		credit{f.alice, 8, "other-amount"},
+		credit{f.alice, 7, "other-currency"},
+		credit{f.alice, 100, "genesis-" + f.currency.String()},
+	)
+	f.run(t)
+	rows := f.rows(t, job.ID)
+	if row := rows["same"]; row.Status != enums.BulkRowStatusSucceeded || !row.Replayed {
+		t.Errorf("matching row %+v, want a replay", row)
+	}
+	for _, key := range []string{"other-owner", "other-amount", "other-currency", "genesis-" + f.currency.String()} {
+		if row := rows[key]; row.Status != enums.BulkRowStatusFailed || row.Error != "idempotency key reused" {
+			t.Errorf("row %s %+v, want failed with idempotency key reused", key, row)
+		}
+	}
+	if got := f.balance(t, f.alice); got != 21 {
+		t.Errorf("alice's balance %d, want 21", got)
+	}
+}
+
+func TestProcessorCancel(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	queued := f.upload(t, credit{f.alice, 1, "q1"})
+	cancelled, err := f.jobs.Cancel(ctx, queued.ID.String())
+	if err != nil || cancelled.Status != enums.BulkJobStatusCancelled {
+		t.Fatalf("Cancel = %+v, %v; want a queued job cancelled at once", cancelled, err)
+	}
+	if ran, err := f.processor.RunNext(ctx); ran || err != nil {
+		t.Fatalf("RunNext = %v, %v; want nothing to run", ran, err)
+	}
+
+	// cancelled while bob's row, the second chunk, is being applied
+	running := f.upload(t, credit{f.alice, 1, "r1"}, credit{f.bob, 1, "r2"}, credit{f.alice, 1, "r3"})
+	f.onOpen = func(owner uuid.UUID) {
+		if owner == f.bob {
+			job, err := f.jobs.Cancel(ctx, running.ID.String())
+			if err != nil || job.Status != enums.BulkJobStatusRunning || !job.CancelRequested {
+				t.Errorf("Cancel while running = %+v, %v; want a cancellation request", job, err)
+			}
+		}
+	}
+	f.run(t)
+	got := f.job(t, running.ID)
+	if got.Status != enums.BulkJobStatusCancelled || got.SucceededRows != 2 || got.PendingRows != 1 {
+		t.Fatalf("job %+v, want cancelled after its second row", got)
+	}
+
+	// retrying a cancelled job resumes its pending rows
+	f.onOpen = func(uuid.UUID) {}
+	for _, id := range []uuid.UUID{queued.ID, running.ID} {
+		if _, err := f.jobs.Retry(ctx, id.String()); err != nil {
+			t.Fatal(err)
+		}
+		f.run(t)
+		if got := f.job(t, id); got.Status != enums.BulkJobStatusCompleted || got.SucceededRows != got.TotalRows {
+			t.Errorf("resumed job %+v, want every row succeeded", got)
+		}
+	}
+	if _, err := f.jobs.Cancel(ctx, running.ID.String()); !errors.Is(err, repository.ErrBulkJobCompleted) {
+		t.Errorf("Cancel of a completed job = %v, want ErrBulkJobCompleted", err)
+	}
+	if got := f.balance(t, f.alice); got != 3 {
+		t.Errorf("balance %d, want 3", got)
+	}
+}
+
+func TestClaimHonoursLeases(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	job := f.upload(t, credit{f.alice, 1, "l1"})
+
+	claimed, err := f.jobs.Claim(ctx, "worker-a", time.Hour)
+	if err != nil || claimed == nil || claimed.ID != job.ID || claimed.StartedAt == nil {
+		t.Fatalf("Claim = %+v, %v; want the queued job", claimed, err)
+	}
+	if other, err := f.jobs.Claim(ctx, "worker-b", time.Hour); other != nil || err != nil {
+		t.Fatalf("second Claim = %+v, %v; want nothing while the lease holds", other, err)
+	}
+	if _, err := f.jobs.Checkpoint(ctx, job.ID.String(), "worker-b", time.Hour); !errors.Is(err, repository.ErrBulkJobLeaseLost) {
+		t.Errorf("Checkpoint by a non-owner = %v, want ErrBulkJobLeaseLost", err)
+	}
+
+	// an expired lease is taken over, and the old owner finds out
+	if _, err := f.jobs.Checkpoint(ctx, job.ID.String(), "worker-a", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	taken, err := f.jobs.Claim(ctx, "worker-b", time.Hour)
+	if err != nil || taken == nil || !taken.StartedAt.Equal(*claimed.StartedAt) {
+		t.Fatalf("Claim after expiry = %+v, %v; want the job with its original start", taken, err)
+	}
+	if err := f.jobs.Finish(ctx, job.ID.String(), "worker-a", enums.BulkJobStatusCompleted); !errors.Is(err, repository.ErrBulkJobLeaseLost) {
+		t.Errorf("Finish by the old owner = %v, want ErrBulkJobLeaseLost", err)
+	}
+
+	// a released job goes back to the queue
+	if err := f.jobs.Release(ctx, job.ID.String(), "worker-b"); err != nil {
+		t.Fatal(err)
+	}
+	f.run(t)
+	if got := f.job(t, job.ID); got.Status != enums.BulkJobStatusCompleted || got.SucceededRows != 1 {
+		t.Errorf("job %+v, want completed", got)
+	}
+}
@@ -0,0 +1,207 @@
+// Package bulk applies uploaded batches of credits, such as bonus campaigns
+// and airdrops, in the background. An upload is parsed into rows up front;
+// a Processor on every API instance claims queued jobs and applies their
+// rows through the ledger in chunks.
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+
+	maxKeyLength = 64
+)
+
+var ErrNoRows = errors.New("upload has no rows")
+
+var columns = []string{"owner_id", "currency_type_id", "amount", "idempotency_key"}
+
+// Parse reads a CSV upload with a header naming the columns, in any order,
+// or a JSONL upload of one object per line. Rows that fail validation are
+// returned as invalid with the reason, numbered by their line in the file;
+// only an unreadable file is an error. maxAmount caps each row's amount
+// unless it is 0.
+func Parse(r io.Reader, format string, maxAmount int64) ([]repository.BulkJobRow, error) {
+	var rows []repository.BulkJobRow
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = parseCSV(r)
+	case FormatJSONL:
+		rows, err = parseJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+	validate(rows, maxAmount)
+	return rows, nil
+}
+
+// record is a row's fields as uploaded.
+type record struct {
+	line           int
+	ownerID        string
+	currencyTypeID string
+	amount         string
+	idempotencyKey string
+}
+
+func parseCSV(r io.Reader) ([]repository.BulkJobRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		// spreadsheet exports often start with a byte order mark
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("header must name the columns %s", strings.Join(columns, ", "))
+		}
+	}
+	field := func(fields []string, column string) string {
+		if i := index[column]; i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	var rows []repository.BulkJobRow
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, record{
+			line:           line,
+			ownerID:        field(fields, "owner_id"),
+			currencyTypeID: field(fields, "currency_type_id"),
+			amount:         field(fields, "amount"),
+			idempotencyKey: field(fields, "idempotency_key"),
+		}.row())
+	}
+}
+
+func parseJSONL(r io.Reader) ([]repository.BulkJobRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var rows []repository.BulkJobRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object struct {
+			OwnerID        string      `json:"owner_id"`
+			CurrencyTypeID string      `json:"currency_type_id"`
+			Amount         json.Number `json:"amount"`
+			IdempotencyKey string      `json:"idempotency_key"`
+		}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			rows = append(rows, invalid(line, "line is not a JSON object with string ids and a numeric amount"))
+			continue
+		}
+		rows = append(rows, record{
+			line:           line,
+			ownerID:        strings.TrimSpace(object.OwnerID),
+			currencyTypeID: strings.TrimSpace(object.CurrencyTypeID),
+			amount:         object.Amount.String(),
+			idempotencyKey: strings.TrimSpace(object.IdempotencyKey),
+		}.row())
+	}
+	return rows, scanner.Err()
+}
+
+// row converts the record, keeping only the fields that parse.
+func (rec record) row() repository.BulkJobRow {
+	row := repository.BulkJobRow{Line: rec.line}
+	var problems []string
+	if id, err := uuid.Parse(rec.ownerID); err == nil {
+		row.OwnerID = id.String()
+	} else {
+		problems = append(problems, "owner_id must be a valid UUID")
+	}
+	if id, err := uuid.Parse(rec.currencyTypeID); err == nil {
+		row.CurrencyTypeID = id.String()
+	} else {
+		problems = append(problems, "currency_type_id must be a valid UUID")
+	}
+	if amount, err := strconv.ParseInt(rec.amount, 10, 64); err == nil && amount > 0 {
+		row.Amount = amount
+	} else {
+		problems = append(problems, "amount must be a positive whole number")
+	}
+	switch {
+	case rec.idempotencyKey == "":
+		problems = append(problems, "idempotency_key is required")
+	case len(rec.idempotencyKey) > maxKeyLength:
+		problems = append(problems, fmt.Sprintf("idempotency_key must be at most %d bytes", maxKeyLength))
+	default:
+		row.IdempotencyKey = rec.idempotencyKey
+	}
+	if len(problems) > 0 {
+		row.Status = enums.BulkRowStatusInvalid
+		row.Error = strings.Join(problems, "; ")
+	}
+	return row
+}
+
+func invalid(line int, reason string) repository.BulkJobRow {
+	return repository.BulkJobRow{Line: line, Status: enums.BulkRowStatusInvalid, Error: reason}
+}
+
+// validate applies the checks that span rows: the amount limit and keys
+// repeated within the upload, which the ledger would silently replay.
+func validate(rows []repository.BulkJobRow, maxAmount int64) {
+	seen := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if row.Status == enums.BulkRowStatusInvalid {
+			continue
+		}
+		if maxAmount > 0 && row.Amount > maxAmount {
+			row.Status = enums.BulkRowStatusInvalid
+			row.Error = fmt.Sprintf("amount exceeds the limit of %d", maxAmount)
+			continue
+		}
+		if first, ok := seen[row.IdempotencyKey]; ok {
+			row.Status = enums.BulkRowStatusInvalid
+			row.Error = fmt.Sprintf("idempotency_key repeats line %d", first)
+			continue
+		}
+		seen[row.IdempotencyKey] = row.Line
+	}
+}
@@ -0,0 +1,213 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultChunkSize = 500
+	defaultLease     = time.Minute
+	releaseTimeout   = 5 * time.Second
+)
+
+// OpenWallet returns the owner's wallet in a currency, opening it if needed.
+type OpenWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+
+type ProcessorOptions struct {
+	// Interval between looks for queued jobs.
+	Interval time.Duration
+	// ChunkSize is how many rows are applied between progress updates and
+	// cancellation checks; 0 is 500.
+	ChunkSize int
+	// Lease is how long a claimed job is held without a checkpoint before
+	// another instance may take it over; 0 is one minute.
+	Lease time.Duration
+}
+
+// Processor runs queued bulk jobs. Every row is applied with its own
+// idempotency key, so applying one again after a crash, a lost lease or a
+// retry is safe; see WalletRepository.Transfer. A key the ledger already
+// holds for a different credit fails the row.
+type Processor struct {
+	jobs       repository.BulkJobRepository
+	wallets    repository.WalletRepository
+	openWallet OpenWallet
+	options    ProcessorOptions
+	owner      string
+}
+
+func NewProcessor(jobs repository.BulkJobRepository, wallets repository.WalletRepository, openWallet OpenWallet, options ProcessorOptions) *Processor {
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = defaultChunkSize
+	}
+	if options.Lease <= 0 {
+		options.Lease = defaultLease
+	}
+	return &Processor{jobs: jobs, wallets: wallets, openWallet: openWallet, options: options, owner: lifecycle.InstanceID()}
+}
+
+// Run processes jobs until ctx is cancelled; it is meant for
+// lifecycle.Manager.Go.
+func (p *Processor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.options.Interval)
+	defer ticker.Stop()
+	for {
+		ran, err := p.RunNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "bulk job failed", "error", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext claims one job and works on it until it completes, is cancelled
+// or ctx is done, in which case the job goes back to the queue. It reports
+// whether there was a job to run.
+func (p *Processor) RunNext(ctx context.Context) (bool, error) {
+	// results are read back right after they are written
+	ctx = consistency.WithPrimary(ctx)
+	job, err := p.jobs.Claim(ctx, p.owner, p.options.Lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+	log := slog.With("bulk_job_id", job.ID.String())
+	log.InfoContext(ctx, "bulk job claimed", "pending_rows", job.PendingRows)
+	id := job.ID.String()
+	for {
+		if ctx.Err() != nil {
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+			defer cancel()
+			return true, p.jobs.Release(releaseCtx, id, p.owner)
+		}
+		job, err = p.jobs.Checkpoint(ctx, id, p.owner, p.options.Lease)
+		if err != nil {
+			return true, err
+		}
+		if job.CancelRequested {
+			log.InfoContext(ctx, "bulk job cancelled", "pending_rows", job.PendingRows)
+			return true, p.jobs.Finish(ctx, id, p.owner, enums.BulkJobStatusCancelled)
+		}
+		rows, err := p.jobs.PendingRows(ctx, id, p.options.ChunkSize)
+		if err != nil {
+			return true, err
+		}
+		if len(rows) == 0 {
+			if err := p.jobs.Finish(ctx, id, p.owner, enums.BulkJobStatusCompleted); err != nil {
+				return true, err
+			}
+			log.InfoContext(ctx, "bulk job completed",
+				"succeeded_rows", job.SucceededRows, "failed_rows", job.FailedRows, "invalid_rows", job.InvalidRows)
+			return true, nil
+		}
+		treasuries := make(map[string]*repository.Wallet)
+		applied := rows[:0]
+		for i := range rows {
+			if !p.apply(ctx, job.TransactionType, &rows[i], treasuries) {
+				break
+			}
+			applied = append(applied, rows[i])
+		}
+		// results are saved even when shutting down, so a restart does not
+		// have to replay them
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		err = p.jobs.SaveResults(saveCtx, applied)
+		cancel()
+		if err != nil {
+			return true, err
+		}
+	}
+}
+
+// errKeyReused fails a row whose idempotency key the ledger already holds
+// for another owner, currency or amount.
+var errKeyReused = errors.New("idempotency key reused")
+
+// apply credits one row from its currency's treasury and records the
+// outcome on it. It returns false, leaving the row pending, when ctx ended
+// before the outcome was known.
+func (p *Processor) apply(ctx context.Context, transactionType string, row *repository.BulkJobRow, treasuries map[string]*repository.Wallet) bool {
+	row.Attempts++
+	row.Replayed = false
+	legs, err := p.wallets.ListTransactionsByIdempotencyKey(ctx, row.IdempotencyKey)
+	replayed := err == nil && len(legs) > 0
+	if err == nil && !replayed {
+		err = p.credit(ctx, transactionType, row, treasuries)
+		if err == nil {
+			legs, err = p.wallets.ListTransactionsByIdempotencyKey(ctx, row.IdempotencyKey)
+		}
+	}
+	if err == nil {
+		// also catches a key applied elsewhere between the lookup and the credit
+		err = p.checkCredit(ctx, row, legs)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		row.Status, row.Error = enums.BulkRowStatusFailed, err.Error()
+		return true
+	}
+	row.Status, row.Error, row.ReferenceID, row.Replayed = enums.BulkRowStatusSucceeded, "", legs[0].ReferenceID, replayed
+	return true
+}
+
+// checkCredit returns errKeyReused unless legs are a transfer crediting the
+// row's amount to the row's owner in the row's currency.
+func (p *Processor) checkCredit(ctx context.Context, row *repository.BulkJobRow, legs []repository.WalletTransaction) error {
+	if len(legs) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	credit := legs[len(legs)-1]
+	if len(legs) != 2 || credit.Amount != row.Amount {
+		return errKeyReused
+	}
+	wallet, err := p.wallets.GetWalletByID(ctx, credit.WalletID.String())
+	if err != nil {
+		return err
+	}
+	if wallet.OwnerID.String() != row.OwnerID || wallet.CurrencyTypeID.String() != row.CurrencyTypeID {
+		return errKeyReused
+	}
+	return nil
+}
+
+func (p *Processor) credit(ctx context.Context, transactionType string, row *repository.BulkJobRow, treasuries map[string]*repository.Wallet) error {
+	treasury, ok := treasuries[row.CurrencyTypeID]
+	if !ok {
+		var err error
+		treasury, err = p.wallets.GetSystemWalletByCurrencyType(ctx, row.CurrencyTypeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("currency has no treasury wallet")
+		}
+		if err != nil {
+			return err
+		}
+		treasuries[row.CurrencyTypeID] = treasury
+	}
+	wallet, err := p.openWallet(ctx, uuid.MustParse(row.OwnerID), uuid.MustParse(row.CurrencyTypeID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("owner not found")
+	}
+	if err != nil {
+		return err
+	}
+	return p.wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(),
+		row.CurrencyTypeID, row.IdempotencyKey, row.Amount, enums.TransactionType(transactionType))
+}
@@ -1,40 +1,198 @@
 package config_db
 
 import (
-	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 type DB interface {
 	GetDB() *gorm.DB
-	Migrate()
+	// Replicas are the connection pools of the read replicas, if any.
+	Replicas() []*sql.DB
+	Close() error
+}
+
+type dbGorm struct {
+	db       *gorm.DB
+	replicas []*sql.DB
 }
 
-func (d *dbGorm) Migrate() {
-	if d.db == nil {
-		panic("gorm db is nil — migration aborted")
+func NewGormDB(cfg config_env.DbConfig, log *slog.Logger) (DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
 	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// map driver errors such as unique violations to gorm.ErrDuplicatedKey
+		TranslateError: true,
+		// parameterized so bound values never reach the logs
+		Logger: gormlogger.NewSlogLogger(log, gormlogger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, db.Dialector.Name(), cfg)
+	d := &dbGorm{db: db}
+	if len(cfg.ReplicaDSNs) > 0 {
+		if err := d.useReplicas(cfg); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
 
-	if err := d.db.AutoMigrate(
-		&repository.User{},
-		&repository.Wallet{},
-		&repository.WalletTransaction{},
-		&repository.CurrencyType{},
-	); err != nil {
-		panic(err)
+func configurePool(sqlDB *sql.DB, driver string, cfg config_env.DbConfig) {
+	if driver == "sqlite" {
+		// SQLite has no row locks. A single connection serializes every
+		// transaction, which stands in for SELECT ... FOR UPDATE, and keeps
+		// an in-memory database alive for the life of the pool.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+		return
 	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 }
 
-type dbGorm struct {
-	db *gorm.DB
+// useReplicas sends queries outside a transaction to the replicas, unless
+// their context asks for the primary (see consistency.WithPrimary) or they
+// lock rows. Transactions begin on the primary and keep every statement
+// there, so Transfer, Mint and Burn never touch a replica.
+func (d *dbGorm) useReplicas(cfg config_env.DbConfig) error {
+	driver := d.db.Dialector.Name()
+	replicas := make([]gorm.Dialector, 0, len(cfg.ReplicaDSNs))
+	for i, dsn := range cfg.ReplicaDSNs {
+		sqlDB, dialector, err := openReplica(driver, dsn)
+		if err != nil {
+			return fmt.Errorf("replica %d: %w", i+1, err)
+		}
+		configurePool(sqlDB, driver, cfg)
+		d.replicas = append(d.replicas, sqlDB)
+		replicas = append(replicas, dialector)
+	}
+	if err := d.db.Use(dbresolver.Register(dbresolver.Config{Replicas: replicas})); err != nil {
+		return err
+	}
+
+	routePrimary := func(db *gorm.DB) {
+		if consistency.Primary(db.Statement.Context) {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	// dbresolver claims the front of the chain, so this cannot be ordered
+	// before it; Write.ModifyStatement resolves the connection again anyway
+	callbacks := d.db.Callback()
+	return errors.Join(
+		callbacks.Query().Before("*").Register("wallet:read_your_writes", routePrimary),
+		callbacks.Row().Before("*").Register("wallet:read_your_writes", routePrimary),
+		callbacks.Raw().Before("*").Register("wallet:read_your_writes", routePrimary),
+	)
+}
+
+// openReplica opens the pool itself rather than leaving it to dbresolver, so
+// it can be sized, monitored and closed like the primary's.
+func openReplica(driver, dsn string) (*sql.DB, gorm.Dialector, error) {
+	var sqlDB *sql.DB
+	var err error
+	switch driver {
+	case "mysql":
+		sqlDB, err = sql.Open(mysql.DefaultDriverName, dsn)
+	case "postgres":
+		sqlDB, err = sql.Open("pgx", dsn)
+	case "sqlite":
+		sqlDB, err = sql.Open(sqlite.DriverName, sqliteDSN(dsn))
+	default:
+		return nil, nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	switch driver {
+	case "mysql":
+		return sqlDB, mysql.New(mysql.Config{Conn: sqlDB}), nil
+	case "postgres":
+		return sqlDB, postgres.New(postgres.Config{Conn: sqlDB}), nil
+	}
+	return sqlDB, &sqlite.Dialector{Conn: sqlDB}, nil
 }
 
-func NewGormDB(dsn string) (DB, error) {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	return &dbGorm{db: db}, err
+func newDialector(cfg config_env.DbConfig) (gorm.Dialector, error) {
+	driver := cfg.Driver
+	if driver == "" {
+		driver = config_env.DetectDriver(cfg.DSN)
+	}
+	switch driver {
+	case "mysql":
+		return mysql.Open(cfg.DSN), nil
+	case "postgres":
+		return postgres.Open(cfg.DSN), nil
+	case "sqlite":
+		return sqlite.Open(sqliteDSN(cfg.DSN)), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
 }
 
 func (d *dbGorm) GetDB() *gorm.DB {
 	return d.db
 }
+
+func (d *dbGorm) Replicas() []*sql.DB {
+	return d.replicas
+}
+
+// Close closes the connection pools of the primary and the replicas.
+func (d *dbGorm) Close() error {
+	sqlDB, err := d.db.DB()
+	if err != nil {
+		return err
+	}
+	errs := []error{sqlDB.Close()}
+	for _, replica := range d.replicas {
+		errs = append(errs, replica.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// sqliteDSN enables foreign keys and a busy timeout, so a second process such
+// as `migrate` waits for the file lock instead of failing, unless the DSN
+// already sets pragmas of its own.
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "_pragma=") {
+		return dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+}
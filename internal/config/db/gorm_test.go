@@ -0,0 +1,105 @@
+package config_db_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T, cfg config_env.DbConfig) config_db.DB {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	database, err := config_db.NewGormDB(cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	migrator, err := migrations.New(database.GetDB(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(consistency.WithPrimary(context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// TestReplicaRouting uses a second, never replicated database as the
+// replica, so every read it answers is visibly stale.
+func TestReplicaRouting(t *testing.T) {
+	dir := t.TempDir()
+	replica := filepath.Join(dir, "replica.db")
+	openDB(t, config_env.DbConfig{Driver: "sqlite", DSN: replica})
+	database := openDB(t, config_env.DbConfig{
+		Driver:      "sqlite",
+		DSN:         filepath.Join(dir, "primary.db"),
+		ReplicaDSNs: []string{replica},
+	})
+	if len(database.Replicas()) != 1 {
+		t.Fatalf("got %d replica pools, want 1", len(database.Replicas()))
+	}
+
+	ctx := context.Background()
+	wallets := repository.NewWalletRepository(database.GetDB())
+	currency := repository.CurrencyType{ID: uuid.New(), Name: "gold"}
+	if err := repository.NewCurrencyTypeRepository(database.GetDB()).CreateCurrencyType(ctx, &currency); err != nil {
+		t.Fatal(err)
+	}
+	treasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: currency.ID}
+	user := repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: uuid.New(), CurrencyTypeID: currency.ID}
+	for _, wallet := range []*repository.Wallet{&treasury, &user} {
+		if err := wallets.CreateWallet(ctx, wallet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wallets.Mint(ctx, currency.ID.String(), "mint", 100); err != nil {
+		t.Fatal(err)
+	}
+	// the transaction reads and locks the wallets it moves, so it fails
+	// unless it runs on the primary
+	if err := wallets.Transfer(ctx, treasury.ID.String(), user.ID.String(), currency.ID.String(), "fund", 40, enums.TransactionTypeTopUp); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	if _, err := wallets.GetWalletByID(ctx, user.ID.String()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("read without override: got %v, want the replica's not found", err)
+	}
+	wallet, err := wallets.GetWalletByID(consistency.WithPrimary(ctx), user.ID.String())
+	if err != nil {
+		t.Fatalf("read your writes: %v", err)
+	}
+	if wallet.Balance != 40 {
+		t.Errorf("balance %d on the primary, want 40", wallet.Balance)
+	}
+	history, err := wallets.ListTransactions(consistency.WithPrimary(ctx), user.ID.String(), 10)
+	if err != nil || len(history) != 1 {
+		t.Errorf("history on the primary: %d entries, %v; want 1", len(history), err)
+	}
+}
+
+func TestWithoutReplicas(t *testing.T) {
+	database := openDB(t, config_env.DbConfig{Driver: "sqlite", DSN: ":memory:"})
+	if len(database.Replicas()) != 0 {
+		t.Fatalf("got %d replica pools, want none", len(database.Replicas()))
+	}
+	users := repository.NewUserRepository(database.GetDB())
+	user := repository.User{ID: uuid.New(), Name: "ada", Role: "user"}
+	if err := users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetUserByID(context.Background(), user.ID.String()); err != nil {
+		t.Errorf("read without replicas: %v", err)
+	}
+}
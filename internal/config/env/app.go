@@ -1,36 +1,519 @@
 package config_env
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/uuid"
+	"github.com/pelletier/go-toml/v2"
 )
 
 type AppEnv struct {
-	Port           string
-	Seed           bool
+	Port string
+	Seed bool
+	// SeedOnStart seeds the database and then keeps serving, unlike Seed
+	// which exits after seeding. Meant for throwaway in-memory databases.
+	SeedOnStart    bool
 	DatabaseConfig DbConfig
+	HTTPConfig     HTTPConfig
+	GRPCConfig     GRPCConfig
+	TracingConfig  TracingConfig
+	LogConfig      LogConfig
+	ShutdownConfig ShutdownConfig
+	FeatureConfig  FeatureConfig
+	LimitsConfig   LimitsConfig
+	AuthConfig     AuthConfig
+	WorkersConfig  WorkersConfig
+	ReferralConfig ReferralConfig
+	RiskConfig     RiskConfig
+	ApprovalConfig ApprovalConfig
+
+	// File is the config file the settings were read from, if any.
+	File    string
+	sources map[string]string
 }
 
 type DbConfig struct {
-	DSN string
+	// Driver is "mysql", "postgres" or "sqlite". When empty it is inferred from DSN.
+	Driver string
+	DSN    string
+	// AutoMigrate applies pending migrations on startup instead of refusing to start.
+	AutoMigrate bool
+	// ReplicaDSNs are read replicas of the primary, using the same driver.
+	// Balance and history reads are spread over them; transactions and
+	// requests that need to read their own writes stay on the primary.
+	ReplicaDSNs []string
+	// Pool settings, applied to the primary and to each replica; zero values
+	// keep the database/sql defaults. SQLite always uses a single connection.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// RequestTimeout is the deadline on the context of every API request.
+	RequestTimeout     time.Duration
+	HealthCheckTimeout time.Duration
+}
+
+type GRPCConfig struct {
+	// Port serves the wallet.v1 gRPC API; empty disables it.
+	Port       string
+	Reflection bool
+}
+
+type LogConfig struct {
+	// Level is one of "debug", "info", "warn" or "error".
+	Level string
+	// Format is "json" or "text".
+	Format string
+}
+
+type ShutdownConfig struct {
+	// DrainDelay is how long readiness reports not-ready before the server
+	// stops accepting connections, so load balancers can drain it.
+	DrainDelay time.Duration
+	// Timeout bounds how long in-flight requests and workers are awaited.
+	Timeout time.Duration
+}
+
+type TracingConfig struct {
+	ServiceName string
+	// Exporter is one of "none", "otlp", "stdout" or "file".
+	Exporter     string
+	OTLPEndpoint string
+	FilePath     string
+	SampleRatio  float64
 }
 
+type FeatureConfig struct {
+	Transfers bool
+	Metrics   bool
+}
+
+type LimitsConfig struct {
+	// MaxAmount caps the amount of a single top-up, spend, bonus or transfer.
+	MaxAmount    int64
+	MaxBodyBytes int64
+	// MaxUploadBytes bounds bulk job uploads instead of MaxBodyBytes.
+	MaxUploadBytes int64
+}
+
+type AuthConfig struct {
+	// APIKeys are accepted as "Authorization: Bearer <key>" or "X-API-Key".
+	// Authentication is off while the list is empty.
+	APIKeys []string
+	// AdminKeys are the keys of the admin API, each "<admin>:<key>". The
+	// admin a key names is the one recorded as proposing, approving or
+	// reviewing, so every admin needs a key of their own. The admin API is
+	// not served while the list is empty.
+	AdminKeys []string
+}
+
+// Admins maps each admin in AdminKeys to their key.
+func (c AuthConfig) Admins() map[string]string {
+	admins := make(map[string]string, len(c.AdminKeys))
+	for _, entry := range c.AdminKeys {
+		admin, key, _ := strings.Cut(entry, ":")
+		admins[admin] = key
+	}
+	return admins
+}
+
+type WorkersConfig struct {
+	ReconcileInterval time.Duration
+	// EventPollInterval paces the wallet event poller behind the balance
+	// streams; 0 disables both.
+	EventPollInterval time.Duration
+	EventRetention    time.Duration
+	// BulkPollInterval paces the bulk job processor; 0 leaves this instance
+	// out of processing, though it still accepts uploads.
+	BulkPollInterval time.Duration
+	BulkChunkSize    int
+	// SchedulePollInterval paces the runner of scheduled transfers; 0 leaves
+	// them to other instances.
+	SchedulePollInterval time.Duration
+	// TradeExpiryInterval paces the refunds of trades left open past their
+	// expiry; 0 leaves them to other instances.
+	TradeExpiryInterval time.Duration
+	// ReferralPollInterval paces the payout of referrals whose invitee
+	// topped up; 0 leaves them to other instances.
+	ReferralPollInterval time.Duration
+	// ApprovalExpiryInterval paces the expiry of operations left pending
+	// approval and the execution of those left approved by a stopped
+	// instance; 0 leaves both to other instances.
+	ApprovalExpiryInterval time.Duration
+}
+
+type ReferralConfig struct {
+	// Currency is the name or ID of the currency referral rewards are paid
+	// in; empty turns the payouts off, though referrals are still recorded.
+	Currency string
+	// MinTopUp is the smallest first top-up of an invitee that qualifies.
+	MinTopUp       int64
+	ReferrerReward int64
+	RefereeReward  int64
+}
+
+// RiskConfig sets the rules spends and transfers are screened by. A rule
+// with a zero threshold is off.
+type RiskConfig struct {
+	// Enabled screens every spend and transfer and records each decision.
+	Enabled bool
+	// VelocityLimit denies an owner's operation once they attempted this
+	// many within VelocityWindow.
+	VelocityLimit  int64
+	VelocityWindow time.Duration
+	// UnusualAmountFactor holds for review amounts over this many times the
+	// owner's average debit in the currency within HistoryWindow, once they
+	// have UnusualAmountMinHistory debits.
+	UnusualAmountFactor     float64
+	UnusualAmountMinHistory int64
+	HistoryWindow           time.Duration
+	// NewAccountAge holds for review amounts over NewAccountMaxAmount from
+	// owners whose user is younger.
+	NewAccountAge       time.Duration
+	NewAccountMaxAmount int64
+	// BlockedOwners are denied every spend and transfer, whether they send
+	// or receive.
+	BlockedOwners []string
+}
+
+// ApprovalConfig sets which admin operations need a second admin's approval
+// before they move funds.
+type ApprovalConfig struct {
+	// Threshold makes every operation over this amount need approval; 0
+	// leaves it to Privileged.
+	Threshold int64
+	// Privileged are the kinds of operation that need approval whatever the
+	// amount: mint, burn, adjustment or transfer.
+	Privileged []string
+	// Expiry is how long an operation waits for approval.
+	Expiry time.Duration
+}
+
+// Setting is one resolved configuration value, for display.
+type Setting struct {
+	Key string `json:"key"`
+	// Value has secrets redacted.
+	Value string `json:"value"`
+	// Source is "default", "file", "env", "flag" or, for a driver derived
+	// from the DSN, "inferred".
+	Source string `json:"source"`
+	Env    string `json:"env"`
+}
+
+// LoadOptions are the inputs beyond the environment.
+type LoadOptions struct {
+	// File is a YAML or TOML config file. When empty, CONFIG_FILE is used,
+	// and without either only defaults, environment and flags apply.
+	File string
+	// Flags maps setting keys to values given on the command line.
+	Flags map[string]string
+}
+
+// Error lists every problem found while loading the configuration.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// LoadAppEnv loads the configuration from the environment alone.
 func LoadAppEnv() (*AppEnv, error) {
-	db_dsn := os.Getenv("DATABASE_DSN")
-	appPort := os.Getenv("APP_PORT")
-	seed := os.Getenv("SEED") == "true"
-
-	if appPort == "" {
-		appPort = "8080"
-	}
-	if db_dsn == "" {
-		return nil, fmt.Errorf("DATABASE_DSN")
-	}
-	return &AppEnv{
-		Port: appPort,
-		Seed: seed,
-		DatabaseConfig: DbConfig{
-			DSN: db_dsn,
-		},
-	}, nil
+	return Load(LoadOptions{})
+}
+
+// Load resolves every setting from, in increasing order of precedence, its
+// default, the config file, the environment and the command line, then
+// validates the result. All problems are reported at once as an *Error.
+func Load(opts LoadOptions) (*AppEnv, error) {
+	cfg := defaults()
+	cfg.sources = make(map[string]string, len(settings))
+	var problems []string
+
+	cfg.File = opts.File
+	if cfg.File == "" {
+		cfg.File = os.Getenv("CONFIG_FILE")
+	}
+	if cfg.File != "" {
+		values, err := readFile(cfg.File)
+		if err != nil {
+			return nil, &Error{Problems: []string{err.Error()}}
+		}
+		for _, s := range settings {
+			raw, ok := values[s.key]
+			if !ok {
+				continue
+			}
+			delete(values, s.key)
+			if err := s.set(cfg, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s in %s %v", s.key, cfg.File, err))
+				continue
+			}
+			cfg.sources[s.key] = "file"
+		}
+		for _, key := range sortedKeys(values) {
+			problems = append(problems, fmt.Sprintf("%s: unknown setting %q", cfg.File, key))
+		}
+	}
+
+	for _, s := range settings {
+		if raw := os.Getenv(s.env); raw != "" {
+			if err := s.set(cfg, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s %v", s.env, err))
+				continue
+			}
+			cfg.sources[s.key] = "env"
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := opts.Flags[s.key]; ok {
+			if err := s.set(cfg, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s %v", s.key, err))
+				continue
+			}
+			cfg.sources[s.key] = "flag"
+		}
+	}
+
+	if cfg.DatabaseConfig.Driver == "" && cfg.DatabaseConfig.DSN != "" {
+		cfg.DatabaseConfig.Driver = DetectDriver(cfg.DatabaseConfig.DSN)
+		cfg.sources["database.driver"] = "inferred"
+	}
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// RegisterFlags adds -config and one flag per setting, named after its key,
+// to flags. The returned options hold whatever the parsed flags set.
+func RegisterFlags(flags *flag.FlagSet) *LoadOptions {
+	opts := &LoadOptions{Flags: make(map[string]string)}
+	flags.StringVar(&opts.File, "config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	for _, s := range settings {
+		flags.Func(s.key, fmt.Sprintf("%s ($%s)", s.usage, s.env), func(value string) error {
+			opts.Flags[s.key] = value
+			return nil
+		})
+	}
+	return opts
+}
+
+// Settings lists every setting with its redacted value and where it came from.
+func (cfg *AppEnv) Settings() []Setting {
+	result := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		source := cfg.sources[s.key]
+		if source == "" {
+			source = "default"
+		}
+		result = append(result, Setting{Key: s.key, Value: s.format(cfg), Source: source, Env: s.env})
+	}
+	return result
+}
+
+func (cfg *AppEnv) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(cfg.Port)
+	check(err == nil && port > 0 && port < 65536, "port must be between 1 and 65535, got %q", cfg.Port)
+
+	db := cfg.DatabaseConfig
+	check(db.DSN != "", "database.dsn is required: set it in the config file, with DATABASE_DSN or with -database.dsn")
+	check(db.Driver == "" || oneOf(db.Driver, "mysql", "postgres", "sqlite"),
+		"database.driver must be mysql, postgres or sqlite, got %q", db.Driver)
+	check(len(db.ReplicaDSNs) == 0 || db.Driver != "sqlite", "database.replica_dsns is not supported by the sqlite driver")
+	check(db.MaxOpenConns >= 0, "database.max_open_conns must not be negative, got %d", db.MaxOpenConns)
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns must not be negative, got %d", db.MaxIdleConns)
+	// an idle default above a smaller explicit max_open_conns is clamped by database/sql
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns || cfg.sources["database.max_idle_conns"] == "",
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+
+	if cfg.GRPCConfig.Port != "" {
+		grpcPort, err := strconv.Atoi(cfg.GRPCConfig.Port)
+		check(err == nil && grpcPort > 0 && grpcPort < 65536, "grpc.port must be empty or between 1 and 65535, got %q", cfg.GRPCConfig.Port)
+		check(cfg.GRPCConfig.Port != cfg.Port, "grpc.port must differ from port %s", cfg.Port)
+	}
+
+	check(cfg.HTTPConfig.ReadHeaderTimeout > 0, "http.read_header_timeout must be positive")
+	check(cfg.HTTPConfig.HealthCheckTimeout > 0, "http.health_check_timeout must be positive")
+
+	check(oneOf(cfg.LogConfig.Level, "debug", "info", "warn", "error"),
+		"log.level must be debug, info, warn or error, got %q", cfg.LogConfig.Level)
+	check(oneOf(cfg.LogConfig.Format, "json", "text"), "log.format must be json or text, got %q", cfg.LogConfig.Format)
+
+	tracing := cfg.TracingConfig
+	check(oneOf(tracing.Exporter, "none", "otlp", "stdout", "file"),
+		"tracing.exporter must be one of none, otlp, stdout, file, got %q", tracing.Exporter)
+	check(tracing.SampleRatio >= 0 && tracing.SampleRatio <= 1,
+		"tracing.sample_ratio must be between 0 and 1, got %g", tracing.SampleRatio)
+	check(tracing.Exporter != "file" || tracing.FilePath != "", "tracing.file_path is required by the file exporter")
+
+	check(cfg.LimitsConfig.MaxAmount >= 0, "limits.max_amount must not be negative, got %d", cfg.LimitsConfig.MaxAmount)
+	check(cfg.LimitsConfig.MaxBodyBytes >= 0, "limits.max_body_bytes must not be negative, got %d", cfg.LimitsConfig.MaxBodyBytes)
+	check(cfg.LimitsConfig.MaxUploadBytes >= 0, "limits.max_upload_bytes must not be negative, got %d", cfg.LimitsConfig.MaxUploadBytes)
+
+	for i, key := range cfg.AuthConfig.APIKeys {
+		check(len(key) >= 16, "auth.api_keys: key %d is shorter than 16 characters", i+1)
+	}
+	admins, adminKeys := map[string]bool{}, map[string]bool{}
+	for i, entry := range cfg.AuthConfig.AdminKeys {
+		admin, key, ok := strings.Cut(entry, ":")
+		admin = strings.ToLower(admin)
+		check(ok && admin != "" && len(admin) <= 100 && strings.TrimSpace(admin) == admin,
+			"auth.admin_keys: entry %d must be <admin>:<key> with an admin name of at most 100 characters", i+1)
+		check(len(key) >= 16, "auth.admin_keys: the key of entry %d is shorter than 16 characters", i+1)
+		check(!admins[admin], "auth.admin_keys: %q has more than one key", admin)
+		check(!adminKeys[key], "auth.admin_keys: the key of entry %d is given to another admin too", i+1)
+		check(!slices.Contains(cfg.AuthConfig.APIKeys, key), "auth.admin_keys: the key of entry %d is also in auth.api_keys", i+1)
+		admins[admin], adminKeys[key] = true, true
+	}
+
+	interval := cfg.WorkersConfig.ReconcileInterval
+	check(interval == 0 || interval >= time.Second,
+		"workers.reconcile_interval must be 0 (off) or at least 1s, got %s", interval)
+	poll := cfg.WorkersConfig.EventPollInterval
+	check(poll == 0 || poll >= 10*time.Millisecond,
+		"workers.event_poll_interval must be 0 (off) or at least 10ms, got %s", poll)
+	check(cfg.WorkersConfig.EventRetention >= 0,
+		"workers.event_retention must not be negative, got %s", cfg.WorkersConfig.EventRetention)
+	bulkPoll := cfg.WorkersConfig.BulkPollInterval
+	check(bulkPoll == 0 || bulkPoll >= 10*time.Millisecond,
+		"workers.bulk_poll_interval must be 0 (off) or at least 10ms, got %s", bulkPoll)
+	check(cfg.WorkersConfig.BulkChunkSize >= 1 && cfg.WorkersConfig.BulkChunkSize <= 10_000,
+		"workers.bulk_chunk_size must be between 1 and 10000, got %d", cfg.WorkersConfig.BulkChunkSize)
+	schedulePoll := cfg.WorkersConfig.SchedulePollInterval
+	check(schedulePoll == 0 || schedulePoll >= 10*time.Millisecond,
+		"workers.schedule_poll_interval must be 0 (off) or at least 10ms, got %s", schedulePoll)
+	tradeExpiry := cfg.WorkersConfig.TradeExpiryInterval
+	check(tradeExpiry == 0 || tradeExpiry >= 10*time.Millisecond,
+		"workers.trade_expiry_interval must be 0 (off) or at least 10ms, got %s", tradeExpiry)
+	referralPoll := cfg.WorkersConfig.ReferralPollInterval
+	check(referralPoll == 0 || referralPoll >= 10*time.Millisecond,
+		"workers.referral_poll_interval must be 0 (off) or at least 10ms, got %s", referralPoll)
+	approvalExpiry := cfg.WorkersConfig.ApprovalExpiryInterval
+	check(approvalExpiry == 0 || approvalExpiry >= 10*time.Millisecond,
+		"workers.approval_expiry_interval must be 0 (off) or at least 10ms, got %s", approvalExpiry)
+
+	referrals := cfg.ReferralConfig
+	check(referrals.MinTopUp >= 1, "referrals.min_top_up must be positive, got %d", referrals.MinTopUp)
+	check(referrals.ReferrerReward >= 0 && referrals.RefereeReward >= 0,
+		"referrals.referrer_reward and referrals.referee_reward must not be negative")
+	check(referrals.Currency == "" || referrals.ReferrerReward+referrals.RefereeReward > 0,
+		"referrals.currency needs a positive referrals.referrer_reward or referrals.referee_reward")
+
+	risk := cfg.RiskConfig
+	check(risk.VelocityLimit >= 0, "risk.velocity_limit must not be negative, got %d", risk.VelocityLimit)
+	check(risk.VelocityLimit == 0 || risk.VelocityWindow > 0, "risk.velocity_window must be positive when risk.velocity_limit is set")
+	check(risk.UnusualAmountFactor == 0 || risk.UnusualAmountFactor >= 1,
+		"risk.unusual_amount_factor must be 0 (off) or at least 1, got %g", risk.UnusualAmountFactor)
+	check(risk.UnusualAmountMinHistory >= 1, "risk.unusual_amount_min_history must be positive, got %d", risk.UnusualAmountMinHistory)
+	check(risk.UnusualAmountFactor == 0 || risk.HistoryWindow > 0, "risk.history_window must be positive when risk.unusual_amount_factor is set")
+	check(risk.NewAccountAge >= 0, "risk.new_account_age must not be negative, got %s", risk.NewAccountAge)
+	check(risk.NewAccountMaxAmount >= 0, "risk.new_account_max_amount must not be negative, got %d", risk.NewAccountMaxAmount)
+	for _, owner := range risk.BlockedOwners {
+		_, err := uuid.Parse(owner)
+		check(err == nil, "risk.blocked_owners: %q is not a UUID", owner)
+	}
+
+	approvals := cfg.ApprovalConfig
+	check(approvals.Threshold >= 0, "approvals.threshold must not be negative, got %d", approvals.Threshold)
+	check(approvals.Expiry > 0, "approvals.expiry must be positive, got %s", approvals.Expiry)
+	for _, kind := range approvals.Privileged {
+		check(kind == "mint" || kind == "burn" || kind == "adjustment" || kind == "transfer",
+			"approvals.privileged: %q is not mint, burn, adjustment or transfer", kind)
+	}
+	return problems
+}
+
+// readFile decodes a YAML or TOML file into settings keyed like
+// "database.dsn".
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	tree := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	values := map[string]any{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]any, values map[string]any) {
+	for key, value := range tree {
+		if nested, ok := value.(map[string]any); ok {
+			flatten(prefix+key+".", nested, values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+func sortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectDriver infers the database driver from the shape of dsn: postgres
+// URLs and libpq key/value strings select postgres, ":memory:", "file:" URIs
+// and *.db/*.sqlite paths select sqlite, anything else mysql.
+func DetectDriver(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") ||
+		strings.HasPrefix(dsn, "host=") || strings.Contains(dsn, " dbname=") {
+		return "postgres"
+	}
+	path, _, _ := strings.Cut(dsn, "?")
+	if path == ":memory:" || strings.HasPrefix(path, "file:") ||
+		strings.HasSuffix(path, ".db") || strings.HasSuffix(path, ".sqlite") || strings.HasSuffix(path, ".sqlite3") {
+		return "sqlite"
+	}
+	return "mysql"
 }
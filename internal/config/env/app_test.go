@@ -0,0 +1,164 @@
+package config_env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+port: 9000
+database:
+  dsn: wallet:secret@tcp(db:3306)/wallet
+  max_open_conns: 20
+  conn_max_lifetime: 5m
+log:
+  level: warn
+auth:
+  api_keys: [0123456789abcdef]
+`)
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("DATABASE_MAX_OPEN_CONNS", "30")
+
+	cfg, err := Load(LoadOptions{File: file, Flags: map[string]string{"database.max_open_conns": "40"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9000" || cfg.LogConfig.Level != "debug" || cfg.DatabaseConfig.MaxOpenConns != 40 {
+		t.Errorf("port %s, level %s, max_open_conns %d: want 9000 from the file, debug from the env and 40 from the flag",
+			cfg.Port, cfg.LogConfig.Level, cfg.DatabaseConfig.MaxOpenConns)
+	}
+	if cfg.DatabaseConfig.ConnMaxLifetime != 5*time.Minute || cfg.DatabaseConfig.Driver != "mysql" {
+		t.Errorf("database = %+v", cfg.DatabaseConfig)
+	}
+	if cfg.ShutdownConfig.Timeout != 30*time.Second || !cfg.FeatureConfig.Transfers {
+		t.Errorf("defaults were not kept: %+v %+v", cfg.ShutdownConfig, cfg.FeatureConfig)
+	}
+
+	sources := map[string]Setting{}
+	for _, s := range cfg.Settings() {
+		sources[s.Key] = s
+	}
+	for key, want := range map[string]string{
+		"port":                    "file",
+		"log.level":               "env",
+		"database.max_open_conns": "flag",
+		"database.driver":         "inferred",
+		"shutdown.timeout":        "default",
+	} {
+		if got := sources[key].Source; got != want {
+			t.Errorf("%s came from %s, want %s", key, got, want)
+		}
+	}
+	if got := sources["database.dsn"].Value; got != "wallet:********@tcp(db:3306)/wallet" {
+		t.Errorf("printed dsn %q", got)
+	}
+	if got := sources["auth.api_keys"].Value; got != "********" {
+		t.Errorf("printed api keys %q", got)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, "config.toml", `
+[database]
+dsn = "postgres://wallet:secret@db/wallet"
+
+[workers]
+reconcile_interval = "1m"
+`)
+	cfg, err := Load(LoadOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatabaseConfig.Driver != "postgres" || cfg.WorkersConfig.ReconcileInterval != time.Minute {
+		t.Errorf("loaded %+v %+v", cfg.DatabaseConfig, cfg.WorkersConfig)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+port: 70000
+database:
+  max_open_conns: ten
+  max_idle_conns: 5
+  max_open: 5
+log:
+  format: xml
+auth:
+  api_keys: short
+  admin_keys: [dana, "erin:0123456789abcdef", "Erin:0123456789abcdef"]
+`)
+	t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+	_, err := Load(LoadOptions{File: file, Flags: map[string]string{"limits.max_amount": "-1"}})
+	if err == nil {
+		t.Fatal("invalid configuration was accepted")
+	}
+	for _, want := range []string{
+		`database.max_open_conns in ` + file + ` must be a whole number, got "ten"`,
+		`unknown setting "database.max_open"`,
+		`SHUTDOWN_TIMEOUT must be a non-negative duration such as 30s or 5m, got "soon"`,
+		`port must be between 1 and 65535, got "70000"`,
+		"database.dsn is required",
+		`log.format must be json or text, got "xml"`,
+		"limits.max_amount must not be negative",
+		"auth.api_keys: key 1 is shorter than 16 characters",
+		"auth.admin_keys: entry 1 must be <admin>:<key>",
+		`auth.admin_keys: "erin" has more than one key`,
+		"auth.admin_keys: the key of entry 3 is given to another admin too",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := map[string]string{
+		"wallet:secret@tcp(db:3306)/wallet?parseTime=true": "wallet:********@tcp(db:3306)/wallet?parseTime=true",
+		"postgres://wallet:secret@db:5432/wallet":          "postgres://wallet:********@db:5432/wallet",
+		"postgres://db/wallet?user=w&password=secret&x=1":  "postgres://db/wallet?user=w&password=********&x=1",
+		"host=db user=wallet password=secret dbname=w":     "host=db user=wallet password=******** dbname=w",
+		"file:wallet.db": "file:wallet.db",
+	}
+	for dsn, want := range tests {
+		if got := redactDSN(dsn); got != want {
+			t.Errorf("redactDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestReplicaDSNs(t *testing.T) {
+	t.Setenv("DATABASE_DSN", "wallet:secret@tcp(db:3306)/wallet")
+	t.Setenv("DATABASE_REPLICA_DSNS", "wallet:secret@tcp(replica-1:3306)/wallet, wallet:secret@tcp(replica-2:3306)/wallet")
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.DatabaseConfig.ReplicaDSNs) != 2 {
+		t.Fatalf("replicas %q, want 2", cfg.DatabaseConfig.ReplicaDSNs)
+	}
+	for _, s := range cfg.Settings() {
+		if s.Key == "database.replica_dsns" &&
+			s.Value != "wallet:********@tcp(replica-1:3306)/wallet,wallet:********@tcp(replica-2:3306)/wallet" {
+			t.Errorf("printed replicas as %q", s.Value)
+		}
+	}
+
+	t.Setenv("DATABASE_DSN", "wallet.db")
+	t.Setenv("DATABASE_REPLICA_DSNS", "replica.db")
+	if _, err := Load(LoadOptions{}); err == nil || !strings.Contains(err.Error(), "not supported by the sqlite driver") {
+		t.Errorf("sqlite replicas: got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package config_env
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one configuration value. It is read from the config file under
+// key, from the environment variable env and from the command-line flag
+// -<key>, in increasing order of precedence.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	// secret values are hidden when printed; dsn values only lose their
+	// password.
+	secret bool
+	dsn    bool
+	field  func(cfg *AppEnv) any
+}
+
+var settings = []setting{
+	{key: "port", env: "APP_PORT", usage: "HTTP listen port",
+		field: func(c *AppEnv) any { return &c.Port }},
+	{key: "seed", env: "SEED", usage: "seed the demo fixture and exit instead of serving",
+		field: func(c *AppEnv) any { return &c.Seed }},
+	{key: "seed_on_start", env: "SEED_ON_START", usage: "seed the demo fixture, then serve",
+		field: func(c *AppEnv) any { return &c.SeedOnStart }},
+
+	{key: "database.driver", env: "DATABASE_DRIVER", usage: "mysql, postgres or sqlite; inferred from the DSN when empty",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.Driver }},
+	{key: "database.dsn", env: "DATABASE_DSN", usage: "database connection string", dsn: true,
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.DSN }},
+	{key: "database.replica_dsns", env: "DATABASE_REPLICA_DSNS", usage: "comma-separated read replicas of the same driver for balance and history reads", dsn: true,
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.ReplicaDSNs }},
+	{key: "database.auto_migrate", env: "DATABASE_AUTO_MIGRATE", usage: "apply pending migrations when serving",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.AutoMigrate }},
+	{key: "database.max_open_conns", env: "DATABASE_MAX_OPEN_CONNS", usage: "maximum open connections; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.MaxOpenConns }},
+	{key: "database.max_idle_conns", env: "DATABASE_MAX_IDLE_CONNS", usage: "maximum idle connections kept in the pool",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.MaxIdleConns }},
+	{key: "database.conn_max_lifetime", env: "DATABASE_CONN_MAX_LIFETIME", usage: "close connections after this long; 0 keeps them",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.ConnMaxLifetime }},
+	{key: "database.conn_max_idle_time", env: "DATABASE_CONN_MAX_IDLE_TIME", usage: "close connections idle for this long; 0 keeps them",
+		field: func(c *AppEnv) any { return &c.DatabaseConfig.ConnMaxIdleTime }},
+
+	{key: "http.read_header_timeout", env: "HTTP_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.ReadHeaderTimeout }},
+	{key: "http.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "time allowed to read a whole request; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.ReadTimeout }},
+	{key: "http.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "time allowed to write a response; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.WriteTimeout }},
+	{key: "http.idle_timeout", env: "HTTP_IDLE_TIMEOUT", usage: "keep-alive timeout; 0 uses the read timeout",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.IdleTimeout }},
+	{key: "http.request_timeout", env: "HTTP_REQUEST_TIMEOUT", usage: "deadline for handling an API request; 0 is none",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.RequestTimeout }},
+	{key: "http.health_check_timeout", env: "HTTP_HEALTH_CHECK_TIMEOUT", usage: "deadline for the readiness checks",
+		field: func(c *AppEnv) any { return &c.HTTPConfig.HealthCheckTimeout }},
+
+	{key: "grpc.port", env: "GRPC_PORT", usage: "gRPC listen port; empty disables the gRPC API",
+		field: func(c *AppEnv) any { return &c.GRPCConfig.Port }},
+	{key: "grpc.reflection", env: "GRPC_REFLECTION", usage: "serve gRPC server reflection",
+		field: func(c *AppEnv) any { return &c.GRPCConfig.Reflection }},
+
+	{key: "log.level", env: "LOG_LEVEL", usage: "debug, info, warn or error",
+		field: func(c *AppEnv) any { return &c.LogConfig.Level }},
+	{key: "log.format", env: "LOG_FORMAT", usage: "json or text",
+		field: func(c *AppEnv) any { return &c.LogConfig.Format }},
+
+	{key: "shutdown.drain_delay", env: "SHUTDOWN_DRAIN_DELAY", usage: "time readiness fails before the server stops accepting connections",
+		field: func(c *AppEnv) any { return &c.ShutdownConfig.DrainDelay }},
+	{key: "shutdown.timeout", env: "SHUTDOWN_TIMEOUT", usage: "time in-flight requests and workers are awaited",
+		field: func(c *AppEnv) any { return &c.ShutdownConfig.Timeout }},
+
+	{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", usage: "service name on exported spans",
+		field: func(c *AppEnv) any { return &c.TracingConfig.ServiceName }},
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", usage: "none, otlp, stdout or file",
+		field: func(c *AppEnv) any { return &c.TracingConfig.Exporter }},
+	{key: "tracing.otlp_endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", usage: "OTLP collector endpoint",
+		field: func(c *AppEnv) any { return &c.TracingConfig.OTLPEndpoint }},
+	{key: "tracing.file_path", env: "OTEL_TRACES_FILE", usage: "file the file exporter writes to",
+		field: func(c *AppEnv) any { return &c.TracingConfig.FilePath }},
+	{key: "tracing.sample_ratio", env: "OTEL_TRACES_SAMPLER_ARG", usage: "fraction of traces sampled, between 0 and 1",
+		field: func(c *AppEnv) any { return &c.TracingConfig.SampleRatio }},
+
+	{key: "features.transfers", env: "FEATURE_TRANSFERS", usage: "serve POST /api/v1/wallets/transfer",
+		field: func(c *AppEnv) any { return &c.FeatureConfig.Transfers }},
+	{key: "features.metrics", env: "FEATURE_METRICS", usage: "serve GET /metrics",
+		field: func(c *AppEnv) any { return &c.FeatureConfig.Metrics }},
+
+	{key: "limits.max_amount", env: "LIMIT_MAX_AMOUNT", usage: "largest amount of a single operation; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.LimitsConfig.MaxAmount }},
+	{key: "limits.max_body_bytes", env: "LIMIT_MAX_BODY_BYTES", usage: "largest accepted request body; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.LimitsConfig.MaxBodyBytes }},
+	{key: "limits.max_upload_bytes", env: "LIMIT_MAX_UPLOAD_BYTES", usage: "largest accepted bulk job upload; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.LimitsConfig.MaxUploadBytes }},
+
+	{key: "auth.api_keys", env: "AUTH_API_KEYS", usage: "comma-separated keys accepted by /api/v1; empty disables authentication", secret: true,
+		field: func(c *AppEnv) any { return &c.AuthConfig.APIKeys }},
+	{key: "auth.admin_keys", env: "AUTH_ADMIN_KEYS", usage: "comma-separated <admin>:<key> pairs accepted by /api/v1/admin; empty leaves the admin API off", secret: true,
+		field: func(c *AppEnv) any { return &c.AuthConfig.AdminKeys }},
+
+	{key: "workers.reconcile_interval", env: "WORKER_RECONCILE_INTERVAL", usage: "how often the server reconciles the ledger; 0 disables it",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.ReconcileInterval }},
+	{key: "workers.event_poll_interval", env: "WORKER_EVENT_POLL_INTERVAL", usage: "how often wallet events written by other instances are picked up for streaming; 0 disables streaming",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.EventPollInterval }},
+	{key: "workers.event_retention", env: "WORKER_EVENT_RETENTION", usage: "how long wallet events are kept for streams to resume from; 0 keeps them",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.EventRetention }},
+	{key: "workers.bulk_poll_interval", env: "WORKER_BULK_POLL_INTERVAL", usage: "how often this instance looks for queued bulk jobs; 0 leaves processing to other instances",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.BulkPollInterval }},
+	{key: "workers.bulk_chunk_size", env: "WORKER_BULK_CHUNK_SIZE", usage: "bulk job rows applied between progress updates and cancellation checks",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.BulkChunkSize }},
+	{key: "workers.schedule_poll_interval", env: "WORKER_SCHEDULE_POLL_INTERVAL", usage: "how often this instance looks for due scheduled transfers; 0 leaves them to other instances",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.SchedulePollInterval }},
+	{key: "workers.trade_expiry_interval", env: "WORKER_TRADE_EXPIRY_INTERVAL", usage: "how often this instance refunds expired trades; 0 leaves them to other instances",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.TradeExpiryInterval }},
+	{key: "workers.referral_poll_interval", env: "WORKER_REFERRAL_POLL_INTERVAL", usage: "how often this instance pays referrals whose invitee topped up; 0 leaves them to other instances",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.ReferralPollInterval }},
+	{key: "workers.approval_expiry_interval", env: "WORKER_APPROVAL_EXPIRY_INTERVAL", usage: "how often this instance expires operations left pending approval and executes those left approved; 0 leaves them to other instances",
+		field: func(c *AppEnv) any { return &c.WorkersConfig.ApprovalExpiryInterval }},
+
+	{key: "referrals.currency", env: "REFERRAL_CURRENCY", usage: "name or ID of the currency referral rewards are paid in; empty turns payouts off",
+		field: func(c *AppEnv) any { return &c.ReferralConfig.Currency }},
+	{key: "referrals.min_top_up", env: "REFERRAL_MIN_TOP_UP", usage: "smallest first top-up of an invitee that pays the referral",
+		field: func(c *AppEnv) any { return &c.ReferralConfig.MinTopUp }},
+	{key: "referrals.referrer_reward", env: "REFERRAL_REFERRER_REWARD", usage: "reward paid to the user who invited",
+		field: func(c *AppEnv) any { return &c.ReferralConfig.ReferrerReward }},
+	{key: "referrals.referee_reward", env: "REFERRAL_REFEREE_REWARD", usage: "reward paid to the invitee",
+		field: func(c *AppEnv) any { return &c.ReferralConfig.RefereeReward }},
+
+	{key: "risk.enabled", env: "RISK_ENABLED", usage: "screen spends and transfers with the risk rules and record each decision",
+		field: func(c *AppEnv) any { return &c.RiskConfig.Enabled }},
+	{key: "risk.velocity_limit", env: "RISK_VELOCITY_LIMIT", usage: "operations an owner may attempt within risk.velocity_window before being denied; 0 is unlimited",
+		field: func(c *AppEnv) any { return &c.RiskConfig.VelocityLimit }},
+	{key: "risk.velocity_window", env: "RISK_VELOCITY_WINDOW", usage: "window of the velocity limit",
+		field: func(c *AppEnv) any { return &c.RiskConfig.VelocityWindow }},
+	{key: "risk.unusual_amount_factor", env: "RISK_UNUSUAL_AMOUNT_FACTOR", usage: "review amounts over this many times the owner's average debit; 0 turns the rule off",
+		field: func(c *AppEnv) any { return &c.RiskConfig.UnusualAmountFactor }},
+	{key: "risk.unusual_amount_min_history", env: "RISK_UNUSUAL_AMOUNT_MIN_HISTORY", usage: "debits an owner needs before an amount can be unusual",
+		field: func(c *AppEnv) any { return &c.RiskConfig.UnusualAmountMinHistory }},
+	{key: "risk.history_window", env: "RISK_HISTORY_WINDOW", usage: "how far back the average debit looks",
+		field: func(c *AppEnv) any { return &c.RiskConfig.HistoryWindow }},
+	{key: "risk.new_account_age", env: "RISK_NEW_ACCOUNT_AGE", usage: "users younger than this are new accounts; 0 turns the rule off",
+		field: func(c *AppEnv) any { return &c.RiskConfig.NewAccountAge }},
+	{key: "risk.new_account_max_amount", env: "RISK_NEW_ACCOUNT_MAX_AMOUNT", usage: "largest amount a new account may move without review",
+		field: func(c *AppEnv) any { return &c.RiskConfig.NewAccountMaxAmount }},
+	{key: "risk.blocked_owners", env: "RISK_BLOCKED_OWNERS", usage: "comma-separated owner IDs denied every spend and transfer",
+		field: func(c *AppEnv) any { return &c.RiskConfig.BlockedOwners }},
+
+	{key: "approvals.threshold", env: "APPROVAL_THRESHOLD", usage: "amount over which any admin operation needs a second admin's approval; 0 leaves it to approvals.privileged",
+		field: func(c *AppEnv) any { return &c.ApprovalConfig.Threshold }},
+	{key: "approvals.privileged", env: "APPROVAL_PRIVILEGED", usage: "comma-separated kinds of admin operation that always need approval: mint, burn, adjustment, transfer",
+		field: func(c *AppEnv) any { return &c.ApprovalConfig.Privileged }},
+	{key: "approvals.expiry", env: "APPROVAL_EXPIRY", usage: "how long an operation waits for approval before it expires",
+		field: func(c *AppEnv) any { return &c.ApprovalConfig.Expiry }},
+}
+
+func defaults() *AppEnv {
+	return &AppEnv{
+		Port: "8080",
+		HTTPConfig: HTTPConfig{
+			ReadHeaderTimeout:  10 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		DatabaseConfig: DbConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		GRPCConfig: GRPCConfig{Port: "9090", Reflection: true},
+		LogConfig:  LogConfig{Level: "info", Format: "json"},
+		ShutdownConfig: ShutdownConfig{
+			DrainDelay: 5 * time.Second,
+			Timeout:    30 * time.Second,
+		},
+		TracingConfig: TracingConfig{
+			ServiceName: "wallet-service",
+			Exporter:    "none",
+			FilePath:    "traces.jsonl",
+			SampleRatio: 1,
+		},
+		FeatureConfig: FeatureConfig{Transfers: true, Metrics: true},
+		LimitsConfig:  LimitsConfig{MaxBodyBytes: 1 << 20, MaxUploadBytes: 32 << 20},
+		WorkersConfig: WorkersConfig{
+			EventPollInterval:      time.Second,
+			EventRetention:         24 * time.Hour,
+			BulkPollInterval:       time.Second,
+			BulkChunkSize:          500,
+			SchedulePollInterval:   time.Second,
+			TradeExpiryInterval:    10 * time.Second,
+			ReferralPollInterval:   time.Second,
+			ApprovalExpiryInterval: 10 * time.Second,
+		},
+		ReferralConfig: ReferralConfig{MinTopUp: 1},
+		RiskConfig: RiskConfig{
+			VelocityWindow:          time.Minute,
+			UnusualAmountMinHistory: 5,
+			HistoryWindow:           30 * 24 * time.Hour,
+		},
+		ApprovalConfig: ApprovalConfig{
+			Privileged: []string{"mint", "burn", "adjustment"},
+			Expiry:     72 * time.Hour,
+		},
+	}
+}
+
+// set parses raw, a string from the environment or a flag or a decoded
+// file value, into the setting's field.
+func (s setting) set(cfg *AppEnv, raw any) error {
+	if list, ok := raw.([]any); ok {
+		values := make([]string, len(list))
+		for i, v := range list {
+			values[i] = fmt.Sprint(v)
+		}
+		raw = strings.Join(values, ",")
+	}
+	value := strings.TrimSpace(fmt.Sprint(raw))
+
+	switch field := s.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*field = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", value)
+		}
+		*field = parsed
+	case *int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", value)
+		}
+		*field = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("must be a non-negative duration such as 30s or 5m, got %q", value)
+		}
+		*field = parsed
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("setting %s has unsupported type %T", s.key, field))
+	}
+	return nil
+}
+
+// format renders the setting's current value, hiding secrets.
+func (s setting) format(cfg *AppEnv) string {
+	switch field := s.field(cfg).(type) {
+	case *string:
+		return s.hide(*field)
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *int:
+		return strconv.Itoa(*field)
+	case *int64:
+		return strconv.FormatInt(*field, 10)
+	case *float64:
+		return strconv.FormatFloat(*field, 'g', -1, 64)
+	case *time.Duration:
+		return field.String()
+	case *[]string:
+		values := make([]string, len(*field))
+		for i, value := range *field {
+			values[i] = s.hide(value)
+		}
+		return strings.Join(values, ",")
+	}
+	panic(fmt.Sprintf("setting %s has unsupported type %T", s.key, s.field(cfg)))
+}
+
+func (s setting) hide(value string) string {
+	switch {
+	case value == "":
+		return value
+	case s.secret:
+		return redacted
+	case s.dsn:
+		return redactDSN(value)
+	}
+	return value
+}
+
+const redacted = "********"
+
+var (
+	mysqlPassword    = regexp.MustCompile(`^([^:@/]*):[^@]*@`)
+	keyValuePassword = regexp.MustCompile(`(password=)[^&\s]*`)
+)
+
+// redactDSN hides the password in a MySQL, URL or key/value DSN and keeps
+// the rest, which is what an operator needs to tell databases apart.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				dsn = strings.Replace(u.Redacted(), ":xxxxx@", ":"+redacted+"@", 1)
+			}
+		}
+	} else {
+		dsn = mysqlPassword.ReplaceAllString(dsn, "${1}:"+redacted+"@")
+	}
+	return keyValuePassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
@@ -0,0 +1,48 @@
+// Package consistency decides which reads may be served by a read replica.
+// Reads go to a replica unless their context asks for the primary;
+// transactions, and with them every ledger write, always run on the primary.
+package consistency
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header lets a client ask for read-your-writes on a single request, e.g. a
+// balance read right after a top-up made through another instance.
+const Header = "X-Read-Your-Writes"
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads are served by the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Primary reports whether reads made with ctx must see the primary.
+func Primary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// GinMiddleware routes every read of a request to the primary when the
+// request writes, since handlers read back what they are about to change, or
+// when the client sets the Header to true. Other GET and HEAD requests may be
+// answered from a replica.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		readOnly := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+		requested, _ := strconv.ParseBool(c.GetHeader(Header))
+		if !readOnly || requested {
+			c.Request = c.Request.WithContext(WithPrimary(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
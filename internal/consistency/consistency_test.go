@@ -0,0 +1,40 @@
+package consistency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		method string
+		header string
+		want   bool
+	}{
+		{http.MethodGet, "", false},
+		{http.MethodGet, "true", true},
+		{http.MethodGet, "nonsense", false},
+		{http.MethodHead, "1", true},
+		{http.MethodPost, "", true},
+		{http.MethodPost, "false", true},
+	}
+	for _, tt := range tests {
+		var primary bool
+		r := gin.New()
+		r.Use(GinMiddleware())
+		r.Handle(tt.method, "/", func(c *gin.Context) { primary = Primary(c.Request.Context()) })
+
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(Header, tt.header)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if primary != tt.want {
+			t.Errorf("%s with %s %q: primary %v, want %v", tt.method, Header, tt.header, primary, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package consistency
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor is GinMiddleware for gRPC. Methods listed in
+// readOnly may be answered from a replica unless the call carries the
+// "x-read-your-writes: true" metadata; every other method reads the primary.
+func UnaryServerInterceptor(readOnly ...string) grpc.UnaryServerInterceptor {
+	reads := make(map[string]bool, len(readOnly))
+	for _, method := range readOnly {
+		reads[method] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requested := false
+		if values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(Header)); len(values) > 0 {
+			requested, _ = strconv.ParseBool(values[0])
+		}
+		if !reads[info.FullMethod] || requested {
+			ctx = WithPrimary(ctx)
+		}
+		return handler(ctx, req)
+	}
+}
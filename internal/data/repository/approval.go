@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotPending       = errors.New("operation is not pending approval")
+	ErrSameAdmin        = errors.New("operation must be approved by an admin other than its proposer")
+	ErrOperationExpired = errors.New("operation expired before it was approved")
+)
+
+// PendingOperation is an admin operation that moves funds once approved.
+// It is applied to the ledger under its IdempotencyKey.
+type PendingOperation struct {
+	ID             uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	Kind           string    `gorm:"type:varchar(16);not null" json:"kind"`
+	IdempotencyKey string    `gorm:"type:varchar(64);not null" json:"idempotency_key"`
+	CurrencyTypeID uuid.UUID `gorm:"type:char(36);not null" json:"currency_type_id"`
+	// OwnerID and Direction are set on adjustments of a user's wallet.
+	OwnerID   *uuid.UUID `gorm:"type:char(36)" json:"owner_id,omitempty"`
+	Direction string     `gorm:"type:varchar(8);not null;default:''" json:"direction,omitempty"`
+	// FromWalletID and ToWalletID are set on transfers.
+	FromWalletID *uuid.UUID `gorm:"type:char(36)" json:"from_wallet_id,omitempty"`
+	ToWalletID   *uuid.UUID `gorm:"type:char(36)" json:"to_wallet_id,omitempty"`
+	Amount       int64      `gorm:"not null" json:"amount"`
+	// Note is the proposer's reason for the operation.
+	Note       string `gorm:"type:varchar(255);not null;default:''" json:"note,omitempty"`
+	Status     string `gorm:"type:varchar(16);not null" json:"status"`
+	ProposedBy string `gorm:"type:varchar(100);not null" json:"proposed_by"`
+	// DecidedBy and DecidedAt record who approved or rejected the
+	// operation; DecidedAt is also set when it expired.
+	DecidedBy   string     `gorm:"type:varchar(100);not null;default:''" json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	ReferenceID string     `gorm:"type:varchar(64);not null;default:''" json:"reference_id,omitempty"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// ApprovalAuditEntry is one step of a pending operation. Entries are only
+// ever added; the database refuses to change or remove them.
+type ApprovalAuditEntry struct {
+	ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	OperationID uuid.UUID `gorm:"type:char(36);not null" json:"operation_id"`
+	Action      string    `gorm:"type:varchar(16);not null" json:"action"`
+	// Actor names the admin who took the step; it is empty for expiry.
+	Actor string `gorm:"type:varchar(100);not null;default:''" json:"actor,omitempty"`
+	// Detail is the admin's note or, for failed executions, the error.
+	Detail    string    `gorm:"type:varchar(255);not null;default:''" json:"detail,omitempty"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// PendingOperationFilter narrows ListOperations; empty fields match
+// everything.
+type PendingOperationFilter struct {
+	Status string
+	Kind   string
+}
+
+type ApprovalRepository interface {
+	// Propose records op under its idempotency key, with a proposed entry in
+	// the audit trail. With execute nil the operation waits for approval;
+	// otherwise it needs none and execute applies it right away, as
+	// Approve would, except that an operation execute fails on is failed
+	// rather than pending. A key proposed before returns that operation
+	// with existing true, or ErrIdempotencyKeyReused when it was proposed
+	// for a different operation or the ledger already used it.
+	Propose(ctx context.Context, op *PendingOperation,
+		execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (_ *PendingOperation, existing bool, err error)
+	GetOperation(ctx context.Context, id string) (*PendingOperation, error)
+	// ListOperations returns the most recent operations, newest first.
+	ListOperations(ctx context.Context, filter PendingOperationFilter, limit int) ([]PendingOperation, error)
+	// ListAudit returns the most recent audit entries, newest first, of one
+	// operation or, with operationID empty, of all of them.
+	ListAudit(ctx context.Context, operationID string, limit int) ([]ApprovalAuditEntry, error)
+	// Approve marks a pending operation approved by approver, then calls
+	// execute to apply it and records the reference ID it returns. When
+	// execute fails the operation is pending again. The proposer cannot
+	// approve their own operation (ErrSameAdmin); operations not pending
+	// are ErrNotPending and those past their expiry are expired and
+	// ErrOperationExpired.
+	Approve(ctx context.Context, id, approver, note string,
+		execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (*PendingOperation, error)
+	// Reject marks a pending operation rejected by admin, who may be its
+	// proposer withdrawing it; it is never applied.
+	Reject(ctx context.Context, id, admin, note string) (*PendingOperation, error)
+	// Expire marks up to limit operations still pending at now expired and
+	// returns how many it marked.
+	Expire(ctx context.Context, now time.Time, limit int) (int, error)
+	// Resume calls execute on up to limit operations left approved since
+	// before, by a process that stopped before it executed them, and
+	// returns how many it executed. Their outcome is recorded as Approve
+	// records it.
+	Resume(ctx context.Context, before time.Time, limit int,
+		execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (int, error)
+}
+
+type approvalRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewApprovalRepository(db *gorm.DB) ApprovalRepository {
+	return &approvalRepositoryImpl{db: db}
+}
+
+// audit adds an entry to the trail of op; detail is cut to fit.
+func audit(tx *gorm.DB, op *PendingOperation, action, actor, detail string, at time.Time) error {
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	return tx.Create(&ApprovalAuditEntry{ID: uuid.New(), OperationID: op.ID, Action: action,
+		Actor: actor, Detail: detail, CreatedAt: at}).Error
+}
+
+// sameOperation reports whether a proposal repeats the recorded one.
+func sameOperation(a, b *PendingOperation) bool {
+	equal := func(x, y *uuid.UUID) bool { return x == nil && y == nil || x != nil && y != nil && *x == *y }
+	return a.Kind == b.Kind && a.CurrencyTypeID == b.CurrencyTypeID && a.Amount == b.Amount &&
+		a.Direction == b.Direction && equal(a.OwnerID, b.OwnerID) &&
+		equal(a.FromWalletID, b.FromWalletID) && equal(a.ToWalletID, b.ToWalletID)
+}
+
+// Propose implements ApprovalRepository.
+func (r *approvalRepositoryImpl) Propose(ctx context.Context, op *PendingOperation,
+	execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (_ *PendingOperation, existing bool, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.Propose",
+		attribute.String("approval.kind", op.Kind), attribute.String("approval.proposed_by", op.ProposedBy))
+	defer func() { finishSpan(span, err) }()
+
+	var recorded PendingOperation
+	start := time.Now()
+	propose := func(tx *gorm.DB) error {
+		err := tx.Where("idempotency_key = ?", op.IdempotencyKey).First(&recorded).Error
+		if err == nil {
+			existing = true
+			if !sameOperation(&recorded, op) {
+				return ErrIdempotencyKeyReused
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var applied int64
+		if err := tx.Model(&WalletTransaction{}).Where("idempotency_key = ?", op.IdempotencyKey).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			return ErrIdempotencyKeyReused
+		}
+		recorded = *op
+		recorded.ID = uuid.New()
+		recorded.Status = enums.ApprovalPending
+		if execute != nil {
+			recorded.Status = enums.ApprovalApproved
+		}
+		recorded.CreatedAt = time.Now().UTC()
+		recorded.UpdatedAt = recorded.CreatedAt
+		if err := tx.Create(&recorded).Error; err != nil {
+			return err
+		}
+		detail := ""
+		if execute != nil {
+			detail = "approval not required"
+		}
+		return audit(tx, &recorded, enums.ApprovalActionProposed, recorded.ProposedBy, detail, recorded.CreatedAt)
+	}
+	err = r.db.WithContext(ctx).Transaction(propose)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// a concurrent proposal of the same key won; answer with it
+		existing, err = false, r.db.WithContext(ctx).Transaction(propose)
+	}
+	metrics.ObserveDBTransaction("approval_propose", err, time.Since(start))
+	if err != nil {
+		return nil, existing, err
+	}
+	if !existing {
+		logger.FromContext(ctx).Info("admin operation proposed", "operation_id", recorded.ID.String(),
+			"kind", recorded.Kind, "amount", recorded.Amount, "proposed_by", recorded.ProposedBy, "status", recorded.Status)
+	}
+	// An operation left approved by a stopped process is applied by the
+	// retry under its key (see WalletRepository.Transfer).
+	if recorded.Status != enums.ApprovalApproved || execute == nil {
+		return &recorded, existing, nil
+	}
+	executed, err := r.execute(ctx, &recorded, recorded.ProposedBy, execute)
+	return executed, existing, err
+}
+
+// GetOperation implements ApprovalRepository.
+func (r *approvalRepositoryImpl) GetOperation(ctx context.Context, id string) (_ *PendingOperation, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.GetOperation", attribute.String("approval.operation_id", id))
+	defer func() { finishSpan(span, err) }()
+
+	var op PendingOperation
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&op).Error; err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// ListOperations implements ApprovalRepository.
+func (r *approvalRepositoryImpl) ListOperations(ctx context.Context, filter PendingOperationFilter, limit int) (_ []PendingOperation, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.ListOperations",
+		attribute.String("approval.status", filter.Status), attribute.String("approval.kind", filter.Kind))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	var ops []PendingOperation
+	if err := query.Order("created_at DESC").Order("id").Limit(limit).Find(&ops).Error; err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// ListAudit implements ApprovalRepository.
+func (r *approvalRepositoryImpl) ListAudit(ctx context.Context, operationID string, limit int) (_ []ApprovalAuditEntry, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.ListAudit", attribute.String("approval.operation_id", operationID))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx)
+	if operationID != "" {
+		query = query.Where("operation_id = ?", operationID)
+	}
+	var entries []ApprovalAuditEntry
+	if err := query.Order("created_at DESC").Order("id").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Approve implements ApprovalRepository. The operation is claimed as
+// approved before execute runs, outside the claiming transaction since
+// execute opens its own, so a concurrent approval or rejection finds it no
+// longer pending.
+func (r *approvalRepositoryImpl) Approve(ctx context.Context, id, approver, note string,
+	execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (_ *PendingOperation, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.Approve",
+		attribute.String("approval.operation_id", id), attribute.String("approval.decided_by", approver))
+	defer func() { finishSpan(span, err) }()
+
+	op, err := r.decide(ctx, id, enums.ApprovalApproved, approver, note)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("admin operation approved", "operation_id", id, "proposed_by", op.ProposedBy, "decided_by", approver)
+	return r.execute(ctx, op, approver, execute)
+}
+
+// Reject implements ApprovalRepository.
+func (r *approvalRepositoryImpl) Reject(ctx context.Context, id, admin, note string) (_ *PendingOperation, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.Reject",
+		attribute.String("approval.operation_id", id), attribute.String("approval.decided_by", admin))
+	defer func() { finishSpan(span, err) }()
+
+	op, err := r.decide(ctx, id, enums.ApprovalRejected, admin, note)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("admin operation rejected", "operation_id", id, "decided_by", admin)
+	return op, nil
+}
+
+// Expire implements ApprovalRepository.
+func (r *approvalRepositoryImpl) Expire(ctx context.Context, now time.Time, limit int) (expired int, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.Expire")
+	defer func() { finishSpan(span, err) }()
+
+	var due []PendingOperation
+	if err := r.db.WithContext(ctx).Where("status = ? AND expires_at <= ?", enums.ApprovalPending, now).
+		Order("expires_at").Limit(limit).Find(&due).Error; err != nil {
+		return 0, err
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired = 0
+		for i := range due {
+			if err := expire(tx, &due[i], now); errors.Is(err, ErrNotPending) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	metrics.ObserveDBTransaction("approval_expire", err, time.Since(start))
+	if err != nil {
+		return 0, err
+	}
+	if expired > 0 {
+		logger.FromContext(ctx).Info("admin operations expired", "count", expired)
+	}
+	return expired, nil
+}
+
+// Resume implements ApprovalRepository. An approval still executing is
+// only resumed once it is older than before, and executing it twice moves
+// nothing twice since both run under its key (see WalletRepository.Transfer).
+func (r *approvalRepositoryImpl) Resume(ctx context.Context, before time.Time, limit int,
+	execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (executed int, err error) {
+	ctx, span := startSpan(ctx, "ApprovalRepository.Resume")
+	defer func() { finishSpan(span, err) }()
+
+	var stalled []PendingOperation
+	if err := r.db.WithContext(ctx).Where("status = ? AND updated_at <= ?", enums.ApprovalApproved, before).
+		Order("updated_at").Limit(limit).Find(&stalled).Error; err != nil {
+		return 0, err
+	}
+	for i := range stalled {
+		op := &stalled[i]
+		actor := op.DecidedBy
+		if actor == "" {
+			actor = op.ProposedBy
+		}
+		// a failure is in the audit trail and the log; the others go on
+		if _, err := r.execute(ctx, op, actor, execute); err == nil {
+			executed++
+		}
+	}
+	return executed, nil
+}
+
+// expire marks op expired unless a decision got to it first.
+func expire(tx *gorm.DB, op *PendingOperation, now time.Time) error {
+	result := tx.Model(&PendingOperation{}).Where("id = ? AND status = ?", op.ID, enums.ApprovalPending).
+		Updates(map[string]any{"status": enums.ApprovalExpired, "decided_at": now, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotPending
+	}
+	op.Status, op.DecidedAt, op.UpdatedAt = enums.ApprovalExpired, &now, now
+	return audit(tx, op, enums.ApprovalActionExpired, "", "", now)
+}
+
+// decide moves a pending operation to status on admin's decision. An
+// operation past its expiry is expired instead, and that is kept.
+func (r *approvalRepositoryImpl) decide(ctx context.Context, id, status, admin, note string) (*PendingOperation, error) {
+	var op PendingOperation
+	overdue := false
+	start := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&op).Error; err != nil {
+			return err
+		}
+		if op.Status != enums.ApprovalPending {
+			return ErrNotPending
+		}
+		now := time.Now().UTC()
+		if !now.Before(op.ExpiresAt) {
+			overdue = true
+			return expire(tx, &op, now)
+		}
+		if status == enums.ApprovalApproved && strings.EqualFold(strings.TrimSpace(admin), strings.TrimSpace(op.ProposedBy)) {
+			return ErrSameAdmin
+		}
+		action := enums.ApprovalActionApproved
+		if status == enums.ApprovalRejected {
+			action = enums.ApprovalActionRejected
+		}
+		if err := tx.Model(&PendingOperation{}).Where("id = ?", op.ID).Updates(map[string]any{
+			"status":     status,
+			"decided_by": admin,
+			"decided_at": now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		op.Status, op.DecidedBy, op.DecidedAt, op.UpdatedAt = status, admin, &now, now
+		return audit(tx, &op, action, admin, note, now)
+	})
+	metrics.ObserveDBTransaction("approval_decide", err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if overdue {
+		return nil, ErrOperationExpired
+	}
+	return &op, nil
+}
+
+// execute applies an approved operation and records the outcome on behalf
+// of actor: executed with the reference ID or, with the error in the audit
+// trail, pending again for another approval. An operation that needed no
+// approval, so was never decided, is failed instead.
+func (r *approvalRepositoryImpl) execute(ctx context.Context, op *PendingOperation, actor string,
+	execute func(ctx context.Context, op *PendingOperation) (referenceID string, err error)) (*PendingOperation, error) {
+	referenceID, execErr := execute(ctx, op)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		if execErr != nil {
+			outcome := map[string]any{"status": enums.ApprovalPending, "decided_by": "", "decided_at": nil, "updated_at": now}
+			if op.DecidedBy == "" {
+				outcome = map[string]any{"status": enums.ApprovalFailed, "updated_at": now}
+			}
+			result := tx.Model(&PendingOperation{}).Where("id = ? AND status = ?", op.ID, enums.ApprovalApproved).Updates(outcome)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return audit(tx, op, enums.ApprovalActionFailed, actor, execErr.Error(), now)
+		}
+		result := tx.Model(&PendingOperation{}).Where("id = ? AND status = ?", op.ID, enums.ApprovalApproved).
+			Updates(map[string]any{"status": enums.ApprovalExecuted, "reference_id": referenceID, "updated_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		op.Status, op.ReferenceID, op.UpdatedAt = enums.ApprovalExecuted, referenceID, now
+		return audit(tx, op, enums.ApprovalActionExecuted, actor, "", now)
+	})
+	if execErr != nil {
+		logger.FromContext(ctx).Warn("admin operation failed", "operation_id", op.ID.String(), "error", execErr)
+		return nil, errors.Join(execErr, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("admin operation executed", "operation_id", op.ID.String(),
+		"kind", op.Kind, "amount", op.Amount, "reference_id", referenceID)
+	return op, nil
+}
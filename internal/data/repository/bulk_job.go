@@ -0,0 +1,410 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBulkJobActive    = errors.New("job is still queued or running")
+	ErrBulkJobCompleted = errors.New("job has already completed")
+	ErrNothingToRetry   = errors.New("job has no failed rows to retry")
+	ErrBulkJobLeaseLost = errors.New("job was claimed by another worker")
+)
+
+// BulkJob is an uploaded batch of credits applied in the background. The row
+// counts are refreshed after every chunk.
+type BulkJob struct {
+	ID              uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	TransactionType string     `gorm:"type:varchar(32);not null" json:"transaction_type"`
+	Status          string     `gorm:"type:varchar(16);not null" json:"status"`
+	TotalRows       int        `gorm:"not null" json:"total_rows"`
+	PendingRows     int        `gorm:"not null" json:"pending_rows"`
+	SucceededRows   int        `gorm:"not null" json:"succeeded_rows"`
+	FailedRows      int        `gorm:"not null" json:"failed_rows"`
+	InvalidRows     int        `gorm:"not null" json:"invalid_rows"`
+	CancelRequested bool       `gorm:"not null;default:false" json:"cancel_requested"`
+	LeaseOwner      string     `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	LeaseUntil      *time.Time `json:"-"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+	CreatedAt       time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// BulkJobRow is one line of an upload and its result. Fields that failed to
+// parse are left empty on invalid rows.
+type BulkJobRow struct {
+	JobID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"-"`
+	Line           int       `gorm:"primaryKey;autoIncrement:false" json:"line"`
+	OwnerID        string    `gorm:"type:varchar(36);not null" json:"owner_id"`
+	CurrencyTypeID string    `gorm:"type:varchar(36);not null" json:"currency_type_id"`
+	Amount         int64     `gorm:"not null" json:"amount"`
+	IdempotencyKey string    `gorm:"type:varchar(64);not null" json:"idempotency_key"`
+	Status         string    `gorm:"type:varchar(16);not null" json:"status"`
+	Error          string    `gorm:"type:text;not null" json:"error,omitempty"`
+	ReferenceID    string    `gorm:"type:varchar(64);not null" json:"reference_id,omitempty"`
+	// Replayed rows found their idempotency key already applied.
+	Replayed  bool      `gorm:"not null;default:false" json:"replayed"`
+	Attempts  int       `gorm:"not null;default:0" json:"attempts"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+type BulkJobRepository interface {
+	// CreateJob stores a job and its rows and sets the job's counts.
+	CreateJob(ctx context.Context, job *BulkJob, rows []BulkJobRow) error
+	GetJob(ctx context.Context, id string) (*BulkJob, error)
+	// ListJobs returns the most recent jobs, newest first.
+	ListJobs(ctx context.Context, limit int) ([]BulkJob, error)
+	// ListRows returns up to limit rows after the line afterLine, optionally
+	// only those in status.
+	ListRows(ctx context.Context, jobID, status string, afterLine, limit int) ([]BulkJobRow, error)
+	// Claim leases the oldest queued job, or a running one whose lease
+	// expired, to owner. It returns nil when there is nothing to run.
+	Claim(ctx context.Context, owner string, lease time.Duration) (*BulkJob, error)
+	// Checkpoint refreshes the job's counts and extends owner's lease; it
+	// fails with ErrBulkJobLeaseLost once the job is no longer owner's.
+	Checkpoint(ctx context.Context, jobID, owner string, lease time.Duration) (*BulkJob, error)
+	// Release hands a running job back to the queue.
+	Release(ctx context.Context, jobID, owner string) error
+	// Finish ends owner's run of the job as completed or cancelled.
+	Finish(ctx context.Context, jobID, owner, status string) error
+	// PendingRows returns the next limit rows still to be applied.
+	PendingRows(ctx context.Context, jobID string, limit int) ([]BulkJobRow, error)
+	// SaveResults records the outcome of applied rows.
+	SaveResults(ctx context.Context, rows []BulkJobRow) error
+	// Cancel cancels a queued job at once and asks the worker running one to
+	// stop after its current chunk.
+	Cancel(ctx context.Context, id string) (*BulkJob, error)
+	// Retry queues the failed rows of a completed or cancelled job again,
+	// together with any a cancellation left pending.
+	Retry(ctx context.Context, id string) (*BulkJob, error)
+}
+
+type bulkJobRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewBulkJobRepository(db *gorm.DB) BulkJobRepository {
+	return &bulkJobRepositoryImpl{db: db}
+}
+
+// bulkRowBatch keeps inserts under every driver's bound parameter limit.
+const bulkRowBatch = 1000
+
+// CreateJob implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) CreateJob(ctx context.Context, job *BulkJob, rows []BulkJobRow) (err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.CreateJob",
+		attribute.String("bulk_job.id", job.ID.String()), attribute.Int("bulk_job.rows", len(rows)))
+	defer func() { finishSpan(span, err) }()
+
+	now := time.Now().UTC()
+	job.Status = enums.BulkJobStatusPending
+	job.TotalRows, job.PendingRows, job.InvalidRows = len(rows), 0, 0
+	for i := range rows {
+		rows[i].JobID = job.ID
+		rows[i].UpdatedAt = now
+		if rows[i].Status == enums.BulkRowStatusInvalid {
+			job.InvalidRows++
+		} else {
+			rows[i].Status = enums.BulkRowStatusPending
+			job.PendingRows++
+		}
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, bulkRowBatch).Error
+	})
+}
+
+// GetJob implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) GetJob(ctx context.Context, id string) (_ *BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.GetJob", attribute.String("bulk_job.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	var job BulkJob
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) ListJobs(ctx context.Context, limit int) (_ []BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.ListJobs")
+	defer func() { finishSpan(span, err) }()
+
+	var jobs []BulkJob
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Order("id").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ListRows implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) ListRows(ctx context.Context, jobID, status string, afterLine, limit int) (_ []BulkJobRow, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.ListRows",
+		attribute.String("bulk_job.id", jobID), attribute.String("bulk_job.row_status", status))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx).Where("job_id = ? AND line > ?", jobID, afterLine)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var rows []BulkJobRow
+	if err := query.Order("line").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Claim implements BulkJobRepository. Candidates are claimed with a
+// conditional update, so two instances racing for a job cannot both win.
+func (r *bulkJobRepositoryImpl) Claim(ctx context.Context, owner string, lease time.Duration) (_ *BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Claim")
+	defer func() { finishSpan(span, err) }()
+
+	db := r.db.WithContext(ctx)
+	for {
+		now := time.Now().UTC()
+		runnable := "(status = ? OR (status = ? AND lease_until < ?))"
+		var candidate BulkJob
+		err := db.Where(runnable, enums.BulkJobStatusPending, enums.BulkJobStatusRunning, now).
+			Order("created_at").Order("id").First(&candidate).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result := db.Model(&BulkJob{}).
+			Where("id = ?", candidate.ID).
+			Where(runnable, enums.BulkJobStatusPending, enums.BulkJobStatusRunning, now).
+			Updates(map[string]any{
+				"status":      enums.BulkJobStatusRunning,
+				"lease_owner": owner,
+				"lease_until": now.Add(lease),
+				"started_at":  gorm.Expr("COALESCE(started_at, ?)", now),
+				"updated_at":  now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return r.GetJob(ctx, candidate.ID.String())
+		}
+	}
+}
+
+// Checkpoint implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) Checkpoint(ctx context.Context, jobID, owner string, lease time.Duration) (_ *BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Checkpoint", attribute.String("bulk_job.id", jobID))
+	defer func() { finishSpan(span, err) }()
+
+	now := time.Now().UTC()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.updateOwned(tx, jobID, owner, map[string]any{"lease_until": now.Add(lease), "updated_at": now})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetJob(ctx, jobID)
+}
+
+// Release implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) Release(ctx context.Context, jobID, owner string) (err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Release", attribute.String("bulk_job.id", jobID))
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.updateOwned(tx, jobID, owner, map[string]any{
+			"status":      enums.BulkJobStatusPending,
+			"lease_owner": "",
+			"lease_until": nil,
+			"updated_at":  time.Now().UTC(),
+		})
+	})
+}
+
+// Finish implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) Finish(ctx context.Context, jobID, owner, status string) (err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Finish",
+		attribute.String("bulk_job.id", jobID), attribute.String("bulk_job.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.updateOwned(tx, jobID, owner, map[string]any{
+			"status":      status,
+			"lease_owner": "",
+			"lease_until": nil,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	})
+}
+
+// updateOwned applies values and the current row counts to a job owner is
+// running.
+func (r *bulkJobRepositoryImpl) updateOwned(tx *gorm.DB, jobID, owner string, values map[string]any) error {
+	counts, err := countRows(tx, jobID)
+	if err != nil {
+		return err
+	}
+	for column, count := range counts {
+		values[column] = count
+	}
+	owned := tx.Model(&BulkJob{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", jobID, enums.BulkJobStatusRunning, owner)
+	result := owned.Session(&gorm.Session{}).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL reports unchanged rows as unaffected, so tell "lost" from
+		// "updated twice within a millisecond"
+		var still int64
+		if err := owned.Session(&gorm.Session{}).Count(&still).Error; err != nil {
+			return err
+		}
+		if still == 0 {
+			return ErrBulkJobLeaseLost
+		}
+	}
+	return nil
+}
+
+// countRows returns the job's row counts keyed by their bulk_jobs column.
+func countRows(tx *gorm.DB, jobID string) (map[string]int, error) {
+	var byStatus []struct {
+		Status string
+		Count  int
+	}
+	if err := tx.Model(&BulkJobRow{}).Select("status, COUNT(*) AS count").
+		Where("job_id = ?", jobID).Group("status").Scan(&byStatus).Error; err != nil {
+		return nil, err
+	}
+	counts := map[string]int{"pending_rows": 0, "succeeded_rows": 0, "failed_rows": 0, "invalid_rows": 0}
+	for _, row := range byStatus {
+		counts[row.Status+"_rows"] = row.Count
+	}
+	return counts, nil
+}
+
+// PendingRows implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) PendingRows(ctx context.Context, jobID string, limit int) (_ []BulkJobRow, err error) {
+	return r.ListRows(ctx, jobID, enums.BulkRowStatusPending, 0, limit)
+}
+
+// SaveResults implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) SaveResults(ctx context.Context, rows []BulkJobRow) (err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.SaveResults", attribute.Int("bulk_job.rows", len(rows)))
+	defer func() { finishSpan(span, err) }()
+
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			rows[i].UpdatedAt = now
+			if err := tx.Model(&BulkJobRow{}).
+				Where("job_id = ? AND line = ?", rows[i].JobID, rows[i].Line).
+				Updates(map[string]any{
+					"status":       rows[i].Status,
+					"error":        rows[i].Error,
+					"reference_id": rows[i].ReferenceID,
+					"replayed":     rows[i].Replayed,
+					"attempts":     rows[i].Attempts,
+					"updated_at":   now,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Cancel implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) Cancel(ctx context.Context, id string) (_ *BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Cancel", attribute.String("bulk_job.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job BulkJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&job).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		switch job.Status {
+		case enums.BulkJobStatusPending:
+			return tx.Model(&job).Updates(map[string]any{
+				"status":      enums.BulkJobStatusCancelled,
+				"finished_at": now,
+				"updated_at":  now,
+			}).Error
+		case enums.BulkJobStatusRunning:
+			return tx.Model(&job).Updates(map[string]any{"cancel_requested": true, "updated_at": now}).Error
+		case enums.BulkJobStatusCompleted:
+			return ErrBulkJobCompleted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetJob(ctx, id)
+}
+
+// Retry implements BulkJobRepository.
+func (r *bulkJobRepositoryImpl) Retry(ctx context.Context, id string) (_ *BulkJob, err error) {
+	ctx, span := startSpan(ctx, "BulkJobRepository.Retry", attribute.String("bulk_job.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job BulkJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&job).Error; err != nil {
+			return err
+		}
+		if job.Status != enums.BulkJobStatusCompleted && job.Status != enums.BulkJobStatusCancelled {
+			return ErrBulkJobActive
+		}
+		now := time.Now().UTC()
+		requeued := tx.Model(&BulkJobRow{}).
+			Where("job_id = ? AND status = ?", id, enums.BulkRowStatusFailed).
+			Updates(map[string]any{"status": enums.BulkRowStatusPending, "error": "", "updated_at": now})
+		if requeued.Error != nil {
+			return requeued.Error
+		}
+		if requeued.RowsAffected == 0 && job.PendingRows == 0 {
+			return ErrNothingToRetry
+		}
+		values := map[string]any{
+			"status":           enums.BulkJobStatusPending,
+			"cancel_requested": false,
+			"finished_at":      nil,
+			"updated_at":       now,
+		}
+		counts, err := countRows(tx, id)
+		if err != nil {
+			return err
+		}
+		for column, count := range counts {
+			values[column] = count
+		}
+		return tx.Model(&job).Updates(values).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetJob(ctx, id)
+}
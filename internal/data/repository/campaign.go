@@ -0,0 +1,413 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCampaignEnded           = errors.New("campaign has ended")
+	ErrCampaignNotActive       = errors.New("campaign is not running")
+	ErrCampaignCapReached      = errors.New("owner has claimed this campaign as often as allowed")
+	ErrCampaignNotEligible     = errors.New("owner does not meet the campaign's requirement")
+	ErrCampaignBudgetExhausted = errors.New("campaign budget is exhausted")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used by another operation")
+)
+
+// Campaign pays Reward to each owner who claims it while it runs, between
+// StartsAt and EndsAt, up to MaxClaimsPerOwner times and only once they meet
+// its Requirement. Rewards are paid out of the campaign's wallet, funded from
+// the treasury, so Budget is what is left to pay out.
+type Campaign struct {
+	ID                uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	Name              string    `gorm:"type:varchar(100);not null" json:"name"`
+	CurrencyTypeID    uuid.UUID `gorm:"type:char(36);not null" json:"currency_type_id"`
+	WalletID          uuid.UUID `gorm:"type:char(36);not null" json:"wallet_id"`
+	Reward            int64     `gorm:"not null" json:"reward"`
+	MaxClaimsPerOwner int       `gorm:"not null" json:"max_claims_per_owner"`
+	Requirement       string    `gorm:"type:varchar(16);not null" json:"requirement"`
+	// MinAmount is the least first top-up or total spend the requirement
+	// asks for.
+	MinAmount          int64      `gorm:"not null;default:0" json:"min_amount,omitempty"`
+	SpendWindowSeconds int64      `gorm:"not null;default:0" json:"spend_window_seconds,omitempty"`
+	StartsAt           time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	Status             string     `gorm:"type:varchar(16);not null" json:"status"`
+	ClaimCount         int64      `gorm:"not null;default:0" json:"claim_count"`
+	ClaimedAmount      int64      `gorm:"not null;default:0" json:"claimed_amount"`
+	Budget             int64      `gorm:"->" json:"budget"`
+	CreatedAt          time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// CampaignClaim is an owner's Claim-th claim of a campaign.
+type CampaignClaim struct {
+	CampaignID     uuid.UUID `gorm:"type:char(36);primaryKey" json:"campaign_id"`
+	OwnerID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"owner_id"`
+	Claim          int       `gorm:"primaryKey;autoIncrement:false" json:"claim"`
+	Amount         int64     `gorm:"not null" json:"amount"`
+	IdempotencyKey string    `gorm:"type:varchar(64);not null" json:"idempotency_key"`
+	ReferenceID    string    `gorm:"type:varchar(64);not null" json:"reference_id"`
+	CreatedAt      time.Time `gorm:"not null" json:"created_at"`
+	// Replayed claims repeated an idempotency key that had already paid out.
+	Replayed bool `gorm:"-" json:"replayed"`
+}
+
+type CampaignRepository interface {
+	// CreateCampaign stores campaign with an empty budget wallet.
+	CreateCampaign(ctx context.Context, campaign *Campaign) error
+	GetCampaign(ctx context.Context, id string) (*Campaign, error)
+	// ListCampaigns returns the most recent campaigns, newest first,
+	// optionally only those in status.
+	ListCampaigns(ctx context.Context, status string, limit int) ([]Campaign, error)
+	// ListClaims returns a campaign's most recent claims, newest first,
+	// optionally only the owner's.
+	ListClaims(ctx context.Context, campaignID, ownerID string, limit int) ([]CampaignClaim, error)
+	// Fund moves amount from the currency's treasury into the campaign's
+	// budget. It is idempotent on idempotencyKey.
+	Fund(ctx context.Context, id, idempotencyKey string, amount int64) (*Campaign, error)
+	// Claim pays the campaign's reward from its budget into walletID, the
+	// owner's wallet in its currency, once the campaign is running, the
+	// owner is under its cap and meets its requirement. Repeating
+	// idempotencyKey answers the claim it made.
+	Claim(ctx context.Context, id string, ownerID, walletID uuid.UUID, idempotencyKey string) (*CampaignClaim, error)
+	Pause(ctx context.Context, id string) (*Campaign, error)
+	Resume(ctx context.Context, id string) (*Campaign, error)
+	// End stops the campaign for good and returns what is left of its budget
+	// to the treasury.
+	End(ctx context.Context, id string) (*Campaign, error)
+}
+
+type campaignRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewCampaignRepository(db *gorm.DB) CampaignRepository {
+	return &campaignRepositoryImpl{db: db}
+}
+
+// CreateCampaign implements CampaignRepository.
+func (r *campaignRepositoryImpl) CreateCampaign(ctx context.Context, campaign *Campaign) (err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.CreateCampaign", attribute.String("campaign.id", campaign.ID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet := Wallet{ID: uuid.New(), OwnerType: enums.OwnerTypeCampaign, OwnerID: campaign.ID, CurrencyTypeID: campaign.CurrencyTypeID}
+		if err := tx.Create(&wallet).Error; err != nil {
+			return err
+		}
+		campaign.WalletID, campaign.Status, campaign.Budget = wallet.ID, enums.CampaignStatusActive, 0
+		return tx.Create(campaign).Error
+	})
+}
+
+// GetCampaign implements CampaignRepository.
+func (r *campaignRepositoryImpl) GetCampaign(ctx context.Context, id string) (_ *Campaign, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.GetCampaign", attribute.String("campaign.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return getCampaign(r.db.WithContext(ctx), id)
+}
+
+// ListCampaigns implements CampaignRepository.
+func (r *campaignRepositoryImpl) ListCampaigns(ctx context.Context, status string, limit int) (_ []Campaign, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.ListCampaigns", attribute.String("campaign.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	query := withBudget(r.db.WithContext(ctx))
+	if status != "" {
+		query = query.Where("campaigns.status = ?", status)
+	}
+	var campaigns []Campaign
+	if err := query.Order("campaigns.created_at DESC").Order("campaigns.id").Limit(limit).Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ListClaims implements CampaignRepository.
+func (r *campaignRepositoryImpl) ListClaims(ctx context.Context, campaignID, ownerID string, limit int) (_ []CampaignClaim, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.ListClaims",
+		attribute.String("campaign.id", campaignID), attribute.String("campaign.owner_id", ownerID))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx).Where("campaign_id = ?", campaignID)
+	if ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	var claims []CampaignClaim
+	if err := query.Order("created_at DESC").Order("owner_id").Order("claim DESC").Limit(limit).Find(&claims).Error; err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Fund implements CampaignRepository.
+func (r *campaignRepositoryImpl) Fund(ctx context.Context, id, idempotencyKey string, amount int64) (_ *Campaign, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.Fund",
+		attribute.String("campaign.id", id), attribute.Int64("campaign.amount", amount))
+	defer func() { finishSpan(span, err) }()
+
+	applied := false
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		campaign, err := lockCampaign(tx, id)
+		if err != nil {
+			return err
+		}
+		if campaign.Status == enums.CampaignStatusEnded {
+			return ErrCampaignEnded
+		}
+		var existing int64
+		if err := tx.Model(&WalletTransaction{}).Where("idempotency_key = ?", idempotencyKey).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		treasury, err := treasuryWallet(tx, campaign.CurrencyTypeID)
+		if err != nil {
+			return err
+		}
+		_, err = post(tx, idempotencyKey, enums.TransactionTypeCampaignFunding,
+			[]posting{{treasury.ID, -amount}, {campaign.WalletID, amount}})
+		applied = err == nil
+		return err
+	})
+	metrics.ObserveDBTransaction("campaign_fund", err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if applied {
+		notifyWalletEventsCommitted()
+		logger.FromContext(ctx).Info("campaign funded", "campaign_id", id, "amount", amount)
+	}
+	return r.GetCampaign(ctx, id)
+}
+
+// Claim implements CampaignRepository. The campaign stays locked from the
+// checks to the payout, so concurrent claims cannot overrun a cap or the
+// budget.
+func (r *campaignRepositoryImpl) Claim(ctx context.Context, id string, ownerID, walletID uuid.UUID, idempotencyKey string) (_ *CampaignClaim, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.Claim",
+		attribute.String("campaign.id", id), attribute.String("campaign.owner_id", ownerID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	var claim CampaignClaim
+	var campaign *Campaign
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if campaign, err = lockCampaign(tx, id); err != nil {
+			return err
+		}
+		err = tx.Where("campaign_id = ? AND idempotency_key = ?", id, idempotencyKey).First(&claim).Error
+		if err == nil {
+			claim.Replayed = true
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var existing int64
+		if err := tx.Model(&WalletTransaction{}).Where("idempotency_key = ?", idempotencyKey).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrIdempotencyKeyReused
+		}
+
+		now := time.Now().UTC()
+		if campaign.Status != enums.CampaignStatusActive || now.Before(campaign.StartsAt) ||
+			campaign.EndsAt != nil && !now.Before(*campaign.EndsAt) {
+			return ErrCampaignNotActive
+		}
+		var claims int64
+		if err := tx.Model(&CampaignClaim{}).Where("campaign_id = ? AND owner_id = ?", id, ownerID).Count(&claims).Error; err != nil {
+			return err
+		}
+		if claims >= int64(campaign.MaxClaimsPerOwner) {
+			return ErrCampaignCapReached
+		}
+		if err := checkRequirement(tx, campaign, walletID, now); err != nil {
+			return err
+		}
+
+		referenceID, err := post(tx, idempotencyKey, enums.TransactionTypeBonus,
+			[]posting{{campaign.WalletID, -campaign.Reward}, {walletID, campaign.Reward}})
+		if errors.Is(err, ErrInsufficientBalance) {
+			return ErrCampaignBudgetExhausted
+		}
+		if err != nil {
+			return err
+		}
+		claim = CampaignClaim{
+			CampaignID:     campaign.ID,
+			OwnerID:        ownerID,
+			Claim:          int(claims) + 1,
+			Amount:         campaign.Reward,
+			IdempotencyKey: idempotencyKey,
+			ReferenceID:    referenceID,
+		}
+		if err := tx.Create(&claim).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Campaign{}).Where("id = ?", id).Updates(map[string]any{
+			"claim_count":    gorm.Expr("claim_count + 1"),
+			"claimed_amount": gorm.Expr("claimed_amount + ?", campaign.Reward),
+			"updated_at":     now,
+		}).Error
+	})
+	metrics.ObserveDBTransaction("campaign_claim", err, time.Since(start))
+	if errors.Is(err, ErrCampaignBudgetExhausted) {
+		metrics.ObserveInsufficientBalance(enums.TransactionTypeBonus, campaign.CurrencyTypeID.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !claim.Replayed {
+		notifyWalletEventsCommitted()
+		metrics.ObserveTransfer(enums.TransactionTypeBonus, campaign.CurrencyTypeID.String(), claim.Amount)
+		logger.FromContext(ctx).Info("campaign claimed", "campaign_id", id, "owner_id", ownerID.String(), "reference_id", claim.ReferenceID)
+	}
+	return &claim, nil
+}
+
+// Pause implements CampaignRepository.
+func (r *campaignRepositoryImpl) Pause(ctx context.Context, id string) (*Campaign, error) {
+	return r.setStatus(ctx, id, enums.CampaignStatusPaused)
+}
+
+// Resume implements CampaignRepository.
+func (r *campaignRepositoryImpl) Resume(ctx context.Context, id string) (*Campaign, error) {
+	return r.setStatus(ctx, id, enums.CampaignStatusActive)
+}
+
+// End implements CampaignRepository.
+func (r *campaignRepositoryImpl) End(ctx context.Context, id string) (*Campaign, error) {
+	return r.setStatus(ctx, id, enums.CampaignStatusEnded)
+}
+
+func (r *campaignRepositoryImpl) setStatus(ctx context.Context, id, status string) (_ *Campaign, err error) {
+	ctx, span := startSpan(ctx, "CampaignRepository.setStatus",
+		attribute.String("campaign.id", id), attribute.String("campaign.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	returned := false
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		campaign, err := lockCampaign(tx, id)
+		if err != nil {
+			return err
+		}
+		if campaign.Status == status {
+			return nil
+		}
+		if campaign.Status == enums.CampaignStatusEnded {
+			return ErrCampaignEnded
+		}
+		if status == enums.CampaignStatusEnded {
+			var wallet Wallet
+			if err := tx.Where("id = ?", campaign.WalletID).First(&wallet).Error; err != nil {
+				return err
+			}
+			if wallet.Balance > 0 {
+				treasury, err := treasuryWallet(tx, campaign.CurrencyTypeID)
+				if err != nil {
+					return err
+				}
+				if _, err := post(tx, "campaign:"+id+":return", enums.TransactionTypeCampaignReturn,
+					[]posting{{wallet.ID, -wallet.Balance}, {treasury.ID, wallet.Balance}}); err != nil {
+					return err
+				}
+				returned = true
+			}
+		}
+		return tx.Model(&Campaign{}).Where("id = ?", id).
+			Updates(map[string]any{"status": status, "updated_at": time.Now().UTC()}).Error
+	})
+	if status == enums.CampaignStatusEnded {
+		metrics.ObserveDBTransaction("campaign_end", err, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if returned {
+		notifyWalletEventsCommitted()
+	}
+	return r.GetCampaign(ctx, id)
+}
+
+// checkRequirement fails with ErrCampaignNotEligible unless the ledger of
+// walletID meets the campaign's requirement at now.
+func checkRequirement(tx *gorm.DB, campaign *Campaign, walletID uuid.UUID, now time.Time) error {
+	switch campaign.Requirement {
+	case enums.CampaignRequirementFirstTopUp:
+		var first WalletTransaction
+		err := tx.Where("wallet_id = ? AND transaction_type = ?", walletID, enums.TransactionTypeTopUp).
+			Order("created_at").Order("id").First(&first).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCampaignNotEligible
+		}
+		if err != nil {
+			return err
+		}
+		if first.Amount < campaign.MinAmount {
+			return ErrCampaignNotEligible
+		}
+	case enums.CampaignRequirementMinSpend:
+		// a spend debits the owner and credits the treasury as spend under
+		// one reference
+		var spent int64
+		since := now.Add(-time.Duration(campaign.SpendWindowSeconds) * time.Second)
+		err := tx.Table("wallet_transactions AS d").
+			Joins("JOIN wallet_transactions AS c ON c.reference_id = d.reference_id AND c.transaction_type = ?", enums.TransactionTypeSpend).
+			Where("d.wallet_id = ? AND d.amount < 0 AND d.created_at >= ?", walletID, since).
+			Select("COALESCE(SUM(-d.amount), 0)").Scan(&spent).Error
+		if err != nil {
+			return err
+		}
+		if spent < campaign.MinAmount {
+			return ErrCampaignNotEligible
+		}
+	}
+	return nil
+}
+
+func withBudget(db *gorm.DB) *gorm.DB {
+	return db.Model(&Campaign{}).Select("campaigns.*, wallets.balance AS budget").
+		Joins("JOIN wallets ON wallets.id = campaigns.wallet_id")
+}
+
+func getCampaign(db *gorm.DB, id string) (*Campaign, error) {
+	var campaign Campaign
+	if err := withBudget(db).Where("campaigns.id = ?", id).First(&campaign).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func lockCampaign(tx *gorm.DB, id string) (*Campaign, error) {
+	var campaign Campaign
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&campaign).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func treasuryWallet(tx *gorm.DB, currencyTypeID uuid.UUID) (*Wallet, error) {
+	var treasury Wallet
+	if err := tx.Where("owner_type = ? AND currency_type_id = ?", "system", currencyTypeID).First(&treasury).Error; err != nil {
+		return nil, err
+	}
+	return &treasury, nil
+}
@@ -1,9 +1,47 @@
 package repository
 
-import "github.com/google/uuid"
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type CurrencyType struct {
 	ID   uuid.UUID `gorm:"type:char(36);primaryKey"`
 	Name string    `gorm:"type:varchar(50);uniqueIndex;not null"`
 	BaseTimeStamps
 }
+
+type CurrencyTypeRepository interface {
+	ListCurrencyTypes(ctx context.Context) ([]CurrencyType, error)
+	CreateCurrencyType(ctx context.Context, currencyType *CurrencyType) error
+}
+
+type currencyTypeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewCurrencyTypeRepository(db *gorm.DB) CurrencyTypeRepository {
+	return &currencyTypeRepositoryImpl{db: db}
+}
+
+// ListCurrencyTypes implements CurrencyTypeRepository.
+func (r *currencyTypeRepositoryImpl) ListCurrencyTypes(ctx context.Context) (_ []CurrencyType, err error) {
+	ctx, span := startSpan(ctx, "CurrencyTypeRepository.ListCurrencyTypes")
+	defer func() { finishSpan(span, err) }()
+
+	var currencyTypes []CurrencyType
+	if err := r.db.WithContext(ctx).Order("name").Find(&currencyTypes).Error; err != nil {
+		return nil, err
+	}
+	return currencyTypes, nil
+}
+
+// CreateCurrencyType implements CurrencyTypeRepository.
+func (r *currencyTypeRepositoryImpl) CreateCurrencyType(ctx context.Context, currencyType *CurrencyType) (err error) {
+	ctx, span := startSpan(ctx, "CurrencyTypeRepository.CreateCurrencyType")
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Create(currencyType).Error
+}
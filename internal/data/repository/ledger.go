@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// posting is one wallet's share of a ledger entry; negative amounts leave
+// the wallet.
+type posting struct {
+	walletID uuid.UUID
+	amount   int64
+}
+
+// post records postings, which must balance within each currency, as one
+// ledger entry under a new reference ID, which it returns. Postings to the
+// same wallet are combined. Wallets are locked in ID order, like Transfer
+// locks them, and a frozen wallet or one that would go negative fails the
+// entry. Credits are recorded as transactionType and debits as debit. The
+// caller calls notifyWalletEventsCommitted once tx commits.
+func post(tx *gorm.DB, idempotencyKey, transactionType string, postings []posting) (string, error) {
+	net := make(map[uuid.UUID]int64, len(postings))
+	for _, p := range postings {
+		net[p.walletID] += p.amount
+	}
+	walletIDs := make([]uuid.UUID, 0, len(net))
+	for id, amount := range net {
+		if amount != 0 {
+			walletIDs = append(walletIDs, id)
+		}
+	}
+	sort.Slice(walletIDs, func(i, j int) bool { return walletIDs[i].String() < walletIDs[j].String() })
+
+	wallets := make([]Wallet, len(walletIDs))
+	for i, id := range walletIDs {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&wallets[i]).Error; err != nil {
+			return "", err
+		}
+		if wallets[i].Frozen {
+			return "", ErrWalletFrozen
+		}
+		if wallets[i].Balance+net[id] < 0 {
+			return "", ErrInsufficientBalance
+		}
+	}
+
+	referenceID := uuid.New().String()
+	events := make([]WalletEvent, 0, len(wallets))
+	for i := range wallets {
+		wallet := &wallets[i]
+		amount := net[wallet.ID]
+		leg := WalletTransaction{
+			ID:              uuid.New(),
+			WalletID:        wallet.ID,
+			TransactionType: transactionType,
+			Amount:          amount,
+			BalanceAfter:    wallet.Balance + amount,
+			ReferenceID:     referenceID,
+			IdempotencyKey:  idempotencyKey,
+		}
+		if amount < 0 {
+			leg.TransactionType = enums.TransactionTypeDebit
+		}
+		if err := tx.Create(&leg).Error; err != nil {
+			return "", err
+		}
+		if err := tx.Model(wallet).Update("balance", leg.BalanceAfter).Error; err != nil {
+			return "", err
+		}
+		events = append(events, walletEvent(wallet, &leg))
+	}
+	if len(events) > 0 {
+		if err := tx.Create(&events).Error; err != nil {
+			return "", err
+		}
+	}
+	return referenceID, nil
+}
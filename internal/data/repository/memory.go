@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+)
+
+// The in-memory repositories mirror the GORM implementations closely enough
+// to stand in for them in handler tests: they return gorm.ErrRecordNotFound
+// and gorm.ErrDuplicatedKey where the database would, and hand out copies so
+// callers never share state with the store.
+
+type inMemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]User
+}
+
+func NewInMemoryUserRepository() UserRepository {
+	return &inMemoryUserRepository{users: make(map[uuid.UUID]User)}
+}
+
+// GetUserByID implements UserRepository.
+func (r *inMemoryUserRepository) GetUserByID(_ context.Context, userID string) (*User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// CreateUser implements UserRepository.
+func (r *inMemoryUserRepository) CreateUser(_ context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if _, ok := r.users[user.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	touch(&user.BaseTimeStamps)
+	r.users[user.ID] = *user
+	return nil
+}
+
+// ListUsers implements UserRepository.
+func (r *inMemoryUserRepository) ListUsers(_ context.Context, role string, limit, offset int) ([]User, error) {
+	r.mu.RLock()
+	users := make([]User, 0, len(r.users))
+	for _, user := range r.users {
+		if role == "" || user.Role == role {
+			users = append(users, user)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].ID.String() < users[j].ID.String()
+	})
+	if offset >= len(users) {
+		return []User{}, nil
+	}
+	users = users[offset:]
+	if limit >= 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+type ownerCurrencyKey struct {
+	ownerType      string
+	ownerID        uuid.UUID
+	currencyTypeID uuid.UUID
+}
+
+type inMemoryWalletRepository struct {
+	// mu guards the maps below; wallet balances are guarded by walletLocks.
+	mu           sync.RWMutex
+	wallets      map[uuid.UUID]*Wallet
+	byOwner      map[ownerCurrencyKey]uuid.UUID
+	transactions []WalletTransaction
+	byKey        map[string]int
+	walletLocks  map[uuid.UUID]*sync.Mutex
+}
+
+func NewInMemoryWalletRepository() WalletRepository {
+	return &inMemoryWalletRepository{
+		wallets:     make(map[uuid.UUID]*Wallet),
+		byOwner:     make(map[ownerCurrencyKey]uuid.UUID),
+		byKey:       make(map[string]int),
+		walletLocks: make(map[uuid.UUID]*sync.Mutex),
+	}
+}
+
+// CreateWallet implements WalletRepository.
+func (w *inMemoryWalletRepository) CreateWallet(_ context.Context, wallet *Wallet) error {
+	if wallet.Balance < 0 {
+		return gorm.ErrCheckConstraintViolated
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wallet.ID == uuid.Nil {
+		wallet.ID = uuid.New()
+	}
+	key := ownerCurrencyKey{wallet.OwnerType, wallet.OwnerID, wallet.CurrencyTypeID}
+	if _, ok := w.wallets[wallet.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if _, ok := w.byOwner[key]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	touch(&wallet.BaseTimeStamps)
+	stored := *wallet
+	w.wallets[wallet.ID] = &stored
+	w.byOwner[key] = wallet.ID
+	w.walletLocks[wallet.ID] = &sync.Mutex{}
+	return nil
+}
+
+// GetSystemWalletByCurrencyType implements WalletRepository.
+func (w *inMemoryWalletRepository) GetSystemWalletByCurrencyType(ctx context.Context, currencyTypeID string) (*Wallet, error) {
+	wallets, err := w.ListSystemWallets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, wallet := range wallets {
+		if wallet.CurrencyTypeID.String() == currencyTypeID {
+			return &wallet, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// ListSystemWallets implements WalletRepository.
+func (w *inMemoryWalletRepository) ListSystemWallets(_ context.Context) ([]Wallet, error) {
+	w.mu.RLock()
+	ids := make([]uuid.UUID, 0)
+	for key, id := range w.byOwner {
+		if key.ownerType == "system" {
+			ids = append(ids, id)
+		}
+	}
+	w.mu.RUnlock()
+
+	result := make([]Wallet, 0, len(ids))
+	for _, id := range ids {
+		if wallet, ok := w.snapshot(id); ok {
+			result = append(result, wallet)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID.String() < result[j].ID.String() })
+	return result, nil
+}
+
+// GetWalletByOwner implements WalletRepository.
+func (w *inMemoryWalletRepository) GetWalletByOwner(_ context.Context, ownerType string, ownerID string, currencyTypeID string) (*Wallet, error) {
+	owner, err := uuid.Parse(ownerID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	currency, err := uuid.Parse(currencyTypeID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	w.mu.RLock()
+	id, ok := w.byOwner[ownerCurrencyKey{ownerType, owner, currency}]
+	w.mu.RUnlock()
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	wallet, ok := w.snapshot(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &wallet, nil
+}
+
+// GetTransactionByIdempotencyKey implements WalletRepository.
+func (w *inMemoryWalletRepository) GetTransactionByIdempotencyKey(_ context.Context, idempotencyKey string) (*WalletTransaction, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	i, ok := w.byKey[idempotencyKey]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	transaction := w.transactions[i]
+	return &transaction, nil
+}
+
+// ListTransactionsByIdempotencyKey implements WalletRepository.
+func (w *inMemoryWalletRepository) ListTransactionsByIdempotencyKey(_ context.Context, idempotencyKey string) ([]WalletTransaction, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var result []WalletTransaction
+	if i, ok := w.byKey[idempotencyKey]; ok {
+		// the legs of a key are appended together, debit first
+		for ; i < len(w.transactions) && w.transactions[i].IdempotencyKey == idempotencyKey; i++ {
+			result = append(result, w.transactions[i])
+		}
+	}
+	return result, nil
+}
+
+// Transfer implements WalletRepository. Wallet mutexes are taken in sorted ID
+// order, like the row locks of the GORM implementation, so opposing
+// transfers cannot deadlock.
+func (w *inMemoryWalletRepository) Transfer(_ context.Context, fromWalletID, toWalletID, currencyTypeID, idempotencyKey string, amount int64, transactionType enums.TransactionType) error {
+	if transactionType == "" {
+		transactionType = enums.TransactionTypeTopUp
+	}
+	walletIDs := []string{fromWalletID, toWalletID}
+	sort.Strings(walletIDs)
+
+	wallets := make(map[string]*Wallet, 2)
+	for _, id := range walletIDs {
+		if _, ok := wallets[id]; ok {
+			continue
+		}
+		wallet, lock, err := w.lookup(id, currencyTypeID)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		wallets[id] = wallet
+	}
+	fromWallet, toWallet := wallets[fromWalletID], wallets[toWalletID]
+
+	if fromWallet.Frozen || toWallet.Frozen {
+		return ErrWalletFrozen
+	}
+
+	if fromWallet.Balance < amount {
+		return ErrInsufficientBalance
+	}
+	if fromWallet.ID == toWallet.ID {
+		return errors.New("cannot transfer to the same wallet")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.byKey[idempotencyKey]; ok {
+		return nil
+	}
+	referenceID := uuid.New().String()
+	now := time.Now()
+	debit := WalletTransaction{
+		ID:              uuid.New(),
+		WalletID:        fromWallet.ID,
+		TransactionType: enums.TransactionTypeDebit,
+		Amount:          -amount,
+		BalanceAfter:    fromWallet.Balance - amount,
+		ReferenceID:     referenceID,
+		IdempotencyKey:  idempotencyKey,
+		BaseTimeStamps:  BaseTimeStamps{CreatedAt: now, UpdatedAt: now},
+	}
+	credit := WalletTransaction{
+		ID:              uuid.New(),
+		WalletID:        toWallet.ID,
+		TransactionType: string(transactionType),
+		Amount:          amount,
+		BalanceAfter:    toWallet.Balance + amount,
+		ReferenceID:     referenceID,
+		IdempotencyKey:  idempotencyKey,
+		BaseTimeStamps:  BaseTimeStamps{CreatedAt: now, UpdatedAt: now},
+	}
+	w.byKey[idempotencyKey] = len(w.transactions)
+	w.transactions = append(w.transactions, debit, credit)
+
+	fromWallet.Balance -= amount
+	fromWallet.UpdatedAt = now
+	toWallet.Balance += amount
+	toWallet.UpdatedAt = now
+	return nil
+}
+
+// GetWalletByID implements WalletRepository.
+func (w *inMemoryWalletRepository) GetWalletByID(_ context.Context, walletID string) (*Wallet, error) {
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	wallet, ok := w.snapshot(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &wallet, nil
+}
+
+// ListWalletsByOwner implements WalletRepository.
+func (w *inMemoryWalletRepository) ListWalletsByOwner(_ context.Context, ownerID string) ([]Wallet, error) {
+	owner, err := uuid.Parse(ownerID)
+	if err != nil {
+		return []Wallet{}, nil
+	}
+	w.mu.RLock()
+	var ids []uuid.UUID
+	for key, id := range w.byOwner {
+		if key.ownerID == owner {
+			ids = append(ids, id)
+		}
+	}
+	w.mu.RUnlock()
+
+	result := make([]Wallet, 0, len(ids))
+	for _, id := range ids {
+		if wallet, ok := w.snapshot(id); ok {
+			result = append(result, wallet)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CurrencyTypeID.String() < result[j].CurrencyTypeID.String()
+	})
+	return result, nil
+}
+
+// ListTransactions implements WalletRepository.
+func (w *inMemoryWalletRepository) ListTransactions(_ context.Context, walletID string, limit int) ([]WalletTransaction, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	result := []WalletTransaction{}
+	for i := len(w.transactions) - 1; i >= 0 && (limit < 0 || len(result) < limit); i-- {
+		if w.transactions[i].WalletID.String() == walletID {
+			result = append(result, w.transactions[i])
+		}
+	}
+	return result, nil
+}
+
+// SetFrozen implements WalletRepository.
+func (w *inMemoryWalletRepository) SetFrozen(_ context.Context, walletID string, frozen bool) error {
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		return gorm.ErrRecordNotFound
+	}
+	w.mu.RLock()
+	wallet, ok := w.wallets[id]
+	lock := w.walletLocks[id]
+	w.mu.RUnlock()
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	wallet.Frozen = frozen
+	wallet.UpdatedAt = time.Now()
+	return nil
+}
+
+// Mint implements WalletRepository.
+func (w *inMemoryWalletRepository) Mint(_ context.Context, currencyTypeID, idempotencyKey string, amount int64) error {
+	return w.adjustSupply(currencyTypeID, idempotencyKey, amount, enums.TransactionTypeMint)
+}
+
+// Burn implements WalletRepository.
+func (w *inMemoryWalletRepository) Burn(_ context.Context, currencyTypeID, idempotencyKey string, amount int64) error {
+	return w.adjustSupply(currencyTypeID, idempotencyKey, -amount, enums.TransactionTypeBurn)
+}
+
+func (w *inMemoryWalletRepository) adjustSupply(currencyTypeID, idempotencyKey string, delta int64, transactionType string) error {
+	currency, err := uuid.Parse(currencyTypeID)
+	if err != nil {
+		return gorm.ErrRecordNotFound
+	}
+	w.mu.RLock()
+	var treasury *Wallet
+	var lock *sync.Mutex
+	for key, id := range w.byOwner {
+		if key.ownerType == "system" && key.currencyTypeID == currency {
+			treasury, lock = w.wallets[id], w.walletLocks[id]
+			break
+		}
+	}
+	w.mu.RUnlock()
+	if treasury == nil {
+		return gorm.ErrRecordNotFound
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if treasury.Frozen {
+		return ErrWalletFrozen
+	}
+	if treasury.Balance+delta < 0 {
+		return ErrInsufficientBalance
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.byKey[idempotencyKey]; ok {
+		return nil
+	}
+	now := time.Now()
+	w.byKey[idempotencyKey] = len(w.transactions)
+	w.transactions = append(w.transactions, WalletTransaction{
+		ID:              uuid.New(),
+		WalletID:        treasury.ID,
+		TransactionType: transactionType,
+		Amount:          delta,
+		BalanceAfter:    treasury.Balance + delta,
+		ReferenceID:     uuid.New().String(),
+		IdempotencyKey:  idempotencyKey,
+		BaseTimeStamps:  BaseTimeStamps{CreatedAt: now, UpdatedAt: now},
+	})
+	treasury.Balance += delta
+	treasury.UpdatedAt = now
+	return nil
+}
+
+// lookup returns the stored wallet and its lock, matching the GORM
+// implementation's "id = ? AND currency_type_id = ?" lookup.
+func (w *inMemoryWalletRepository) lookup(walletID, currencyTypeID string) (*Wallet, *sync.Mutex, error) {
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	wallet, ok := w.wallets[id]
+	if !ok || wallet.CurrencyTypeID.String() != currencyTypeID {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return wallet, w.walletLocks[id], nil
+}
+
+// snapshot copies a wallet while holding its lock, so a concurrent Transfer
+// is never observed half-applied.
+func (w *inMemoryWalletRepository) snapshot(id uuid.UUID) (Wallet, bool) {
+	w.mu.RLock()
+	wallet, ok := w.wallets[id]
+	lock := w.walletLocks[id]
+	w.mu.RUnlock()
+	if !ok {
+		return Wallet{}, false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return *wallet, true
+}
+
+func touch(ts *BaseTimeStamps) {
+	now := time.Now()
+	if ts.CreatedAt.IsZero() {
+		ts.CreatedAt = now
+	}
+	if ts.UpdatedAt.IsZero() {
+		ts.UpdatedAt = now
+	}
+}
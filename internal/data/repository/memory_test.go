@@ -0,0 +1,17 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository/repositorytest"
+)
+
+func TestInMemoryRepositoriesConform(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		return repositorytest.Repositories{
+			Wallets: repository.NewInMemoryWalletRepository(),
+			Users:   repository.NewInMemoryUserRepository(),
+		}
+	})
+}
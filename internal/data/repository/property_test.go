@@ -0,0 +1,109 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+const propertyWallets = 4
+
+// randomOp is one generated Transfer call. Wallets are indexes into the
+// ledger and keys come from a small pool so replays happen often.
+type randomOp struct {
+	From, To int
+	Key      int
+	Amount   int64
+}
+
+type randomOps []randomOp
+
+func (randomOps) Generate(rng *rand.Rand, size int) reflect.Value {
+	ops := make(randomOps, rng.Intn(size+1))
+	for i := range ops {
+		ops[i] = randomOp{
+			From:   rng.Intn(propertyWallets),
+			To:     rng.Intn(propertyWallets),
+			Key:    rng.Intn(size/2 + 1),
+			Amount: int64(rng.Intn(60) + 1),
+		}
+	}
+	return reflect.ValueOf(ops)
+}
+
+// TestTransferMatchesModel applies random operation sequences to a
+// repository and to a plain map model and requires both to agree on every
+// result and on the final balances.
+func TestTransferMatchesModel(t *testing.T) {
+	forEachImplementation(t, func(t *testing.T, wallets repository.WalletRepository) {
+		ctx := context.Background()
+		property := func(ops randomOps) bool {
+			l := newLedger(t, wallets, 200, 50, 20, 0)
+			model := make([]int64, propertyWallets)
+			for i, wallet := range l.wallets {
+				model[i] = wallet.Balance
+			}
+			applied := make(map[int]bool)
+
+			for step, op := range ops {
+				from, to := l.wallets[op.From], l.wallets[op.To]
+				key := fmt.Sprintf("property-%s-%d", l.currency, op.Key)
+				err := wallets.Transfer(ctx, from.ID.String(), to.ID.String(), l.currency.String(), key, op.Amount, enums.TransactionTypeTopUp)
+
+				// same order of checks as Transfer: balance, same wallet, then idempotency key
+				var wantErr bool
+				switch {
+				case model[op.From] < op.Amount:
+					if !errors.Is(err, repository.ErrInsufficientBalance) {
+						t.Logf("step %d %+v: err = %v, want ErrInsufficientBalance", step, op, err)
+						return false
+					}
+					continue
+				case op.From == op.To:
+					wantErr = true
+				case applied[op.Key]:
+				default:
+					applied[op.Key] = true
+					model[op.From] -= op.Amount
+					model[op.To] += op.Amount
+				}
+				if (err != nil) != wantErr {
+					t.Logf("step %d %+v: err = %v, want error %v", step, op, err, wantErr)
+					return false
+				}
+			}
+
+			got := l.balances(t, wallets)
+			for i, wallet := range l.wallets {
+				if got[wallet.ID] != model[i] {
+					t.Logf("wallet %d balance = %d, model says %d", i, got[wallet.ID], model[i])
+					return false
+				}
+			}
+			for key := range applied {
+				if _, err := wallets.GetTransactionByIdempotencyKey(ctx, fmt.Sprintf("property-%s-%d", l.currency, key)); err != nil {
+					t.Logf("applied key %d has no ledger entry: %v", key, err)
+					return false
+				}
+			}
+			return true
+		}
+		seed := time.Now().UnixNano()
+		t.Logf("seed %d", seed)
+		config := &quick.Config{MaxCount: 100, Rand: rand.New(rand.NewSource(seed))}
+		if testing.Short() {
+			config.MaxCount = 10
+		}
+		if err := quick.Check(property, config); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSelfReferral    = errors.New("users cannot refer themselves")
+	ErrAlreadyReferred = errors.New("user was already referred by someone else")
+	ErrReferralCycle   = errors.New("referrer was referred by the user they would refer")
+	ErrAlreadyToppedUp = errors.New("user has already topped up")
+)
+
+// Referral is the invitation of RefereeID by ReferrerID. It stays pending
+// until the referee's first top-up in the rewarded currency, which settles
+// it once: rewarded when the top-up reached the threshold, disqualified
+// otherwise.
+type Referral struct {
+	RefereeID  uuid.UUID `gorm:"type:char(36);primaryKey" json:"referee_id"`
+	ReferrerID uuid.UUID `gorm:"type:char(36);not null" json:"referrer_id"`
+	Status     string    `gorm:"type:varchar(16);not null" json:"status"`
+	// CurrencyTypeID and the fields after it are set when the referral
+	// settles.
+	CurrencyTypeID          *uuid.UUID `gorm:"type:char(36)" json:"currency_type_id,omitempty"`
+	QualifyingTransactionID *uuid.UUID `gorm:"type:char(36)" json:"qualifying_transaction_id,omitempty"`
+	QualifyingAmount        int64      `gorm:"not null;default:0" json:"qualifying_amount,omitempty"`
+	ReferrerReward          int64      `gorm:"not null;default:0" json:"referrer_reward,omitempty"`
+	RefereeReward           int64      `gorm:"not null;default:0" json:"referee_reward,omitempty"`
+	ReferenceID             string     `gorm:"type:varchar(64);not null;default:''" json:"reference_id,omitempty"`
+	SettledAt               *time.Time `json:"settled_at,omitempty"`
+	CreatedAt               time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt               time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// ReferralTerms are what a referral pays, and for which first top-up.
+type ReferralTerms struct {
+	CurrencyTypeID uuid.UUID
+	MinTopUp       int64
+	ReferrerReward int64
+	RefereeReward  int64
+}
+
+// ReferralReport sums the referrals settled between From and To.
+type ReferralReport struct {
+	From         *time.Time       `json:"from,omitempty"`
+	To           time.Time        `json:"to"`
+	Payouts      []ReferralPayout `json:"payouts"`
+	Disqualified int64            `json:"disqualified"`
+	// Pending counts every referral still waiting for a first top-up.
+	Pending      int64            `json:"pending"`
+	TopReferrers []ReferrerPayout `json:"top_referrers"`
+}
+
+// ReferralPayout is what the rewarded referrals paid in one currency.
+type ReferralPayout struct {
+	CurrencyTypeID  uuid.UUID `json:"currency_type_id"`
+	Referrals       int64     `json:"referrals"`
+	ReferrerRewards int64     `json:"referrer_rewards"`
+	RefereeRewards  int64     `json:"referee_rewards"`
+}
+
+// ReferrerPayout is what one referrer earned in one currency.
+type ReferrerPayout struct {
+	ReferrerID     uuid.UUID `json:"referrer_id"`
+	CurrencyTypeID uuid.UUID `json:"currency_type_id"`
+	Referrals      int64     `json:"referrals"`
+	Amount         int64     `json:"amount"`
+}
+
+type ReferralRepository interface {
+	// Refer records that referrerID invited refereeID, on the referee's user
+	// and as a pending referral. Referring the same pair again returns the
+	// referral with created false.
+	Refer(ctx context.Context, refereeID, referrerID uuid.UUID) (_ *Referral, created bool, err error)
+	GetReferral(ctx context.Context, refereeID string) (*Referral, error)
+	// ListReferrals returns the most recent referrals, newest first,
+	// optionally only a referrer's or those in status.
+	ListReferrals(ctx context.Context, referrerID, status string, limit int) ([]Referral, error)
+	// ListQualifying returns up to limit pending referrals whose referee has
+	// a top-up in currencyTypeID among the wallet events, oldest first.
+	// Referrals whose events were pruned before they were seen stay pending.
+	ListQualifying(ctx context.Context, currencyTypeID uuid.UUID, limit int) ([]Referral, error)
+	// Qualify settles a pending referral by the referee's first top-up into
+	// refereeWalletID. It pays the rewards from the treasury to both wallets
+	// as one ledger entry when that top-up came after the referral and
+	// reached terms.MinTopUp, and disqualifies the referral otherwise. A
+	// settled referral is returned unchanged.
+	Qualify(ctx context.Context, refereeID uuid.UUID, terms ReferralTerms, referrerWalletID, refereeWalletID uuid.UUID) (*Referral, error)
+	// Report sums the referrals settled from from, or ever when nil, until
+	// to, with the limit referrers who earned the most.
+	Report(ctx context.Context, from *time.Time, to time.Time, limit int) (*ReferralReport, error)
+}
+
+type referralRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewReferralRepository(db *gorm.DB) ReferralRepository {
+	return &referralRepositoryImpl{db: db}
+}
+
+// Refer implements ReferralRepository.
+func (r *referralRepositoryImpl) Refer(ctx context.Context, refereeID, referrerID uuid.UUID) (_ *Referral, created bool, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.Refer",
+		attribute.String("referral.referee_id", refereeID.String()), attribute.String("referral.referrer_id", referrerID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	if refereeID == referrerID {
+		return nil, false, ErrSelfReferral
+	}
+	var referral Referral
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var referee, referrer User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", refereeID).First(&referee).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", referrerID).First(&referrer).Error; err != nil {
+			return err
+		}
+		if referee.ReferredBy != nil {
+			if *referee.ReferredBy != referrerID {
+				return ErrAlreadyReferred
+			}
+			return tx.Where("referee_id = ?", refereeID).First(&referral).Error
+		}
+		if referrer.ReferredBy != nil && *referrer.ReferredBy == refereeID {
+			return ErrReferralCycle
+		}
+		var topUps int64
+		err := tx.Model(&WalletTransaction{}).Joins("JOIN wallets ON wallets.id = wallet_transactions.wallet_id").
+			Where("wallets.owner_id = ? AND wallet_transactions.transaction_type = ?", refereeID, enums.TransactionTypeTopUp).
+			Count(&topUps).Error
+		if err != nil {
+			return err
+		}
+		if topUps > 0 {
+			return ErrAlreadyToppedUp
+		}
+		if err := tx.Model(&User{}).Where("id = ?", refereeID).Update("referred_by", referrerID).Error; err != nil {
+			return err
+		}
+		referral = Referral{RefereeID: refereeID, ReferrerID: referrerID, Status: enums.ReferralStatusPending}
+		created = true
+		return tx.Create(&referral).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		logger.FromContext(ctx).Info("referral recorded", "referee_id", refereeID.String(), "referrer_id", referrerID.String())
+	}
+	return &referral, created, nil
+}
+
+// GetReferral implements ReferralRepository.
+func (r *referralRepositoryImpl) GetReferral(ctx context.Context, refereeID string) (_ *Referral, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.GetReferral", attribute.String("referral.referee_id", refereeID))
+	defer func() { finishSpan(span, err) }()
+
+	var referral Referral
+	if err := r.db.WithContext(ctx).Where("referee_id = ?", refereeID).First(&referral).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// ListReferrals implements ReferralRepository.
+func (r *referralRepositoryImpl) ListReferrals(ctx context.Context, referrerID, status string, limit int) (_ []Referral, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.ListReferrals",
+		attribute.String("referral.referrer_id", referrerID), attribute.String("referral.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx)
+	if referrerID != "" {
+		query = query.Where("referrer_id = ?", referrerID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var referrals []Referral
+	if err := query.Order("created_at DESC").Order("referee_id").Limit(limit).Find(&referrals).Error; err != nil {
+		return nil, err
+	}
+	return referrals, nil
+}
+
+// ListQualifying implements ReferralRepository.
+func (r *referralRepositoryImpl) ListQualifying(ctx context.Context, currencyTypeID uuid.UUID, limit int) (_ []Referral, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.ListQualifying", attribute.String("wallet.currency_type_id", currencyTypeID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	var referrals []Referral
+	err = r.db.WithContext(ctx).Where("status = ?", enums.ReferralStatusPending).
+		Where("EXISTS (SELECT 1 FROM wallet_events WHERE wallet_events.owner_id = referrals.referee_id"+
+			" AND wallet_events.currency_type_id = ? AND wallet_events.transaction_type = ?)", currencyTypeID, enums.TransactionTypeTopUp).
+		Order("created_at").Order("referee_id").Limit(limit).Find(&referrals).Error
+	if err != nil {
+		return nil, err
+	}
+	return referrals, nil
+}
+
+// Qualify implements ReferralRepository. The referral stays locked from the
+// status check to the payout, so concurrent qualifying top-ups, or workers,
+// settle it once; the payout's idempotency key guards it as well.
+func (r *referralRepositoryImpl) Qualify(ctx context.Context, refereeID uuid.UUID, terms ReferralTerms, referrerWalletID, refereeWalletID uuid.UUID) (_ *Referral, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.Qualify", attribute.String("referral.referee_id", refereeID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	var referral Referral
+	settled := false
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("referee_id = ?", refereeID).First(&referral).Error; err != nil {
+			return err
+		}
+		if referral.Status != enums.ReferralStatusPending {
+			return nil
+		}
+		var first WalletTransaction
+		err := tx.Where("wallet_id = ? AND transaction_type = ?", refereeWalletID, enums.TransactionTypeTopUp).
+			Order("created_at").Order("id").First(&first).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		referral.CurrencyTypeID = &terms.CurrencyTypeID
+		referral.QualifyingTransactionID = &first.ID
+		referral.QualifyingAmount = first.Amount
+		referral.SettledAt = &now
+		referral.Status = enums.ReferralStatusDisqualified
+		if !first.CreatedAt.Before(referral.CreatedAt) && first.Amount >= terms.MinTopUp {
+			treasury, err := treasuryWallet(tx, terms.CurrencyTypeID)
+			if err != nil {
+				return err
+			}
+			referral.ReferenceID, err = post(tx, "referral:"+refereeID.String(), enums.TransactionTypeReferralReward, []posting{
+				{treasury.ID, -(terms.ReferrerReward + terms.RefereeReward)},
+				{referrerWalletID, terms.ReferrerReward},
+				{refereeWalletID, terms.RefereeReward},
+			})
+			if err != nil {
+				return err
+			}
+			referral.Status = enums.ReferralStatusRewarded
+			referral.ReferrerReward, referral.RefereeReward = terms.ReferrerReward, terms.RefereeReward
+		}
+		settled = true
+		return tx.Model(&Referral{}).Where("referee_id = ?", refereeID).Updates(map[string]any{
+			"status":                    referral.Status,
+			"currency_type_id":          referral.CurrencyTypeID,
+			"qualifying_transaction_id": referral.QualifyingTransactionID,
+			"qualifying_amount":         referral.QualifyingAmount,
+			"referrer_reward":           referral.ReferrerReward,
+			"referee_reward":            referral.RefereeReward,
+			"reference_id":              referral.ReferenceID,
+			"settled_at":                now,
+			"updated_at":                now,
+		}).Error
+	})
+	metrics.ObserveDBTransaction("referral_qualify", err, time.Since(start))
+	if errors.Is(err, ErrInsufficientBalance) {
+		metrics.ObserveInsufficientBalance(enums.TransactionTypeReferralReward, terms.CurrencyTypeID.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settled {
+		logger.FromContext(ctx).Info("referral settled", "referee_id", refereeID.String(), "status", referral.Status,
+			"reference_id", referral.ReferenceID)
+	}
+	if settled && referral.Status == enums.ReferralStatusRewarded {
+		notifyWalletEventsCommitted()
+		metrics.ObserveTransfer(enums.TransactionTypeReferralReward, terms.CurrencyTypeID.String(), terms.ReferrerReward+terms.RefereeReward)
+	}
+	return &referral, nil
+}
+
+// Report implements ReferralRepository.
+func (r *referralRepositoryImpl) Report(ctx context.Context, from *time.Time, to time.Time, limit int) (_ *ReferralReport, err error) {
+	ctx, span := startSpan(ctx, "ReferralRepository.Report")
+	defer func() { finishSpan(span, err) }()
+
+	db := r.db.WithContext(ctx)
+	settled := func(status string) *gorm.DB {
+		query := db.Model(&Referral{}).Where("status = ? AND settled_at < ?", status, to)
+		if from != nil {
+			query = query.Where("settled_at >= ?", *from)
+		}
+		return query
+	}
+	report := &ReferralReport{From: from, To: to, Payouts: []ReferralPayout{}, TopReferrers: []ReferrerPayout{}}
+	err = settled(enums.ReferralStatusRewarded).
+		Select("currency_type_id, COUNT(*) AS referrals, SUM(referrer_reward) AS referrer_rewards, SUM(referee_reward) AS referee_rewards").
+		Group("currency_type_id").Order("currency_type_id").Scan(&report.Payouts).Error
+	if err != nil {
+		return nil, err
+	}
+	err = settled(enums.ReferralStatusRewarded).
+		Select("referrer_id, currency_type_id, COUNT(*) AS referrals, SUM(referrer_reward) AS amount").
+		Group("referrer_id, currency_type_id").Order("amount DESC").Order("referrer_id").Limit(limit).Scan(&report.TopReferrers).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := settled(enums.ReferralStatusDisqualified).Count(&report.Disqualified).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&Referral{}).Where("status = ?", enums.ReferralStatusPending).Count(&report.Pending).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
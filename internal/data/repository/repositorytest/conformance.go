@@ -0,0 +1,548 @@
+// Package repositorytest holds the conformance suite every WalletRepository
+// and UserRepository implementation must pass.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+)
+
+// Repositories is one freshly emptied store under test.
+type Repositories struct {
+	Wallets repository.WalletRepository
+	Users   repository.UserRepository
+}
+
+// Factory returns empty repositories; it is called once per subtest.
+type Factory func(t *testing.T) Repositories
+
+// Run runs the full conformance suite against the repositories made by newRepos.
+func Run(t *testing.T, newRepos Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repos Repositories)
+	}{
+		{"CreateUserAndGetByID", testCreateUserAndGetByID},
+		{"CreateUserRejectsDuplicateID", testCreateUserRejectsDuplicateID},
+		{"GetUserByIDNotFound", testGetUserByIDNotFound},
+		{"CreateWalletRejectsDuplicateOwnerCurrency", testCreateWalletRejectsDuplicateOwnerCurrency},
+		{"CreateWalletRejectsNegativeBalance", testCreateWalletRejectsNegativeBalance},
+		{"GetWalletByOwner", testGetWalletByOwner},
+		{"SystemWallets", testSystemWallets},
+		{"TransferMovesFunds", testTransferMovesFunds},
+		{"TransferRejectsInsufficientBalance", testTransferRejectsInsufficientBalance},
+		{"TransferRejectsSameWallet", testTransferRejectsSameWallet},
+		{"TransferRejectsCurrencyMismatch", testTransferRejectsCurrencyMismatch},
+		{"TransferIsIdempotent", testTransferIsIdempotent},
+		{"ConcurrentSpendsNeverOverdraw", testConcurrentSpendsNeverOverdraw},
+		{"ConcurrentOpposingTransfersConserveFunds", testConcurrentOpposingTransfersConserveFunds},
+		{"ListUsersFiltersAndPages", testListUsersFiltersAndPages},
+		{"GetWalletByIDAndOwner", testGetWalletByIDAndOwner},
+		{"ListTransactionsNewestFirst", testListTransactionsNewestFirst},
+		{"FrozenWalletRejectsTransfers", testFrozenWalletRejectsTransfers},
+		{"MintAndBurnChangeTreasury", testMintAndBurnChangeTreasury},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepos(t))
+		})
+	}
+}
+
+func newWallet(t *testing.T, repos Repositories, ownerType string, currencyTypeID uuid.UUID, balance int64) repository.Wallet {
+	t.Helper()
+	wallet := repository.Wallet{
+		ID:             uuid.New(),
+		OwnerType:      ownerType,
+		OwnerID:        uuid.New(),
+		CurrencyTypeID: currencyTypeID,
+		Balance:        balance,
+	}
+	if err := repos.Wallets.CreateWallet(context.Background(), &wallet); err != nil {
+		t.Fatalf("create wallet: %v", err)
+	}
+	return wallet
+}
+
+func balanceOf(t *testing.T, repos Repositories, wallet repository.Wallet) int64 {
+	t.Helper()
+	got, err := repos.Wallets.GetWalletByOwner(context.Background(),
+		wallet.OwnerType, wallet.OwnerID.String(), wallet.CurrencyTypeID.String())
+	if err != nil {
+		t.Fatalf("get wallet: %v", err)
+	}
+	return got.Balance
+}
+
+func testCreateUserAndGetByID(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := &repository.User{ID: uuid.New(), Name: "Alice", Role: "user"}
+	if err := repos.Users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.Users.GetUserByID(ctx, user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || got.Name != "Alice" || got.Role != "user" {
+		t.Errorf("got %+v, want %+v", got, user)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("CreatedAt not set")
+	}
+}
+
+func testCreateUserRejectsDuplicateID(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := &repository.User{ID: uuid.New(), Name: "Alice", Role: "user"}
+	if err := repos.Users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	duplicate := &repository.User{ID: user.ID, Name: "Mallory", Role: "user"}
+	if err := repos.Users.CreateUser(ctx, duplicate); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("err = %v, want gorm.ErrDuplicatedKey", err)
+	}
+}
+
+func testGetUserByIDNotFound(t *testing.T, repos Repositories) {
+	_, err := repos.Users.GetUserByID(context.Background(), uuid.NewString())
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testCreateWalletRejectsDuplicateOwnerCurrency(t *testing.T, repos Repositories) {
+	currency := uuid.New()
+	wallet := newWallet(t, repos, "user", currency, 0)
+	duplicate := repository.Wallet{
+		ID:             uuid.New(),
+		OwnerType:      wallet.OwnerType,
+		OwnerID:        wallet.OwnerID,
+		CurrencyTypeID: currency,
+	}
+	err := repos.Wallets.CreateWallet(context.Background(), &duplicate)
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("err = %v, want gorm.ErrDuplicatedKey", err)
+	}
+
+	// the same owner may hold one wallet per currency
+	other := repository.Wallet{
+		ID:             uuid.New(),
+		OwnerType:      wallet.OwnerType,
+		OwnerID:        wallet.OwnerID,
+		CurrencyTypeID: uuid.New(),
+	}
+	if err := repos.Wallets.CreateWallet(context.Background(), &other); err != nil {
+		t.Errorf("second currency: %v", err)
+	}
+}
+
+func testCreateWalletRejectsNegativeBalance(t *testing.T, repos Repositories) {
+	wallet := repository.Wallet{
+		ID:             uuid.New(),
+		OwnerType:      "user",
+		OwnerID:        uuid.New(),
+		CurrencyTypeID: uuid.New(),
+		Balance:        -1,
+	}
+	if err := repos.Wallets.CreateWallet(context.Background(), &wallet); err == nil {
+		t.Error("created a wallet with a negative balance")
+	}
+}
+
+func testGetWalletByOwner(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	wallet := newWallet(t, repos, "user", uuid.New(), 42)
+
+	got, err := repos.Wallets.GetWalletByOwner(ctx, "user", wallet.OwnerID.String(), wallet.CurrencyTypeID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != wallet.ID || got.Balance != 42 {
+		t.Errorf("got %+v, want %+v", got, wallet)
+	}
+
+	_, err = repos.Wallets.GetWalletByOwner(ctx, "system", wallet.OwnerID.String(), wallet.CurrencyTypeID.String())
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("wrong owner type: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+	_, err = repos.Wallets.GetWalletByOwner(ctx, "user", wallet.OwnerID.String(), uuid.NewString())
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("wrong currency: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testSystemWallets(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	gold, diamond := uuid.New(), uuid.New()
+	goldTreasury := newWallet(t, repos, "system", gold, 1_000)
+	newWallet(t, repos, "system", diamond, 500)
+	newWallet(t, repos, "user", gold, 10)
+
+	got, err := repos.Wallets.GetSystemWalletByCurrencyType(ctx, gold.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != goldTreasury.ID {
+		t.Errorf("got wallet %s, want %s", got.ID, goldTreasury.ID)
+	}
+	if _, err := repos.Wallets.GetSystemWalletByCurrencyType(ctx, uuid.NewString()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("unknown currency: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	all, err := repos.Wallets.ListSystemWallets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("got %d system wallets, want 2", len(all))
+	}
+}
+
+func testTransferMovesFunds(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 100)
+
+	err := repos.Wallets.Transfer(ctx, user.ID.String(), treasury.ID.String(), currency.String(), "spend-1", 40, enums.TransactionTypeSpend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := balanceOf(t, repos, user); got != 60 {
+		t.Errorf("user balance = %d, want 60", got)
+	}
+	if got := balanceOf(t, repos, treasury); got != 1_040 {
+		t.Errorf("treasury balance = %d, want 1040", got)
+	}
+
+	transaction, err := repos.Wallets.GetTransactionByIdempotencyKey(ctx, "spend-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.ReferenceID == "" {
+		t.Error("transaction has no reference ID")
+	}
+	switch transaction.WalletID {
+	case user.ID:
+		if transaction.Amount != -40 || transaction.BalanceAfter != 60 {
+			t.Errorf("unexpected debit leg %+v", transaction)
+		}
+	case treasury.ID:
+		if transaction.Amount != 40 || transaction.BalanceAfter != 1_040 {
+			t.Errorf("unexpected credit leg %+v", transaction)
+		}
+	default:
+		t.Errorf("transaction belongs to unrelated wallet %s", transaction.WalletID)
+	}
+
+	legs, err := repos.Wallets.ListTransactionsByIdempotencyKey(ctx, "spend-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 2 || legs[0].WalletID != user.ID || legs[1].WalletID != treasury.ID || legs[1].Amount != 40 {
+		t.Errorf("legs %+v, want the user's debit then the treasury's credit of 40", legs)
+	}
+	if legs, err := repos.Wallets.ListTransactionsByIdempotencyKey(ctx, "unknown"); err != nil || len(legs) != 0 {
+		t.Errorf("legs of an unknown key = %+v, %v; want none", legs, err)
+	}
+}
+
+func testTransferRejectsInsufficientBalance(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 100)
+
+	err := repos.Wallets.Transfer(ctx, user.ID.String(), treasury.ID.String(), currency.String(), "spend-too-much", 101, enums.TransactionTypeSpend)
+	if !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("err = %v, want ErrInsufficientBalance", err)
+	}
+	if got := balanceOf(t, repos, user); got != 100 {
+		t.Errorf("user balance = %d, want 100", got)
+	}
+	if _, err := repos.Wallets.GetTransactionByIdempotencyKey(ctx, "spend-too-much"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("rejected transfer left a ledger row: err = %v", err)
+	}
+}
+
+func testTransferRejectsSameWallet(t *testing.T, repos Repositories) {
+	currency := uuid.New()
+	user := newWallet(t, repos, "user", currency, 100)
+	err := repos.Wallets.Transfer(context.Background(), user.ID.String(), user.ID.String(), currency.String(), "self", 1, enums.TransactionTypeSpend)
+	if err == nil {
+		t.Fatal("transfer to the same wallet succeeded")
+	}
+	if got := balanceOf(t, repos, user); got != 100 {
+		t.Errorf("user balance = %d, want 100", got)
+	}
+}
+
+func testTransferRejectsCurrencyMismatch(t *testing.T, repos Repositories) {
+	gold, diamond := uuid.New(), uuid.New()
+	treasury := newWallet(t, repos, "system", gold, 1_000)
+	user := newWallet(t, repos, "user", diamond, 100)
+	err := repos.Wallets.Transfer(context.Background(), treasury.ID.String(), user.ID.String(), gold.String(), "mismatch", 1, enums.TransactionTypeTopUp)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testTransferIsIdempotent(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 100)
+	for i := 0; i < 3; i++ {
+		err := repos.Wallets.Transfer(ctx, treasury.ID.String(), user.ID.String(), currency.String(), "top-up-1", 25, enums.TransactionTypeTopUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := balanceOf(t, repos, user); got != 125 {
+		t.Errorf("user balance = %d, want 125", got)
+	}
+	if got := balanceOf(t, repos, treasury); got != 975 {
+		t.Errorf("treasury balance = %d, want 975", got)
+	}
+}
+
+func testConcurrentSpendsNeverOverdraw(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 100)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repos.Wallets.Transfer(ctx, user.ID.String(), treasury.ID.String(), currency.String(), uuid.NewString(), 10, enums.TransactionTypeSpend)
+			if err != nil && !errors.Is(err, repository.ErrInsufficientBalance) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 10 {
+		t.Errorf("%d spends succeeded, want 10", succeeded)
+	}
+	if got := balanceOf(t, repos, user); got != 0 {
+		t.Errorf("user balance = %d, want 0", got)
+	}
+	if got := balanceOf(t, repos, treasury); got != 1_100 {
+		t.Errorf("treasury balance = %d, want 1100", got)
+	}
+}
+
+func testConcurrentOpposingTransfersConserveFunds(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	a := newWallet(t, repos, "user", currency, 500)
+	b := newWallet(t, repos, "user", currency, 500)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		from, to := a, b
+		if i%2 == 1 {
+			from, to = b, a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repos.Wallets.Transfer(ctx, from.ID.String(), to.ID.String(), currency.String(), uuid.NewString(), 7, enums.TransactionTypeTopUp)
+			if err != nil && !errors.Is(err, repository.ErrInsufficientBalance) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if total := balanceOf(t, repos, a) + balanceOf(t, repos, b); total != 1_000 {
+		t.Errorf("total supply = %d, want 1000", total)
+	}
+}
+
+func testListUsersFiltersAndPages(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	for _, user := range []repository.User{
+		{ID: uuid.New(), Name: "carol", Role: "user"},
+		{ID: uuid.New(), Name: "alice", Role: "user"},
+		{ID: uuid.New(), Name: "system", Role: "system"},
+		{ID: uuid.New(), Name: "bob", Role: "user"},
+	} {
+		if err := repos.Users.CreateUser(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(users []repository.User) []string {
+		result := make([]string, len(users))
+		for i, user := range users {
+			result[i] = user.Name
+		}
+		return result
+	}
+
+	all, err := repos.Users.ListUsers(ctx, "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(all); len(got) != 4 || got[0] != "alice" || got[3] != "system" {
+		t.Errorf("all users = %v, want 4 ordered by name", got)
+	}
+	page, err := repos.Users.ListUsers(ctx, "user", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(page); len(got) != 2 || got[0] != "bob" || got[1] != "carol" {
+		t.Errorf("second page of users = %v, want [bob carol]", got)
+	}
+}
+
+func testGetWalletByIDAndOwner(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	wallet := newWallet(t, repos, "user", uuid.New(), 5)
+	other := repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: wallet.OwnerID, CurrencyTypeID: uuid.New()}
+	if err := repos.Wallets.CreateWallet(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+	newWallet(t, repos, "user", wallet.CurrencyTypeID, 0)
+
+	got, err := repos.Wallets.GetWalletByID(ctx, wallet.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OwnerID != wallet.OwnerID || got.Balance != 5 {
+		t.Errorf("got %+v, want %+v", got, wallet)
+	}
+	if _, err := repos.Wallets.GetWalletByID(ctx, uuid.NewString()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("unknown wallet: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	owned, err := repos.Wallets.ListWalletsByOwner(ctx, wallet.OwnerID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owned) != 2 {
+		t.Errorf("owner has %d wallets, want 2", len(owned))
+	}
+}
+
+func testListTransactionsNewestFirst(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 0)
+	for i, amount := range []int64{10, 20, 30} {
+		key := "list-" + string(rune('a'+i))
+		if err := repos.Wallets.Transfer(ctx, treasury.ID.String(), user.ID.String(), currency.String(), key, amount, enums.TransactionTypeTopUp); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got, err := repos.Wallets.ListTransactions(ctx, user.ID.String(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Amount != 30 || got[1].Amount != 20 {
+		t.Errorf("got %+v, want the 30 and 20 credits", got)
+	}
+	for _, transaction := range got {
+		if transaction.WalletID != user.ID {
+			t.Errorf("listed a leg of wallet %s", transaction.WalletID)
+		}
+	}
+}
+
+func testFrozenWalletRejectsTransfers(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 1_000)
+	user := newWallet(t, repos, "user", currency, 100)
+
+	if err := repos.Wallets.SetFrozen(ctx, user.ID.String(), true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.Wallets.GetWalletByID(ctx, user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Frozen {
+		t.Error("wallet not reported as frozen")
+	}
+	err = repos.Wallets.Transfer(ctx, user.ID.String(), treasury.ID.String(), currency.String(), "frozen-out", 10, enums.TransactionTypeSpend)
+	if !errors.Is(err, repository.ErrWalletFrozen) {
+		t.Errorf("spend from frozen wallet: err = %v, want ErrWalletFrozen", err)
+	}
+	err = repos.Wallets.Transfer(ctx, treasury.ID.String(), user.ID.String(), currency.String(), "frozen-in", 10, enums.TransactionTypeTopUp)
+	if !errors.Is(err, repository.ErrWalletFrozen) {
+		t.Errorf("top-up of frozen wallet: err = %v, want ErrWalletFrozen", err)
+	}
+	if got := balanceOf(t, repos, user); got != 100 {
+		t.Errorf("frozen wallet balance = %d, want 100", got)
+	}
+
+	if err := repos.Wallets.SetFrozen(ctx, user.ID.String(), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Wallets.Transfer(ctx, user.ID.String(), treasury.ID.String(), currency.String(), "thawed", 10, enums.TransactionTypeSpend); err != nil {
+		t.Errorf("spend after unfreezing: %v", err)
+	}
+	if err := repos.Wallets.SetFrozen(ctx, uuid.NewString(), true); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("freeze unknown wallet: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testMintAndBurnChangeTreasury(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	currency := uuid.New()
+	treasury := newWallet(t, repos, "system", currency, 100)
+
+	for i := 0; i < 2; i++ {
+		if err := repos.Wallets.Mint(ctx, currency.String(), "mint-1", 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := balanceOf(t, repos, treasury); got != 150 {
+		t.Errorf("after mint: treasury = %d, want 150", got)
+	}
+	leg, err := repos.Wallets.GetTransactionByIdempotencyKey(ctx, "mint-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leg.WalletID != treasury.ID || leg.Amount != 50 || leg.TransactionType != enums.TransactionTypeMint {
+		t.Errorf("unexpected mint leg %+v", leg)
+	}
+
+	if err := repos.Wallets.Burn(ctx, currency.String(), "burn-1", 120); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Wallets.Burn(ctx, currency.String(), "burn-2", 31); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Errorf("burn beyond supply: err = %v, want ErrInsufficientBalance", err)
+	}
+	if got := balanceOf(t, repos, treasury); got != 30 {
+		t.Errorf("after burn: treasury = %d, want 30", got)
+	}
+	if err := repos.Wallets.Mint(ctx, uuid.NewString(), "mint-unknown", 1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("mint unknown currency: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
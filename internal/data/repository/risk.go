@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNotUnderReview = errors.New("decision is not awaiting review")
+
+// RiskDecision is what the risk rules decided about one spend or transfer.
+// An operation held for review is applied under its IdempotencyKey once an
+// admin approves it.
+type RiskDecision struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	Operation string    `gorm:"type:varchar(16);not null" json:"operation"`
+	OwnerID   uuid.UUID `gorm:"type:char(36);not null" json:"owner_id"`
+	// ToOwnerID is the recipient of a transfer.
+	ToOwnerID      *uuid.UUID `gorm:"type:char(36)" json:"to_owner_id,omitempty"`
+	CurrencyTypeID uuid.UUID  `gorm:"type:char(36);not null" json:"currency_type_id"`
+	Amount         int64      `gorm:"not null" json:"amount"`
+	IdempotencyKey string     `gorm:"type:varchar(64);not null" json:"idempotency_key"`
+	Outcome        string     `gorm:"type:varchar(16);not null" json:"outcome"`
+	// Rule and Reason explain a deny or review outcome.
+	Rule   string `gorm:"type:varchar(64);not null;default:''" json:"rule,omitempty"`
+	Reason string `gorm:"type:varchar(255);not null;default:''" json:"reason,omitempty"`
+	// ReviewStatus and the fields after it are only set on review outcomes.
+	ReviewStatus string     `gorm:"type:varchar(16);not null;default:''" json:"review_status,omitempty"`
+	ReviewedBy   string     `gorm:"type:varchar(100);not null;default:''" json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReferenceID  string     `gorm:"type:varchar(64);not null;default:''" json:"reference_id,omitempty"`
+	CreatedAt    time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// RiskHistory is what the risk rules know of the owner of an operation.
+type RiskHistory interface {
+	// OwnerSince is when the owner's user was created.
+	OwnerSince() time.Time
+	// Attempts counts the owner's operations screened since, whatever their
+	// outcome.
+	Attempts(since time.Time) (int64, error)
+	// Debits counts and sums what left the owner's wallet in currencyTypeID
+	// since.
+	Debits(currencyTypeID uuid.UUID, since time.Time) (count, total int64, err error)
+}
+
+// RiskDecisionFilter narrows ListDecisions; empty fields match everything.
+type RiskDecisionFilter struct {
+	OwnerID      string
+	Outcome      string
+	ReviewStatus string
+}
+
+type RiskRepository interface {
+	// Decide returns the owner's decision recorded under
+	// decision.IdempotencyKey, if any, with existing true; a key recorded for
+	// a different operation, or by another owner, is ErrIdempotencyKeyReused.
+	// Otherwise it locks the owner's user, so that an owner's operations are
+	// screened one at a time, calls evaluate to set the outcome from the
+	// owner's history and records the decision.
+	Decide(ctx context.Context, decision *RiskDecision, evaluate func(history RiskHistory) error) (_ *RiskDecision, existing bool, err error)
+	GetDecision(ctx context.Context, id string) (*RiskDecision, error)
+	// ListDecisions returns the most recent decisions, newest first.
+	ListDecisions(ctx context.Context, filter RiskDecisionFilter, limit int) ([]RiskDecision, error)
+	// Approve marks a decision pending review approved by reviewer, then
+	// calls release to apply the operation and records the reference ID it
+	// returns. When release fails the decision is pending again. Decisions
+	// not pending review are ErrNotUnderReview.
+	Approve(ctx context.Context, id, reviewer string,
+		release func(ctx context.Context, decision *RiskDecision) (referenceID string, err error)) (*RiskDecision, error)
+	// Reject marks a decision pending review rejected by reviewer; the
+	// operation is never applied.
+	Reject(ctx context.Context, id, reviewer string) (*RiskDecision, error)
+}
+
+type riskRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRiskRepository(db *gorm.DB) RiskRepository {
+	return &riskRepositoryImpl{db: db}
+}
+
+// riskHistory answers from inside the transaction of Decide.
+type riskHistory struct {
+	tx    *gorm.DB
+	owner User
+}
+
+func (h *riskHistory) OwnerSince() time.Time {
+	return h.owner.CreatedAt
+}
+
+func (h *riskHistory) Attempts(since time.Time) (int64, error) {
+	var attempts int64
+	err := h.tx.Model(&RiskDecision{}).Where("owner_id = ? AND created_at >= ?", h.owner.ID, since).Count(&attempts).Error
+	return attempts, err
+}
+
+func (h *riskHistory) Debits(currencyTypeID uuid.UUID, since time.Time) (count, total int64, err error) {
+	var debits struct {
+		Count int64
+		Total int64
+	}
+	err = h.tx.Model(&WalletTransaction{}).Joins("JOIN wallets ON wallets.id = wallet_transactions.wallet_id").
+		Where("wallets.owner_id = ? AND wallets.currency_type_id = ?", h.owner.ID, currencyTypeID).
+		Where("wallet_transactions.amount < 0 AND wallet_transactions.created_at >= ?", since).
+		Select("COUNT(*) AS count, COALESCE(SUM(-wallet_transactions.amount), 0) AS total").Scan(&debits).Error
+	return debits.Count, debits.Total, err
+}
+
+// Decide implements RiskRepository.
+func (r *riskRepositoryImpl) Decide(ctx context.Context, decision *RiskDecision, evaluate func(history RiskHistory) error) (_ *RiskDecision, existing bool, err error) {
+	ctx, span := startSpan(ctx, "RiskRepository.Decide",
+		attribute.String("risk.operation", decision.Operation), attribute.String("wallet.owner_id", decision.OwnerID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	var recorded RiskDecision
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		history := &riskHistory{tx: tx}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", decision.OwnerID).First(&history.owner).Error; err != nil {
+			return err
+		}
+		err := tx.Where("owner_id = ? AND idempotency_key = ?", decision.OwnerID, decision.IdempotencyKey).First(&recorded).Error
+		if err == nil {
+			existing = true
+			if !sameRiskOperation(&recorded, decision) {
+				return ErrIdempotencyKeyReused
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := evaluate(history); err != nil {
+			return err
+		}
+		recorded = *decision
+		recorded.ID = uuid.New()
+		recorded.CreatedAt = time.Now().UTC()
+		recorded.UpdatedAt = recorded.CreatedAt
+		return tx.Create(&recorded).Error
+	})
+	metrics.ObserveDBTransaction("risk_decide", err, time.Since(start))
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// the owner's lock serialises their own retries, so the key is
+		// another owner's
+		err = ErrIdempotencyKeyReused
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !existing && recorded.Outcome != enums.RiskOutcomeAllow {
+		logger.FromContext(ctx).Warn("risk rule stopped operation", "decision_id", recorded.ID.String(),
+			"outcome", recorded.Outcome, "rule", recorded.Rule, "reason", recorded.Reason)
+	}
+	return &recorded, existing, nil
+}
+
+// sameRiskOperation reports whether an operation repeats the one a decision
+// was recorded for.
+func sameRiskOperation(a, b *RiskDecision) bool {
+	sameRecipient := a.ToOwnerID == nil && b.ToOwnerID == nil || a.ToOwnerID != nil && b.ToOwnerID != nil && *a.ToOwnerID == *b.ToOwnerID
+	return a.Operation == b.Operation && a.OwnerID == b.OwnerID && a.CurrencyTypeID == b.CurrencyTypeID &&
+		a.Amount == b.Amount && sameRecipient
+}
+
+// GetDecision implements RiskRepository.
+func (r *riskRepositoryImpl) GetDecision(ctx context.Context, id string) (_ *RiskDecision, err error) {
+	ctx, span := startSpan(ctx, "RiskRepository.GetDecision", attribute.String("risk.decision_id", id))
+	defer func() { finishSpan(span, err) }()
+
+	var decision RiskDecision
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&decision).Error; err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// ListDecisions implements RiskRepository.
+func (r *riskRepositoryImpl) ListDecisions(ctx context.Context, filter RiskDecisionFilter, limit int) (_ []RiskDecision, err error) {
+	ctx, span := startSpan(ctx, "RiskRepository.ListDecisions",
+		attribute.String("wallet.owner_id", filter.OwnerID), attribute.String("risk.outcome", filter.Outcome))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx)
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.ReviewStatus != "" {
+		query = query.Where("review_status = ?", filter.ReviewStatus)
+	}
+	var decisions []RiskDecision
+	if err := query.Order("created_at DESC").Order("id").Limit(limit).Find(&decisions).Error; err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// Approve implements RiskRepository. The decision is claimed as approved
+// before release runs, outside the claiming transaction since release opens
+// its own, so a concurrent approval or rejection finds it no longer pending.
+// Should the process stop in between, the client's retry of the operation
+// finds the decision approved and applies it.
+func (r *riskRepositoryImpl) Approve(ctx context.Context, id, reviewer string,
+	release func(ctx context.Context, decision *RiskDecision) (referenceID string, err error)) (_ *RiskDecision, err error) {
+	ctx, span := startSpan(ctx, "RiskRepository.Approve", attribute.String("risk.decision_id", id))
+	defer func() { finishSpan(span, err) }()
+
+	decision, err := r.review(ctx, id, enums.RiskReviewApproved, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	referenceID, err := release(ctx, decision)
+	if err != nil {
+		reopen := r.db.WithContext(ctx).Model(&RiskDecision{}).
+			Where("id = ? AND review_status = ?", decision.ID, enums.RiskReviewApproved).
+			Updates(map[string]any{"review_status": enums.RiskReviewPending, "reviewed_by": "", "reviewed_at": nil,
+				"updated_at": time.Now().UTC()})
+		return nil, errors.Join(err, reopen.Error)
+	}
+	if err := r.db.WithContext(ctx).Model(&RiskDecision{}).Where("id = ?", decision.ID).
+		Update("reference_id", referenceID).Error; err != nil {
+		return nil, err
+	}
+	decision.ReferenceID = referenceID
+	logger.FromContext(ctx).Info("risk review approved", "decision_id", id, "reviewed_by", reviewer, "reference_id", referenceID)
+	return decision, nil
+}
+
+// Reject implements RiskRepository.
+func (r *riskRepositoryImpl) Reject(ctx context.Context, id, reviewer string) (_ *RiskDecision, err error) {
+	ctx, span := startSpan(ctx, "RiskRepository.Reject", attribute.String("risk.decision_id", id))
+	defer func() { finishSpan(span, err) }()
+
+	decision, err := r.review(ctx, id, enums.RiskReviewRejected, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("risk review rejected", "decision_id", id, "reviewed_by", reviewer)
+	return decision, nil
+}
+
+func (r *riskRepositoryImpl) review(ctx context.Context, id, status, reviewer string) (*RiskDecision, error) {
+	var decision RiskDecision
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&decision).Error; err != nil {
+			return err
+		}
+		if decision.ReviewStatus != enums.RiskReviewPending {
+			return ErrNotUnderReview
+		}
+		now := time.Now().UTC()
+		decision.ReviewStatus, decision.ReviewedBy, decision.ReviewedAt, decision.UpdatedAt = status, reviewer, &now, now
+		return tx.Model(&RiskDecision{}).Where("id = ?", decision.ID).Updates(map[string]any{
+			"review_status": status,
+			"reviewed_by":   reviewer,
+			"reviewed_at":   now,
+			"updated_at":    now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+func TestDecideRejectsAKeyReusedForAnotherOperation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cfg config_env.DbConfig) {
+		ctx := context.Background()
+		db := openEmptyDB(t, cfg)
+		users := repository.NewUserRepository(db)
+		decisions := repository.NewRiskRepository(db)
+		alice, bob := uuid.New(), uuid.New()
+		for _, id := range []uuid.UUID{alice, bob} {
+			if err := users.CreateUser(ctx, &repository.User{ID: id, Name: "player", Role: "user"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		key, currency := uuid.NewString(), uuid.New()
+		spend := func(owner uuid.UUID, amount int64) *repository.RiskDecision {
+			return &repository.RiskDecision{Operation: enums.RiskOperationSpend, OwnerID: owner,
+				CurrencyTypeID: currency, Amount: amount, IdempotencyKey: key}
+		}
+		allow := func(d *repository.RiskDecision) func(repository.RiskHistory) error {
+			return func(repository.RiskHistory) error {
+				d.Outcome = enums.RiskOutcomeAllow
+				return nil
+			}
+		}
+
+		first := spend(alice, 40)
+		recorded, existing, err := decisions.Decide(ctx, first, allow(first))
+		if err != nil || existing {
+			t.Fatalf("first decision: existing %v, %v", existing, err)
+		}
+		retry := spend(alice, 40)
+		again, existing, err := decisions.Decide(ctx, retry, allow(retry))
+		if err != nil || !existing || again.ID != recorded.ID {
+			t.Fatalf("retry got %+v, existing %v, %v; want decision %s", again, existing, err, recorded.ID)
+		}
+
+		for name, reused := range map[string]*repository.RiskDecision{
+			"another amount": spend(alice, 400),
+			"another owner":  spend(bob, 40),
+		} {
+			if _, _, err := decisions.Decide(ctx, reused, allow(reused)); !errors.Is(err, repository.ErrIdempotencyKeyReused) {
+				t.Errorf("%s: got %v, want ErrIdempotencyKeyReused", name, err)
+			}
+		}
+	})
+}
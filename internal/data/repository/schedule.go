@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrScheduleFinished  = errors.New("schedule has already completed or been cancelled")
+	ErrScheduleLeaseLost = errors.New("schedule was claimed by another worker")
+)
+
+// Schedule moves a fixed amount between an owner's wallet and the currency's
+// treasury once, at a fixed interval or on a cron expression. OccurrenceAt is
+// the nominal time of the occurrence due next and NextRunAt when it is next
+// attempted, which is later while a failed attempt waits to be retried; both
+// are empty once the schedule has completed.
+type Schedule struct {
+	ID                uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	OwnerID           uuid.UUID  `gorm:"type:char(36);not null" json:"owner_id"`
+	CurrencyTypeID    uuid.UUID  `gorm:"type:char(36);not null" json:"currency_type_id"`
+	Direction         string     `gorm:"type:varchar(16);not null" json:"direction"`
+	Amount            int64      `gorm:"not null" json:"amount"`
+	Kind              string     `gorm:"type:varchar(16);not null" json:"kind"`
+	Cron              string     `gorm:"type:varchar(128);not null;default:''" json:"cron,omitempty"`
+	IntervalSeconds   int64      `gorm:"not null;default:0" json:"interval_seconds,omitempty"`
+	Timezone          string     `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	EndAt             *time.Time `json:"end_at"`
+	OnFailure         string     `gorm:"type:varchar(16);not null" json:"on_failure"`
+	MaxRetries        int        `gorm:"not null;default:0" json:"max_retries"`
+	RetryDelaySeconds int64      `gorm:"not null;default:0" json:"retry_delay_seconds"`
+	Status            string     `gorm:"type:varchar(16);not null" json:"status"`
+	OccurrenceAt      *time.Time `json:"occurrence_at"`
+	NextRunAt         *time.Time `json:"next_run_at"`
+	// Attempts counts the failed attempts at OccurrenceAt.
+	Attempts     int        `gorm:"not null;default:0" json:"attempts"`
+	RunCount     int        `gorm:"not null;default:0" json:"run_count"`
+	FailureCount int        `gorm:"not null;default:0" json:"failure_count"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	LastError    string     `gorm:"type:text;not null" json:"last_error,omitempty"`
+	LeaseOwner   string     `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	LeaseUntil   *time.Time `json:"-"`
+	CreatedAt    time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// ScheduleRun is the outcome of one occurrence of a schedule, recorded once
+// it succeeded or failed all its attempts.
+type ScheduleRun struct {
+	ScheduleID     uuid.UUID `gorm:"type:char(36);primaryKey" json:"-"`
+	OccurrenceAt   time.Time `gorm:"primaryKey" json:"occurrence_at"`
+	Status         string    `gorm:"type:varchar(16);not null" json:"status"`
+	Attempts       int       `gorm:"not null" json:"attempts"`
+	Error          string    `gorm:"type:text;not null" json:"error,omitempty"`
+	IdempotencyKey string    `gorm:"type:varchar(64);not null" json:"idempotency_key"`
+	ReferenceID    string    `gorm:"type:varchar(64);not null" json:"reference_id,omitempty"`
+	// Replayed runs found their occurrence already applied by the ledger.
+	Replayed  bool      `gorm:"not null;default:false" json:"replayed"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	GetSchedule(ctx context.Context, id string) (*Schedule, error)
+	// ListSchedules returns the most recent schedules, newest first,
+	// optionally only the owner's or those in status.
+	ListSchedules(ctx context.Context, ownerID, status string, limit int) ([]Schedule, error)
+	// ListRuns returns a schedule's most recent runs, newest first.
+	ListRuns(ctx context.Context, scheduleID string, limit int) ([]ScheduleRun, error)
+	// ClaimDue leases up to limit active schedules whose next run is due,
+	// and which no other worker holds, to owner.
+	ClaimDue(ctx context.Context, owner string, lease time.Duration, limit int) ([]Schedule, error)
+	// Advance saves the state owner's attempt left the schedule in, records
+	// run unless it is nil, and releases the lease. A status the schedule was
+	// paused or cancelled into meanwhile is kept. It fails with
+	// ErrScheduleLeaseLost once the schedule is no longer owner's.
+	Advance(ctx context.Context, schedule *Schedule, owner string, run *ScheduleRun) error
+	// Release gives up owner's lease without changing the schedule.
+	Release(ctx context.Context, id, owner string) error
+	// Pause stops an active schedule from running.
+	Pause(ctx context.Context, id string) (*Schedule, error)
+	// Resume reactivates a paused schedule. An occurrence that became due
+	// while it was paused, or that paused it by failing, runs at once.
+	Resume(ctx context.Context, id string) (*Schedule, error)
+	Cancel(ctx context.Context, id string) (*Schedule, error)
+}
+
+type scheduleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepositoryImpl{db: db}
+}
+
+// CreateSchedule implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) CreateSchedule(ctx context.Context, schedule *Schedule) (err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.CreateSchedule", attribute.String("schedule.id", schedule.ID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+// GetSchedule implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) GetSchedule(ctx context.Context, id string) (_ *Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.GetSchedule", attribute.String("schedule.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	var schedule Schedule
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) ListSchedules(ctx context.Context, ownerID, status string, limit int) (_ []Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.ListSchedules",
+		attribute.String("schedule.owner_id", ownerID), attribute.String("schedule.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx)
+	if ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var schedules []Schedule
+	if err := query.Order("created_at DESC").Order("id").Limit(limit).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ListRuns implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) ListRuns(ctx context.Context, scheduleID string, limit int) (_ []ScheduleRun, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.ListRuns", attribute.String("schedule.id", scheduleID))
+	defer func() { finishSpan(span, err) }()
+
+	var runs []ScheduleRun
+	if err := r.db.WithContext(ctx).Where("schedule_id = ?", scheduleID).
+		Order("occurrence_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// ClaimDue implements ScheduleRepository. Each candidate is claimed with a
+// conditional update, so two instances racing for a schedule cannot both
+// win.
+func (r *scheduleRepositoryImpl) ClaimDue(ctx context.Context, owner string, lease time.Duration, limit int) (_ []Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.ClaimDue")
+	defer func() { finishSpan(span, err) }()
+
+	db := r.db.WithContext(ctx)
+	now := time.Now().UTC()
+	due := "status = ? AND next_run_at <= ? AND (lease_until IS NULL OR lease_until < ?)"
+	var candidates []uuid.UUID
+	if err := db.Model(&Schedule{}).Where(due, enums.ScheduleStatusActive, now, now).
+		Order("next_run_at").Order("id").Limit(limit).Pluck("id", &candidates).Error; err != nil {
+		return nil, err
+	}
+	var claimed []uuid.UUID
+	for _, id := range candidates {
+		result := db.Model(&Schedule{}).Where("id = ?", id).Where(due, enums.ScheduleStatusActive, now, now).
+			Updates(map[string]any{"lease_owner": owner, "lease_until": now.Add(lease)})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, id)
+		}
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+	var schedules []Schedule
+	if err := db.Where("id IN ?", claimed).Order("next_run_at").Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Advance implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) Advance(ctx context.Context, schedule *Schedule, owner string, run *ScheduleRun) (err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.Advance",
+		attribute.String("schedule.id", schedule.ID.String()), attribute.String("schedule.status", schedule.Status))
+	defer func() { finishSpan(span, err) }()
+
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := r.updateOwned(tx, schedule.ID.String(), owner, map[string]any{
+			"status":        gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", enums.ScheduleStatusActive, schedule.Status),
+			"occurrence_at": schedule.OccurrenceAt,
+			"next_run_at":   schedule.NextRunAt,
+			"attempts":      schedule.Attempts,
+			"run_count":     schedule.RunCount,
+			"failure_count": schedule.FailureCount,
+			"last_run_at":   schedule.LastRunAt,
+			"last_error":    schedule.LastError,
+			"lease_owner":   "",
+			"lease_until":   nil,
+			"updated_at":    now,
+		})
+		if err != nil || run == nil {
+			return err
+		}
+		run.ScheduleID = schedule.ID
+		run.CreatedAt = now
+		// an occurrence that paused its schedule runs again on resume
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(run).Error
+	})
+}
+
+// Release implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) Release(ctx context.Context, id, owner string) (err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.Release", attribute.String("schedule.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.updateOwned(tx, id, owner, map[string]any{"lease_owner": "", "lease_until": nil})
+	})
+}
+
+func (r *scheduleRepositoryImpl) updateOwned(tx *gorm.DB, id, owner string, values map[string]any) error {
+	owned := tx.Model(&Schedule{}).Where("id = ? AND lease_owner = ?", id, owner)
+	result := owned.Session(&gorm.Session{}).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL reports unchanged rows as unaffected
+		var still int64
+		if err := owned.Session(&gorm.Session{}).Count(&still).Error; err != nil {
+			return err
+		}
+		if still == 0 {
+			return ErrScheduleLeaseLost
+		}
+	}
+	return nil
+}
+
+// Pause implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) Pause(ctx context.Context, id string) (_ *Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.Pause", attribute.String("schedule.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return r.transition(ctx, id, func(schedule *Schedule) (map[string]any, error) {
+		if finished(schedule) {
+			return nil, ErrScheduleFinished
+		}
+		if schedule.Status != enums.ScheduleStatusActive {
+			return nil, nil
+		}
+		return map[string]any{"status": enums.ScheduleStatusPaused}, nil
+	})
+}
+
+// Resume implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) Resume(ctx context.Context, id string) (_ *Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.Resume", attribute.String("schedule.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return r.transition(ctx, id, func(schedule *Schedule) (map[string]any, error) {
+		if finished(schedule) {
+			return nil, ErrScheduleFinished
+		}
+		if schedule.Status != enums.ScheduleStatusPaused {
+			return nil, nil
+		}
+		next := time.Now().UTC()
+		if schedule.OccurrenceAt != nil && schedule.OccurrenceAt.After(next) {
+			next = *schedule.OccurrenceAt
+		}
+		return map[string]any{"status": enums.ScheduleStatusActive, "next_run_at": next, "attempts": 0}, nil
+	})
+}
+
+// Cancel implements ScheduleRepository.
+func (r *scheduleRepositoryImpl) Cancel(ctx context.Context, id string) (_ *Schedule, err error) {
+	ctx, span := startSpan(ctx, "ScheduleRepository.Cancel", attribute.String("schedule.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return r.transition(ctx, id, func(schedule *Schedule) (map[string]any, error) {
+		switch schedule.Status {
+		case enums.ScheduleStatusCompleted:
+			return nil, ErrScheduleFinished
+		case enums.ScheduleStatusCancelled:
+			return nil, nil
+		}
+		return map[string]any{"status": enums.ScheduleStatusCancelled, "next_run_at": nil}, nil
+	})
+}
+
+// transition applies the values change returns for the locked schedule, if
+// any.
+func (r *scheduleRepositoryImpl) transition(ctx context.Context, id string, change func(*Schedule) (map[string]any, error)) (*Schedule, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var schedule Schedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&schedule).Error; err != nil {
+			return err
+		}
+		values, err := change(&schedule)
+		if err != nil || values == nil {
+			return err
+		}
+		values["updated_at"] = time.Now().UTC()
+		return tx.Model(&schedule).Updates(values).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSchedule(ctx, id)
+}
+
+func finished(schedule *Schedule) bool {
+	return schedule.Status == enums.ScheduleStatusCompleted || schedule.Status == enums.ScheduleStatusCancelled
+}
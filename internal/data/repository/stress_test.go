@@ -0,0 +1,203 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+// forEachImplementation runs test against the in-memory repository and
+// every configured database backend. Each call gets a repository of its own.
+func forEachImplementation(t *testing.T, test func(t *testing.T, wallets repository.WalletRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewInMemoryWalletRepository())
+	})
+	forEachBackend(t, func(t *testing.T, cfg config_env.DbConfig) {
+		test(t, repository.NewWalletRepository(openEmptyDB(t, cfg)))
+	})
+}
+
+// ledger is a set of wallets in one currency, created with known balances.
+type ledger struct {
+	currency uuid.UUID
+	wallets  []repository.Wallet
+	initial  map[uuid.UUID]int64
+}
+
+func newLedger(t *testing.T, wallets repository.WalletRepository, balances ...int64) *ledger {
+	t.Helper()
+	l := &ledger{currency: uuid.New(), initial: make(map[uuid.UUID]int64)}
+	for i, balance := range balances {
+		ownerType := "user"
+		if i == 0 {
+			ownerType = "system"
+		}
+		wallet := repository.Wallet{ID: uuid.New(), OwnerType: ownerType, OwnerID: uuid.New(), CurrencyTypeID: l.currency, Balance: balance}
+		if err := wallets.CreateWallet(context.Background(), &wallet); err != nil {
+			t.Fatal(err)
+		}
+		l.wallets = append(l.wallets, wallet)
+		l.initial[wallet.ID] = balance
+	}
+	return l
+}
+
+func (l *ledger) balances(t *testing.T, wallets repository.WalletRepository) map[uuid.UUID]int64 {
+	t.Helper()
+	result := make(map[uuid.UUID]int64, len(l.wallets))
+	for _, wallet := range l.wallets {
+		got, err := wallets.GetWalletByOwner(context.Background(), wallet.OwnerType, wallet.OwnerID.String(), l.currency.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		result[wallet.ID] = got.Balance
+	}
+	return result
+}
+
+type transferOp struct {
+	key      string
+	from, to repository.Wallet
+	amount   int64
+	kind     enums.TransactionType
+}
+
+func TestStressConcurrentTransfers(t *testing.T) {
+	operations := 2_000
+	if testing.Short() {
+		operations = 200
+	}
+	forEachImplementation(t, func(t *testing.T, wallets repository.WalletRepository) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		rng := rand.New(rand.NewSource(1))
+
+		// wallet 0 is the treasury; a handful of hot user wallets keep lock contention high
+		l := newLedger(t, wallets, 5_000, 100, 100, 100, 100, 100)
+		treasury, users := l.wallets[0], l.wallets[1:]
+
+		// about a quarter of the keys are submitted twice
+		unique := operations * 4 / 5
+		ops := make([]transferOp, unique)
+		for i := range ops {
+			user := users[rng.Intn(len(users))]
+			op := transferOp{key: fmt.Sprintf("stress-%s-%d", l.currency, i), amount: int64(rng.Intn(40) + 1)}
+			switch rng.Intn(3) {
+			case 0:
+				op.from, op.to, op.kind = treasury, user, enums.TransactionTypeTopUp
+			case 1:
+				op.from, op.to, op.kind = user, treasury, enums.TransactionTypeSpend
+			default:
+				other := users[(rng.Intn(len(users)-1)+1+indexOf(users, user))%len(users)]
+				op.from, op.to, op.kind = user, other, enums.TransactionTypeTopUp
+			}
+			ops[i] = op
+		}
+		submissions := make([]transferOp, 0, operations)
+		submissions = append(submissions, ops...)
+		for len(submissions) < operations {
+			submissions = append(submissions, ops[rng.Intn(unique)])
+		}
+		rng.Shuffle(len(submissions), func(i, j int) { submissions[i], submissions[j] = submissions[j], submissions[i] })
+
+		// sample balances while transfers run: none may ever be observed negative
+		stop := make(chan struct{})
+		var sampled sync.WaitGroup
+		var negative atomic.Bool
+		sampled.Add(1)
+		go func() {
+			defer sampled.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, wallet := range l.wallets {
+					got, err := wallets.GetWalletByOwner(ctx, wallet.OwnerType, wallet.OwnerID.String(), l.currency.String())
+					if err == nil && got.Balance < 0 {
+						negative.Store(true)
+					}
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		var rejected atomic.Int64
+		for _, op := range submissions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := wallets.Transfer(ctx, op.from.ID.String(), op.to.ID.String(), l.currency.String(), op.key, op.amount, op.kind)
+				if errors.Is(err, repository.ErrInsufficientBalance) {
+					rejected.Add(1)
+				} else if err != nil {
+					t.Errorf("transfer %s: %v", op.key, err)
+				}
+			}()
+		}
+		wg.Wait()
+		close(stop)
+		sampled.Wait()
+
+		if negative.Load() {
+			t.Error("observed a negative balance while transfers were running")
+		}
+
+		// a key has a ledger entry exactly when it was applied; replaying the
+		// applied keys on the initial balances must give the final balances
+		want := make(map[uuid.UUID]int64, len(l.initial))
+		for id, balance := range l.initial {
+			want[id] = balance
+		}
+		applied := 0
+		for _, op := range ops {
+			_, err := wallets.GetTransactionByIdempotencyKey(ctx, op.key)
+			if err != nil {
+				continue
+			}
+			applied++
+			want[op.from.ID] -= op.amount
+			want[op.to.ID] += op.amount
+		}
+
+		got := l.balances(t, wallets)
+		var total, initialTotal int64
+		for id, balance := range got {
+			if balance < 0 {
+				t.Errorf("wallet %s has negative balance %d", id, balance)
+			}
+			if balance != want[id] {
+				t.Errorf("wallet %s balance = %d, want %d from applied keys", id, balance, want[id])
+			}
+			total += balance
+			initialTotal += l.initial[id]
+		}
+		if total != initialTotal {
+			t.Errorf("total supply = %d, want %d", total, initialTotal)
+		}
+		if applied == 0 {
+			t.Error("no transfer was applied")
+		}
+		t.Logf("%d submissions, %d unique keys applied, %d rejected for insufficient balance", len(submissions), applied, rejected.Load())
+	})
+}
+
+func indexOf(wallets []repository.Wallet, wallet repository.Wallet) int {
+	for i, w := range wallets {
+		if w.ID == wallet.ID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTradeExists   = errors.New("trade already exists")
+	ErrTradeSettled  = errors.New("trade has already been released or refunded")
+	ErrTradeExpired  = errors.New("trade has expired")
+	ErrNotTradeParty = errors.New("owner has nothing to deposit in this trade")
+)
+
+// Trade exchanges funds between players through escrow. Each party deposits
+// its legs into the trade's escrow wallets, one per currency, and once every
+// leg is in they are all released to their recipients in one ledger entry.
+// A trade cancelled or left open past ExpiresAt refunds what was deposited.
+// ReferenceID is the ledger entry of the release or refund.
+type Trade struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	Status      string     `gorm:"type:varchar(16);not null" json:"status"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	ReferenceID string     `gorm:"type:varchar(64);not null;default:''" json:"reference_id,omitempty"`
+	SettledAt   *time.Time `json:"settled_at"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+	Legs        []TradeLeg `gorm:"foreignKey:TradeID" json:"legs"`
+}
+
+// TradeLeg is an amount one party gives another. DepositReferenceID is the
+// ledger entry that moved it into escrow.
+type TradeLeg struct {
+	TradeID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"-"`
+	Leg                int        `gorm:"primaryKey;autoIncrement:false" json:"leg"`
+	FromOwnerID        uuid.UUID  `gorm:"type:char(36);not null" json:"from_owner_id"`
+	ToOwnerID          uuid.UUID  `gorm:"type:char(36);not null" json:"to_owner_id"`
+	CurrencyTypeID     uuid.UUID  `gorm:"type:char(36);not null" json:"currency_type_id"`
+	Amount             int64      `gorm:"not null" json:"amount"`
+	FromWalletID       uuid.UUID  `gorm:"type:char(36);not null" json:"-"`
+	ToWalletID         uuid.UUID  `gorm:"type:char(36);not null" json:"-"`
+	EscrowWalletID     uuid.UUID  `gorm:"type:char(36);not null" json:"escrow_wallet_id"`
+	DepositReferenceID string     `gorm:"type:varchar(64);not null;default:''" json:"deposit_reference_id,omitempty"`
+	DepositedAt        *time.Time `json:"deposited_at"`
+}
+
+type TradeRepository interface {
+	// CreateTrade opens trade, with its legs' wallets set, and the escrow
+	// wallets it holds deposits in.
+	CreateTrade(ctx context.Context, trade *Trade) error
+	GetTrade(ctx context.Context, id string) (*Trade, error)
+	// ListTrades returns the most recent trades, newest first, optionally
+	// only those ownerID is a party to or those in status.
+	ListTrades(ctx context.Context, ownerID, status string, limit int) ([]Trade, error)
+	// Deposit moves the legs ownerID gives into escrow and, once every leg
+	// is in, releases the trade. Depositing again is a no-op.
+	Deposit(ctx context.Context, id, ownerID string) (*Trade, error)
+	// Cancel refunds an open trade's deposits. Cancelling a refunded trade
+	// is a no-op.
+	Cancel(ctx context.Context, id string) (*Trade, error)
+	// Expire refunds up to limit open trades past their expiry and returns
+	// how many it expired.
+	Expire(ctx context.Context, limit int) (int, error)
+}
+
+type tradeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewTradeRepository(db *gorm.DB) TradeRepository {
+	return &tradeRepositoryImpl{db: db}
+}
+
+// CreateTrade implements TradeRepository.
+func (r *tradeRepositoryImpl) CreateTrade(ctx context.Context, trade *Trade) (err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.CreateTrade", attribute.String("trade.id", trade.ID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&Trade{}).Where("id = ?", trade.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrTradeExists
+		}
+		escrows := make(map[uuid.UUID]uuid.UUID)
+		for i := range trade.Legs {
+			leg := &trade.Legs[i]
+			leg.TradeID, leg.Leg = trade.ID, i+1
+			escrowID, ok := escrows[leg.CurrencyTypeID]
+			if !ok {
+				escrow := Wallet{ID: uuid.New(), OwnerType: enums.OwnerTypeEscrow, OwnerID: trade.ID, CurrencyTypeID: leg.CurrencyTypeID}
+				if err := tx.Create(&escrow).Error; err != nil {
+					return err
+				}
+				escrowID = escrow.ID
+				escrows[leg.CurrencyTypeID] = escrowID
+			}
+			leg.EscrowWalletID = escrowID
+		}
+		trade.Status = enums.TradeStatusOpen
+		return tx.Create(trade).Error
+	})
+}
+
+// GetTrade implements TradeRepository.
+func (r *tradeRepositoryImpl) GetTrade(ctx context.Context, id string) (_ *Trade, err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.GetTrade", attribute.String("trade.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	return getTrade(r.db.WithContext(ctx), id, false)
+}
+
+// ListTrades implements TradeRepository.
+func (r *tradeRepositoryImpl) ListTrades(ctx context.Context, ownerID, status string, limit int) (_ []Trade, err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.ListTrades",
+		attribute.String("trade.owner_id", ownerID), attribute.String("trade.status", status))
+	defer func() { finishSpan(span, err) }()
+
+	db := r.db.WithContext(ctx)
+	query := db
+	if ownerID != "" {
+		query = query.Where("id IN (?)", db.Model(&TradeLeg{}).Select("trade_id").
+			Where("from_owner_id = ? OR to_owner_id = ?", ownerID, ownerID))
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var trades []Trade
+	if err := query.Preload("Legs", orderLegs).Order("created_at DESC").Order("id").Limit(limit).Find(&trades).Error; err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// Deposit implements TradeRepository.
+func (r *tradeRepositoryImpl) Deposit(ctx context.Context, id, ownerID string) (_ *Trade, err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.Deposit",
+		attribute.String("trade.id", id), attribute.String("trade.owner_id", ownerID))
+	defer func() { finishSpan(span, err) }()
+
+	var trade *Trade
+	applied := false
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if trade, err = getTrade(tx, id, true); err != nil {
+			return err
+		}
+		var postings []posting
+		var legs []*TradeLeg
+		party, pending := false, false
+		for i := range trade.Legs {
+			leg := &trade.Legs[i]
+			if leg.FromOwnerID.String() == ownerID {
+				party = true
+				if leg.DepositedAt == nil {
+					legs = append(legs, leg)
+					postings = append(postings, posting{leg.FromWalletID, -leg.Amount}, posting{leg.EscrowWalletID, leg.Amount})
+					continue
+				}
+			}
+			pending = pending || leg.DepositedAt == nil
+		}
+		now := time.Now().UTC()
+		switch {
+		case !party:
+			return ErrNotTradeParty
+		case trade.Status == enums.TradeStatusReleased:
+			// a replay of the deposit that released it
+			return nil
+		case trade.Status == enums.TradeStatusExpired, trade.Status == enums.TradeStatusOpen && !now.Before(trade.ExpiresAt):
+			return ErrTradeExpired
+		case trade.Status != enums.TradeStatusOpen:
+			return ErrTradeSettled
+		case len(legs) == 0:
+			return nil
+		}
+		key := fmt.Sprintf("escrow:%s:deposit:%d", trade.ID, legs[0].Leg)
+		referenceID, err := post(tx, key, enums.TransactionTypeEscrowDeposit, postings)
+		if err != nil {
+			return err
+		}
+		for _, leg := range legs {
+			leg.DepositReferenceID, leg.DepositedAt = referenceID, &now
+			if err := tx.Model(leg).Updates(map[string]any{"deposit_reference_id": referenceID, "deposited_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		applied = true
+		if pending {
+			return nil
+		}
+		return release(tx, trade)
+	})
+	metrics.ObserveDBTransaction("trade_deposit", err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if applied {
+		notifyWalletEventsCommitted()
+		logger.FromContext(ctx).Info("trade deposit committed", "trade_id", id, "owner_id", ownerID, "status", trade.Status)
+	}
+	return trade, nil
+}
+
+// Cancel implements TradeRepository.
+func (r *tradeRepositoryImpl) Cancel(ctx context.Context, id string) (_ *Trade, err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.Cancel", attribute.String("trade.id", id))
+	defer func() { finishSpan(span, err) }()
+
+	var trade *Trade
+	applied := false
+	start := time.Now()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if trade, err = getTrade(tx, id, true); err != nil {
+			return err
+		}
+		switch trade.Status {
+		case enums.TradeStatusReleased:
+			return ErrTradeSettled
+		case enums.TradeStatusOpen:
+			applied = true
+			return refund(tx, trade, enums.TradeStatusCancelled)
+		}
+		return nil
+	})
+	metrics.ObserveDBTransaction("trade_cancel", err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if applied {
+		notifyWalletEventsCommitted()
+		logger.FromContext(ctx).Info("trade cancelled", "trade_id", id, "reference_id", trade.ReferenceID)
+	}
+	return trade, nil
+}
+
+// Expire implements TradeRepository. Each trade is refunded in its own
+// transaction, so one that cannot be refunded does not hold up the rest.
+func (r *tradeRepositoryImpl) Expire(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := startSpan(ctx, "TradeRepository.Expire")
+	defer func() { finishSpan(span, err) }()
+
+	db := r.db.WithContext(ctx)
+	var ids []uuid.UUID
+	if err := db.Model(&Trade{}).Where("status = ? AND expires_at <= ?", enums.TradeStatusOpen, time.Now().UTC()).
+		Order("expires_at").Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	expired := 0
+	var errs []error
+	for _, id := range ids {
+		start := time.Now()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			trade, err := getTrade(tx, id.String(), true)
+			if err != nil || trade.Status != enums.TradeStatusOpen {
+				// settled meanwhile
+				return err
+			}
+			if err := refund(tx, trade, enums.TradeStatusExpired); err != nil {
+				return err
+			}
+			expired++
+			return nil
+		})
+		metrics.ObserveDBTransaction("trade_expire", err, time.Since(start))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trade %s: %w", id, err))
+		}
+	}
+	if expired > 0 {
+		notifyWalletEventsCommitted()
+		logger.FromContext(ctx).Info("trades expired", "count", expired)
+	}
+	return expired, errors.Join(errs...)
+}
+
+func orderLegs(db *gorm.DB) *gorm.DB {
+	return db.Order("leg")
+}
+
+// getTrade reads a trade with its legs, locking the trade when lock is set.
+func getTrade(db *gorm.DB, id string, lock bool) (*Trade, error) {
+	query := db.Where("id = ?", id)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var trade Trade
+	if err := query.First(&trade).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("trade_id = ?", id).Order("leg").Find(&trade.Legs).Error; err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// release pays every leg out of escrow to its recipient.
+func release(tx *gorm.DB, trade *Trade) error {
+	postings := make([]posting, 0, 2*len(trade.Legs))
+	for _, leg := range trade.Legs {
+		postings = append(postings, posting{leg.EscrowWalletID, -leg.Amount}, posting{leg.ToWalletID, leg.Amount})
+	}
+	referenceID, err := post(tx, fmt.Sprintf("escrow:%s:release", trade.ID), enums.TransactionTypeEscrowRelease, postings)
+	if err != nil {
+		return err
+	}
+	return settle(tx, trade, enums.TradeStatusReleased, referenceID)
+}
+
+// refund returns the deposited legs from escrow to their parties and
+// settles the trade as status.
+func refund(tx *gorm.DB, trade *Trade, status string) error {
+	var postings []posting
+	for _, leg := range trade.Legs {
+		if leg.DepositedAt != nil {
+			postings = append(postings, posting{leg.EscrowWalletID, -leg.Amount}, posting{leg.FromWalletID, leg.Amount})
+		}
+	}
+	referenceID := ""
+	if len(postings) > 0 {
+		var err error
+		referenceID, err = post(tx, fmt.Sprintf("escrow:%s:refund", trade.ID), enums.TransactionTypeEscrowRefund, postings)
+		if err != nil {
+			return err
+		}
+	}
+	return settle(tx, trade, status, referenceID)
+}
+
+func settle(tx *gorm.DB, trade *Trade, status, referenceID string) error {
+	now := time.Now().UTC()
+	trade.Status, trade.ReferenceID, trade.SettledAt, trade.UpdatedAt = status, referenceID, &now, now
+	return tx.Model(&Trade{}).Where("id = ?", trade.ID).Updates(map[string]any{
+		"status": status, "reference_id": referenceID, "settled_at": now, "updated_at": now,
+	}).Error
+}
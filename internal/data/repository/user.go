@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
@@ -9,12 +12,16 @@ type User struct {
 	ID   uuid.UUID `gorm:"type:char(36);primaryKey"`
 	Name string    `gorm:"not null"`
 	Role string    `gorm:"not null, default:'user'"` //system/user
+	// ReferredBy is the user who invited this one, if any.
+	ReferredBy *uuid.UUID `gorm:"type:char(36)"`
 	BaseTimeStamps
 }
 
 type UserRepository interface {
-	GetUserByID(userID string) (*User, error)
-	CreateUser(user *User) error
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+	CreateUser(ctx context.Context, user *User) error
+	// ListUsers pages through users ordered by name; an empty role matches every role.
+	ListUsers(ctx context.Context, role string, limit, offset int) ([]User, error)
 }
 
 type userRepositoryImpl struct {
@@ -25,14 +32,35 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepositoryImpl{db: db}
 }
 
-func (r *userRepositoryImpl) GetUserByID(userID string) (*User, error) {
+func (r *userRepositoryImpl) GetUserByID(ctx context.Context, userID string) (_ *User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetUserByID", attribute.String("user.id", userID))
+	defer func() { finishSpan(span, err) }()
+
 	var user User
-	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepositoryImpl) CreateUser(user *User) error {
-	return r.db.Create(user).Error
+func (r *userRepositoryImpl) CreateUser(ctx context.Context, user *User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.CreateUser")
+	defer func() { finishSpan(span, err) }()
+
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *userRepositoryImpl) ListUsers(ctx context.Context, role string, limit, offset int) (_ []User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.ListUsers", attribute.String("user.role", role))
+	defer func() { finishSpan(span, err) }()
+
+	query := r.db.WithContext(ctx).Order("name").Order("id").Limit(limit).Offset(offset)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	var users []User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
 }
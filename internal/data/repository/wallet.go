@@ -1,15 +1,24 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
 	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrWalletFrozen = errors.New("wallet is frozen")
+
 type Wallet struct {
 	ID             uuid.UUID `gorm:"type:char(36);primaryKey"`
 	OwnerType      string    `gorm:"type:varchar(32);not null;uniqueIndex:uniq_owner_currency,priority:1"`
@@ -17,6 +26,8 @@ type Wallet struct {
 	CurrencyTypeID uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:uniq_owner_currency,priority:3"`
 	Balance        int64     `gorm:"not null;default:0;check:balance >= 0"`
 	Version        int       `gorm:"not null;default:0"`
+	// Frozen wallets can neither send nor receive funds.
+	Frozen bool `gorm:"not null;default:false"`
 	BaseTimeStamps
 }
 
@@ -32,11 +43,29 @@ type WalletTransaction struct {
 }
 
 type WalletRepository interface {
-	GetWalletByOwner(ownerType string, ownerID string, currencyTypeID string) (*Wallet, error)
-	GetSystemWalletByCurrencyType(currencyTypeID string) (*Wallet, error)
-	CreateWallet(wallet *Wallet) error
-	Transfer(fromWalletID, toWalletID, currencyTypeID, idempotencyKey string, amount int64, transactionType enums.TransactionType) error
-	GetTransactionByIdempotencyKey(idempotencyKey string) (*WalletTransaction, error)
+	GetWalletByOwner(ctx context.Context, ownerType string, ownerID string, currencyTypeID string) (*Wallet, error)
+	GetSystemWalletByCurrencyType(ctx context.Context, currencyTypeID string) (*Wallet, error)
+	ListSystemWallets(ctx context.Context) ([]Wallet, error)
+	CreateWallet(ctx context.Context, wallet *Wallet) error
+	// Transfer moves amount between two wallets of a currency, recording a
+	// debit and a credit leg under idempotencyKey. The ledger applies a key
+	// once: a later Transfer with the same key moves nothing and returns nil,
+	// so an operation repeated after a crash, a timeout or a retry keeps its
+	// key and is replayed instead of applied twice.
+	Transfer(ctx context.Context, fromWalletID, toWalletID, currencyTypeID, idempotencyKey string, amount int64, transactionType enums.TransactionType) error
+	GetTransactionByIdempotencyKey(ctx context.Context, idempotencyKey string) (*WalletTransaction, error)
+	// ListTransactionsByIdempotencyKey returns every leg recorded under the
+	// key, debits first.
+	ListTransactionsByIdempotencyKey(ctx context.Context, idempotencyKey string) ([]WalletTransaction, error)
+	GetWalletByID(ctx context.Context, walletID string) (*Wallet, error)
+	ListWalletsByOwner(ctx context.Context, ownerID string) ([]Wallet, error)
+	// ListTransactions returns a wallet's most recent ledger entries, newest first.
+	ListTransactions(ctx context.Context, walletID string, limit int) ([]WalletTransaction, error)
+	SetFrozen(ctx context.Context, walletID string, frozen bool) error
+	// Mint adds amount to the currency's treasury and Burn removes it; both
+	// are idempotent on idempotencyKey and record a single ledger leg.
+	Mint(ctx context.Context, currencyTypeID, idempotencyKey string, amount int64) error
+	Burn(ctx context.Context, currencyTypeID, idempotencyKey string, amount int64) error
 }
 
 type walletRepositoryImpl struct {
@@ -47,63 +76,123 @@ func NewWalletRepository(db *gorm.DB) WalletRepository {
 	return &walletRepositoryImpl{db: db}
 }
 
-func (w *walletRepositoryImpl) CreateWallet(wallet *Wallet) error {
-	return w.db.Create(wallet).Error
+func (w *walletRepositoryImpl) CreateWallet(ctx context.Context, wallet *Wallet) (err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.CreateWallet",
+		attribute.String("wallet.owner_id", wallet.OwnerID.String()),
+		attribute.String("wallet.currency_type_id", wallet.CurrencyTypeID.String()))
+	defer func() { finishSpan(span, err) }()
+
+	return w.db.WithContext(ctx).Create(wallet).Error
 }
-func (w *walletRepositoryImpl) GetSystemWalletByCurrencyType(currencyTypeID string) (*Wallet, error) {
+func (w *walletRepositoryImpl) GetSystemWalletByCurrencyType(ctx context.Context, currencyTypeID string) (_ *Wallet, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.GetSystemWalletByCurrencyType",
+		attribute.String("wallet.currency_type_id", currencyTypeID))
+	defer func() { finishSpan(span, err) }()
+
 	var wallet Wallet
-	if err := w.db.Where("owner_type = ? AND currency_type_id = ?", "system", currencyTypeID).First(&wallet).Error; err != nil {
+	if err := w.db.WithContext(ctx).Where("owner_type = ? AND currency_type_id = ?", "system", currencyTypeID).First(&wallet).Error; err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 
 }
 
+// ListSystemWallets implements WalletRepository.
+func (w *walletRepositoryImpl) ListSystemWallets(ctx context.Context) (_ []Wallet, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.ListSystemWallets")
+	defer func() { finishSpan(span, err) }()
+
+	var wallets []Wallet
+	if err := w.db.WithContext(ctx).Where("owner_type = ?", "system").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
 // GetWalletByOwner implements WalletRepository.
-func (w *walletRepositoryImpl) GetWalletByOwner(ownerType string, ownerID string, currencyTypeID string) (*Wallet, error) {
+func (w *walletRepositoryImpl) GetWalletByOwner(ctx context.Context, ownerType string, ownerID string, currencyTypeID string) (_ *Wallet, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.GetWalletByOwner",
+		attribute.String("wallet.owner_type", ownerType),
+		attribute.String("wallet.owner_id", ownerID),
+		attribute.String("wallet.currency_type_id", currencyTypeID))
+	defer func() { finishSpan(span, err) }()
+
 	var wallet Wallet
-	if err := w.db.Where("owner_type = ? AND owner_id = ? AND currency_type_id = ?", ownerType, ownerID, currencyTypeID).First(&wallet).Error; err != nil {
+	if err := w.db.WithContext(ctx).Where("owner_type = ? AND owner_id = ? AND currency_type_id = ?", ownerType, ownerID, currencyTypeID).First(&wallet).Error; err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 }
 
 // GetTransactionByIdempotencyKey implements WalletRepository.
-func (w *walletRepositoryImpl) GetTransactionByIdempotencyKey(idempotencyKey string) (*WalletTransaction, error) {
+func (w *walletRepositoryImpl) GetTransactionByIdempotencyKey(ctx context.Context, idempotencyKey string) (_ *WalletTransaction, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.GetTransactionByIdempotencyKey")
+	defer func() { finishSpan(span, err) }()
+
 	var transaction WalletTransaction
-	if err := w.db.Where("idempotency_key = ?", idempotencyKey).First(&transaction).Error; err != nil {
+	if err := w.db.WithContext(ctx).Where("idempotency_key = ?", idempotencyKey).First(&transaction).Error; err != nil {
 		return nil, err
 	}
 	return &transaction, nil
 }
 
-// UpdateWalletBalance implements WalletRepository.
-func (w *walletRepositoryImpl) Transfer(fromWalletID, toWalletID, currencyTypeID, idempotencyKey string, amount int64, transactionType enums.TransactionType) error {
-	return w.db.Transaction(func(tx *gorm.DB) error {
+// ListTransactionsByIdempotencyKey implements WalletRepository.
+func (w *walletRepositoryImpl) ListTransactionsByIdempotencyKey(ctx context.Context, idempotencyKey string) (_ []WalletTransaction, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.ListTransactionsByIdempotencyKey")
+	defer func() { finishSpan(span, err) }()
+
+	var transactions []WalletTransaction
+	if err := w.db.WithContext(ctx).Where("idempotency_key = ?", idempotencyKey).Order("amount").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// Transfer implements WalletRepository.
+func (w *walletRepositoryImpl) Transfer(ctx context.Context, fromWalletID, toWalletID, currencyTypeID, idempotencyKey string, amount int64, transactionType enums.TransactionType) (err error) {
+	transactionType = transferType(transactionType)
+	ctx, span := startSpan(ctx, "WalletRepository.Transfer",
+		attribute.String("wallet.from_id", fromWalletID),
+		attribute.String("wallet.to_id", toWalletID),
+		attribute.String("wallet.currency_type_id", currencyTypeID),
+		attribute.String("wallet.transaction_type", string(transactionType)),
+		attribute.Int64("wallet.amount", amount))
+	defer func() { finishSpan(span, err) }()
+
+	applied := false
+	referenceID := ""
+	start := time.Now()
+	err = w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Lock the wallet record for update
 		var fromWallet Wallet
 		var toWallet Wallet
-		if transactionType != "" {
-			transactionType = enums.TransactionTypeTopUp
-		}
 
+		lockCtx, lockSpan := startSpan(ctx, "WalletRepository.Transfer.lockWallets")
+		lockStart := time.Now()
 		walletIDs := []string{fromWalletID, toWalletID}
 		sort.Strings(walletIDs)
 		wallets := make(map[string]*Wallet)
 		for _, id := range walletIDs {
 			var wlt Wallet
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			if err := tx.WithContext(lockCtx).Clauses(clause.Locking{Strength: "UPDATE"}).
 				Where("id = ? AND currency_type_id = ?",
 					id, currencyTypeID).First(&wlt).Error; err != nil {
+				finishSpan(lockSpan, err)
 				return err
 			}
 			wallets[id] = &wlt
 		}
+		metrics.ObserveLockWait("transfer", time.Since(lockStart))
+		finishSpan(lockSpan, nil)
 		toWallet = *wallets[toWalletID]
 		fromWallet = *wallets[fromWalletID]
 
+		if fromWallet.Frozen || toWallet.Frozen {
+			return ErrWalletFrozen
+		}
+
 		if fromWallet.Balance < amount {
-			return errors.New("insufficient balance")
+			return ErrInsufficientBalance
 		}
 
 		if fromWallet.Balance-amount < 0 {
@@ -113,19 +202,22 @@ func (w *walletRepositoryImpl) Transfer(fromWalletID, toWalletID, currencyTypeID
 		if fromWallet.ID == toWallet.ID {
 			return errors.New("cannot transfer to the same wallet")
 		}
-		key, err := w.GetTransactionByIdempotencyKey(idempotencyKey)
-		if err == nil && key != nil {
+		// read inside tx: the rows are locked, and on SQLite the pool's
+		// only connection is the one running this transaction
+		var existing WalletTransaction
+		if err := tx.Where("idempotency_key = ?", idempotencyKey).First(&existing).Error; err == nil {
 			// If a transaction with the same idempotency key exists, return it without creating a new one
 			return nil
 		}
-		referenceId := uuid.New().String()
+		referenceID = uuid.New().String()
+		span.SetAttributes(attribute.String("wallet.reference_id", referenceID))
 		debit := WalletTransaction{
 			ID:              uuid.New(),
 			WalletID:        fromWallet.ID,
 			TransactionType: enums.TransactionTypeDebit,
 			Amount:          -amount,
 			BalanceAfter:    fromWallet.Balance - amount,
-			ReferenceID:     referenceId,
+			ReferenceID:     referenceID,
 			IdempotencyKey:  idempotencyKey,
 		}
 		credit := WalletTransaction{
@@ -134,7 +226,7 @@ func (w *walletRepositoryImpl) Transfer(fromWalletID, toWalletID, currencyTypeID
 			TransactionType: string(transactionType),
 			Amount:          amount,
 			BalanceAfter:    toWallet.Balance + amount,
-			ReferenceID:     referenceId,
+			ReferenceID:     referenceID,
 			IdempotencyKey:  idempotencyKey,
 		}
 		if err := tx.Create(&debit).Error; err != nil {
@@ -151,7 +243,164 @@ func (w *walletRepositoryImpl) Transfer(fromWalletID, toWalletID, currencyTypeID
 		if err := tx.Model(&toWallet).Update("balance", toWallet.Balance+amount).Error; err != nil {
 			return err
 		}
+		events := []WalletEvent{walletEvent(&fromWallet, &debit), walletEvent(&toWallet, &credit)}
+		if err := tx.Create(&events).Error; err != nil {
+			return err
+		}
 
+		applied = true
 		return nil // commit
 	})
+	metrics.ObserveDBTransaction("transfer", err, time.Since(start))
+	log := logger.FromContext(ctx).With(
+		"from_wallet_id", fromWalletID,
+		"to_wallet_id", toWalletID,
+		"transaction_type", string(transactionType),
+		"amount", amount,
+	)
+	if errors.Is(err, ErrInsufficientBalance) {
+		metrics.ObserveInsufficientBalance(string(transactionType), currencyTypeID)
+	}
+	switch {
+	case err != nil:
+		log.Warn("transfer rolled back", "error", err)
+	case applied:
+		notifyWalletEventsCommitted()
+		metrics.ObserveTransfer(string(transactionType), currencyTypeID, amount)
+		log.Info("transfer committed", "reference_id", referenceID)
+	default:
+		log.Info("transfer skipped, idempotency key already applied")
+	}
+	return err
+}
+
+// GetWalletByID implements WalletRepository.
+func (w *walletRepositoryImpl) GetWalletByID(ctx context.Context, walletID string) (_ *Wallet, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.GetWalletByID", attribute.String("wallet.id", walletID))
+	defer func() { finishSpan(span, err) }()
+
+	var wallet Wallet
+	if err := w.db.WithContext(ctx).Where("id = ?", walletID).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// ListWalletsByOwner implements WalletRepository.
+func (w *walletRepositoryImpl) ListWalletsByOwner(ctx context.Context, ownerID string) (_ []Wallet, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.ListWalletsByOwner", attribute.String("wallet.owner_id", ownerID))
+	defer func() { finishSpan(span, err) }()
+
+	var wallets []Wallet
+	if err := w.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("currency_type_id").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// ListTransactions implements WalletRepository.
+func (w *walletRepositoryImpl) ListTransactions(ctx context.Context, walletID string, limit int) (_ []WalletTransaction, err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.ListTransactions", attribute.String("wallet.id", walletID))
+	defer func() { finishSpan(span, err) }()
+
+	var transactions []WalletTransaction
+	if err := w.db.WithContext(ctx).Where("wallet_id = ?", walletID).
+		Order("created_at DESC").Order("id").Limit(limit).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// SetFrozen implements WalletRepository.
+func (w *walletRepositoryImpl) SetFrozen(ctx context.Context, walletID string, frozen bool) (err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.SetFrozen",
+		attribute.String("wallet.id", walletID), attribute.Bool("wallet.frozen", frozen))
+	defer func() { finishSpan(span, err) }()
+
+	result := w.db.WithContext(ctx).Model(&Wallet{}).Where("id = ?", walletID).Update("frozen", frozen)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL reports unchanged rows as unaffected, so tell "missing" from "already set"
+		if _, err := w.GetWalletByID(consistency.WithPrimary(ctx), walletID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mint implements WalletRepository.
+func (w *walletRepositoryImpl) Mint(ctx context.Context, currencyTypeID, idempotencyKey string, amount int64) error {
+	return w.adjustSupply(ctx, currencyTypeID, idempotencyKey, amount, enums.TransactionTypeMint)
+}
+
+// Burn implements WalletRepository.
+func (w *walletRepositoryImpl) Burn(ctx context.Context, currencyTypeID, idempotencyKey string, amount int64) error {
+	return w.adjustSupply(ctx, currencyTypeID, idempotencyKey, -amount, enums.TransactionTypeBurn)
+}
+
+func (w *walletRepositoryImpl) adjustSupply(ctx context.Context, currencyTypeID, idempotencyKey string, delta int64, transactionType string) (err error) {
+	ctx, span := startSpan(ctx, "WalletRepository.adjustSupply",
+		attribute.String("wallet.currency_type_id", currencyTypeID),
+		attribute.String("wallet.transaction_type", transactionType),
+		attribute.Int64("wallet.amount", delta))
+	defer func() { finishSpan(span, err) }()
+
+	applied := false
+	start := time.Now()
+	err = w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var treasury Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("owner_type = ? AND currency_type_id = ?", "system", currencyTypeID).First(&treasury).Error; err != nil {
+			return err
+		}
+		if treasury.Frozen {
+			return ErrWalletFrozen
+		}
+		if treasury.Balance+delta < 0 {
+			return ErrInsufficientBalance
+		}
+		var existing WalletTransaction
+		if err := tx.Where("idempotency_key = ?", idempotencyKey).First(&existing).Error; err == nil {
+			return nil
+		}
+		entry := WalletTransaction{
+			ID:              uuid.New(),
+			WalletID:        treasury.ID,
+			TransactionType: transactionType,
+			Amount:          delta,
+			BalanceAfter:    treasury.Balance + delta,
+			ReferenceID:     uuid.New().String(),
+			IdempotencyKey:  idempotencyKey,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&treasury).Update("balance", treasury.Balance+delta).Error; err != nil {
+			return err
+		}
+		event := walletEvent(&treasury, &entry)
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	metrics.ObserveDBTransaction(transactionType, err, time.Since(start))
+	if applied {
+		notifyWalletEventsCommitted()
+		logger.FromContext(ctx).Info("treasury supply changed",
+			"currency_type_id", currencyTypeID, "transaction_type", transactionType, "amount", delta)
+	}
+	return err
+}
+
+// transferType is the type the credit leg of a transfer is recorded as: the
+// one the caller names, or a top-up when it names none.
+func transferType(transactionType enums.TransactionType) enums.TransactionType {
+	if transactionType == "" {
+		return enums.TransactionTypeTopUp
+	}
+	return transactionType
 }
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// WalletEvent is the outbox record of one committed ledger leg. It is written
+// in the transaction that writes the leg, so it exists exactly when the leg
+// does. IDs increase in insertion order but may commit out of order.
+type WalletEvent struct {
+	ID              int64     `gorm:"primaryKey;autoIncrement"`
+	WalletID        uuid.UUID `gorm:"type:char(36);not null"`
+	OwnerType       string    `gorm:"type:varchar(32);not null"`
+	OwnerID         uuid.UUID `gorm:"type:char(36);not null;index:idx_wallet_events_owner,priority:1"`
+	CurrencyTypeID  uuid.UUID `gorm:"type:char(36);not null"`
+	TransactionID   uuid.UUID `gorm:"type:char(36);not null"`
+	TransactionType string    `gorm:"type:varchar(32);not null"`
+	Amount          int64     `gorm:"not null"`
+	BalanceAfter    int64     `gorm:"not null"`
+	ReferenceID     string    `gorm:"type:varchar(64);not null"`
+	CreatedAt       time.Time `gorm:"not null;index"`
+}
+
+type WalletEventRepository interface {
+	// LatestID returns the highest event ID, or 0 when there are none.
+	LatestID(ctx context.Context) (int64, error)
+	// OldestID returns the lowest retained event ID, or 0 when there are none.
+	OldestID(ctx context.Context) (int64, error)
+	// ListAfter returns up to limit events with an ID above afterID, oldest first.
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]WalletEvent, error)
+	ListByIDs(ctx context.Context, ids []int64) ([]WalletEvent, error)
+	// ListForOwnerAfter is ListAfter restricted to one owner's wallets.
+	ListForOwnerAfter(ctx context.Context, ownerID string, afterID int64, limit int) ([]WalletEvent, error)
+	// DeleteBefore prunes events created before the cutoff.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type walletEventRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewWalletEventRepository(db *gorm.DB) WalletEventRepository {
+	return &walletEventRepositoryImpl{db: db}
+}
+
+var (
+	eventsCommittedMu sync.Mutex
+	eventsCommitted   = make(chan struct{})
+)
+
+// WalletEventsCommitted returns a channel that is closed the next time this
+// process commits wallet events, so pollers need not wait for their interval.
+func WalletEventsCommitted() <-chan struct{} {
+	eventsCommittedMu.Lock()
+	defer eventsCommittedMu.Unlock()
+	return eventsCommitted
+}
+
+func notifyWalletEventsCommitted() {
+	eventsCommittedMu.Lock()
+	defer eventsCommittedMu.Unlock()
+	close(eventsCommitted)
+	eventsCommitted = make(chan struct{})
+}
+
+// walletEvent records leg, just written to wallet, in the outbox.
+func walletEvent(wallet *Wallet, leg *WalletTransaction) WalletEvent {
+	return WalletEvent{
+		WalletID:        wallet.ID,
+		OwnerType:       wallet.OwnerType,
+		OwnerID:         wallet.OwnerID,
+		CurrencyTypeID:  wallet.CurrencyTypeID,
+		TransactionID:   leg.ID,
+		TransactionType: leg.TransactionType,
+		Amount:          leg.Amount,
+		BalanceAfter:    leg.BalanceAfter,
+		ReferenceID:     leg.ReferenceID,
+		CreatedAt:       time.Now().UTC(),
+	}
+}
+
+// LatestID implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) LatestID(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.LatestID")
+	defer func() { finishSpan(span, err) }()
+
+	var id int64
+	err = r.db.WithContext(ctx).Model(&WalletEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// OldestID implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) OldestID(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.OldestID")
+	defer func() { finishSpan(span, err) }()
+
+	var id int64
+	err = r.db.WithContext(ctx).Model(&WalletEvent{}).Select("COALESCE(MIN(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// ListAfter implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) ListAfter(ctx context.Context, afterID int64, limit int) (_ []WalletEvent, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.ListAfter", attribute.Int64("event.after_id", afterID))
+	defer func() { finishSpan(span, err) }()
+
+	var events []WalletEvent
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListByIDs implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) ListByIDs(ctx context.Context, ids []int64) (_ []WalletEvent, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.ListByIDs", attribute.Int("event.count", len(ids)))
+	defer func() { finishSpan(span, err) }()
+
+	var events []WalletEvent
+	if len(ids) == 0 {
+		return events, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListForOwnerAfter implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) ListForOwnerAfter(ctx context.Context, ownerID string, afterID int64, limit int) (_ []WalletEvent, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.ListForOwnerAfter",
+		attribute.String("wallet.owner_id", ownerID), attribute.Int64("event.after_id", afterID))
+	defer func() { finishSpan(span, err) }()
+
+	var events []WalletEvent
+	if err := r.db.WithContext(ctx).Where("owner_id = ? AND id > ?", ownerID, afterID).
+		Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DeleteBefore implements WalletEventRepository.
+func (r *walletEventRepositoryImpl) DeleteBefore(ctx context.Context, cutoff time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "WalletEventRepository.DeleteBefore")
+	defer func() { finishSpan(span, err) }()
+
+	result := r.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&WalletEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+func TestTransferTypeKeepsTheCallersType(t *testing.T) {
+	for given, want := range map[enums.TransactionType]enums.TransactionType{
+		"":                         enums.TransactionTypeTopUp,
+		enums.TransactionTypeTopUp: enums.TransactionTypeTopUp,
+		enums.TransactionTypeSpend: enums.TransactionTypeSpend,
+		enums.TransactionTypeBonus: enums.TransactionTypeBonus,
+	} {
+		if got := transferType(given); got != want {
+			t.Errorf("transferType(%q) = %q, want %q", given, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository/repositorytest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"gorm.io/gorm"
+)
+
+// testBackends lists the databases the repository suites run against. Each
+// backend is configured by its DSN variable; backends without a default DSN
+// are skipped when it is unset. SQLite always runs, in memory by default.
+var testBackends = []struct {
+	driver     string
+	dsnEnv     string
+	defaultDSN string
+}{
+	{driver: "sqlite", dsnEnv: "TEST_SQLITE_DSN", defaultDSN: ":memory:"},
+	{driver: "mysql", dsnEnv: "TEST_MYSQL_DSN"},
+	{driver: "postgres", dsnEnv: "TEST_POSTGRES_DSN"},
+}
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+type fixture struct {
+	db         *gorm.DB
+	wallets    repository.WalletRepository
+	currency   repository.CurrencyType
+	treasury   repository.Wallet
+	userWallet repository.Wallet
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, cfg config_env.DbConfig)) {
+	for _, backend := range testBackends {
+		t.Run(backend.driver, func(t *testing.T) {
+			dsn := os.Getenv(backend.dsnEnv)
+			if dsn == "" {
+				dsn = backend.defaultDSN
+			}
+			if dsn == "" {
+				t.Skipf("%s not set", backend.dsnEnv)
+			}
+			test(t, config_env.DbConfig{Driver: backend.driver, DSN: dsn})
+		})
+	}
+}
+
+func TestGormRepositoriesConform(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cfg config_env.DbConfig) {
+		repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+			db := openEmptyDB(t, cfg)
+			return repositorytest.Repositories{
+				Wallets: repository.NewWalletRepository(db),
+				Users:   repository.NewUserRepository(db),
+			}
+		})
+	})
+}
+
+// openEmptyDB opens cfg, migrates it to the latest version and empties every table.
+func openEmptyDB(t *testing.T, cfg config_env.DbConfig) *gorm.DB {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	database, err := config_db.NewGormDB(cfg, log)
+	if err != nil {
+		t.Fatalf("open %s: %v", cfg.Driver, err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db := database.GetDB()
+
+	migrator, err := migrations.New(db, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, table := range []string{"wallet_events", "wallet_transactions", "wallets", "users", "currency_types"} {
+		if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func newFixture(t *testing.T, cfg config_env.DbConfig) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	db := openEmptyDB(t, cfg)
+
+	f := &fixture{db: db, wallets: repository.NewWalletRepository(db)}
+	f.currency = repository.CurrencyType{ID: uuid.New(), Name: "gold"}
+	if err := db.Create(&f.currency).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.treasury = repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: f.currency.ID, Balance: 1_000}
+	f.userWallet = repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: uuid.New(), CurrencyTypeID: f.currency.ID, Balance: 100}
+	for _, wallet := range []*repository.Wallet{&f.treasury, &f.userWallet} {
+		if err := f.wallets.CreateWallet(ctx, wallet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func (f *fixture) balance(t *testing.T, wallet repository.Wallet) int64 {
+	t.Helper()
+	got, err := f.wallets.GetWalletByOwner(context.Background(), wallet.OwnerType, wallet.OwnerID.String(), wallet.CurrencyTypeID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got.Balance
+}
+
+func TestTransferMovesFundsAndRecordsBothLegs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cfg config_env.DbConfig) {
+		f := newFixture(t, cfg)
+		ctx := context.Background()
+		err := f.wallets.Transfer(ctx, f.userWallet.ID.String(), f.treasury.ID.String(),
+			f.currency.ID.String(), "spend-1", 40, enums.TransactionTypeSpend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.balance(t, f.userWallet); got != 60 {
+			t.Errorf("user balance = %d, want 60", got)
+		}
+		if got := f.balance(t, f.treasury); got != 1_040 {
+			t.Errorf("treasury balance = %d, want 1040", got)
+		}
+
+		var legs []repository.WalletTransaction
+		if err := f.db.Where("idempotency_key = ?", "spend-1").Order("amount").Find(&legs).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(legs) != 2 {
+			t.Fatalf("got %d ledger rows, want 2", len(legs))
+		}
+		if legs[0].Amount != -40 || legs[0].WalletID != f.userWallet.ID || legs[0].TransactionType != enums.TransactionTypeDebit {
+			t.Errorf("unexpected debit leg %+v", legs[0])
+		}
+		if legs[1].Amount != 40 || legs[1].WalletID != f.treasury.ID || legs[1].TransactionType != enums.TransactionTypeSpend {
+			t.Errorf("unexpected credit leg %+v", legs[1])
+		}
+		if legs[0].ReferenceID != legs[1].ReferenceID {
+			t.Errorf("legs have different reference IDs %q and %q", legs[0].ReferenceID, legs[1].ReferenceID)
+		}
+	})
+}
+
+func TestCommittedLegsAreWrittenToTheOutbox(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cfg config_env.DbConfig) {
+		f := newFixture(t, cfg)
+		ctx := context.Background()
+		events := repository.NewWalletEventRepository(f.db)
+		before, err := events.LatestID(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		committed := repository.WalletEventsCommitted()
+		if err := f.wallets.Transfer(ctx, f.userWallet.ID.String(), f.treasury.ID.String(),
+			f.currency.ID.String(), "spend-1", 40, enums.TransactionTypeSpend); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-committed:
+		default:
+			t.Error("commit did not wake event pollers")
+		}
+		// neither a replay nor a rollback writes events
+		_ = f.wallets.Transfer(ctx, f.userWallet.ID.String(), f.treasury.ID.String(),
+			f.currency.ID.String(), "spend-1", 40, enums.TransactionTypeSpend)
+		if err := f.wallets.Transfer(ctx, f.userWallet.ID.String(), f.treasury.ID.String(),
+			f.currency.ID.String(), "overspend", 1_000, enums.TransactionTypeSpend); !errors.Is(err, repository.ErrInsufficientBalance) {
+			t.Fatalf("overspend: %v", err)
+		}
+
+		written, err := events.ListAfter(ctx, before, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(written) != 2 {
+			t.Fatalf("got %d events, want 2", len(written))
+		}
+		debit, credit := written[0], written[1]
+		if debit.OwnerID != f.userWallet.OwnerID || debit.Amount != -40 || debit.BalanceAfter != 60 {
+			t.Errorf("unexpected debit event %+v", debit)
+		}
+		if credit.WalletID != f.treasury.ID || credit.Amount != 40 || credit.TransactionType != enums.TransactionTypeSpend {
+			t.Errorf("unexpected credit event %+v", credit)
+		}
+		if debit.ReferenceID != credit.ReferenceID || debit.ID >= credit.ID {
+			t.Errorf("events %d and %d of one transfer, want one reference in order", debit.ID, credit.ID)
+		}
+		mine, err := events.ListForOwnerAfter(ctx, f.userWallet.OwnerID.String(), before, 10)
+		if err != nil || len(mine) != 1 || mine[0].ID != debit.ID {
+			t.Errorf("owner's events %+v, %v; want only the debit", mine, err)
+		}
+	})
+}
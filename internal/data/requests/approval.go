@@ -0,0 +1,25 @@
+package data_requests
+
+import "github.com/google/uuid"
+
+// ApprovalProposalRequest proposes an admin operation. Mints, burns and
+// adjustments name the currency; adjustments also name the user and
+// whether their wallet is credited or debited against the treasury, and
+// transfers name the two wallets instead.
+type ApprovalProposalRequest struct {
+	Kind           string     `json:"kind" binding:"required,oneof=mint burn adjustment transfer"`
+	CurrencyTypeID *uuid.UUID `json:"currency_type_id"`
+	OwnerID        *uuid.UUID `json:"owner_id"`
+	Direction      string     `json:"direction" binding:"omitempty,oneof=credit debit"`
+	FromWalletID   *uuid.UUID `json:"from_wallet_id"`
+	ToWalletID     *uuid.UUID `json:"to_wallet_id"`
+	Amount         int64      `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string     `json:"idempotency_key" binding:"required,max=64"`
+	Note           string     `json:"note" binding:"max=255"`
+}
+
+// ApprovalDecisionRequest approves or rejects a pending operation. The body
+// is optional.
+type ApprovalDecisionRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
@@ -0,0 +1,31 @@
+package data_requests
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignRequest defines a bonus campaign. SpendWindow is a duration such
+// as "168h" and only applies to min_spend; StartsAt defaults to now.
+type CampaignRequest struct {
+	Name              string     `json:"name" binding:"required,max=100"`
+	CurrencyTypeID    uuid.UUID  `json:"currency_type_id" binding:"required"`
+	Reward            int64      `json:"reward" binding:"required,gt=0"`
+	MaxClaimsPerOwner int        `json:"max_claims_per_owner" binding:"gte=0,lte=1000"`
+	Requirement       string     `json:"requirement" binding:"omitempty,oneof=none first_top_up min_spend"`
+	MinAmount         int64      `json:"min_amount" binding:"gte=0"`
+	SpendWindow       string     `json:"spend_window"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+}
+
+type CampaignFundRequest struct {
+	IdempotencyKey string `json:"idempotency_key" binding:"required"`
+	Amount         int64  `json:"amount" binding:"required,gt=0"`
+}
+
+type CampaignClaimRequest struct {
+	IdempotencyKey string    `json:"idempotency_key" binding:"required"`
+	OwnerID        uuid.UUID `json:"owner_id" binding:"required"`
+}
@@ -0,0 +1,8 @@
+package data_requests
+
+import "github.com/google/uuid"
+
+type ReferralRequest struct {
+	ReferrerID uuid.UUID `json:"referrer_id" binding:"required"`
+	RefereeID  uuid.UUID `json:"referee_id" binding:"required"`
+}
@@ -0,0 +1,25 @@
+package data_requests
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduleRequest defines a scheduled transfer. Interval and RetryDelay are
+// durations such as "24h"; StartAt defaults to now.
+type ScheduleRequest struct {
+	OwnerID        uuid.UUID  `json:"owner_id" binding:"required"`
+	CurrencyTypeID uuid.UUID  `json:"currency_type_id" binding:"required"`
+	Direction      string     `json:"direction" binding:"required,oneof=credit debit"`
+	Amount         int64      `json:"amount" binding:"required,gt=0"`
+	Kind           string     `json:"kind" binding:"required,oneof=once interval cron"`
+	Cron           string     `json:"cron"`
+	Interval       string     `json:"interval"`
+	Timezone       string     `json:"timezone"`
+	StartAt        *time.Time `json:"start_at"`
+	EndAt          *time.Time `json:"end_at"`
+	OnFailure      string     `json:"on_failure" binding:"omitempty,oneof=skip pause cancel"`
+	MaxRetries     int        `json:"max_retries" binding:"gte=0,lte=100"`
+	RetryDelay     string     `json:"retry_delay"`
+}
@@ -0,0 +1,22 @@
+package data_requests
+
+import "github.com/google/uuid"
+
+// TradeRequest opens a trade under a caller-chosen ID. ExpiresIn is a
+// duration such as "30m"; it defaults to an hour.
+type TradeRequest struct {
+	TradeID   uuid.UUID         `json:"trade_id" binding:"required"`
+	Legs      []TradeLegRequest `json:"legs" binding:"required,min=1,max=20,dive"`
+	ExpiresIn string            `json:"expires_in"`
+}
+
+type TradeLegRequest struct {
+	FromOwnerID    uuid.UUID `json:"from_owner_id" binding:"required"`
+	ToOwnerID      uuid.UUID `json:"to_owner_id" binding:"required"`
+	CurrencyTypeID uuid.UUID `json:"currency_type_id" binding:"required"`
+	Amount         int64     `json:"amount" binding:"required,gt=0"`
+}
+
+type TradeDepositRequest struct {
+	OwnerID uuid.UUID `json:"owner_id" binding:"required"`
+}
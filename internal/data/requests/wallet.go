@@ -21,3 +21,11 @@ type SpendRequest struct {
 	CurrencyTypeID uuid.UUID `json:"currency_type_id" binding:"required"`
 	Amount         int64     `json:"amount" binding:"required,gt=0"`
 }
+
+type TransferRequest struct {
+	IdempotencyKey string    `json:"idempotency_key" binding:"required"`
+	FromOwnerID    uuid.UUID `json:"from_owner_id" binding:"required"`
+	ToOwnerID      uuid.UUID `json:"to_owner_id" binding:"required"`
+	CurrencyTypeID uuid.UUID `json:"currency_type_id" binding:"required"`
+	Amount         int64     `json:"amount" binding:"required,gt=0"`
+}
@@ -0,0 +1,69 @@
+// Package dbtest sets up the SQLite databases the packages built on the
+// ledger test against.
+package dbtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"gorm.io/gorm"
+)
+
+// Open returns an in-memory SQLite database migrated to the latest version,
+// closed when the test ends.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	database, err := config_db.NewGormDB(config_env.DbConfig{Driver: "sqlite", DSN: ":memory:"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	migrator, err := migrations.New(database.GetDB(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return database.GetDB()
+}
+
+// Users creates a player for each of ids.
+func Users(t testing.TB, db *gorm.DB, ids ...uuid.UUID) {
+	t.Helper()
+	users := repository.NewUserRepository(db)
+	for _, id := range ids {
+		if err := users.CreateUser(context.Background(), &repository.User{ID: id, Name: "player", Role: "user"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Currency creates the currency type name with its treasury and mints supply
+// into the treasury under the key "genesis-<currency type ID>".
+func Currency(t testing.TB, db *gorm.DB, name string, supply int64) (currency uuid.UUID, treasury repository.Wallet) {
+	t.Helper()
+	ctx := context.Background()
+	currency = uuid.New()
+	if err := repository.NewCurrencyTypeRepository(db).CreateCurrencyType(ctx, &repository.CurrencyType{ID: currency, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	wallets := repository.NewWalletRepository(db)
+	treasury = repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: currency}
+	if err := wallets.CreateWallet(ctx, &treasury); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallets.Mint(ctx, currency.String(), "genesis-"+currency.String(), supply); err != nil {
+		t.Fatal(err)
+	}
+	treasury.Balance = supply
+	return currency, treasury
+}
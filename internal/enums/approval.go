@@ -0,0 +1,53 @@
+package enums
+
+// ApprovalKind is the kind of admin operation that can need a second
+// admin's approval.
+type ApprovalKind string
+
+const (
+	ApprovalKindMint = "mint"
+	ApprovalKindBurn = "burn"
+	// Adjustment credits or debits a user's wallet against the treasury,
+	// such as a correction after a support case.
+	ApprovalKindAdjustment = "adjustment"
+	// Transfer moves funds between two wallets by ID, such as between
+	// treasuries and campaign or escrow wallets.
+	ApprovalKindTransfer = "transfer"
+)
+
+// AdjustmentDirection says whether an adjustment credits or debits the
+// user's wallet.
+type AdjustmentDirection string
+
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending = "pending"
+	// Approved operations are being executed; they end executed or, when
+	// execution fails, pending again.
+	ApprovalApproved = "approved"
+	ApprovalExecuted = "executed"
+	// Failed operations needed no approval and could not be executed; they
+	// are not retried.
+	ApprovalFailed   = "failed"
+	ApprovalRejected = "rejected"
+	// Expired operations waited past their expiry without approval.
+	ApprovalExpired = "expired"
+)
+
+// ApprovalAction is a step in an operation's audit trail.
+type ApprovalAction string
+
+const (
+	ApprovalActionProposed = "proposed"
+	ApprovalActionApproved = "approved"
+	ApprovalActionExecuted = "executed"
+	ApprovalActionFailed   = "failed"
+	ApprovalActionRejected = "rejected"
+	ApprovalActionExpired  = "expired"
+)
@@ -0,0 +1,20 @@
+package enums
+
+type BulkJobStatus string
+
+const (
+	BulkJobStatusPending   = "pending"
+	BulkJobStatusRunning   = "running"
+	BulkJobStatusCompleted = "completed"
+	BulkJobStatusCancelled = "cancelled"
+)
+
+type BulkRowStatus string
+
+const (
+	BulkRowStatusPending   = "pending"
+	BulkRowStatusSucceeded = "succeeded"
+	// Failed rows can be retried; invalid rows were rejected at upload.
+	BulkRowStatusFailed  = "failed"
+	BulkRowStatusInvalid = "invalid"
+)
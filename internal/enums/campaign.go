@@ -0,0 +1,28 @@
+package enums
+
+// OwnerTypeCampaign marks the wallets campaign budgets are held in; their
+// owner ID is the campaign's.
+const OwnerTypeCampaign = "campaign"
+
+type CampaignStatus string
+
+const (
+	CampaignStatusActive = "active"
+	CampaignStatusPaused = "paused"
+	// Ended campaigns returned what was left of their budget to the treasury.
+	CampaignStatusEnded = "ended"
+)
+
+// CampaignRequirement is what an owner must have done in the campaign's
+// currency to claim it.
+type CampaignRequirement string
+
+const (
+	CampaignRequirementNone = "none"
+	// FirstTopUp requires a top-up, the first of which was at least the
+	// campaign's minimum amount.
+	CampaignRequirementFirstTopUp = "first_top_up"
+	// MinSpend requires spends adding up to the minimum amount within the
+	// campaign's spend window before the claim.
+	CampaignRequirementMinSpend = "min_spend"
+)
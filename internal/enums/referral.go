@@ -0,0 +1,13 @@
+package enums
+
+type ReferralStatus string
+
+const (
+	// Pending referrals wait for the invitee's first top-up.
+	ReferralStatusPending = "pending"
+	// Rewarded referrals paid both users once the first top-up qualified.
+	ReferralStatusRewarded = "rewarded"
+	// Disqualified referrals had a first top-up below the threshold, and no
+	// later top-up counts.
+	ReferralStatusDisqualified = "disqualified"
+)
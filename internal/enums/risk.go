@@ -0,0 +1,28 @@
+package enums
+
+type RiskOutcome string
+
+const (
+	RiskOutcomeAllow = "allow"
+	RiskOutcomeDeny  = "deny"
+	// Review holds the operation until an admin approves or rejects it.
+	RiskOutcomeReview = "review"
+)
+
+// RiskOperation is the kind of operation the risk rules screen.
+type RiskOperation string
+
+const (
+	RiskOperationSpend    = "spend"
+	RiskOperationTransfer = "transfer"
+)
+
+type RiskReviewStatus string
+
+const (
+	RiskReviewPending = "pending"
+	// Approved operations were applied under their original idempotency
+	// key.
+	RiskReviewApproved = "approved"
+	RiskReviewRejected = "rejected"
+)
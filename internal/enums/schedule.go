@@ -0,0 +1,45 @@
+package enums
+
+type ScheduleKind string
+
+const (
+	ScheduleKindOnce     = "once"
+	ScheduleKindInterval = "interval"
+	ScheduleKindCron     = "cron"
+)
+
+// ScheduleDirection is which way a schedule moves funds: credits pay the
+// owner from the currency's treasury, debits charge the owner into it.
+type ScheduleDirection string
+
+const (
+	ScheduleDirectionCredit = "credit"
+	ScheduleDirectionDebit  = "debit"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduleFailurePolicy is what happens to a schedule once an occurrence has
+// failed all its attempts.
+type ScheduleFailurePolicy string
+
+const (
+	// Skip moves on to the next occurrence.
+	ScheduleFailureSkip   = "skip"
+	ScheduleFailurePause  = "pause"
+	ScheduleFailureCancel = "cancel"
+)
+
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunStatusSucceeded = "succeeded"
+	ScheduleRunStatusFailed    = "failed"
+)
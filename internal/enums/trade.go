@@ -0,0 +1,15 @@
+package enums
+
+// OwnerTypeEscrow marks the wallets a trade holds deposits in; their owner
+// ID is the trade's.
+const OwnerTypeEscrow = "escrow"
+
+type TradeStatus string
+
+const (
+	TradeStatusOpen     = "open"
+	TradeStatusReleased = "released"
+	// Cancelled and expired trades refunded their deposits.
+	TradeStatusCancelled = "cancelled"
+	TradeStatusExpired   = "expired"
+)
@@ -3,8 +3,27 @@ package enums
 type TransactionType string
 
 const (
-	TransactionTypeTopUp = "top_up"
-	TransactionTypeDebit = "debit"
-	TransactionTypeSpend = "spend"
-	TransactionTypeBonus = "bonus"
+	TransactionTypeTopUp    = "top_up"
+	TransactionTypeDebit    = "debit"
+	TransactionTypeSpend    = "spend"
+	TransactionTypeBonus    = "bonus"
+	TransactionTypeTransfer = "transfer"
+	// Mint and Burn change a treasury's supply and have a single ledger leg.
+	TransactionTypeMint = "mint"
+	TransactionTypeBurn = "burn"
+	// Escrow entries move trade deposits into, out of and back from a
+	// trade's escrow wallets.
+	TransactionTypeEscrowDeposit = "escrow_deposit"
+	TransactionTypeEscrowRelease = "escrow_release"
+	TransactionTypeEscrowRefund  = "escrow_refund"
+	// Campaign entries move a campaign's budget from the treasury into its
+	// wallet and what is left of it back.
+	TransactionTypeCampaignFunding = "campaign_funding"
+	TransactionTypeCampaignReturn  = "campaign_return"
+	// ReferralReward pays an invitee and the user who invited them from the
+	// treasury once the invitee's first top-up qualifies.
+	TransactionTypeReferralReward = "referral_reward"
+	// Adjustment entries are admin operations approved by a second admin:
+	// corrections of a user's balance and transfers between wallets by ID.
+	TransactionTypeAdjustment = "adjustment"
 )
@@ -0,0 +1,250 @@
+package escrow_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/escrow"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fixture is a migrated SQLite database with two players holding 100 gold
+// and 10 gems each, minted into the treasuries and granted through the
+// ledger.
+type fixture struct {
+	db      *gorm.DB
+	trades  repository.TradeRepository
+	wallets repository.WalletRepository
+	open    func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	gold    uuid.UUID
+	gems    uuid.UUID
+	alice   uuid.UUID
+	bob     uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	f := &fixture{
+		db:      db,
+		trades:  repository.NewTradeRepository(db),
+		wallets: repository.NewWalletRepository(db),
+		alice:   uuid.New(),
+		bob:     uuid.New(),
+	}
+	f.open = handler.NewWalletHandler(f.wallets, repository.NewUserRepository(db)).CheckUserWalletIfNotCreate
+	dbtest.Users(t, db, f.alice, f.bob)
+	var goldTreasury, gemsTreasury repository.Wallet
+	f.gold, goldTreasury = dbtest.Currency(t, db, "gold", 1000)
+	f.gems, gemsTreasury = dbtest.Currency(t, db, "gems", 1000)
+	for _, grant := range []struct {
+		currency uuid.UUID
+		treasury repository.Wallet
+		amount   int64
+	}{{f.gold, goldTreasury, 100}, {f.gems, gemsTreasury, 10}} {
+		for _, owner := range []uuid.UUID{f.alice, f.bob} {
+			wallet, err := f.open(ctx, owner, grant.currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.wallets.Transfer(ctx, grant.treasury.ID.String(), wallet.ID.String(), grant.currency.String(),
+				"grant-"+owner.String()+grant.currency.String()[:8], grant.amount, enums.TransactionTypeBonus); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f
+}
+
+// swap opens a trade of alice's gold for bob's gems.
+func (f *fixture) swap(t *testing.T, gold, gems int64) *repository.Trade {
+	t.Helper()
+	ctx := context.Background()
+	trade := &repository.Trade{ID: uuid.New(), ExpiresAt: time.Now().UTC().Add(time.Hour)}
+	for _, leg := range []repository.TradeLeg{
+		{FromOwnerID: f.alice, ToOwnerID: f.bob, CurrencyTypeID: f.gold, Amount: gold},
+		{FromOwnerID: f.bob, ToOwnerID: f.alice, CurrencyTypeID: f.gems, Amount: gems},
+	} {
+		from, err := f.open(ctx, leg.FromOwnerID, leg.CurrencyTypeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := f.open(ctx, leg.ToOwnerID, leg.CurrencyTypeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leg.FromWalletID, leg.ToWalletID = from.ID, to.ID
+		trade.Legs = append(trade.Legs, leg)
+	}
+	if err := f.trades.CreateTrade(ctx, trade); err != nil {
+		t.Fatal(err)
+	}
+	return trade
+}
+
+func (f *fixture) deposit(t *testing.T, trade *repository.Trade, owner uuid.UUID, wantStatus string) *repository.Trade {
+	t.Helper()
+	got, err := f.trades.Deposit(context.Background(), trade.ID.String(), owner.String())
+	if err != nil || got.Status != wantStatus {
+		t.Fatalf("Deposit = %+v, %v; want %s", got, err, wantStatus)
+	}
+	return got
+}
+
+func (f *fixture) balance(t *testing.T, ownerType string, owner, currency uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := f.wallets.GetWalletByOwner(context.Background(), ownerType, owner.String(), currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+// holdings returns alice's and bob's gold and gems.
+func (f *fixture) holdings(t *testing.T) [4]int64 {
+	t.Helper()
+	return [4]int64{
+		f.balance(t, "user", f.alice, f.gold), f.balance(t, "user", f.alice, f.gems),
+		f.balance(t, "user", f.bob, f.gold), f.balance(t, "user", f.bob, f.gems),
+	}
+}
+
+func (f *fixture) reconcile(t *testing.T) {
+	t.Helper()
+	report, err := admin.Reconcile(context.Background(), f.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Errorf("ledger does not reconcile: %+v", report)
+	}
+}
+
+func TestTradeReleasesOnceEveryDepositIsIn(t *testing.T) {
+	f := newFixture(t)
+	trade := f.swap(t, 60, 4)
+	if trade.Status != enums.TradeStatusOpen || trade.Legs[0].EscrowWalletID == trade.Legs[1].EscrowWalletID {
+		t.Fatalf("trade %+v, want open with an escrow wallet per currency", trade)
+	}
+
+	got := f.deposit(t, trade, f.alice, enums.TradeStatusOpen)
+	aliceDeposit := got.Legs[0].DepositReferenceID
+	if aliceDeposit == "" || got.Legs[1].DepositedAt != nil {
+		t.Errorf("legs %+v, want only alice's deposited", got.Legs)
+	}
+	if h := f.holdings(t); h != [4]int64{40, 10, 100, 10} {
+		t.Errorf("holdings after alice's deposit %v, want her gold in escrow and nothing received", h)
+	}
+	if escrowed := f.balance(t, enums.OwnerTypeEscrow, trade.ID, f.gold); escrowed != 60 {
+		t.Errorf("gold in escrow %d, want 60", escrowed)
+	}
+	if again := f.deposit(t, trade, f.alice, enums.TradeStatusOpen); again.Legs[0].DepositReferenceID != aliceDeposit {
+		t.Errorf("repeated deposit recorded %s, want the first deposit kept", again.Legs[0].DepositReferenceID)
+	}
+
+	got = f.deposit(t, trade, f.bob, enums.TradeStatusReleased)
+	references := map[string]bool{aliceDeposit: true, got.Legs[1].DepositReferenceID: true, got.ReferenceID: true}
+	if len(references) != 3 || got.ReferenceID == "" || got.SettledAt == nil {
+		t.Errorf("trade %+v, want its deposits and release as three ledger entries", got)
+	}
+	if h := f.holdings(t); h != [4]int64{40, 14, 160, 6} {
+		t.Errorf("holdings after the release %v, want the swap completed", h)
+	}
+	for _, currency := range []uuid.UUID{f.gold, f.gems} {
+		if escrowed := f.balance(t, enums.OwnerTypeEscrow, trade.ID, currency); escrowed != 0 {
+			t.Errorf("escrow holds %d of %s after the release, want 0", escrowed, currency)
+		}
+	}
+	release, err := f.wallets.GetTransactionByIdempotencyKey(context.Background(), "escrow:"+trade.ID.String()+":release")
+	if err != nil || release.ReferenceID != got.ReferenceID {
+		t.Errorf("release entry %+v, %v; want it under the release key", release, err)
+	}
+	f.deposit(t, trade, f.bob, enums.TradeStatusReleased)
+	if _, err := f.trades.Cancel(context.Background(), trade.ID.String()); !errors.Is(err, repository.ErrTradeSettled) {
+		t.Errorf("Cancel of a released trade = %v, want ErrTradeSettled", err)
+	}
+	f.reconcile(t)
+}
+
+func TestTradeRefunds(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	cancelled := f.swap(t, 60, 4)
+	f.deposit(t, cancelled, f.bob, enums.TradeStatusOpen)
+	if _, err := f.trades.Deposit(ctx, cancelled.ID.String(), uuid.NewString()); !errors.Is(err, repository.ErrNotTradeParty) {
+		t.Errorf("Deposit by a stranger = %v, want ErrNotTradeParty", err)
+	}
+	got, err := f.trades.Cancel(ctx, cancelled.ID.String())
+	if err != nil || got.Status != enums.TradeStatusCancelled || got.ReferenceID == "" {
+		t.Fatalf("Cancel = %+v, %v; want cancelled with a refund", got, err)
+	}
+	if again, err := f.trades.Cancel(ctx, cancelled.ID.String()); err != nil || again.ReferenceID != got.ReferenceID {
+		t.Errorf("repeated Cancel = %+v, %v; want the first refund kept", again, err)
+	}
+	if _, err := f.trades.Deposit(ctx, cancelled.ID.String(), f.alice.String()); !errors.Is(err, repository.ErrTradeSettled) {
+		t.Errorf("Deposit into a cancelled trade = %v, want ErrTradeSettled", err)
+	}
+
+	// alice cannot cover her leg, so nothing of hers moves
+	short := f.swap(t, 500, 4)
+	if _, err := f.trades.Deposit(ctx, short.ID.String(), f.alice.String()); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Errorf("Deposit over the balance = %v, want ErrInsufficientBalance", err)
+	}
+	f.deposit(t, short, f.bob, enums.TradeStatusOpen)
+	untouched := f.swap(t, 1, 1)
+	if err := f.db.Model(&repository.Trade{}).Where("id IN ?", []uuid.UUID{short.ID, untouched.ID}).
+		Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.trades.Deposit(ctx, short.ID.String(), f.alice.String()); !errors.Is(err, repository.ErrTradeExpired) {
+		t.Errorf("Deposit past the expiry = %v, want ErrTradeExpired", err)
+	}
+
+	expire := escrow.Expirer(f.trades, time.Hour)
+	stop, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- expire(stop) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		trade, err := f.trades.GetTrade(ctx, untouched.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trade.Status == enums.TradeStatusExpired || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for id, wantRefund := range map[uuid.UUID]bool{short.ID: true, untouched.ID: false} {
+		trade, err := f.trades.GetTrade(ctx, id.String())
+		if err != nil || trade.Status != enums.TradeStatusExpired || (trade.ReferenceID != "") != wantRefund {
+			t.Errorf("trade %+v, %v; want expired, refunded: %v", trade, err, wantRefund)
+		}
+	}
+	if h := f.holdings(t); h != [4]int64{100, 10, 100, 10} {
+		t.Errorf("holdings %v, want every deposit refunded", h)
+	}
+	f.reconcile(t)
+}
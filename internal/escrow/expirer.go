@@ -0,0 +1,38 @@
+// Package escrow refunds trades that were left open past their expiry.
+package escrow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+const batchSize = 100
+
+// Expirer returns a background worker that refunds expired trades every
+// interval. A trade that cannot be refunded, say because a party's wallet
+// is frozen, is logged and tried again on the next tick.
+func Expirer(trades repository.TradeRepository, interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx = consistency.WithPrimary(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			expired, err := trades.Expire(ctx, batchSize)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "expiring trades failed", "error", err)
+			}
+			if expired == batchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package events delivers committed ledger legs to streaming clients. A
+// Poller on every API instance reads the wallet_events outbox and publishes to
+// a Broker, which fans events out to the subscribers of each owner.
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+// Event is the wire form of one ledger leg on a user's wallet.
+type Event struct {
+	ID              int64     `json:"id"`
+	WalletID        uuid.UUID `json:"wallet_id"`
+	OwnerID         uuid.UUID `json:"owner_id"`
+	CurrencyTypeID  uuid.UUID `json:"currency_type_id"`
+	TransactionID   uuid.UUID `json:"transaction_id"`
+	TransactionType string    `json:"transaction_type"`
+	Amount          int64     `json:"amount"`
+	Balance         int64     `json:"balance"`
+	ReferenceID     string    `json:"reference_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func FromWalletEvent(event *repository.WalletEvent) Event {
+	return Event{
+		ID:              event.ID,
+		WalletID:        event.WalletID,
+		OwnerID:         event.OwnerID,
+		CurrencyTypeID:  event.CurrencyTypeID,
+		TransactionID:   event.TransactionID,
+		TransactionType: event.TransactionType,
+		Amount:          event.Amount,
+		Balance:         event.BalanceAfter,
+		ReferenceID:     event.ReferenceID,
+		CreatedAt:       event.CreatedAt,
+	}
+}
+
+var (
+	ErrLagged = errors.New("stream fell behind; reconnect and resume from the last event")
+	ErrClosed = errors.New("server is shutting down; reconnect and resume from the last event")
+)
+
+// Subscription receives the events of one owner until it is dropped.
+type Subscription struct {
+	ownerID uuid.UUID
+	events  chan Event
+	done    chan struct{}
+	err     error
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed when the broker drops the subscription, after which Err
+// says why.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Subscription) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Broker fans published events out to the subscribers of their owner.
+type Broker struct {
+	mu     sync.Mutex
+	buffer int
+	closed bool
+	owners map[uuid.UUID]map[*Subscription]struct{}
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{buffer: buffer, owners: make(map[uuid.UUID]map[*Subscription]struct{})}
+}
+
+// Subscribe returns a subscription to ownerID's events and the function
+// that ends it.
+func (b *Broker) Subscribe(ownerID uuid.UUID) (*Subscription, func()) {
+	sub := &Subscription{ownerID: ownerID, events: make(chan Event, b.buffer), done: make(chan struct{})}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.done)
+		return sub, func() {}
+	}
+	if b.owners[ownerID] == nil {
+		b.owners[ownerID] = make(map[*Subscription]struct{})
+	}
+	b.owners[ownerID][sub] = struct{}{}
+	return sub, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(sub)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, subs := range b.owners {
+		n += len(subs)
+	}
+	return n
+}
+
+// Publish never blocks: a subscriber whose buffer is full is dropped with
+// ErrLagged.
+func (b *Broker) Publish(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range events {
+		for sub := range b.owners[event.OwnerID] {
+			select {
+			case sub.events <- event:
+			default:
+				b.drop(sub, ErrLagged)
+			}
+		}
+	}
+}
+
+// Close drops every subscription with ErrClosed, so open streams end and
+// the HTTP server can drain; it is meant for http.Server.RegisterOnShutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subs := range b.owners {
+		for sub := range subs {
+			b.drop(sub, ErrClosed)
+		}
+	}
+}
+
+func (b *Broker) drop(sub *Subscription, err error) {
+	sub.err = err
+	close(sub.done)
+	b.remove(sub)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	subs := b.owners[sub.ownerID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.owners, sub.ownerID)
+	}
+}
@@ -0,0 +1,144 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/events"
+	"gorm.io/gorm"
+)
+
+func TestBroker(t *testing.T) {
+	broker := events.NewBroker(2)
+	alice, bob := uuid.New(), uuid.New()
+	sub, unsubscribe := broker.Subscribe(alice)
+	slow, _ := broker.Subscribe(alice)
+	other, _ := broker.Subscribe(bob)
+
+	broker.Publish(events.Event{ID: 1, OwnerID: alice}, events.Event{ID: 2, OwnerID: bob})
+	if got := (<-sub.Events()).ID; got != 1 {
+		t.Errorf("alice got event %d, want 1", got)
+	}
+	if got := (<-other.Events()).ID; got != 2 {
+		t.Errorf("bob got event %d, want 2", got)
+	}
+
+	// slow never reads, so the third event overflows its buffer of two
+	broker.Publish(events.Event{ID: 3, OwnerID: alice})
+	<-sub.Events()
+	broker.Publish(events.Event{ID: 4, OwnerID: alice})
+	<-sub.Events()
+	select {
+	case <-slow.Done():
+		if !errors.Is(slow.Err(), events.ErrLagged) {
+			t.Errorf("slow subscriber dropped with %v, want ErrLagged", slow.Err())
+		}
+	default:
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if got := broker.Subscribers(); got != 2 {
+		t.Errorf("%d subscribers after the drop, want 2", got)
+	}
+
+	unsubscribe()
+	broker.Close()
+	if !errors.Is(other.Err(), events.ErrClosed) {
+		t.Errorf("open subscriber closed with %v, want ErrClosed", other.Err())
+	}
+	late, _ := broker.Subscribe(alice)
+	if !errors.Is(late.Err(), events.ErrClosed) {
+		t.Errorf("subscription after Close: %v, want ErrClosed", late.Err())
+	}
+}
+
+func insertEvent(t *testing.T, db *gorm.DB, id int64, ownerID uuid.UUID, createdAt time.Time) {
+	t.Helper()
+	event := repository.WalletEvent{
+		ID: id, WalletID: uuid.New(), OwnerType: "user", OwnerID: ownerID, CurrencyTypeID: uuid.New(),
+		TransactionID: uuid.New(), TransactionType: "bonus", Amount: id, BalanceAfter: id, ReferenceID: uuid.NewString(),
+		CreatedAt: createdAt,
+	}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func received(sub *events.Subscription) []int64 {
+	var ids []int64
+	for {
+		select {
+		case event := <-sub.Events():
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+// TestPollerFillsGaps commits event 3 before event 2, as concurrent
+// transactions can, and expects both to be published once.
+func TestPollerFillsGaps(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	owner := uuid.New()
+	now := time.Now().UTC()
+	insertEvent(t, db, 1, owner, now)
+
+	broker := events.NewBroker(16)
+	sub, _ := broker.Subscribe(owner)
+	poller := events.NewPoller(repository.NewWalletEventRepository(db), broker, events.PollerOptions{
+		Interval:   time.Hour,
+		GapTimeout: time.Hour,
+	})
+	poll := func() []int64 {
+		t.Helper()
+		if err := poller.Poll(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return received(sub)
+	}
+
+	if got := poll(); len(got) != 0 {
+		t.Errorf("first poll published %v; history is only for resuming clients", got)
+	}
+	insertEvent(t, db, 3, owner, now)
+	if got := poll(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("published %v, want [3]", got)
+	}
+	insertEvent(t, db, 2, owner, now)
+	insertEvent(t, db, 4, uuid.New(), now)
+	if got := poll(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("published %v, want the late event [2]", got)
+	}
+	if got := poll(); len(got) != 0 {
+		t.Errorf("published %v again", got)
+	}
+}
+
+func TestPruneKeepsRecentEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := uuid.New()
+	now := time.Now().UTC()
+	insertEvent(t, db, 1, owner, now.Add(-48*time.Hour))
+	insertEvent(t, db, 2, owner, now.Add(-time.Hour))
+
+	deleted, err := events.Prune(context.Background(), repository.NewWalletEventRepository(db), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("pruned %d events, want 1", deleted)
+	}
+	var ids []int64
+	if err := db.Model(&repository.WalletEvent{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("kept events %v, want [2]", ids)
+	}
+}
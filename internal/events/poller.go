@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+const (
+	defaultBatch      = 500
+	defaultGapTimeout = time.Minute
+	// maxGaps bounds the skipped IDs retried at once; sequences can jump by
+	// far more than any number of in-flight transactions.
+	maxGaps = 1000
+)
+
+type PollerOptions struct {
+	// Interval between polls. A commit in this process wakes the poller
+	// early, so the interval only delays events written by other instances.
+	Interval time.Duration
+	// Batch is the most events read per query; 0 is 500.
+	Batch int
+	// GapTimeout is how long an ID skipped by the cursor is retried, in case
+	// its transaction commits after a later one; 0 is one minute.
+	GapTimeout time.Duration
+}
+
+// Poller publishes the wallet events committed by any instance to a Broker.
+// It starts at the newest event: history is for clients that resume.
+type Poller struct {
+	events  repository.WalletEventRepository
+	broker  *Broker
+	options PollerOptions
+
+	started bool
+	cursor  int64
+	gaps    map[int64]time.Time
+}
+
+func NewPoller(events repository.WalletEventRepository, broker *Broker, options PollerOptions) *Poller {
+	if options.Batch <= 0 {
+		options.Batch = defaultBatch
+	}
+	if options.GapTimeout <= 0 {
+		options.GapTimeout = defaultGapTimeout
+	}
+	return &Poller{events: events, broker: broker, options: options, gaps: make(map[int64]time.Time)}
+}
+
+// Run polls until ctx is cancelled; it is meant for lifecycle.Manager.Go.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.options.Interval)
+	defer ticker.Stop()
+	for {
+		// taken before polling so a commit during the poll is not missed
+		committed := repository.WalletEventsCommitted()
+		if err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "wallet event poll failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-committed:
+		}
+	}
+}
+
+// Poll publishes the events committed since the previous poll.
+func (p *Poller) Poll(ctx context.Context) error {
+	// replicas may lag behind events this instance was just woken for
+	ctx = consistency.WithPrimary(ctx)
+	now := time.Now()
+	if !p.started {
+		latest, err := p.events.LatestID(ctx)
+		if err != nil {
+			return err
+		}
+		p.cursor, p.started = latest, true
+		return nil
+	}
+	if err := p.retryGaps(ctx, now); err != nil {
+		return err
+	}
+	for {
+		batch, err := p.events.ListAfter(ctx, p.cursor, p.options.Batch)
+		if err != nil {
+			return err
+		}
+		published := make([]Event, len(batch))
+		for i := range batch {
+			for id := p.cursor + 1; id < batch[i].ID && len(p.gaps) < maxGaps; id++ {
+				p.gaps[id] = now
+			}
+			p.cursor = batch[i].ID
+			published[i] = FromWalletEvent(&batch[i])
+		}
+		p.broker.Publish(published...)
+		if len(batch) < p.options.Batch {
+			break
+		}
+	}
+	return nil
+}
+
+// retryGaps publishes skipped events whose transactions have since
+// committed, and forgets the ones that never will.
+func (p *Poller) retryGaps(ctx context.Context, now time.Time) error {
+	if len(p.gaps) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(p.gaps))
+	for id := range p.gaps {
+		ids = append(ids, id)
+	}
+	found, err := p.events.ListByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	published := make([]Event, len(found))
+	for i := range found {
+		delete(p.gaps, found[i].ID)
+		published[i] = FromWalletEvent(&found[i])
+	}
+	p.broker.Publish(published...)
+	for id, seen := range p.gaps {
+		if now.Sub(seen) >= p.options.GapTimeout {
+			delete(p.gaps, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+// pruneInterval paces Pruner; retention is measured in hours, so pruning
+// more often only costs queries.
+const pruneInterval = time.Minute
+
+// Pruner returns a background worker that deletes the events older than
+// retention every minute. Every ledger leg writes an event whether or not
+// this instance streams them, so it runs apart from the Poller. A failed run
+// is logged and retried on the next tick rather than stopping the worker.
+func Pruner(events repository.WalletEventRepository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := Prune(ctx, events, retention); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "wallet event pruning failed", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// Prune deletes the events older than retention and reports how many there
+// were.
+func Prune(ctx context.Context, events repository.WalletEventRepository, retention time.Duration) (int64, error) {
+	return events.DeleteBefore(ctx, time.Now().Add(-retention))
+}
@@ -0,0 +1,47 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeForHTTPStatus maps the statuses REST answers with to their gRPC
+// equivalents, so both APIs classify an error the same way.
+var codeForHTTPStatus = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusUnprocessableEntity: codes.FailedPrecondition,
+}
+
+// statusError converts a repository error to a gRPC status. Ledger rule
+// violations are FailedPrecondition, like REST's 422: the call was valid but
+// the wallet state does not allow it. So is an operation held for review.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, risk.ErrHeld):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, risk.ErrDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	httpStatus, err := utils.CheckGormError(nil, err)
+	code, ok := codeForHTTPStatus[httpStatus]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+func invalidArgument(format string, args ...any) error {
+	return status.Errorf(codes.InvalidArgument, format, args...)
+}
@@ -0,0 +1,76 @@
+// Package grpcapi serves the wallet.v1 gRPC API next to the REST API, with
+// the same authentication, limits, replica routing and error classification.
+package grpcapi
+
+import (
+	"log/slog"
+	"time"
+
+	walletv1 "github.com/jay6909/dino-internal-wallet-service/api/wallet/v1"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/limits"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+	"github.com/jay6909/dino-internal-wallet-service/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// ServerOptions configure the interceptors and built-in services.
+type ServerOptions struct {
+	Logger *slog.Logger
+	Keys   *auth.Keys
+	// RequestTimeout is the deadline on every call; 0 is none.
+	RequestTimeout time.Duration
+	// MaxMessageBytes bounds request messages; 0 keeps the gRPC default.
+	MaxMessageBytes int64
+	// Reflection lets tools such as grpcurl discover the API.
+	Reflection bool
+}
+
+// NewServer returns a gRPC server with the wallet service, the standard
+// health service and, optionally, server reflection registered. The health
+// service reports NOT_SERVING until the caller marks it serving.
+func NewServer(wallets *WalletServer, opts ServerOptions) (*grpc.Server, *health.Server) {
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(tracing.GRPCStatsHandler()),
+		grpc.ChainUnaryInterceptor(
+			logger.UnaryServerInterceptor(opts.Logger),
+			metrics.UnaryServerInterceptor(),
+			limits.UnaryServerInterceptor(opts.RequestTimeout),
+			auth.UnaryServerInterceptor(opts.Keys),
+			consistency.UnaryServerInterceptor(
+				walletv1.WalletService_GetBalance_FullMethodName,
+				walletv1.WalletService_ListTransactions_FullMethodName,
+			),
+		),
+		grpc.ChainStreamInterceptor(auth.StreamServerInterceptor(opts.Keys)),
+	}
+	if opts.MaxMessageBytes > 0 {
+		serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(int(opts.MaxMessageBytes)))
+	}
+	server := grpc.NewServer(serverOptions...)
+
+	walletv1.RegisterWalletServiceServer(server, wallets)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(walletv1.WalletService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	if opts.Reflection {
+		reflection.Register(server)
+	}
+	return server, healthServer
+}
+
+// SetServing flips every service registered with the health server.
+func SetServing(healthServer *health.Server, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	healthServer.SetServingStatus("", status)
+	healthServer.SetServingStatus(walletv1.WalletService_ServiceDesc.ServiceName, status)
+}
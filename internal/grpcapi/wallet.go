@@ -0,0 +1,322 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	walletv1 "github.com/jay6909/dino-internal-wallet-service/api/wallet/v1"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// WalletServer implements wallet.v1.WalletService with the same repositories,
+// options and idempotency rules as handler.WalletHandler.
+type WalletServer struct {
+	walletv1.UnimplementedWalletServiceServer
+	walletRepository repository.WalletRepository
+	userRepository   repository.UserRepository
+	// wallets opens missing wallets exactly like the REST endpoints do
+	wallets *handler.WalletHandler
+	options handler.WalletOptions
+}
+
+func NewWalletServer(walletRepository repository.WalletRepository, userRepository repository.UserRepository) *WalletServer {
+	return &WalletServer{
+		walletRepository: walletRepository,
+		userRepository:   userRepository,
+		wallets:          handler.NewWalletHandler(walletRepository, userRepository),
+		options:          handler.WalletOptions{Transfers: true},
+	}
+}
+
+// WithOptions replaces the default options, which allow any amount and
+// serve transfers.
+func (s *WalletServer) WithOptions(options handler.WalletOptions) *WalletServer {
+	s.options = options
+	return s
+}
+
+// ownerOperation is the common shape of TopUp, Spend and Bonus.
+type ownerOperation struct {
+	key            string
+	ownerID        uuid.UUID
+	currencyTypeID uuid.UUID
+	amount         int64
+}
+
+type ownerRequest interface {
+	GetIdempotencyKey() string
+	GetOwnerId() string
+	GetCurrencyTypeId() string
+	GetAmount() int64
+}
+
+func (s *WalletServer) TopUp(ctx context.Context, req *walletv1.TopUpRequest) (*walletv1.OperationResponse, error) {
+	return s.treasuryTransfer(ctx, req, enums.TransactionTypeTopUp, true)
+}
+
+func (s *WalletServer) Spend(ctx context.Context, req *walletv1.SpendRequest) (*walletv1.OperationResponse, error) {
+	return s.treasuryTransfer(ctx, req, enums.TransactionTypeSpend, false)
+}
+
+func (s *WalletServer) Bonus(ctx context.Context, req *walletv1.BonusRequest) (*walletv1.OperationResponse, error) {
+	return s.treasuryTransfer(ctx, req, enums.TransactionTypeBonus, true)
+}
+
+// treasuryTransfer moves the amount between the owner's wallet, opened on
+// demand, and the currency treasury: to the owner when credit is set, from
+// the owner otherwise.
+func (s *WalletServer) treasuryTransfer(ctx context.Context, req ownerRequest, transactionType enums.TransactionType, credit bool) (*walletv1.OperationResponse, error) {
+	op, err := s.parseOwnerOperation(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logger.With(ctx,
+		"owner_id", op.ownerID.String(),
+		"currency_type_id", op.currencyTypeID.String(),
+		"idempotency_key", op.key,
+	)
+	return s.apply(ctx, op.key, func(ctx context.Context) error {
+		if !credit {
+			err := s.screen(ctx, risk.Operation{Kind: enums.RiskOperationSpend, OwnerID: op.ownerID,
+				CurrencyTypeID: op.currencyTypeID, Amount: op.amount, IdempotencyKey: op.key})
+			if err != nil {
+				return err
+			}
+		}
+		systemWallet, err := s.walletRepository.GetSystemWalletByCurrencyType(ctx, op.currencyTypeID.String())
+		if err != nil {
+			return err
+		}
+		wallet, err := s.wallets.CheckUserWalletIfNotCreate(ctx, op.ownerID, op.currencyTypeID)
+		if err != nil {
+			return err
+		}
+		from, to := systemWallet, wallet
+		if !credit {
+			from, to = wallet, systemWallet
+		}
+		return s.walletRepository.Transfer(ctx, from.ID.String(), to.ID.String(),
+			op.currencyTypeID.String(), op.key, op.amount, transactionType)
+	})
+}
+
+// Transfer moves funds between two users' wallets of the same currency. The
+// sender's wallet must already exist; the recipient's is created on demand.
+func (s *WalletServer) Transfer(ctx context.Context, req *walletv1.TransferRequest) (*walletv1.OperationResponse, error) {
+	if !s.options.Transfers {
+		return nil, status.Error(codes.Unimplemented, "transfers are disabled")
+	}
+	if req.GetIdempotencyKey() == "" {
+		return nil, invalidArgument("idempotency_key is required")
+	}
+	fromOwnerID, err := parseID("from_owner_id", req.GetFromOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	toOwnerID, err := parseID("to_owner_id", req.GetToOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	currencyTypeID, err := parseID("currency_type_id", req.GetCurrencyTypeId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAmount(req.GetAmount()); err != nil {
+		return nil, err
+	}
+	if fromOwnerID == toOwnerID {
+		return nil, invalidArgument("from_owner_id and to_owner_id must differ")
+	}
+	ctx = logger.With(ctx,
+		"from_owner_id", fromOwnerID.String(),
+		"to_owner_id", toOwnerID.String(),
+		"currency_type_id", currencyTypeID.String(),
+		"idempotency_key", req.GetIdempotencyKey(),
+	)
+	return s.apply(ctx, req.GetIdempotencyKey(), func(ctx context.Context) error {
+		err := s.screen(ctx, risk.Operation{Kind: enums.RiskOperationTransfer, OwnerID: fromOwnerID, ToOwnerID: &toOwnerID,
+			CurrencyTypeID: currencyTypeID, Amount: req.GetAmount(), IdempotencyKey: req.GetIdempotencyKey()})
+		if err != nil {
+			return err
+		}
+		sender, err := s.userRepository.GetUserByID(ctx, fromOwnerID.String())
+		if err != nil {
+			return err
+		}
+		fromWallet, err := s.walletRepository.GetWalletByOwner(ctx, sender.Role, fromOwnerID.String(), currencyTypeID.String())
+		if err != nil {
+			return err
+		}
+		toWallet, err := s.wallets.CheckUserWalletIfNotCreate(ctx, toOwnerID, currencyTypeID)
+		if err != nil {
+			return err
+		}
+		return s.walletRepository.Transfer(ctx, fromWallet.ID.String(), toWallet.ID.String(),
+			currencyTypeID.String(), req.GetIdempotencyKey(), req.GetAmount(), enums.TransactionTypeTransfer)
+	})
+}
+
+// screen asks the risk evaluator, if any, whether op may go ahead. Callers
+// screen before writing anything, so that stopped operations open no wallet.
+func (s *WalletServer) screen(ctx context.Context, op risk.Operation) error {
+	if s.options.Risk == nil {
+		return nil
+	}
+	decision, err := s.options.Risk.Evaluate(ctx, op)
+	if err != nil {
+		return err
+	}
+	return risk.Err(decision)
+}
+
+// apply replays an idempotency key that was already applied, like the REST
+// endpoints, and otherwise runs the operation and reports its reference ID.
+func (s *WalletServer) apply(ctx context.Context, key string, run func(ctx context.Context) error) (*walletv1.OperationResponse, error) {
+	transaction, err := s.walletRepository.GetTransactionByIdempotencyKey(ctx, key)
+	if err == nil {
+		logger.FromContext(ctx).Info("idempotent replay", "reference_id", transaction.ReferenceID)
+		return &walletv1.OperationResponse{ReferenceId: transaction.ReferenceID, Replayed: true}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, statusError(err)
+	}
+	if err := run(ctx); err != nil {
+		return nil, statusError(err)
+	}
+	transaction, err = s.walletRepository.GetTransactionByIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.OperationResponse{ReferenceId: transaction.ReferenceID}, nil
+}
+
+func (s *WalletServer) GetBalance(ctx context.Context, req *walletv1.GetBalanceRequest) (*walletv1.Wallet, error) {
+	ownerID, err := parseID("owner_id", req.GetOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	currencyTypeID, err := parseID("currency_type_id", req.GetCurrencyTypeId())
+	if err != nil {
+		return nil, err
+	}
+	ownerType := req.GetOwnerType()
+	if ownerType == "" {
+		owner, err := s.userRepository.GetUserByID(ctx, ownerID.String())
+		if err != nil {
+			return nil, statusError(err)
+		}
+		ownerType = owner.Role
+	}
+	wallet, err := s.walletRepository.GetWalletByOwner(ctx, ownerType, ownerID.String(), currencyTypeID.String())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toWallet(wallet), nil
+}
+
+func (s *WalletServer) ListTransactions(ctx context.Context, req *walletv1.ListTransactionsRequest) (*walletv1.ListTransactionsResponse, error) {
+	walletID, err := parseID("wallet_id", req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+	limit := int(req.GetLimit())
+	switch {
+	case limit < 0:
+		return nil, invalidArgument("limit must not be negative")
+	case limit == 0:
+		limit = defaultListLimit
+	case limit > maxListLimit:
+		limit = maxListLimit
+	}
+	// an unknown wallet is NotFound rather than an empty history
+	if _, err := s.walletRepository.GetWalletByID(ctx, walletID.String()); err != nil {
+		return nil, statusError(err)
+	}
+	transactions, err := s.walletRepository.ListTransactions(ctx, walletID.String(), limit)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &walletv1.ListTransactionsResponse{Transactions: make([]*walletv1.Transaction, len(transactions))}
+	for i := range transactions {
+		resp.Transactions[i] = toTransaction(&transactions[i])
+	}
+	return resp, nil
+}
+
+func (s *WalletServer) parseOwnerOperation(req ownerRequest) (*ownerOperation, error) {
+	if req.GetIdempotencyKey() == "" {
+		return nil, invalidArgument("idempotency_key is required")
+	}
+	ownerID, err := parseID("owner_id", req.GetOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	currencyTypeID, err := parseID("currency_type_id", req.GetCurrencyTypeId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAmount(req.GetAmount()); err != nil {
+		return nil, err
+	}
+	return &ownerOperation{key: req.GetIdempotencyKey(), ownerID: ownerID, currencyTypeID: currencyTypeID, amount: req.GetAmount()}, nil
+}
+
+func (s *WalletServer) checkAmount(amount int64) error {
+	if amount <= 0 {
+		return invalidArgument("amount must be positive")
+	}
+	if s.options.MaxAmount > 0 && amount > s.options.MaxAmount {
+		return invalidArgument("amount exceeds the limit of %d", s.options.MaxAmount)
+	}
+	return nil
+}
+
+func parseID(field, value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, invalidArgument("%s is required", field)
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, invalidArgument("%s must be a valid UUID", field)
+	}
+	return id, nil
+}
+
+func toWallet(wallet *repository.Wallet) *walletv1.Wallet {
+	return &walletv1.Wallet{
+		Id:             wallet.ID.String(),
+		OwnerType:      wallet.OwnerType,
+		OwnerId:        wallet.OwnerID.String(),
+		CurrencyTypeId: wallet.CurrencyTypeID.String(),
+		Balance:        wallet.Balance,
+		Frozen:         wallet.Frozen,
+		UpdatedAt:      timestamppb.New(wallet.UpdatedAt),
+	}
+}
+
+func toTransaction(transaction *repository.WalletTransaction) *walletv1.Transaction {
+	return &walletv1.Transaction{
+		Id:              transaction.ID.String(),
+		WalletId:        transaction.WalletID.String(),
+		TransactionType: transaction.TransactionType,
+		Amount:          transaction.Amount,
+		BalanceAfter:    transaction.BalanceAfter,
+		ReferenceId:     transaction.ReferenceID,
+		IdempotencyKey:  transaction.IdempotencyKey,
+		CreatedAt:       timestamppb.New(transaction.CreatedAt),
+	}
+}
@@ -0,0 +1,272 @@
+package grpcapi_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	walletv1 "github.com/jay6909/dino-internal-wallet-service/api/wallet/v1"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/grpcapi"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const apiKey = "test-key-0123456789"
+
+// testEnv is a gRPC client of a server wired to in-memory repositories
+// holding two users and a treasury of 1000 in one currency.
+type testEnv struct {
+	client   walletv1.WalletServiceClient
+	conn     *grpc.ClientConn
+	wallets  repository.WalletRepository
+	user     repository.User
+	other    repository.User
+	currency uuid.UUID
+}
+
+func newTestEnv(t *testing.T, options handler.WalletOptions) *testEnv {
+	t.Helper()
+	ctx := context.Background()
+	users := repository.NewInMemoryUserRepository()
+	env := &testEnv{
+		wallets:  repository.NewInMemoryWalletRepository(),
+		user:     repository.User{ID: uuid.New(), Name: "Alice", Role: "user"},
+		other:    repository.User{ID: uuid.New(), Name: "Bob", Role: "user"},
+		currency: uuid.New(),
+	}
+	for _, user := range []*repository.User{&env.user, &env.other} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	treasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: env.currency, Balance: 1_000}
+	if err := env.wallets.CreateWallet(ctx, &treasury); err != nil {
+		t.Fatal(err)
+	}
+
+	server, healthServer := grpcapi.NewServer(grpcapi.NewWalletServer(env.wallets, users).WithOptions(options), grpcapi.ServerOptions{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Keys:       auth.NewKeys([]string{apiKey}),
+		Reflection: true,
+	})
+	grpcapi.SetServing(healthServer, true)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	env.conn = conn
+	env.client = walletv1.NewWalletServiceClient(conn)
+	return env
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+apiKey)
+}
+
+func (env *testEnv) topUp(key string, amount int64) (*walletv1.OperationResponse, error) {
+	return env.client.TopUp(authorized(), &walletv1.TopUpRequest{
+		IdempotencyKey: key, OwnerId: env.user.ID.String(), CurrencyTypeId: env.currency.String(), Amount: amount,
+	})
+}
+
+func (env *testEnv) balance(t *testing.T, ownerID uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := env.client.GetBalance(authorized(), &walletv1.GetBalanceRequest{
+		OwnerId: ownerID.String(), CurrencyTypeId: env.currency.String(),
+	})
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	return wallet.Balance
+}
+
+func wantCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("got %v (%v), want %v", got, err, want)
+	}
+}
+
+func TestTopUpIsIdempotent(t *testing.T) {
+	env := newTestEnv(t, handler.WalletOptions{Transfers: true})
+	first, err := env.topUp("top-up-1", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ReferenceId == "" || first.Replayed {
+		t.Errorf("first call: %+v", first)
+	}
+	replay, err := env.topUp("top-up-1", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ReferenceId != first.ReferenceId || !replay.Replayed {
+		t.Errorf("replay %+v, want reference %s replayed", replay, first.ReferenceId)
+	}
+	if got := env.balance(t, env.user.ID); got != 300 {
+		t.Errorf("balance %d, want 300", got)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	env := newTestEnv(t, handler.WalletOptions{Transfers: true, MaxAmount: 500})
+	if _, err := env.topUp("fund", 100); err != nil {
+		t.Fatal(err)
+	}
+	ctx := authorized()
+
+	_, err := env.client.Spend(ctx, &walletv1.SpendRequest{
+		IdempotencyKey: "overspend", OwnerId: env.user.ID.String(), CurrencyTypeId: env.currency.String(), Amount: 101,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	_, err = env.topUp("too-much", 501)
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = env.client.Bonus(ctx, &walletv1.BonusRequest{
+		IdempotencyKey: "bad-owner", OwnerId: "nope", CurrencyTypeId: env.currency.String(), Amount: 1,
+	})
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = env.client.Bonus(ctx, &walletv1.BonusRequest{
+		IdempotencyKey: "unknown-owner", OwnerId: uuid.NewString(), CurrencyTypeId: env.currency.String(), Amount: 1,
+	})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = env.client.GetBalance(ctx, &walletv1.GetBalanceRequest{
+		OwnerId: env.other.ID.String(), CurrencyTypeId: env.currency.String(),
+	})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = env.client.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: uuid.NewString()})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = env.client.TopUp(context.Background(), &walletv1.TopUpRequest{
+		IdempotencyKey: "anonymous", OwnerId: env.user.ID.String(), CurrencyTypeId: env.currency.String(), Amount: 1,
+	})
+	wantCode(t, err, codes.Unauthenticated)
+}
+
+// stubRisk decides by idempotency key and allows the keys it does not know.
+type stubRisk map[string]repository.RiskDecision
+
+func (s stubRisk) Evaluate(_ context.Context, op risk.Operation) (*repository.RiskDecision, error) {
+	decision, ok := s[op.IdempotencyKey]
+	if !ok {
+		decision.Outcome = enums.RiskOutcomeAllow
+	}
+	return &decision, nil
+}
+
+func TestRiskCodes(t *testing.T) {
+	env := newTestEnv(t, handler.WalletOptions{Transfers: true, Risk: stubRisk{
+		"denied": {Outcome: enums.RiskOutcomeDeny, Reason: "owner is blocked"},
+		"held":   {Outcome: enums.RiskOutcomeReview, ReviewStatus: enums.RiskReviewPending},
+	}})
+	if _, err := env.topUp("fund", 100); err != nil {
+		t.Fatal(err)
+	}
+	ctx := authorized()
+	spend := func(key string) error {
+		_, err := env.client.Spend(ctx, &walletv1.SpendRequest{
+			IdempotencyKey: key, OwnerId: env.user.ID.String(), CurrencyTypeId: env.currency.String(), Amount: 10,
+		})
+		return err
+	}
+
+	wantCode(t, spend("denied"), codes.PermissionDenied)
+	_, err := env.client.Transfer(ctx, &walletv1.TransferRequest{
+		IdempotencyKey: "held", FromOwnerId: env.user.ID.String(), ToOwnerId: env.other.ID.String(),
+		CurrencyTypeId: env.currency.String(), Amount: 10,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+	if err := spend("fine"); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.balance(t, env.user.ID); got != 90 {
+		t.Errorf("balance %d, want 90 after the one allowed spend", got)
+	}
+}
+
+func TestTransferAndHistory(t *testing.T) {
+	env := newTestEnv(t, handler.WalletOptions{Transfers: true})
+	if _, err := env.topUp("fund", 100); err != nil {
+		t.Fatal(err)
+	}
+	ctx := authorized()
+	transfer := &walletv1.TransferRequest{
+		IdempotencyKey: "gift", FromOwnerId: env.user.ID.String(), ToOwnerId: env.other.ID.String(),
+		CurrencyTypeId: env.currency.String(), Amount: 40,
+	}
+	resp, err := env.client.Transfer(ctx, transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := env.balance(t, env.other.ID); got != 40 {
+		t.Errorf("recipient balance %d, want 40", got)
+	}
+
+	wallet, err := env.client.GetBalance(ctx, &walletv1.GetBalanceRequest{
+		OwnerId: env.user.ID.String(), CurrencyTypeId: env.currency.String(), OwnerType: "user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := env.client.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: wallet.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Transactions) != 2 {
+		t.Fatalf("got %d ledger entries, want 2", len(history.Transactions))
+	}
+	var gift *walletv1.Transaction
+	for _, entry := range history.Transactions {
+		if entry.IdempotencyKey == "gift" {
+			gift = entry
+		}
+	}
+	if gift == nil || gift.Amount != -40 || gift.ReferenceId != resp.ReferenceId {
+		t.Errorf("transfer leg %+v, want -40 with reference %s", gift, resp.ReferenceId)
+	}
+
+	transfer.ToOwnerId = transfer.FromOwnerId
+	_, err = env.client.Transfer(ctx, transfer)
+	wantCode(t, err, codes.InvalidArgument)
+
+	disabled := newTestEnv(t, handler.WalletOptions{})
+	_, err = disabled.client.Transfer(authorized(), transfer)
+	wantCode(t, err, codes.Unimplemented)
+}
+
+func TestHealthNeedsNoKey(t *testing.T) {
+	env := newTestEnv(t, handler.WalletOptions{})
+	resp, err := healthpb.NewHealthClient(env.conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: walletv1.WalletService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("status %v, want SERVING", resp.Status)
+	}
+}
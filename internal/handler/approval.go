@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+// ApprovalHandler queues mints, burns, adjustments and treasury transfers
+// that need a second admin and lets another admin approve or reject them.
+// Operations the policy lets through are executed right away, and every
+// step of every operation is kept in an audit trail. Its routes belong on
+// a group behind auth.AdminGinMiddleware, which names the admin acting.
+type ApprovalHandler struct {
+	approvalRepository repository.ApprovalRepository
+	walletRepository   repository.WalletRepository
+	openWallet         func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	execute            func(ctx context.Context, op *repository.PendingOperation) (string, error)
+	policy             approval.Policy
+}
+
+// NewApprovalHandler takes openWallet to find or open the wallets of
+// adjusted users, such as WalletHandler.CheckUserWalletIfNotCreate.
+func NewApprovalHandler(approvalRepository repository.ApprovalRepository, walletRepository repository.WalletRepository,
+	openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error), policy approval.Policy) *ApprovalHandler {
+	return &ApprovalHandler{approvalRepository: approvalRepository, walletRepository: walletRepository, openWallet: openWallet,
+		execute: approval.Executor(walletRepository, openWallet), policy: policy}
+}
+
+func (h *ApprovalHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/approvals")
+	route.POST("", h.Propose)
+	route.GET("", h.ListOperations)
+	route.GET("/audit", h.ListAudit)
+	route.GET("/:id", h.GetOperation)
+	route.GET("/:id/audit", h.ListAudit)
+	route.POST("/:id/approve", h.Approve)
+	route.POST("/:id/reject", h.Reject)
+}
+
+// Propose queues the operation and answers 202 with it when it needs
+// approval, or executes it and answers 201. A retried proposal answers 200
+// with the operation as it stands.
+func (h *ApprovalHandler) Propose(c *gin.Context) {
+	req := &data_requests.ApprovalProposalRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	admin := auth.AdminFrom(c)
+	logger.SetRequestContext(c, "kind", req.Kind, "idempotency_key", req.IdempotencyKey, "proposed_by", admin)
+	ctx := c.Request.Context()
+	op := &repository.PendingOperation{
+		Kind:           req.Kind,
+		IdempotencyKey: req.IdempotencyKey,
+		Amount:         req.Amount,
+		Note:           req.Note,
+		ProposedBy:     admin,
+		ExpiresAt:      time.Now().UTC().Add(h.policy.Expiry),
+	}
+	switch req.Kind {
+	case enums.ApprovalKindTransfer:
+		if req.FromWalletID == nil || req.ToWalletID == nil || *req.FromWalletID == *req.ToWalletID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transfers need two different wallets, from_wallet_id and to_wallet_id"})
+			return
+		}
+		from, err := h.walletRepository.GetWalletByID(ctx, req.FromWalletID.String())
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		to, err := h.walletRepository.GetWalletByID(ctx, req.ToWalletID.String())
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		if from.CurrencyTypeID != to.CurrencyTypeID || req.CurrencyTypeID != nil && *req.CurrencyTypeID != from.CurrencyTypeID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "both wallets must hold the currency of the transfer"})
+			return
+		}
+		op.CurrencyTypeID, op.FromWalletID, op.ToWalletID = from.CurrencyTypeID, &from.ID, &to.ID
+	default:
+		if req.CurrencyTypeID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": req.Kind + " needs currency_type_id"})
+			return
+		}
+		if req.Kind == enums.ApprovalKindAdjustment && (req.OwnerID == nil || req.Direction == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "adjustments need owner_id and a direction, credit or debit"})
+			return
+		}
+		_, err := h.walletRepository.GetSystemWalletByCurrencyType(ctx, req.CurrencyTypeID.String())
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		op.CurrencyTypeID = *req.CurrencyTypeID
+		if req.Kind == enums.ApprovalKindAdjustment {
+			if _, err := h.openWallet(ctx, *req.OwnerID, op.CurrencyTypeID); utils.ReturnIfGormError(c, err) {
+				return
+			}
+			op.OwnerID, op.Direction = req.OwnerID, req.Direction
+		}
+	}
+
+	execute := h.execute
+	if h.policy.NeedsApproval(op.Kind, op.Amount) {
+		execute = nil
+	}
+	proposed, existing, err := h.approvalRepository.Propose(ctx, op, execute)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	switch {
+	case existing:
+		c.JSON(http.StatusOK, proposed)
+	case proposed.Status == enums.ApprovalPending:
+		c.JSON(http.StatusAccepted, proposed)
+	default:
+		c.JSON(http.StatusCreated, proposed)
+	}
+}
+
+// ListOperations answers the most recent operations, newest first,
+// optionally only those with a status or of a kind.
+func (h *ApprovalHandler) ListOperations(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	filter := repository.PendingOperationFilter{Status: c.Query("status"), Kind: c.Query("kind")}
+	switch filter.Status {
+	case "", enums.ApprovalPending, enums.ApprovalApproved, enums.ApprovalExecuted, enums.ApprovalFailed, enums.ApprovalRejected, enums.ApprovalExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved, executed, failed, rejected or expired"})
+		return
+	}
+	switch filter.Kind {
+	case "", enums.ApprovalKindMint, enums.ApprovalKindBurn, enums.ApprovalKindAdjustment, enums.ApprovalKindTransfer:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be mint, burn, adjustment or transfer"})
+		return
+	}
+	ops, err := h.approvalRepository.ListOperations(c.Request.Context(), filter, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"operations": ops})
+}
+
+func (h *ApprovalHandler) GetOperation(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	op, err := h.approvalRepository.GetOperation(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, op)
+}
+
+// ListAudit answers the most recent audit entries, newest first, of the
+// operation in the path or of all operations.
+func (h *ApprovalHandler) ListAudit(c *gin.Context) {
+	id := ""
+	if c.Param("id") != "" {
+		var ok bool
+		if id, ok = pathID(c); !ok {
+			return
+		}
+		if _, err := h.approvalRepository.GetOperation(c.Request.Context(), id); utils.ReturnIfGormError(c, err) {
+			return
+		}
+	}
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	entries, err := h.approvalRepository.ListAudit(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// Approve executes the pending operation and answers it executed. The
+// proposer cannot approve their own operation (403). When the wallets no
+// longer allow it the operation stays pending and the answer is 409.
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	id, req, ok := h.bindDecision(c)
+	if !ok {
+		return
+	}
+	op, err := h.approvalRepository.Approve(c.Request.Context(), id, auth.AdminFrom(c), req.Note, h.execute)
+	if errors.Is(err, repository.ErrSameAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if approvalConflict(c, err) || utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, op)
+}
+
+// Reject answers the rejected operation, which is never executed. The
+// proposer may reject their own operation to withdraw it.
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	id, req, ok := h.bindDecision(c)
+	if !ok {
+		return
+	}
+	op, err := h.approvalRepository.Reject(c.Request.Context(), id, auth.AdminFrom(c), req.Note)
+	if approvalConflict(c, err) || utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, op)
+}
+
+// approvalConflict answers 409 for errors the operation's state explains.
+func approvalConflict(c *gin.Context, err error) bool {
+	if errors.Is(err, repository.ErrNotPending) || errors.Is(err, repository.ErrOperationExpired) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+func (h *ApprovalHandler) bindDecision(c *gin.Context) (string, *data_requests.ApprovalDecisionRequest, bool) {
+	id, ok := pathID(c)
+	if !ok {
+		return "", nil, false
+	}
+	// the body only carries an optional note
+	req := &data_requests.ApprovalDecisionRequest{}
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", nil, false
+	}
+	logger.SetRequestContext(c, "operation_id", id, "admin", auth.AdminFrom(c))
+	return id, req, true
+}
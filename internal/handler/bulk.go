@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/bulk"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// BulkJobHandler accepts uploads of bonus credits and reports on the jobs
+// that apply them in the background.
+type BulkJobHandler struct {
+	jobRepository repository.BulkJobRepository
+	maxAmount     int64
+}
+
+func NewBulkJobHandler(jobRepository repository.BulkJobRepository) *BulkJobHandler {
+	return &BulkJobHandler{jobRepository: jobRepository}
+}
+
+// WithMaxAmount rejects rows above maxAmount, like the limit of single
+// operations; 0 is unlimited.
+func (h *BulkJobHandler) WithMaxAmount(maxAmount int64) *BulkJobHandler {
+	h.maxAmount = maxAmount
+	return h
+}
+
+func (h *BulkJobHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/bulk-jobs")
+	route.POST("", h.CreateJob)
+	route.GET("", h.ListJobs)
+	route.GET("/:id", h.GetJob)
+	route.GET("/:id/rows", h.ListRows)
+	route.POST("/:id/cancel", h.CancelJob)
+	route.POST("/:id/retry", h.RetryJob)
+}
+
+// CreateJob queues a CSV (text/csv) or JSONL (application/x-ndjson) upload
+// of bonus credits and answers 202 with the job. Invalid rows are recorded
+// on the job rather than rejecting the upload.
+func (h *BulkJobHandler) CreateJob(c *gin.Context) {
+	var format string
+	switch c.ContentType() {
+	case "text/csv":
+		format = bulk.FormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		format = bulk.FormatJSONL
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "upload must be text/csv or application/x-ndjson"})
+		return
+	}
+	rows, err := bulk.Parse(c.Request.Body, format, h.maxAmount)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job := &repository.BulkJob{ID: uuid.New(), TransactionType: enums.TransactionTypeBonus}
+	if err := h.jobRepository.CreateJob(c.Request.Context(), job, rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "bulk_job_id", job.ID.String())
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListJobs answers the most recent jobs, newest first.
+func (h *BulkJobHandler) ListJobs(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	jobs, err := h.jobRepository.ListJobs(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// GetJob answers a job's status and row counts.
+func (h *BulkJobHandler) GetJob(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	job, err := h.jobRepository.GetJob(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// ListRows pages through a job's per-row results by line, optionally only
+// those with the given status. next_after is the after of the next page and
+// is omitted on the last one.
+func (h *BulkJobHandler) ListRows(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	after, err := strconv.Atoi(c.DefaultQuery("after", "0"))
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a non-negative integer"})
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", enums.BulkRowStatusPending, enums.BulkRowStatusSucceeded, enums.BulkRowStatusFailed, enums.BulkRowStatusInvalid:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, succeeded, failed or invalid"})
+		return
+	}
+	_, err = h.jobRepository.GetJob(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	rows, err := h.jobRepository.ListRows(c.Request.Context(), id, status, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := gin.H{"rows": rows}
+	if len(rows) == limit {
+		response["next_after"] = rows[len(rows)-1].Line
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelJob cancels a queued job at once; a running job stops after the
+// chunk it is applying, so the answer may still say running.
+func (h *BulkJobHandler) CancelJob(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	job, err := h.jobRepository.Cancel(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrBulkJobCompleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// RetryJob queues a finished job's failed rows again. Rows that did credit
+// before failing to record it are replayed by their idempotency key.
+func (h *BulkJobHandler) RetryJob(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	job, err := h.jobRepository.Retry(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrBulkJobActive) || errors.Is(err, repository.ErrNothingToRetry) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func pathID(c *gin.Context) (string, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a valid UUID"})
+		return "", false
+	}
+	return id.String(), true
+}
+
+func pageSize(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return 0, false
+	}
+	return limit, true
+}
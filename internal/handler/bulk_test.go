@@ -0,0 +1,235 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/limits"
+)
+
+// bulkEnv serves the bulk job endpoints from SQLite, with uploads limited to
+// 4 KiB and amounts to 1000.
+type bulkEnv struct {
+	router *gin.Engine
+	jobs   repository.BulkJobRepository
+}
+
+func newBulkEnv(t *testing.T) *bulkEnv {
+	t.Helper()
+	db := dbtest.Open(t)
+	env := &bulkEnv{router: gin.New(), jobs: repository.NewBulkJobRepository(db)}
+	handler.NewBulkJobHandler(env.jobs).WithMaxAmount(1000).
+		RegisterRoutes(env.router.Group("/api/v1", limits.GinMiddleware(4096, 0)))
+	return env
+}
+
+func (env *bulkEnv) do(t *testing.T, method, path, contentType, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	var decoded map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s answered %s", method, path, rec.Body)
+	}
+	return rec, decoded
+}
+
+func (env *bulkEnv) upload(t *testing.T, lines ...string) string {
+	t.Helper()
+	body := "owner_id,currency_type_id,amount,idempotency_key\n" + strings.Join(lines, "\n")
+	rec, job := env.do(t, http.MethodPost, "/api/v1/bulk-jobs", "text/csv; charset=utf-8", body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("upload answered %d: %s", rec.Code, rec.Body)
+	}
+	return job["id"].(string)
+}
+
+func TestCreateBulkJob(t *testing.T) {
+	env := newBulkEnv(t)
+	owner, currency := uuid.NewString(), uuid.NewString()
+	row := func(amount, key string) string {
+		return `{"owner_id":"` + owner + `","currency_type_id":"` + currency + `","amount":` + amount + `,"idempotency_key":"` + key + `"}`
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantTotal   float64
+		wantInvalid float64
+		wantError   string
+	}{
+		{
+			name:        "jsonl with an invalid row",
+			contentType: "application/x-ndjson",
+			body:        row("10", "k1") + "\n" + row("5000", "k2") + "\n",
+			wantStatus:  http.StatusAccepted,
+			wantTotal:   2,
+			wantInvalid: 1,
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body:        "owner_id,currency_type_id,amount,idempotency_key\n" + owner + "," + currency + ",10,k1\n",
+			wantStatus:  http.StatusAccepted,
+			wantTotal:   1,
+		},
+		{
+			name:        "json is not an upload format",
+			contentType: "application/json",
+			body:        "[" + row("10", "k1") + "]",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantError:   "text/csv or application/x-ndjson",
+		},
+		{
+			name:        "csv without a header",
+			contentType: "text/csv",
+			body:        owner + "," + currency + ",10,k1\n",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "header must name",
+		},
+		{
+			name:        "no rows",
+			contentType: "application/x-ndjson",
+			body:        "\n",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "no rows",
+		},
+		{
+			name:        "too large",
+			contentType: "application/x-ndjson",
+			body:        strings.Repeat(row("1", "k")+"\n", 40),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, body := env.do(t, http.MethodPost, "/api/v1/bulk-jobs", tt.contentType, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("body %s, want an error mentioning %q", rec.Body, tt.wantError)
+				}
+				return
+			}
+			if body["status"] != enums.BulkJobStatusPending || body["total_rows"] != tt.wantTotal || body["invalid_rows"] != tt.wantInvalid {
+				t.Errorf("job %v, want pending with %v rows, %v invalid", body, tt.wantTotal, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestBulkJobLifecycle(t *testing.T) {
+	env := newBulkEnv(t)
+	ctx := context.Background()
+	owner, currency := uuid.NewString(), uuid.NewString()
+	var lines []string
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		lines = append(lines, owner+","+currency+",10,"+key)
+	}
+	id := env.upload(t, append(lines, "alice,"+currency+",1,f")...)
+
+	rec, job := env.do(t, http.MethodGet, "/api/v1/bulk-jobs/"+id, "", "")
+	if rec.Code != http.StatusOK || job["pending_rows"] != float64(5) || job["invalid_rows"] != float64(1) {
+		t.Fatalf("GET job = %d %v, want 5 pending and 1 invalid", rec.Code, job)
+	}
+	rec, list := env.do(t, http.MethodGet, "/api/v1/bulk-jobs?limit=10", "", "")
+	if jobs, _ := list["jobs"].([]any); rec.Code != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("GET jobs = %d %v, want the one job", rec.Code, list)
+	}
+
+	// page through the rows two at a time
+	var pages [][]float64
+	after := "0"
+	for after != "" {
+		rec, page := env.do(t, http.MethodGet, "/api/v1/bulk-jobs/"+id+"/rows?limit=2&after="+after, "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET rows = %d %s", rec.Code, rec.Body)
+		}
+		var lines []float64
+		for _, row := range page["rows"].([]any) {
+			lines = append(lines, row.(map[string]any)["line"].(float64))
+		}
+		pages = append(pages, lines)
+		after = ""
+		if next, ok := page["next_after"].(float64); ok {
+			after = strconv.Itoa(int(next))
+		}
+	}
+	if len(pages) != 4 || len(pages[3]) != 0 || pages[0][0] != 2 || pages[2][1] != 7 {
+		t.Errorf("pages of lines %v, want [[2 3] [4 5] [6 7] []]", pages)
+	}
+	rec, invalid := env.do(t, http.MethodGet, "/api/v1/bulk-jobs/"+id+"/rows?status=invalid", "", "")
+	if rows := invalid["rows"].([]any); rec.Code != http.StatusOK || len(rows) != 1 ||
+		!strings.Contains(rows[0].(map[string]any)["error"].(string), "owner_id") {
+		t.Errorf("invalid rows = %d %v, want line 7's owner_id error", rec.Code, invalid)
+	}
+
+	if rec, _ := env.do(t, http.MethodPost, "/api/v1/bulk-jobs/"+id+"/retry", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("retry of a queued job = %d, want 409", rec.Code)
+	}
+	rec, job = env.do(t, http.MethodPost, "/api/v1/bulk-jobs/"+id+"/cancel", "", "")
+	if rec.Code != http.StatusAccepted || job["status"] != enums.BulkJobStatusCancelled {
+		t.Fatalf("cancel = %d %v, want cancelled", rec.Code, job)
+	}
+	rec, job = env.do(t, http.MethodPost, "/api/v1/bulk-jobs/"+id+"/retry", "", "")
+	if rec.Code != http.StatusAccepted || job["status"] != enums.BulkJobStatusPending {
+		t.Fatalf("retry of a cancelled job = %d %v, want queued again", rec.Code, job)
+	}
+
+	// run it to completion as a worker would
+	if _, err := env.jobs.Claim(ctx, "worker", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.jobs.Finish(ctx, id, "worker", enums.BulkJobStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := env.do(t, http.MethodPost, "/api/v1/bulk-jobs/"+id+"/cancel", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("cancel of a completed job = %d, want 409", rec.Code)
+	}
+	if rec, _ := env.do(t, http.MethodPost, "/api/v1/bulk-jobs/"+id+"/retry", "", ""); rec.Code != http.StatusAccepted {
+		t.Errorf("retry of a completed job with pending rows = %d, want 202", rec.Code)
+	}
+}
+
+func TestBulkJobRejects(t *testing.T) {
+	env := newBulkEnv(t)
+	id := env.upload(t, uuid.NewString()+","+uuid.NewString()+",1,k")
+	for name, tt := range map[string]struct {
+		method, path string
+		wantStatus   int
+	}{
+		"malformed id":   {http.MethodGet, "/api/v1/bulk-jobs/nope", http.StatusBadRequest},
+		"unknown job":    {http.MethodGet, "/api/v1/bulk-jobs/" + uuid.NewString(), http.StatusNotFound},
+		"unknown rows":   {http.MethodGet, "/api/v1/bulk-jobs/" + uuid.NewString() + "/rows", http.StatusNotFound},
+		"unknown cancel": {http.MethodPost, "/api/v1/bulk-jobs/" + uuid.NewString() + "/cancel", http.StatusNotFound},
+		"unknown retry":  {http.MethodPost, "/api/v1/bulk-jobs/" + uuid.NewString() + "/retry", http.StatusNotFound},
+		"bad status":     {http.MethodGet, "/api/v1/bulk-jobs/" + id + "/rows?status=done", http.StatusBadRequest},
+		"bad after":      {http.MethodGet, "/api/v1/bulk-jobs/" + id + "/rows?after=-1", http.StatusBadRequest},
+		"bad limit":      {http.MethodGet, "/api/v1/bulk-jobs?limit=5000", http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec, _ := env.do(t, tt.method, tt.path, "", ""); rec.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
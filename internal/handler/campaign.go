@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+const defaultSpendWindow = 7 * 24 * time.Hour
+
+// CampaignHandler runs bonus campaigns: rules for who may claim a bonus,
+// how often and from which budget, in place of the free-form Bonus.
+type CampaignHandler struct {
+	campaignRepository repository.CampaignRepository
+	openWallet         func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	maxAmount          int64
+}
+
+// NewCampaignHandler takes openWallet to find or open the wallets claims
+// are paid into, such as WalletHandler.CheckUserWalletIfNotCreate.
+func NewCampaignHandler(campaignRepository repository.CampaignRepository, openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)) *CampaignHandler {
+	return &CampaignHandler{campaignRepository: campaignRepository, openWallet: openWallet}
+}
+
+// WithMaxAmount rejects campaigns rewarding more than maxAmount per claim,
+// like the limit of single operations; 0 is unlimited.
+func (h *CampaignHandler) WithMaxAmount(maxAmount int64) *CampaignHandler {
+	h.maxAmount = maxAmount
+	return h
+}
+
+func (h *CampaignHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/campaigns")
+	route.POST("", h.CreateCampaign)
+	route.GET("", h.ListCampaigns)
+	route.GET("/:id", h.GetCampaign)
+	route.POST("/:id/fund", h.FundCampaign)
+	route.POST("/:id/claim", h.ClaimCampaign)
+	route.GET("/:id/claims", h.ListClaims)
+	route.POST("/:id/pause", h.PauseCampaign)
+	route.POST("/:id/resume", h.ResumeCampaign)
+	route.POST("/:id/end", h.EndCampaign)
+}
+
+// CreateCampaign defines a campaign with an empty budget and answers 201
+// with it; it pays nothing until it is funded.
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	req := &data_requests.CampaignRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.maxAmount > 0 && req.Reward > h.maxAmount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reward exceeds the limit of %d", h.maxAmount)})
+		return
+	}
+	campaign, err := newCampaign(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.campaignRepository.CreateCampaign(c.Request.Context(), campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "campaign_id", campaign.ID.String())
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func newCampaign(req *data_requests.CampaignRequest) (*repository.Campaign, error) {
+	campaign := &repository.Campaign{
+		ID:                uuid.New(),
+		Name:              req.Name,
+		CurrencyTypeID:    req.CurrencyTypeID,
+		Reward:            req.Reward,
+		MaxClaimsPerOwner: req.MaxClaimsPerOwner,
+		Requirement:       req.Requirement,
+		MinAmount:         req.MinAmount,
+		StartsAt:          time.Now().UTC(),
+	}
+	if campaign.MaxClaimsPerOwner == 0 {
+		campaign.MaxClaimsPerOwner = 1
+	}
+	if campaign.Requirement == "" {
+		campaign.Requirement = enums.CampaignRequirementNone
+	}
+	if req.StartsAt != nil {
+		campaign.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		end := req.EndsAt.UTC()
+		if !end.After(campaign.StartsAt) {
+			return nil, errors.New("ends_at must be after starts_at")
+		}
+		campaign.EndsAt = &end
+	}
+	switch {
+	case campaign.Requirement == enums.CampaignRequirementMinSpend:
+		window := defaultSpendWindow
+		if req.SpendWindow != "" {
+			var err error
+			window, err = time.ParseDuration(req.SpendWindow)
+			if err != nil || window < time.Minute || window%time.Second != 0 {
+				return nil, errors.New("spend_window must be a duration in whole seconds of at least 1m, such as 168h")
+			}
+		}
+		campaign.SpendWindowSeconds = int64(window / time.Second)
+	case req.SpendWindow != "":
+		return nil, errors.New("spend_window only applies to the min_spend requirement")
+	case campaign.Requirement == enums.CampaignRequirementNone && req.MinAmount != 0:
+		return nil, errors.New("min_amount needs a first_top_up or min_spend requirement")
+	}
+	return campaign, nil
+}
+
+// ListCampaigns answers the most recent campaigns, newest first, optionally
+// only those with the given status.
+func (h *CampaignHandler) ListCampaigns(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", enums.CampaignStatusActive, enums.CampaignStatusPaused, enums.CampaignStatusEnded:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, paused or ended"})
+		return
+	}
+	campaigns, err := h.campaignRepository.ListCampaigns(c.Request.Context(), status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign answers a campaign with its remaining budget and claim counts.
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	campaign, err := h.campaignRepository.GetCampaign(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+// FundCampaign moves an amount from the currency's treasury into the
+// campaign's budget.
+func (h *CampaignHandler) FundCampaign(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	req := &data_requests.CampaignFundRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "campaign_id", id, "idempotency_key", req.IdempotencyKey)
+	campaign, err := h.campaignRepository.Fund(c.Request.Context(), id, req.IdempotencyKey, req.Amount)
+	if errors.Is(err, repository.ErrCampaignEnded) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+// ClaimCampaign pays the campaign's reward to an owner who is eligible for
+// it. Replaying the idempotency key answers the original claim.
+func (h *CampaignHandler) ClaimCampaign(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	req := &data_requests.CampaignClaimRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "campaign_id", id, "owner_id", req.OwnerID.String(), "idempotency_key", req.IdempotencyKey)
+	campaign, err := h.campaignRepository.GetCampaign(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	wallet, err := h.openWallet(c.Request.Context(), req.OwnerID, campaign.CurrencyTypeID)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	claim, err := h.campaignRepository.Claim(c.Request.Context(), id, req.OwnerID, wallet.ID, req.IdempotencyKey)
+	switch {
+	case errors.Is(err, repository.ErrCampaignNotActive), errors.Is(err, repository.ErrCampaignCapReached),
+		errors.Is(err, repository.ErrCampaignNotEligible), errors.Is(err, repository.ErrCampaignBudgetExhausted),
+		errors.Is(err, repository.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, claim)
+}
+
+// ListClaims answers a campaign's most recent claims, newest first,
+// optionally only an owner's.
+func (h *CampaignHandler) ListClaims(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	ownerID := c.Query("owner_id")
+	if ownerID != "" {
+		owner, err := uuid.Parse(ownerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner_id must be a valid UUID"})
+			return
+		}
+		ownerID = owner.String()
+	}
+	_, err := h.campaignRepository.GetCampaign(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	claims, err := h.campaignRepository.ListClaims(c.Request.Context(), id, ownerID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"claims": claims})
+}
+
+// PauseCampaign stops claims until the campaign is resumed.
+func (h *CampaignHandler) PauseCampaign(c *gin.Context) {
+	h.transition(c, h.campaignRepository.Pause)
+}
+
+// ResumeCampaign lets a paused campaign be claimed again.
+func (h *CampaignHandler) ResumeCampaign(c *gin.Context) {
+	h.transition(c, h.campaignRepository.Resume)
+}
+
+// EndCampaign stops a campaign for good and returns what is left of its
+// budget to the treasury.
+func (h *CampaignHandler) EndCampaign(c *gin.Context) {
+	h.transition(c, h.campaignRepository.End)
+}
+
+func (h *CampaignHandler) transition(c *gin.Context, change func(ctx context.Context, id string) (*repository.Campaign, error)) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	campaign, err := change(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrCampaignEnded) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
@@ -0,0 +1,271 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"gorm.io/gorm"
+)
+
+// campaignEnv serves the campaign endpoints from SQLite for one user, with a
+// treasury of 1000 and rewards limited to 500.
+type campaignEnv struct {
+	router        *gin.Engine
+	db            *gorm.DB
+	wallets       repository.WalletRepository
+	walletHandler *handler.WalletHandler
+	owner         uuid.UUID
+	currency      uuid.UUID
+	treasury      repository.Wallet
+}
+
+func newCampaignEnv(t *testing.T) *campaignEnv {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	users := repository.NewInMemoryUserRepository()
+	owner := repository.User{ID: uuid.New(), Name: "Alice", Role: "user"}
+	if err := users.CreateUser(ctx, &owner); err != nil {
+		t.Fatal(err)
+	}
+	wallets := repository.NewWalletRepository(db)
+	env := &campaignEnv{
+		router:        gin.New(),
+		db:            db,
+		wallets:       wallets,
+		walletHandler: handler.NewWalletHandler(wallets, users),
+		owner:         owner.ID,
+		currency:      uuid.New(),
+	}
+	env.treasury = repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: env.currency}
+	if err := wallets.CreateWallet(ctx, &env.treasury); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallets.Mint(ctx, env.currency.String(), "mint", 1000); err != nil {
+		t.Fatal(err)
+	}
+	handler.NewCampaignHandler(repository.NewCampaignRepository(db), env.walletHandler.CheckUserWalletIfNotCreate).
+		WithMaxAmount(500).RegisterRoutes(env.router.Group("/api/v1"))
+	return env
+}
+
+func (env *campaignEnv) do(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	var decoded map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Errorf("%s %s answered %s", method, path, rec.Body)
+	}
+	return rec, decoded
+}
+
+// create defines a campaign rewarding 50 in the env's currency with fields
+// overridden, funds it with budget, and returns its path.
+func (env *campaignEnv) create(t *testing.T, fields map[string]any, budget int64) string {
+	t.Helper()
+	definition := map[string]any{"name": "welcome", "currency_type_id": env.currency.String(), "reward": 50}
+	for key, value := range fields {
+		definition[key] = value
+	}
+	body, _ := json.Marshal(definition)
+	rec, campaign := env.do(t, http.MethodPost, "/api/v1/campaigns", string(body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", rec.Code, rec.Body)
+	}
+	path := "/api/v1/campaigns/" + campaign["id"].(string)
+	if budget > 0 {
+		rec, campaign = env.do(t, http.MethodPost, path+"/fund", fmt.Sprintf(`{"idempotency_key":"fund-%s","amount":%d}`, campaign["id"], budget))
+		if rec.Code != http.StatusOK || campaign["budget"] != float64(budget) {
+			t.Fatalf("fund = %d %v, want a budget of %d", rec.Code, campaign, budget)
+		}
+	}
+	return path
+}
+
+func (env *campaignEnv) claim(t *testing.T, path, key string) (int, map[string]any) {
+	t.Helper()
+	rec, body := env.do(t, http.MethodPost, path+"/claim", `{"idempotency_key":"`+key+`","owner_id":"`+env.owner.String()+`"}`)
+	return rec.Code, body
+}
+
+// move records a top-up into, or a spend out of, the owner's wallet.
+func (env *campaignEnv) move(t *testing.T, transactionType string, amount int64) {
+	t.Helper()
+	ctx := context.Background()
+	wallet, err := env.walletHandler.CheckUserWalletIfNotCreate(ctx, env.owner, env.currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := env.treasury.ID.String(), wallet.ID.String()
+	if transactionType == enums.TransactionTypeSpend {
+		from, to = to, from
+	}
+	if err := env.wallets.Transfer(ctx, from, to, env.currency.String(), uuid.NewString(), amount, enums.TransactionType(transactionType)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (env *campaignEnv) balance(t *testing.T, wallet string) int64 {
+	t.Helper()
+	var balance int64
+	if err := env.db.Model(&repository.Wallet{}).Where("id = ?", wallet).Pluck("balance", &balance).Error; err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestCreateCampaignRejects(t *testing.T) {
+	env := newCampaignEnv(t)
+	currency := env.currency.String()
+	for name, tt := range map[string]struct {
+		body      string
+		wantError string
+	}{
+		"reward over the limit":    {`{"name":"x","currency_type_id":"` + currency + `","reward":900}`, "limit of 500"},
+		"unknown requirement":      {`{"name":"x","currency_type_id":"` + currency + `","reward":5,"requirement":"vip"}`, "Requirement"},
+		"window without min_spend": {`{"name":"x","currency_type_id":"` + currency + `","reward":5,"spend_window":"24h"}`, "spend_window only"},
+		"short spend window": {`{"name":"x","currency_type_id":"` + currency + `","reward":5,"requirement":"min_spend","spend_window":"5s"}`,
+			"at least 1m"},
+		"ends before it starts": {`{"name":"x","currency_type_id":"` + currency + `","reward":5,"starts_at":"2030-01-02T00:00:00Z","ends_at":"2030-01-01T00:00:00Z"}`,
+			"ends_at"},
+		"minimum without a requirement": {`{"name":"x","currency_type_id":"` + currency + `","reward":5,"min_amount":10}`, "min_amount"},
+		"malformed":                     {`{`, "unexpected EOF"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, _ := env.do(t, http.MethodPost, "/api/v1/campaigns", tt.body)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("status %d %s, want 400 mentioning %q", rec.Code, rec.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCampaignClaims(t *testing.T) {
+	env := newCampaignEnv(t)
+	path := env.create(t, map[string]any{"max_claims_per_owner": 2}, 120)
+
+	for _, step := range []struct {
+		key      string
+		wantCode int
+		want     string
+	}{
+		{"claim-1", http.StatusOK, ""},
+		{"claim-1", http.StatusOK, "replayed"},
+		{"claim-2", http.StatusOK, ""},
+		{"claim-3", http.StatusConflict, "as often as allowed"},
+		{"fund-" + strings.TrimPrefix(path, "/api/v1/campaigns/"), http.StatusConflict, "already used"},
+	} {
+		code, body := env.claim(t, path, step.key)
+		if code != step.wantCode || step.want == "replayed" && body["replayed"] != true ||
+			step.wantCode != http.StatusOK && !strings.Contains(fmt.Sprint(body["error"]), step.want) {
+			t.Fatalf("claim %s = %d %v, want %d %s", step.key, code, body, step.wantCode, step.want)
+		}
+	}
+	rec, campaign := env.do(t, http.MethodGet, path, "")
+	if rec.Code != http.StatusOK || campaign["budget"] != float64(20) || campaign["claim_count"] != float64(2) ||
+		campaign["claimed_amount"] != float64(100) {
+		t.Errorf("campaign %v, want 100 of its 120 claimed twice", campaign)
+	}
+	rec, claims := env.do(t, http.MethodGet, path+"/claims?owner_id="+env.owner.String(), "")
+	if got, _ := claims["claims"].([]any); rec.Code != http.StatusOK || len(got) != 2 {
+		t.Errorf("claims = %d %v, want two", rec.Code, claims)
+	}
+
+	// another campaign cannot pay more than its budget
+	small := env.create(t, nil, 30)
+	if code, body := env.claim(t, small, "small"); code != http.StatusConflict || !strings.Contains(fmt.Sprint(body["error"]), "exhausted") {
+		t.Errorf("claim over the budget = %d %v, want 409", code, body)
+	}
+	for _, step := range []struct{ action, want string }{
+		{"pause", enums.CampaignStatusPaused},
+		{"end", enums.CampaignStatusEnded},
+		{"end", enums.CampaignStatusEnded},
+	} {
+		rec, campaign := env.do(t, http.MethodPost, small+"/"+step.action, "")
+		if rec.Code != http.StatusOK || campaign["status"] != step.want || campaign["budget"] == nil {
+			t.Fatalf("%s = %d %v, want %s", step.action, rec.Code, campaign, step.want)
+		}
+	}
+	if code, _ := env.claim(t, small, "small"); code != http.StatusConflict {
+		t.Errorf("claim of an ended campaign = %d, want 409", code)
+	}
+	if rec, _ := env.do(t, http.MethodPost, small+"/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resume of an ended campaign = %d, want 409", rec.Code)
+	}
+	// the treasury funded 150 and got the unclaimed 30 back
+	if got := env.balance(t, env.treasury.ID.String()); got != 880 {
+		t.Errorf("treasury %d, want 880", got)
+	}
+	report, err := admin.Reconcile(context.Background(), env.db)
+	if err != nil || !report.OK {
+		t.Errorf("Reconcile = %+v, %v; want the ledger to add up", report, err)
+	}
+}
+
+func TestCampaignRequirements(t *testing.T) {
+	env := newCampaignEnv(t)
+	firstTopUp := env.create(t, map[string]any{"requirement": "first_top_up", "min_amount": 100}, 200)
+	spender := env.create(t, map[string]any{"requirement": "min_spend", "min_amount": 80, "spend_window": "24h"}, 200)
+	future := env.create(t, map[string]any{"starts_at": "2099-01-01T00:00:00Z"}, 200)
+
+	if code, _ := env.claim(t, firstTopUp, "first-1"); code != http.StatusConflict {
+		t.Errorf("claim before any top-up = %d, want 409", code)
+	}
+	env.move(t, enums.TransactionTypeTopUp, 150)
+	env.move(t, enums.TransactionTypeSpend, 50)
+	if code, body := env.claim(t, firstTopUp, "first-2"); code != http.StatusOK {
+		t.Errorf("claim after a top-up of 150 = %d %v, want 200", code, body)
+	}
+	if code, _ := env.claim(t, spender, "spend-1"); code != http.StatusConflict {
+		t.Errorf("claim after spending 50 = %d, want 409", code)
+	}
+	env.move(t, enums.TransactionTypeSpend, 30)
+	if code, body := env.claim(t, spender, "spend-2"); code != http.StatusOK {
+		t.Errorf("claim after spending 80 = %d %v, want 200", code, body)
+	}
+	if code, _ := env.claim(t, future, "future"); code != http.StatusConflict {
+		t.Errorf("claim before the campaign starts = %d, want 409", code)
+	}
+}
+
+func TestConcurrentClaimsRespectTheCap(t *testing.T) {
+	env := newCampaignEnv(t)
+	path := env.create(t, map[string]any{"max_claims_per_owner": 3}, 500)
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := env.claim(t, path, fmt.Sprintf("race-%d", i))
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	paid := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			paid++
+		}
+	}
+	if _, campaign := env.do(t, http.MethodGet, path, ""); paid != 3 || campaign["budget"] != float64(350) {
+		t.Errorf("%d claims paid leaving %v, want 3 leaving 350", paid, campaign["budget"])
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/health"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+)
+
+type HealthHandler struct {
+	lifecycle *lifecycle.Manager
+	checker   *health.Checker
+	startedAt time.Time
+}
+
+func NewHealthHandler(lifecycle *lifecycle.Manager, checker *health.Checker) *HealthHandler {
+	return &HealthHandler{lifecycle: lifecycle, checker: checker, startedAt: time.Now()}
+}
+
+func (h *HealthHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+}
+
+// Liveness only reports that the process is serving HTTP; it never touches dependencies.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+	})
+}
+
+// Readiness reports 503 once shutdown has started so load balancers stop
+// routing here, and otherwise when any critical dependency check fails.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	if !h.lifecycle.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+	report := h.checker.Run(c.Request.Context())
+	status, code := "ready", http.StatusOK
+	if !report.Ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": report.Checks,
+	})
+}
@@ -0,0 +1,96 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/health"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+)
+
+func check(name string, critical bool, err error) health.Check {
+	return health.Check{Name: name, Critical: critical, Run: func(context.Context) (any, error) { return nil, err }}
+}
+
+func TestHealthHandler(t *testing.T) {
+	down := errors.New("down")
+	tests := []struct {
+		name       string
+		path       string
+		ready      bool
+		checks     []health.Check
+		wantStatus int
+		wantState  string
+	}{
+		{
+			name:       "liveness ignores readiness",
+			path:       "/healthz",
+			ready:      false,
+			checks:     []health.Check{check("database", true, down)},
+			wantStatus: http.StatusOK,
+			wantState:  "ok",
+		},
+		{
+			name:       "ready when all checks pass",
+			path:       "/readyz",
+			ready:      true,
+			checks:     []health.Check{check("database", true, nil), check("workers", false, nil)},
+			wantStatus: http.StatusOK,
+			wantState:  "ready",
+		},
+		{
+			name:       "non-critical failure stays ready",
+			path:       "/readyz",
+			ready:      true,
+			checks:     []health.Check{check("database", true, nil), check("workers", false, down)},
+			wantStatus: http.StatusOK,
+			wantState:  "ready",
+		},
+		{
+			name:       "critical failure is not ready",
+			path:       "/readyz",
+			ready:      true,
+			checks:     []health.Check{check("database", true, down)},
+			wantStatus: http.StatusServiceUnavailable,
+			wantState:  "not_ready",
+		},
+		{
+			name:       "shutting down",
+			path:       "/readyz",
+			ready:      false,
+			checks:     []health.Check{check("database", true, nil)},
+			wantStatus: http.StatusServiceUnavailable,
+			wantState:  "shutting_down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := lifecycle.New()
+			lc.SetReady(tt.ready)
+			router := gin.New()
+			handler.NewHealthHandler(lc, health.NewChecker(time.Second, tt.checks...)).RegisterRoutes(router)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var resp struct {
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantState {
+				t.Errorf("status field = %q, want %q", resp.Status, tt.wantState)
+			}
+		})
+	}
+}
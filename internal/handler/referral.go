@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+// ReferralHandler records who invited whom and reports what the referrals
+// paid; the referral.Qualifier worker pays them.
+type ReferralHandler struct {
+	referralRepository repository.ReferralRepository
+}
+
+func NewReferralHandler(referralRepository repository.ReferralRepository) *ReferralHandler {
+	return &ReferralHandler{referralRepository: referralRepository}
+}
+
+func (h *ReferralHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/referrals")
+	route.POST("", h.CreateReferral)
+	route.GET("", h.ListReferrals)
+	route.GET("/report", h.Report)
+	route.GET("/:id", h.GetReferral)
+}
+
+// CreateReferral records that the referrer invited the referee, who must not
+// have topped up yet, and answers 201 with the pending referral. Recording
+// the same pair again answers 200.
+func (h *ReferralHandler) CreateReferral(c *gin.Context) {
+	req := &data_requests.ReferralRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "referee_id", req.RefereeID.String(), "referrer_id", req.ReferrerID.String())
+	referral, created, err := h.referralRepository.Refer(c.Request.Context(), req.RefereeID, req.ReferrerID)
+	switch {
+	case errors.Is(err, repository.ErrSelfReferral):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrAlreadyReferred), errors.Is(err, repository.ErrReferralCycle),
+		errors.Is(err, repository.ErrAlreadyToppedUp):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	if created {
+		c.JSON(http.StatusCreated, referral)
+		return
+	}
+	c.JSON(http.StatusOK, referral)
+}
+
+// ListReferrals answers the most recent referrals, newest first, optionally
+// only a referrer's or those with the given status.
+func (h *ReferralHandler) ListReferrals(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	referrerID := c.Query("referrer_id")
+	if referrerID != "" {
+		referrer, err := uuid.Parse(referrerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "referrer_id must be a valid UUID"})
+			return
+		}
+		referrerID = referrer.String()
+	}
+	status := c.Query("status")
+	switch status {
+	case "", enums.ReferralStatusPending, enums.ReferralStatusRewarded, enums.ReferralStatusDisqualified:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, rewarded or disqualified"})
+		return
+	}
+	referrals, err := h.referralRepository.ListReferrals(c.Request.Context(), referrerID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"referrals": referrals})
+}
+
+// GetReferral answers the referral of the referee with the given ID.
+func (h *ReferralHandler) GetReferral(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	referral, err := h.referralRepository.GetReferral(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, referral)
+}
+
+// Report answers what the referrals settled between from, by default ever,
+// and to, by default now, paid out, with the referrers who earned the most.
+func (h *ReferralHandler) Report(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	var from *time.Time
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return
+		}
+		parsed = parsed.UTC()
+		from = &parsed
+	}
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return
+		}
+		to = parsed.UTC()
+	}
+	if from != nil && !to.After(*from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	report, err := h.referralRepository.Report(c.Request.Context(), from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,61 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+)
+
+func TestReferrals(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	users := repository.NewUserRepository(db)
+	alice, bob := uuid.NewString(), uuid.NewString()
+	for _, id := range []string{alice, bob} {
+		if err := users.CreateUser(ctx, &repository.User{ID: uuid.MustParse(id), Name: "player", Role: "user"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := gin.New()
+	handler.NewReferralHandler(repository.NewReferralRepository(db)).RegisterRoutes(router.Group("/api/v1"))
+	refer := func(referee, referrer string) string {
+		return `{"referee_id":"` + referee + `","referrer_id":"` + referrer + `"}`
+	}
+
+	for _, step := range []struct {
+		method, path, body string
+		wantStatus         int
+		want               string
+	}{
+		{http.MethodPost, "/api/v1/referrals", refer(bob, alice), http.StatusCreated, `"status":"pending"`},
+		{http.MethodPost, "/api/v1/referrals", refer(bob, alice), http.StatusOK, `"referrer_id":"` + alice},
+		{http.MethodPost, "/api/v1/referrals", refer(alice, bob), http.StatusConflict, "referred by the user"},
+		{http.MethodPost, "/api/v1/referrals", refer(alice, alice), http.StatusBadRequest, "themselves"},
+		{http.MethodPost, "/api/v1/referrals", refer(alice, uuid.NewString()), http.StatusNotFound, ""},
+		{http.MethodPost, "/api/v1/referrals", "{", http.StatusBadRequest, ""},
+		{http.MethodGet, "/api/v1/referrals?referrer_id=" + alice, "", http.StatusOK, `"referee_id":"` + bob},
+		{http.MethodGet, "/api/v1/referrals?status=paid", "", http.StatusBadRequest, "status"},
+		{http.MethodGet, "/api/v1/referrals/" + bob, "", http.StatusOK, `"referee_id":"` + bob},
+		{http.MethodGet, "/api/v1/referrals/" + alice, "", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/referrals/report", "", http.StatusOK, `"pending":1`},
+		{http.MethodGet, "/api/v1/referrals/report?from=yesterday", "", http.StatusBadRequest, "from"},
+		{http.MethodGet, "/api/v1/referrals/report?from=2030-01-01T00:00:00Z&to=2020-01-01T00:00:00Z", "", http.StatusBadRequest, "after"},
+	} {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != step.wantStatus || !strings.Contains(rec.Body.String(), step.want) || !json.Valid(rec.Body.Bytes()) {
+			t.Errorf("%s %s = %d %s, want %d mentioning %q", step.method, step.path, rec.Code, rec.Body, step.wantStatus, step.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+// RiskHandler lists the decisions of the risk rules and lets an admin
+// approve or reject the operations they held for review. Its routes belong
+// on a group behind auth.AdminGinMiddleware, which names the reviewer.
+type RiskHandler struct {
+	riskRepository repository.RiskRepository
+	release        func(ctx context.Context, decision *repository.RiskDecision) (string, error)
+}
+
+// NewRiskHandler returns a RiskHandler that applies approved operations with
+// release, such as WalletHandler.Release.
+func NewRiskHandler(riskRepository repository.RiskRepository,
+	release func(ctx context.Context, decision *repository.RiskDecision) (string, error)) *RiskHandler {
+	return &RiskHandler{riskRepository: riskRepository, release: release}
+}
+
+func (h *RiskHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/risk/decisions")
+	route.GET("", h.ListDecisions)
+	route.GET("/:id", h.GetDecision)
+	route.POST("/:id/approve", h.Approve)
+	route.POST("/:id/reject", h.Reject)
+}
+
+// ListDecisions answers the most recent decisions, newest first, optionally
+// only an owner's, those with an outcome or those with a review status.
+func (h *RiskHandler) ListDecisions(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	filter := repository.RiskDecisionFilter{Outcome: c.Query("outcome"), ReviewStatus: c.Query("review_status")}
+	if ownerID := c.Query("owner_id"); ownerID != "" {
+		owner, err := uuid.Parse(ownerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner_id must be a valid UUID"})
+			return
+		}
+		filter.OwnerID = owner.String()
+	}
+	switch filter.Outcome {
+	case "", enums.RiskOutcomeAllow, enums.RiskOutcomeDeny, enums.RiskOutcomeReview:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be allow, deny or review"})
+		return
+	}
+	switch filter.ReviewStatus {
+	case "", enums.RiskReviewPending, enums.RiskReviewApproved, enums.RiskReviewRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "review_status must be pending, approved or rejected"})
+		return
+	}
+	decisions, err := h.riskRepository.ListDecisions(c.Request.Context(), filter, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"decisions": decisions})
+}
+
+func (h *RiskHandler) GetDecision(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	decision, err := h.riskRepository.GetDecision(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, decision)
+}
+
+// Approve applies the held operation and answers the approved decision.
+// When the wallets no longer allow it the decision stays pending and the
+// answer is 422.
+func (h *RiskHandler) Approve(c *gin.Context) {
+	id, ok := h.bindReview(c)
+	if !ok {
+		return
+	}
+	decision, err := h.riskRepository.Approve(c.Request.Context(), id, auth.AdminFrom(c), h.release)
+	if errors.Is(err, repository.ErrNotUnderReview) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, decision)
+}
+
+// Reject answers the rejected decision; the held operation is never applied
+// and retrying it is denied.
+func (h *RiskHandler) Reject(c *gin.Context) {
+	id, ok := h.bindReview(c)
+	if !ok {
+		return
+	}
+	decision, err := h.riskRepository.Reject(c.Request.Context(), id, auth.AdminFrom(c))
+	if errors.Is(err, repository.ErrNotUnderReview) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, decision)
+}
+
+func (h *RiskHandler) bindReview(c *gin.Context) (string, bool) {
+	id, ok := pathID(c)
+	if !ok {
+		return "", false
+	}
+	logger.SetRequestContext(c, "decision_id", id, "reviewer", auth.AdminFrom(c))
+	return id, true
+}
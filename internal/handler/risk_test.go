@@ -0,0 +1,96 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+	"gorm.io/gorm"
+)
+
+func TestRiskScreening(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	users := repository.NewUserRepository(db)
+	wallets := repository.NewWalletRepository(db)
+	alice, bob, mallory := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	dbtest.Users(t, db, uuid.MustParse(alice), uuid.MustParse(bob), uuid.MustParse(mallory))
+	currencyID, _ := dbtest.Currency(t, db, "gold", 1000)
+	currency := currencyID.String()
+	decisions := repository.NewRiskRepository(db)
+	engine := risk.NewEngine(decisions, risk.BlockedOwners(uuid.MustParse(mallory)), risk.NewAccount(time.Hour, 50))
+	walletHandler := handler.NewWalletHandler(wallets, users).WithOptions(handler.WalletOptions{Transfers: true, Risk: engine})
+	router := gin.New()
+	walletHandler.RegisterRoutes(router.Group("/api/v1"))
+	admin := router.Group("/api/v1/admin", auth.AdminGinMiddleware(auth.NewAdminKeys(map[string]string{"ops": "ops-key-0123456789"})))
+	handler.NewRiskHandler(decisions, walletHandler.Release).RegisterRoutes(admin)
+
+	do := func(method, path, body string, wantStatus int, want string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "ops-key-0123456789")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != wantStatus || !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("%s %s = %d %s, want %d mentioning %q", method, path, rec.Code, rec.Body, wantStatus, want)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	spend := func(key string, amount string) string {
+		return `{"owner_id":"` + alice + `","currency_type_id":"` + currency + `","amount":` + amount + `,"idempotency_key":"` + key + `"}`
+	}
+	transfer := func(key, to, amount string) string {
+		return `{"from_owner_id":"` + alice + `","to_owner_id":"` + to + `","currency_type_id":"` + currency +
+			`","amount":` + amount + `,"idempotency_key":"` + key + `"}`
+	}
+	decisionID := func(got map[string]any) string {
+		return got["decision"].(map[string]any)["id"].(string)
+	}
+
+	do(http.MethodPost, "/api/v1/wallets/top-up", spend("top-up", "200"), http.StatusOK, "Top-up successful")
+	do(http.MethodPost, "/api/v1/wallets/spend", spend("small", "30"), http.StatusOK, "Spend successful")
+
+	// a new account's large spend waits for an admin
+	held := decisionID(do(http.MethodPost, "/api/v1/wallets/spend", spend("large", "80"), http.StatusAccepted, "held for review"))
+	if again := decisionID(do(http.MethodPost, "/api/v1/wallets/spend", spend("large", "80"), http.StatusAccepted, "")); again != held {
+		t.Errorf("retry got decision %s, want %s", again, held)
+	}
+	do(http.MethodGet, "/api/v1/admin/risk/decisions?review_status=pending", "", http.StatusOK, held)
+	do(http.MethodPost, "/api/v1/admin/risk/decisions/"+held+"/approve", "", http.StatusOK, `"reviewed_by":"ops"`)
+	do(http.MethodPost, "/api/v1/admin/risk/decisions/"+held+"/reject", "", http.StatusConflict, "not awaiting review")
+	do(http.MethodPost, "/api/v1/wallets/spend", spend("large", "80"), http.StatusOK, "idempotent")
+
+	do(http.MethodPost, "/api/v1/wallets/transfer", transfer("to-mallory", mallory, "10"), http.StatusForbidden, "recipient is blocked")
+	if _, err := wallets.GetWalletByOwner(ctx, "user", mallory, currency); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("denied transfer opened mallory's wallet: %v", err)
+	}
+	rejected := decisionID(do(http.MethodPost, "/api/v1/wallets/transfer", transfer("to-bob", bob, "90"), http.StatusAccepted, "new_account"))
+	do(http.MethodPost, "/api/v1/admin/risk/decisions/"+rejected+"/reject", "", http.StatusOK, `"review_status":"rejected"`)
+	do(http.MethodPost, "/api/v1/wallets/transfer", transfer("to-bob", bob, "90"), http.StatusForbidden, "rejected on review")
+
+	do(http.MethodGet, "/api/v1/admin/risk/decisions?outcome=deny&owner_id="+alice, "", http.StatusOK, "blocked_owner")
+	do(http.MethodGet, "/api/v1/admin/risk/decisions?outcome=maybe", "", http.StatusBadRequest, "outcome")
+	do(http.MethodGet, "/api/v1/admin/risk/decisions/"+rejected, "", http.StatusOK, `"reviewed_by":"ops"`)
+	do(http.MethodGet, "/api/v1/admin/risk/decisions/"+uuid.NewString(), "", http.StatusNotFound, "")
+	wallet, err := wallets.GetWalletByOwner(ctx, "user", alice, currency)
+	if err != nil || wallet.Balance != 90 {
+		t.Errorf("alice's wallet %+v, %v; want 90 left after the two spends", wallet, err)
+	}
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/schedule"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+const defaultRetryDelay = time.Minute
+
+// ScheduleHandler manages scheduled and recurring transfers between owners
+// and their currency's treasury, which a schedule.Runner executes.
+type ScheduleHandler struct {
+	scheduleRepository repository.ScheduleRepository
+	userRepository     repository.UserRepository
+	maxAmount          int64
+}
+
+func NewScheduleHandler(scheduleRepository repository.ScheduleRepository, userRepository repository.UserRepository) *ScheduleHandler {
+	return &ScheduleHandler{scheduleRepository: scheduleRepository, userRepository: userRepository}
+}
+
+// WithMaxAmount rejects schedules moving more than maxAmount per
+// occurrence, like the limit of single operations; 0 is unlimited.
+func (h *ScheduleHandler) WithMaxAmount(maxAmount int64) *ScheduleHandler {
+	h.maxAmount = maxAmount
+	return h
+}
+
+func (h *ScheduleHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/schedules")
+	route.POST("", h.CreateSchedule)
+	route.GET("", h.ListSchedules)
+	route.GET("/:id", h.GetSchedule)
+	route.GET("/:id/runs", h.ListRuns)
+	route.POST("/:id/pause", h.PauseSchedule)
+	route.POST("/:id/resume", h.ResumeSchedule)
+	route.POST("/:id/cancel", h.CancelSchedule)
+}
+
+// CreateSchedule defines a schedule for an existing owner and answers 201
+// with it, including its first occurrence.
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	req := &data_requests.ScheduleRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.maxAmount > 0 && req.Amount > h.maxAmount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("amount exceeds the limit of %d", h.maxAmount)})
+		return
+	}
+	s, err := newSchedule(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	start := time.Now()
+	if req.StartAt != nil {
+		start = *req.StartAt
+	}
+	if err := schedule.Prepare(s, start); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, err = h.userRepository.GetUserByID(c.Request.Context(), req.OwnerID.String())
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	if err := h.scheduleRepository.CreateSchedule(c.Request.Context(), s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "schedule_id", s.ID.String(), "owner_id", s.OwnerID.String())
+	c.JSON(http.StatusCreated, s)
+}
+
+func newSchedule(req *data_requests.ScheduleRequest) (*repository.Schedule, error) {
+	s := &repository.Schedule{
+		ID:             uuid.New(),
+		OwnerID:        req.OwnerID,
+		CurrencyTypeID: req.CurrencyTypeID,
+		Direction:      req.Direction,
+		Amount:         req.Amount,
+		Kind:           req.Kind,
+		Cron:           req.Cron,
+		Timezone:       req.Timezone,
+		OnFailure:      req.OnFailure,
+		MaxRetries:     req.MaxRetries,
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if s.OnFailure == "" {
+		s.OnFailure = enums.ScheduleFailureSkip
+	}
+	if req.EndAt != nil {
+		end := req.EndAt.UTC()
+		s.EndAt = &end
+	}
+	if req.Interval != "" {
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil || interval%time.Second != 0 {
+			return nil, errors.New("interval must be a duration in whole seconds, such as 24h")
+		}
+		s.IntervalSeconds = int64(interval / time.Second)
+	}
+	retryDelay := defaultRetryDelay
+	if req.RetryDelay != "" {
+		var err error
+		retryDelay, err = time.ParseDuration(req.RetryDelay)
+		if err != nil || retryDelay < time.Second || retryDelay%time.Second != 0 {
+			return nil, errors.New("retry_delay must be a duration in whole seconds of at least 1s, such as 10m")
+		}
+	}
+	s.RetryDelaySeconds = int64(retryDelay / time.Second)
+	return s, nil
+}
+
+// ListSchedules answers the most recent schedules, newest first, optionally
+// only an owner's or those with the given status.
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	ownerID := c.Query("owner_id")
+	if ownerID != "" {
+		id, err := uuid.Parse(ownerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner_id must be a valid UUID"})
+			return
+		}
+		ownerID = id.String()
+	}
+	status := c.Query("status")
+	switch status {
+	case "", enums.ScheduleStatusActive, enums.ScheduleStatusPaused, enums.ScheduleStatusCompleted, enums.ScheduleStatusCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, paused, completed or cancelled"})
+		return
+	}
+	schedules, err := h.scheduleRepository.ListSchedules(c.Request.Context(), ownerID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetSchedule answers a schedule with its next occurrence and counts.
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	s, err := h.scheduleRepository.GetSchedule(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// ListRuns answers the outcomes of a schedule's most recent occurrences,
+// newest first.
+func (h *ScheduleHandler) ListRuns(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	_, err := h.scheduleRepository.GetSchedule(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	runs, err := h.scheduleRepository.ListRuns(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// PauseSchedule stops a schedule until it is resumed. An occurrence being
+// attempted at that moment still completes.
+func (h *ScheduleHandler) PauseSchedule(c *gin.Context) {
+	h.transition(c, h.scheduleRepository.Pause)
+}
+
+// ResumeSchedule reactivates a paused schedule; an occurrence that fell due
+// meanwhile, or failed and paused it, runs at once.
+func (h *ScheduleHandler) ResumeSchedule(c *gin.Context) {
+	h.transition(c, h.scheduleRepository.Resume)
+}
+
+// CancelSchedule ends a schedule for good.
+func (h *ScheduleHandler) CancelSchedule(c *gin.Context) {
+	h.transition(c, h.scheduleRepository.Cancel)
+}
+
+func (h *ScheduleHandler) transition(c *gin.Context, change func(ctx context.Context, id string) (*repository.Schedule, error)) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	s, err := change(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrScheduleFinished) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
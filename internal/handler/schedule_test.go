@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+)
+
+// scheduleEnv serves the schedule endpoints from SQLite for one user, with
+// amounts limited to 1000.
+type scheduleEnv struct {
+	router *gin.Engine
+	owner  string
+}
+
+func newScheduleEnv(t *testing.T) *scheduleEnv {
+	t.Helper()
+	db := dbtest.Open(t)
+	users := repository.NewInMemoryUserRepository()
+	owner := repository.User{ID: uuid.New(), Name: "Alice", Role: "user"}
+	if err := users.CreateUser(context.Background(), &owner); err != nil {
+		t.Fatal(err)
+	}
+	env := &scheduleEnv{router: gin.New(), owner: owner.ID.String()}
+	handler.NewScheduleHandler(repository.NewScheduleRepository(db), users).WithMaxAmount(1000).
+		RegisterRoutes(env.router.Group("/api/v1"))
+	return env
+}
+
+func (env *scheduleEnv) do(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	var decoded map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s answered %s", method, path, rec.Body)
+	}
+	return rec, decoded
+}
+
+// definition is a weekly 100 debit of the env's owner with fields
+// overridden; a nil value removes the field.
+func (env *scheduleEnv) definition(fields map[string]any) string {
+	definition := map[string]any{
+		"owner_id":         env.owner,
+		"currency_type_id": uuid.NewString(),
+		"direction":        "debit",
+		"amount":           100,
+		"kind":             "interval",
+		"interval":         "168h",
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(definition, key)
+		} else {
+			definition[key] = value
+		}
+	}
+	body, _ := json.Marshal(definition)
+	return string(body)
+}
+
+func TestCreateSchedule(t *testing.T) {
+	env := newScheduleEnv(t)
+	tests := []struct {
+		name       string
+		fields     map[string]any
+		wantStatus int
+		wantError  string
+		check      func(t *testing.T, schedule map[string]any)
+	}{
+		{
+			name:       "weekly pass with defaults",
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, schedule map[string]any) {
+				if schedule["status"] != enums.ScheduleStatusActive || schedule["interval_seconds"] != float64(604800) ||
+					schedule["on_failure"] != enums.ScheduleFailureSkip || schedule["retry_delay_seconds"] != float64(60) ||
+					schedule["timezone"] != "UTC" || schedule["next_run_at"] == nil {
+					t.Errorf("schedule %v, want an active weekly schedule with the defaults", schedule)
+				}
+			},
+		},
+		{
+			name: "daily reward in a timezone",
+			fields: map[string]any{"direction": "credit", "kind": "cron", "interval": nil, "cron": "0 9 * * *",
+				"timezone": "Asia/Kolkata", "start_at": "2030-01-01T00:00:00Z", "on_failure": "pause", "max_retries": 3, "retry_delay": "10m"},
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, schedule map[string]any) {
+				if schedule["occurrence_at"] != "2030-01-01T03:30:00Z" || schedule["retry_delay_seconds"] != float64(600) {
+					t.Errorf("schedule %v, want the first 09:00 IST at 03:30Z and a 10m retry delay", schedule)
+				}
+			},
+		},
+		{name: "unknown owner", fields: map[string]any{"owner_id": uuid.NewString()}, wantStatus: http.StatusNotFound},
+		{name: "over the amount limit", fields: map[string]any{"amount": 5000}, wantStatus: http.StatusBadRequest, wantError: "limit of 1000"},
+		{name: "unknown direction", fields: map[string]any{"direction": "sideways"}, wantStatus: http.StatusBadRequest, wantError: "Direction"},
+		{name: "unparseable interval", fields: map[string]any{"interval": "weekly"}, wantStatus: http.StatusBadRequest, wantError: "interval must be"},
+		{name: "interval too short", fields: map[string]any{"interval": "30s"}, wantStatus: http.StatusBadRequest, wantError: "at least 1m0s"},
+		{name: "bad retry delay", fields: map[string]any{"retry_delay": "500ms"}, wantStatus: http.StatusBadRequest, wantError: "retry_delay"},
+		{name: "bad cron", fields: map[string]any{"kind": "cron", "interval": nil, "cron": "daily"}, wantStatus: http.StatusBadRequest, wantError: "invalid cron"},
+		{name: "too many retries", fields: map[string]any{"max_retries": 500}, wantStatus: http.StatusBadRequest, wantError: "MaxRetries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, body := env.do(t, http.MethodPost, "/api/v1/schedules", env.definition(tt.fields))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body %s, want an error mentioning %q", rec.Body, tt.wantError)
+			}
+			if tt.check != nil {
+				tt.check(t, body)
+			}
+		})
+	}
+}
+
+func TestScheduleLifecycle(t *testing.T) {
+	env := newScheduleEnv(t)
+	rec, created := env.do(t, http.MethodPost, "/api/v1/schedules", env.definition(nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", rec.Code, rec.Body)
+	}
+	path := "/api/v1/schedules/" + created["id"].(string)
+
+	rec, list := env.do(t, http.MethodGet, "/api/v1/schedules?status=active&owner_id="+env.owner, "")
+	if schedules, _ := list["schedules"].([]any); rec.Code != http.StatusOK || len(schedules) != 1 {
+		t.Errorf("GET schedules = %d %v, want the one schedule", rec.Code, list)
+	}
+	rec, list = env.do(t, http.MethodGet, "/api/v1/schedules?owner_id="+uuid.NewString(), "")
+	if schedules, _ := list["schedules"].([]any); rec.Code != http.StatusOK || len(schedules) != 0 {
+		t.Errorf("GET another owner's schedules = %d %v, want none", rec.Code, list)
+	}
+	rec, runs := env.do(t, http.MethodGet, path+"/runs", "")
+	if got, _ := runs["runs"].([]any); rec.Code != http.StatusOK || len(got) != 0 {
+		t.Errorf("GET runs = %d %v, want none yet", rec.Code, runs)
+	}
+
+	for _, step := range []struct {
+		action, wantStatus string
+	}{
+		{"pause", enums.ScheduleStatusPaused},
+		{"pause", enums.ScheduleStatusPaused},
+		{"resume", enums.ScheduleStatusActive},
+		{"cancel", enums.ScheduleStatusCancelled},
+		{"cancel", enums.ScheduleStatusCancelled},
+	} {
+		rec, schedule := env.do(t, http.MethodPost, path+"/"+step.action, "")
+		if rec.Code != http.StatusOK || schedule["status"] != step.wantStatus {
+			t.Fatalf("%s = %d %v, want %s", step.action, rec.Code, schedule, step.wantStatus)
+		}
+	}
+	if rec, _ := env.do(t, http.MethodPost, path+"/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resume of a cancelled schedule = %d, want 409", rec.Code)
+	}
+}
+
+func TestScheduleRejects(t *testing.T) {
+	env := newScheduleEnv(t)
+	for name, tt := range map[string]struct {
+		method, path string
+		wantStatus   int
+	}{
+		"malformed id":    {http.MethodGet, "/api/v1/schedules/nope", http.StatusBadRequest},
+		"unknown":         {http.MethodGet, "/api/v1/schedules/" + uuid.NewString(), http.StatusNotFound},
+		"unknown runs":    {http.MethodGet, "/api/v1/schedules/" + uuid.NewString() + "/runs", http.StatusNotFound},
+		"unknown pause":   {http.MethodPost, "/api/v1/schedules/" + uuid.NewString() + "/pause", http.StatusNotFound},
+		"bad owner":       {http.MethodGet, "/api/v1/schedules?owner_id=alice", http.StatusBadRequest},
+		"bad status":      {http.MethodGet, "/api/v1/schedules?status=done", http.StatusBadRequest},
+		"bad limit":       {http.MethodGet, "/api/v1/schedules?limit=0", http.StatusBadRequest},
+		"malformed body":  {http.MethodPost, "/api/v1/schedules", http.StatusBadRequest},
+		"unknown resumed": {http.MethodPost, "/api/v1/schedules/" + uuid.NewString() + "/resume", http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec, _ := env.do(t, tt.method, tt.path, "{"); rec.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
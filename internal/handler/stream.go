@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/events"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+const (
+	streamHeartbeat   = 15 * time.Second
+	streamReplayBatch = 500
+	wsWriteTimeout    = 10 * time.Second
+)
+
+// StreamHandler pushes an owner's balance and transaction events over
+// Server-Sent Events or a WebSocket.
+type StreamHandler struct {
+	broker           *events.Broker
+	walletRepository repository.WalletRepository
+	userRepository   repository.UserRepository
+	eventRepository  repository.WalletEventRepository
+	upgrader         websocket.Upgrader
+	heartbeat        time.Duration
+}
+
+func NewStreamHandler(broker *events.Broker, walletRepository repository.WalletRepository,
+	userRepository repository.UserRepository, eventRepository repository.WalletEventRepository) *StreamHandler {
+	return &StreamHandler{
+		broker:           broker,
+		walletRepository: walletRepository,
+		userRepository:   userRepository,
+		eventRepository:  eventRepository,
+		// game clients connect from other origins and authenticate with an
+		// API key, not cookies
+		upgrader:  websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		heartbeat: streamHeartbeat,
+	}
+}
+
+// WithHeartbeat changes how often idle streams are kept alive.
+func (h *StreamHandler) WithHeartbeat(heartbeat time.Duration) *StreamHandler {
+	h.heartbeat = heartbeat
+	return h
+}
+
+func (h *StreamHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/users/:id/wallets")
+	route.GET("/stream", h.ServerSentEvents)
+	route.GET("/ws", h.WebSocket)
+}
+
+// frame is one message of a stream. Balance frames are a snapshot and carry
+// no ID; transaction frames carry the event ID to resume from.
+type frame struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id,omitempty"`
+	Data any    `json:"data"`
+}
+
+type balanceFrame struct {
+	WalletID       uuid.UUID `json:"wallet_id"`
+	CurrencyTypeID uuid.UUID `json:"currency_type_id"`
+	Balance        int64     `json:"balance"`
+	Frozen         bool      `json:"frozen"`
+}
+
+func transactionFrame(event events.Event) frame {
+	return frame{Type: "transaction", ID: event.ID, Data: event}
+}
+
+// stream is an open subscription plus the frames that precede live events.
+type stream struct {
+	sub         *events.Subscription
+	unsubscribe func()
+	backlog     []frame
+	// replayed are skipped when they also arrive live
+	replayed map[int64]bool
+}
+
+// open validates the request and subscribes before reading the backlog, so
+// nothing committed in between is lost. A client resuming from Last-Event-ID
+// (or last_event_id, which browsers' WebSockets must use) gets the events it
+// missed; anyone else, or a client whose resume point was pruned, gets a
+// balance snapshot.
+func (h *StreamHandler) open(c *gin.Context) (*stream, bool) {
+	ownerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a valid UUID"})
+		return nil, false
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var resumeFrom int64 = -1
+	if lastEventID != "" {
+		if resumeFrom, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || resumeFrom < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be a non-negative integer"})
+			return nil, false
+		}
+	}
+	ctx := consistency.WithPrimary(c.Request.Context())
+	_, err = h.userRepository.GetUserByID(ctx, ownerID.String())
+	if utils.ReturnIfGormError(c, err) {
+		return nil, false
+	}
+
+	s := &stream{replayed: make(map[int64]bool)}
+	s.sub, s.unsubscribe = h.broker.Subscribe(ownerID)
+	if resumeFrom >= 0 {
+		oldest, err := h.eventRepository.OldestID(ctx)
+		if err != nil {
+			s.unsubscribe()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if oldest > 0 && resumeFrom < oldest-1 {
+			resumeFrom = -1
+		}
+	}
+	if resumeFrom < 0 {
+		wallets, err := h.walletRepository.ListWalletsByOwner(ctx, ownerID.String())
+		if err != nil {
+			s.unsubscribe()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		for _, wallet := range wallets {
+			s.backlog = append(s.backlog, frame{Type: "balance", Data: balanceFrame{
+				WalletID: wallet.ID, CurrencyTypeID: wallet.CurrencyTypeID, Balance: wallet.Balance, Frozen: wallet.Frozen,
+			}})
+		}
+		return s, true
+	}
+	for {
+		missed, err := h.eventRepository.ListForOwnerAfter(ctx, ownerID.String(), resumeFrom, streamReplayBatch)
+		if err != nil {
+			s.unsubscribe()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		for i := range missed {
+			s.backlog = append(s.backlog, transactionFrame(events.FromWalletEvent(&missed[i])))
+			s.replayed[missed[i].ID] = true
+			resumeFrom = missed[i].ID
+		}
+		if len(missed) < streamReplayBatch {
+			return s, true
+		}
+	}
+}
+
+// ServerSentEvents streams "balance" and "transaction" events. When the
+// client falls behind or the server shuts down it sends a "reconnect" event
+// and ends the response; EventSource reconnects with Last-Event-ID.
+func (h *StreamHandler) ServerSentEvents(c *gin.Context) {
+	s, ok := h.open(c)
+	if !ok {
+		return
+	}
+	defer s.unsubscribe()
+	// the server's write timeout is meant for ordinary requests
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	write := func(f frame) error {
+		data, err := json.Marshal(f.Data)
+		if err != nil {
+			return err
+		}
+		if f.ID > 0 {
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\n", f.ID); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", f.Type, data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	for _, f := range s.backlog {
+		if write(f) != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-s.sub.Done():
+			_ = write(frame{Type: "reconnect", Data: gin.H{"error": s.sub.Err().Error()}})
+			return
+		case event := <-s.sub.Events():
+			if s.replayed[event.ID] {
+				continue
+			}
+			if write(transactionFrame(event)) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// WebSocket sends the frames of ServerSentEvents as JSON text messages. A
+// client that falls behind is closed with 1013 (try again later), and every
+// client with 1001 (going away) at shutdown.
+func (h *StreamHandler) WebSocket(c *gin.Context) {
+	s, ok := h.open(c)
+	if !ok {
+		return
+	}
+	defer s.unsubscribe()
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has already answered
+		return
+	}
+	defer conn.Close()
+
+	// clients only send control frames; reading processes them and notices
+	// when the connection goes away
+	pongWait := 2 * h.heartbeat
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(pongWait)) })
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(f frame) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(f)
+	}
+	for _, f := range s.backlog {
+		if write(f) != nil {
+			return
+		}
+	}
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-c.Request.Context().Done():
+			return
+		case <-s.sub.Done():
+			code := websocket.CloseTryAgainLater
+			if errors.Is(s.sub.Err(), events.ErrClosed) {
+				code = websocket.CloseGoingAway
+			}
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, s.sub.Err().Error()),
+				time.Now().Add(wsWriteTimeout))
+			return
+		case event := <-s.sub.Events():
+			if s.replayed[event.ID] {
+				continue
+			}
+			if write(transactionFrame(event)) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)) != nil {
+				return
+			}
+		}
+	}
+}
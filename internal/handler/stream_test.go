@@ -0,0 +1,243 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/events"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+)
+
+// streamEnv serves the stream endpoints over a real listener, backed by
+// SQLite and a running event poller.
+type streamEnv struct {
+	server   *httptest.Server
+	broker   *events.Broker
+	wallets  repository.WalletRepository
+	user     repository.User
+	treasury repository.Wallet
+	wallet   repository.Wallet
+}
+
+func newStreamEnv(t *testing.T) *streamEnv {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	users := repository.NewUserRepository(db)
+	env := &streamEnv{
+		broker:  events.NewBroker(16),
+		wallets: repository.NewWalletRepository(db),
+		user:    repository.User{ID: uuid.New(), Name: "Alice", Role: "user"},
+	}
+	currency := repository.CurrencyType{ID: uuid.New(), Name: "gold"}
+	if err := repository.NewCurrencyTypeRepository(db).CreateCurrencyType(ctx, &currency); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CreateUser(ctx, &env.user); err != nil {
+		t.Fatal(err)
+	}
+	env.treasury = repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: currency.ID, Balance: 1_000}
+	env.wallet = repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: env.user.ID, CurrencyTypeID: currency.ID, Balance: 5}
+	for _, wallet := range []*repository.Wallet{&env.treasury, &env.wallet} {
+		if err := env.wallets.CreateWallet(ctx, wallet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eventRepository := repository.NewWalletEventRepository(db)
+	pollCtx, stop := context.WithCancel(ctx)
+	poller := events.NewPoller(eventRepository, env.broker, events.PollerOptions{Interval: 10 * time.Millisecond})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		poller.Run(pollCtx)
+	}()
+
+	router := gin.New()
+	handler.NewStreamHandler(env.broker, env.wallets, users, eventRepository).
+		WithHeartbeat(50 * time.Millisecond).
+		RegisterRoutes(router.Group("/api/v1"))
+	env.server = httptest.NewServer(router)
+	t.Cleanup(func() {
+		env.broker.Close()
+		env.server.Close()
+		stop()
+		<-done
+	})
+	return env
+}
+
+func (env *streamEnv) url(path string) string {
+	return env.server.URL + "/api/v1/users/" + env.user.ID.String() + "/wallets/" + path
+}
+
+// bonus credits the user from the treasury.
+func (env *streamEnv) bonus(t *testing.T, key string, amount int64) {
+	t.Helper()
+	if err := env.wallets.Transfer(context.Background(), env.treasury.ID.String(), env.wallet.ID.String(),
+		env.wallet.CurrencyTypeID.String(), key, amount, enums.TransactionTypeBonus); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  map[string]any
+}
+
+// readSSE returns the next event, skipping keep-alive comments.
+func readSSE(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func openSSE(t *testing.T, url, lastEventID string) (*bufio.Reader, func()) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body), func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+func TestServerSentEvents(t *testing.T) {
+	env := newStreamEnv(t)
+	stream, closeStream := openSSE(t, env.url("stream"), "")
+
+	snapshot := readSSE(t, stream)
+	if snapshot.event != "balance" || snapshot.id != "" || snapshot.data["balance"] != float64(5) {
+		t.Fatalf("first event %+v, want a balance snapshot of 5", snapshot)
+	}
+	env.bonus(t, "bonus-1", 10)
+	first := readSSE(t, stream)
+	if first.event != "transaction" || first.data["balance"] != float64(15) || first.data["amount"] != float64(10) {
+		t.Fatalf("got %+v, want the bonus with balance 15", first)
+	}
+	if first.id == "" || first.id != strconv.Itoa(int(first.data["id"].(float64))) {
+		t.Errorf("event id %q does not match its data %v", first.id, first.data["id"])
+	}
+	closeStream()
+
+	// events committed while disconnected are replayed on resume
+	env.bonus(t, "bonus-2", 20)
+	env.bonus(t, "bonus-3", 30)
+	stream, closeStream = openSSE(t, env.url("stream"), first.id)
+	defer closeStream()
+	for _, want := range []float64{35, 65} {
+		event := readSSE(t, stream)
+		if event.event != "transaction" || event.data["balance"] != want {
+			t.Fatalf("resumed with %+v, want balance %v", event, want)
+		}
+	}
+	env.bonus(t, "bonus-4", 1)
+	if event := readSSE(t, stream); event.data["balance"] != float64(66) {
+		t.Errorf("live event after the replay %+v, want balance 66", event)
+	}
+
+	env.broker.Close()
+	if event := readSSE(t, stream); event.event != "reconnect" {
+		t.Errorf("got %+v at shutdown, want reconnect", event)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	env := newStreamEnv(t)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(env.url("ws"), "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var frame struct {
+		Type string
+		ID   int64
+		Data map[string]any
+	}
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "balance" {
+		t.Fatalf("first frame %+v, %v; want a balance snapshot", frame, err)
+	}
+	env.bonus(t, "bonus-1", 10)
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "transaction" || frame.ID == 0 || frame.Data["balance"] != float64(15) {
+		t.Fatalf("got %+v, %v; want the bonus with balance 15", frame, err)
+	}
+
+	env.broker.Close()
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("got %v at shutdown, want close 1001", err)
+	}
+}
+
+func TestStreamRejects(t *testing.T) {
+	env := newStreamEnv(t)
+	for name, tt := range map[string]struct {
+		url        string
+		header     string
+		wantStatus int
+	}{
+		"malformed owner":  {url: env.server.URL + "/api/v1/users/alice/wallets/stream", wantStatus: http.StatusBadRequest},
+		"unknown owner":    {url: env.server.URL + "/api/v1/users/" + uuid.NewString() + "/wallets/stream", wantStatus: http.StatusNotFound},
+		"bad resume point": {url: env.url("stream"), header: "yesterday", wantStatus: http.StatusBadRequest},
+		"bad ws resume":    {url: env.url("ws?last_event_id=-1"), wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+)
+
+const (
+	defaultTradeExpiry = time.Hour
+	minTradeExpiry     = time.Minute
+	maxTradeExpiry     = 30 * 24 * time.Hour
+)
+
+// TradeHandler runs escrowed trades between players: every party deposits
+// what it gives before anyone receives anything.
+type TradeHandler struct {
+	tradeRepository repository.TradeRepository
+	openWallet      func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	maxAmount       int64
+}
+
+// NewTradeHandler takes openWallet to find or open the parties' wallets,
+// such as WalletHandler.CheckUserWalletIfNotCreate.
+func NewTradeHandler(tradeRepository repository.TradeRepository, openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)) *TradeHandler {
+	return &TradeHandler{tradeRepository: tradeRepository, openWallet: openWallet}
+}
+
+// WithMaxAmount rejects trades with a leg over maxAmount, like the limit of
+// single operations; 0 is unlimited.
+func (h *TradeHandler) WithMaxAmount(maxAmount int64) *TradeHandler {
+	h.maxAmount = maxAmount
+	return h
+}
+
+func (h *TradeHandler) RegisterRoutes(r *gin.RouterGroup) {
+	route := r.Group("/trades")
+	route.POST("", h.CreateTrade)
+	route.GET("", h.ListTrades)
+	route.GET("/:id", h.GetTrade)
+	route.POST("/:id/deposit", h.Deposit)
+	route.POST("/:id/cancel", h.CancelTrade)
+}
+
+// CreateTrade opens a trade under the caller's trade ID and answers 201
+// with it; the ID is taken once, so a retried request answers 409.
+func (h *TradeHandler) CreateTrade(c *gin.Context) {
+	req := &data_requests.TradeRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expiresIn := defaultTradeExpiry
+	if req.ExpiresIn != "" {
+		var err error
+		expiresIn, err = time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiresIn < minTradeExpiry || expiresIn > maxTradeExpiry {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a duration between 1m and 720h, such as 30m"})
+			return
+		}
+	}
+	logger.SetRequestContext(c, "trade_id", req.TradeID.String())
+	trade := &repository.Trade{ID: req.TradeID, ExpiresAt: time.Now().UTC().Add(expiresIn)}
+	for i, leg := range req.Legs {
+		if leg.FromOwnerID == leg.ToOwnerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("leg %d gives to its own owner", i+1)})
+			return
+		}
+		if h.maxAmount > 0 && leg.Amount > h.maxAmount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("leg %d amount exceeds the limit of %d", i+1, h.maxAmount)})
+			return
+		}
+		from, err := h.openWallet(c.Request.Context(), leg.FromOwnerID, leg.CurrencyTypeID)
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		to, err := h.openWallet(c.Request.Context(), leg.ToOwnerID, leg.CurrencyTypeID)
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		trade.Legs = append(trade.Legs, repository.TradeLeg{
+			FromOwnerID:    leg.FromOwnerID,
+			ToOwnerID:      leg.ToOwnerID,
+			CurrencyTypeID: leg.CurrencyTypeID,
+			Amount:         leg.Amount,
+			FromWalletID:   from.ID,
+			ToWalletID:     to.ID,
+		})
+	}
+	err := h.tradeRepository.CreateTrade(c.Request.Context(), trade)
+	if errors.Is(err, repository.ErrTradeExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, trade)
+}
+
+// ListTrades answers the most recent trades, newest first, optionally only
+// those an owner is a party to or those with the given status.
+func (h *TradeHandler) ListTrades(c *gin.Context) {
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	ownerID := c.Query("owner_id")
+	if ownerID != "" {
+		id, err := uuid.Parse(ownerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner_id must be a valid UUID"})
+			return
+		}
+		ownerID = id.String()
+	}
+	status := c.Query("status")
+	switch status {
+	case "", enums.TradeStatusOpen, enums.TradeStatusReleased, enums.TradeStatusCancelled, enums.TradeStatusExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, released, cancelled or expired"})
+		return
+	}
+	trades, err := h.tradeRepository.ListTrades(c.Request.Context(), ownerID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trades": trades})
+}
+
+// GetTrade answers a trade with the deposits made so far.
+func (h *TradeHandler) GetTrade(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	trade, err := h.tradeRepository.GetTrade(c.Request.Context(), id)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, trade)
+}
+
+// Deposit moves what an owner gives in a trade into escrow. The deposit
+// that completes the trade also releases it, so the answer shows whether
+// it did. Repeating a deposit is harmless.
+func (h *TradeHandler) Deposit(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	req := &data_requests.TradeDepositRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logger.SetRequestContext(c, "trade_id", id, "owner_id", req.OwnerID.String())
+	trade, err := h.tradeRepository.Deposit(c.Request.Context(), id, req.OwnerID.String())
+	switch {
+	case errors.Is(err, repository.ErrNotTradeParty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrTradeSettled), errors.Is(err, repository.ErrTradeExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, trade)
+}
+
+// CancelTrade refunds the deposits of an open trade. A trade that was
+// already refunded is answered as it is; one that was released cannot be
+// cancelled.
+func (h *TradeHandler) CancelTrade(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	logger.SetRequestContext(c, "trade_id", id)
+	trade, err := h.tradeRepository.Cancel(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrTradeSettled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, trade)
+}
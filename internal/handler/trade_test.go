@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+)
+
+// tradeEnv serves the trade endpoints from SQLite for two users, with legs
+// limited to 1000. Alice holds 100 of the currency.
+type tradeEnv struct {
+	router   *gin.Engine
+	alice    string
+	bob      string
+	currency string
+}
+
+func newTradeEnv(t *testing.T) *tradeEnv {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	users := repository.NewInMemoryUserRepository()
+	alice := repository.User{ID: uuid.New(), Name: "Alice", Role: "user"}
+	bob := repository.User{ID: uuid.New(), Name: "Bob", Role: "user"}
+	for _, user := range []*repository.User{&alice, &bob} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	currency := uuid.New()
+	wallets := repository.NewWalletRepository(db)
+	walletHandler := handler.NewWalletHandler(wallets, users)
+	treasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: currency}
+	if err := wallets.CreateWallet(ctx, &treasury); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallets.Mint(ctx, currency.String(), "mint", 100); err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := walletHandler.CheckUserWalletIfNotCreate(ctx, alice.ID, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(), currency.String(), "grant", 100, enums.TransactionTypeBonus); err != nil {
+		t.Fatal(err)
+	}
+	env := &tradeEnv{router: gin.New(), alice: alice.ID.String(), bob: bob.ID.String(), currency: currency.String()}
+	handler.NewTradeHandler(repository.NewTradeRepository(db), walletHandler.CheckUserWalletIfNotCreate).
+		WithMaxAmount(1000).RegisterRoutes(env.router.Group("/api/v1"))
+	return env
+}
+
+func (env *tradeEnv) do(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	var decoded map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s answered %s", method, path, rec.Body)
+	}
+	return rec, decoded
+}
+
+// offer is a trade of amount from alice to bob with fields overridden.
+func (env *tradeEnv) offer(tradeID string, amount int64, fields map[string]any) string {
+	offer := map[string]any{
+		"trade_id": tradeID,
+		"legs": []map[string]any{
+			{"from_owner_id": env.alice, "to_owner_id": env.bob, "currency_type_id": env.currency, "amount": amount},
+		},
+	}
+	for key, value := range fields {
+		offer[key] = value
+	}
+	body, _ := json.Marshal(offer)
+	return string(body)
+}
+
+func TestCreateTrade(t *testing.T) {
+	env := newTradeEnv(t)
+	tradeID := uuid.NewString()
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "gift through escrow", body: env.offer(tradeID, 10, map[string]any{"expires_in": "30m"}), wantStatus: http.StatusCreated},
+		{name: "trade ID taken", body: env.offer(tradeID, 10, nil), wantStatus: http.StatusConflict, wantError: "already exists"},
+		{name: "over the amount limit", body: env.offer(uuid.NewString(), 5000, nil), wantStatus: http.StatusBadRequest, wantError: "limit of 1000"},
+		{name: "bad expiry", body: env.offer(uuid.NewString(), 10, map[string]any{"expires_in": "10s"}), wantStatus: http.StatusBadRequest, wantError: "expires_in"},
+		{name: "no legs", body: env.offer(uuid.NewString(), 10, map[string]any{"legs": []any{}}), wantStatus: http.StatusBadRequest, wantError: "Legs"},
+		{
+			name: "leg to its own owner",
+			body: env.offer(uuid.NewString(), 10, map[string]any{"legs": []map[string]any{
+				{"from_owner_id": env.alice, "to_owner_id": env.alice, "currency_type_id": env.currency, "amount": 1},
+			}}),
+			wantStatus: http.StatusBadRequest,
+			wantError:  "its own owner",
+		},
+		{
+			name: "unknown recipient",
+			body: env.offer(uuid.NewString(), 10, map[string]any{"legs": []map[string]any{
+				{"from_owner_id": env.alice, "to_owner_id": uuid.NewString(), "currency_type_id": env.currency, "amount": 1},
+			}}),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := env.do(t, http.MethodPost, "/api/v1/trades", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body %s, want an error mentioning %q", rec.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestTradeLifecycle(t *testing.T) {
+	env := newTradeEnv(t)
+	released, another, short := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	for id, amount := range map[string]int64{released: 60, another: 30, short: 50} {
+		if rec, _ := env.do(t, http.MethodPost, "/api/v1/trades", env.offer(id, amount, nil)); rec.Code != http.StatusCreated {
+			t.Fatalf("create answered %d: %s", rec.Code, rec.Body)
+		}
+	}
+	for _, step := range []struct {
+		path, body string
+		wantCode   int
+		wantStatus string
+	}{
+		{"/" + released + "/deposit", `{"owner_id":"` + env.bob + `"}`, http.StatusBadRequest, ""},
+		{"/" + released + "/deposit", `{"owner_id":"` + env.alice + `"}`, http.StatusOK, enums.TradeStatusReleased},
+		{"/" + released + "/deposit", `{"owner_id":"` + env.alice + `"}`, http.StatusOK, enums.TradeStatusReleased},
+		{"/" + released + "/cancel", "", http.StatusConflict, ""},
+		{"/" + another + "/deposit", `{"owner_id":"` + env.alice + `"}`, http.StatusOK, enums.TradeStatusReleased},
+		{"/" + short + "/deposit", `{"owner_id":"` + env.alice + `"}`, http.StatusUnprocessableEntity, ""},
+		{"/" + short + "/cancel", "", http.StatusOK, enums.TradeStatusCancelled},
+		{"/" + short + "/cancel", "", http.StatusOK, enums.TradeStatusCancelled},
+		{"/" + short + "/deposit", `{"owner_id":"` + env.alice + `"}`, http.StatusConflict, ""},
+	} {
+		rec, trade := env.do(t, http.MethodPost, "/api/v1/trades"+step.path, step.body)
+		if rec.Code != step.wantCode || step.wantStatus != "" && trade["status"] != step.wantStatus {
+			t.Fatalf("%s = %d %v, want %d %s", step.path, rec.Code, trade, step.wantCode, step.wantStatus)
+		}
+	}
+
+	rec, list := env.do(t, http.MethodGet, "/api/v1/trades?status=released&owner_id="+env.bob, "")
+	if trades, _ := list["trades"].([]any); rec.Code != http.StatusOK || len(trades) != 2 {
+		t.Errorf("GET released trades = %d %v, want two", rec.Code, list)
+	}
+	rec, trade := env.do(t, http.MethodGet, "/api/v1/trades/"+short, "")
+	if legs, _ := trade["legs"].([]any); rec.Code != http.StatusOK || len(legs) != 1 || trade["reference_id"] != nil {
+		t.Errorf("GET trade = %d %v, want the cancelled trade with nothing refunded", rec.Code, trade)
+	}
+}
+
+func TestTradeRejects(t *testing.T) {
+	env := newTradeEnv(t)
+	for name, tt := range map[string]struct {
+		method, path string
+		wantStatus   int
+	}{
+		"malformed id":    {http.MethodGet, "/api/v1/trades/nope", http.StatusBadRequest},
+		"unknown":         {http.MethodGet, "/api/v1/trades/" + uuid.NewString(), http.StatusNotFound},
+		"unknown cancel":  {http.MethodPost, "/api/v1/trades/" + uuid.NewString() + "/cancel", http.StatusNotFound},
+		"bad owner":       {http.MethodGet, "/api/v1/trades?owner_id=alice", http.StatusBadRequest},
+		"bad status":      {http.MethodGet, "/api/v1/trades?status=done", http.StatusBadRequest},
+		"malformed body":  {http.MethodPost, "/api/v1/trades", http.StatusBadRequest},
+		"malformed owner": {http.MethodPost, "/api/v1/trades/" + uuid.NewString() + "/deposit", http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec, _ := env.do(t, tt.method, tt.path, "{"); rec.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
 	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
 )
@@ -26,7 +27,12 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "id is required"})
 		return
 	}
-	user, err := h.userRepository.GetUserByID(id)
+	// postgres rejects malformed uuids outright, so answer the same way on every backend
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(400, gin.H{"error": "id must be a valid UUID"})
+		return
+	}
+	user, err := h.userRepository.GetUserByID(c.Request.Context(), id)
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
@@ -39,7 +45,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.userRepository.CreateUser(&user); err != nil {
+	if err := h.userRepository.CreateUser(c.Request.Context(), &user); err != nil {
 		if utils.ReturnIfGormError(c, err) {
 			return
 		}
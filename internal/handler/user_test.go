@@ -0,0 +1,53 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+func TestGetUserByID(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         func(env *testEnv) string
+		wantStatus int
+	}{
+		{
+			name:       "existing user",
+			id:         func(env *testEnv) string { return env.user.ID.String() },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown user",
+			id:         func(env *testEnv) string { return uuid.NewString() },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "malformed id",
+			id:         func(env *testEnv) string { return "alice" },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			rec := env.do(t, http.MethodGet, "/api/v1/users/"+tt.id(env), nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var user repository.User
+			if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != env.user.ID || user.Name != env.user.Name {
+				t.Errorf("got %+v, want %+v", user, env.user)
+			}
+		})
+	}
+}
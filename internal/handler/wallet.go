@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,17 +11,45 @@ import (
 	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
 	data_requests "github.com/jay6909/dino-internal-wallet-service/internal/data/requests"
 	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/logger"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
 	"github.com/jay6909/dino-internal-wallet-service/internal/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+var tracer = otel.Tracer("github.com/jay6909/dino-internal-wallet-service/internal/handler")
+
 type WalletHandler struct {
 	walletRepository repository.WalletRepository
 	userRepository   repository.UserRepository
+	options          WalletOptions
+}
+
+// WalletOptions are the operator settings of the wallet endpoints.
+type WalletOptions struct {
+	// MaxAmount caps the amount of a single operation; 0 is unlimited.
+	MaxAmount int64
+	// Transfers serves POST /wallets/transfer.
+	Transfers bool
+	// Risk screens spends and transfers before they move funds; nil lets
+	// them all through.
+	Risk risk.Evaluator
 }
 
 func NewWalletHandler(walletRepository repository.WalletRepository, userRepository repository.UserRepository) *WalletHandler {
-	return &WalletHandler{walletRepository: walletRepository, userRepository: userRepository}
+	return &WalletHandler{walletRepository: walletRepository, userRepository: userRepository,
+		options: WalletOptions{Transfers: true}}
+}
+
+// WithOptions replaces the default options, which allow any amount and
+// serve transfers.
+func (h *WalletHandler) WithOptions(options WalletOptions) *WalletHandler {
+	h.options = options
+	return h
 }
 
 func (h *WalletHandler) RegisterRoutes(r *gin.RouterGroup) {
@@ -27,8 +57,45 @@ func (h *WalletHandler) RegisterRoutes(r *gin.RouterGroup) {
 	route.POST("/top-up", h.TopUp)
 	route.POST("/spend", h.Spend)
 	route.GET("/balance", h.GetWalletByOwner)
+	route.POST("/bonus", h.Bonus)
+	// deprecated: the path segment was never read, clients should use /bonus
 	route.POST("/bonus/:id", h.Bonus)
+	if h.options.Transfers {
+		route.POST("/transfer", h.Transfer)
+	}
+}
 
+// screen asks the risk evaluator whether op may go ahead, and answers 403
+// when it is denied or 202 with the decision when it is held for review.
+func (h *WalletHandler) screen(c *gin.Context, op risk.Operation) bool {
+	if h.options.Risk == nil {
+		return true
+	}
+	decision, err := h.options.Risk.Evaluate(c.Request.Context(), op)
+	if utils.ReturnIfGormError(c, err) {
+		return false
+	}
+	err = risk.Err(decision)
+	switch {
+	case errors.Is(err, risk.ErrHeld):
+		c.JSON(http.StatusAccepted, gin.H{"message": err.Error(), "decision": decision})
+		return false
+	case err != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "decision": decision})
+		return false
+	}
+	return true
+}
+
+// rejectAmountOverLimit answers 400 when amount exceeds the configured limit.
+func (h *WalletHandler) rejectAmountOverLimit(c *gin.Context, amount int64) bool {
+	if h.options.MaxAmount == 0 || amount <= h.options.MaxAmount {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": fmt.Sprintf("amount exceeds the limit of %d", h.options.MaxAmount),
+	})
+	return true
 }
 
 func (h *WalletHandler) Bonus(c *gin.Context) {
@@ -39,10 +106,19 @@ func (h *WalletHandler) Bonus(c *gin.Context) {
 		})
 		return
 	}
-	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(req.IdempotencyKey)
+	if h.rejectAmountOverLimit(c, req.Amount) {
+		return
+	}
+	logger.SetRequestContext(c,
+		"owner_id", req.OwnerID.String(),
+		"currency_type_id", req.CurrencyTypeID.String(),
+		"idempotency_key", req.IdempotencyKey,
+	)
+	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(c.Request.Context(), req.IdempotencyKey)
 
 	if err == nil && transaction != nil {
 		// already processed
+		logger.FromContext(c.Request.Context()).Info("idempotent replay", "reference_id", transaction.ReferenceID)
 		c.JSON(http.StatusOK, gin.H{
 			"message":     "Bonus added (idempotent)",
 			"transaction": transaction,
@@ -57,22 +133,20 @@ func (h *WalletHandler) Bonus(c *gin.Context) {
 		})
 		return
 	}
-	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(req.CurrencyTypeID.String())
+	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(c.Request.Context(), req.CurrencyTypeID.String())
 	if status, err := utils.CheckGormError(c, err); err != nil {
 		c.AbortWithStatusJSON(status, gin.H{"error": err})
 		return
 	}
-	wallet, err := h.CheckUserWalletIfNotCreate(req.OwnerID, req.CurrencyTypeID)
+	wallet, err := h.CheckUserWalletIfNotCreate(c.Request.Context(), req.OwnerID, req.CurrencyTypeID)
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
 
 	//from system wallet to user wallet
-	if err := h.walletRepository.Transfer(systemWallet.ID.String(), wallet.ID.String(),
-		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeBonus); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+	err = h.walletRepository.Transfer(c.Request.Context(), systemWallet.ID.String(), wallet.ID.String(),
+		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeBonus)
+	if utils.ReturnIfGormError(c, err) {
 		return
 	}
 
@@ -89,10 +163,19 @@ func (h *WalletHandler) TopUp(c *gin.Context) {
 		})
 		return
 	}
-	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(req.IdempotencyKey)
+	if h.rejectAmountOverLimit(c, req.Amount) {
+		return
+	}
+	logger.SetRequestContext(c,
+		"owner_id", req.OwnerID.String(),
+		"currency_type_id", req.CurrencyTypeID.String(),
+		"idempotency_key", req.IdempotencyKey,
+	)
+	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(c.Request.Context(), req.IdempotencyKey)
 
 	if err == nil && transaction != nil {
 		// already processed
+		logger.FromContext(c.Request.Context()).Info("idempotent replay", "reference_id", transaction.ReferenceID)
 		c.JSON(http.StatusOK, gin.H{
 			"message":     "Top-up successful (idempotent)",
 			"transaction": transaction,
@@ -107,11 +190,11 @@ func (h *WalletHandler) TopUp(c *gin.Context) {
 		})
 		return
 	}
-	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(req.CurrencyTypeID.String())
+	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(c.Request.Context(), req.CurrencyTypeID.String())
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
-	wallet, err := h.CheckUserWalletIfNotCreate(req.OwnerID, req.CurrencyTypeID)
+	wallet, err := h.CheckUserWalletIfNotCreate(c.Request.Context(), req.OwnerID, req.CurrencyTypeID)
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
@@ -124,11 +207,9 @@ func (h *WalletHandler) TopUp(c *gin.Context) {
 	}
 
 	//from system wallet to user wallet
-	if err := h.walletRepository.Transfer(systemWallet.ID.String(), wallet.ID.String(),
-		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeTopUp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+	err = h.walletRepository.Transfer(c.Request.Context(), systemWallet.ID.String(), wallet.ID.String(),
+		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeTopUp)
+	if utils.ReturnIfGormError(c, err) {
 		return
 	}
 
@@ -145,10 +226,19 @@ func (h *WalletHandler) Spend(c *gin.Context) {
 		})
 		return
 	}
-	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(req.IdempotencyKey)
+	if h.rejectAmountOverLimit(c, req.Amount) {
+		return
+	}
+	logger.SetRequestContext(c,
+		"owner_id", req.OwnerID.String(),
+		"currency_type_id", req.CurrencyTypeID.String(),
+		"idempotency_key", req.IdempotencyKey,
+	)
+	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(c.Request.Context(), req.IdempotencyKey)
 
 	if err == nil && transaction != nil {
 		// already processed
+		logger.FromContext(c.Request.Context()).Info("idempotent replay", "reference_id", transaction.ReferenceID)
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Spend successful (idempotent)",
 			// "transaction": transaction,
@@ -163,38 +253,171 @@ func (h *WalletHandler) Spend(c *gin.Context) {
 		})
 		return
 	}
-	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(req.CurrencyTypeID.String())
+	// screened before anything is written, so that stopped spends open no wallet
+	if !h.screen(c, risk.Operation{Kind: enums.RiskOperationSpend, OwnerID: req.OwnerID, CurrencyTypeID: req.CurrencyTypeID,
+		Amount: req.Amount, IdempotencyKey: req.IdempotencyKey}) {
+		return
+	}
+	systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(c.Request.Context(), req.CurrencyTypeID.String())
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
-	wallet, err := h.CheckUserWalletIfNotCreate(req.OwnerID, req.CurrencyTypeID)
+	wallet, err := h.CheckUserWalletIfNotCreate(c.Request.Context(), req.OwnerID, req.CurrencyTypeID)
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
 
 	//from user wallet to system wallet
-	if err := h.walletRepository.Transfer(wallet.ID.String(), systemWallet.ID.String(),
-		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeSpend); err != nil {
+	err = h.walletRepository.Transfer(c.Request.Context(), wallet.ID.String(), systemWallet.ID.String(),
+		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeSpend)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Spend successful",
+		"wallet":  wallet,
+	})
+
+}
+
+// Transfer moves funds between two users' wallets of the same currency. The
+// sender's wallet must already exist; the recipient's is created on demand.
+func (h *WalletHandler) Transfer(c *gin.Context) {
+	req := &data_requests.TransferRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if h.rejectAmountOverLimit(c, req.Amount) {
+		return
+	}
+	logger.SetRequestContext(c,
+		"from_owner_id", req.FromOwnerID.String(),
+		"to_owner_id", req.ToOwnerID.String(),
+		"currency_type_id", req.CurrencyTypeID.String(),
+		"idempotency_key", req.IdempotencyKey,
+	)
+	if req.FromOwnerID == req.ToOwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "from_owner_id and to_owner_id must differ",
+		})
+		return
+	}
+	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(c.Request.Context(), req.IdempotencyKey)
+
+	if err == nil && transaction != nil {
+		// already processed
+		logger.FromContext(c.Request.Context()).Info("idempotent replay", "reference_id", transaction.ReferenceID)
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Transfer successful (idempotent)",
+			"reference_id": transaction.ReferenceID,
+		})
+		return
+	}
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// real DB error
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
+	// screened before anything is written, so that stopped transfers open
+	// no recipient wallet
+	if !h.screen(c, risk.Operation{Kind: enums.RiskOperationTransfer, OwnerID: req.FromOwnerID, ToOwnerID: &req.ToOwnerID,
+		CurrencyTypeID: req.CurrencyTypeID, Amount: req.Amount, IdempotencyKey: req.IdempotencyKey}) {
+		return
+	}
+	sender, err := h.userRepository.GetUserByID(c.Request.Context(), req.FromOwnerID.String())
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	fromWallet, err := h.walletRepository.GetWalletByOwner(c.Request.Context(), sender.Role, req.FromOwnerID.String(), req.CurrencyTypeID.String())
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+	toWallet, err := h.CheckUserWalletIfNotCreate(c.Request.Context(), req.ToOwnerID, req.CurrencyTypeID)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
+
+	err = h.walletRepository.Transfer(c.Request.Context(), fromWallet.ID.String(), toWallet.ID.String(),
+		req.CurrencyTypeID.String(), req.IdempotencyKey, req.Amount, enums.TransactionTypeTransfer)
+	if utils.ReturnIfGormError(c, err) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Spend successful",
-		"wallet":  wallet,
+		"message": "Transfer successful",
 	})
+}
 
+// Release applies a spend or transfer the risk rules held, once approved, as
+// the endpoint would have, and returns its reference ID. It is meant as the
+// release of RiskRepository.Approve.
+func (h *WalletHandler) Release(ctx context.Context, decision *repository.RiskDecision) (string, error) {
+	var from, to *repository.Wallet
+	var transactionType enums.TransactionType
+	switch decision.Operation {
+	case enums.RiskOperationSpend:
+		systemWallet, err := h.walletRepository.GetSystemWalletByCurrencyType(ctx, decision.CurrencyTypeID.String())
+		if err != nil {
+			return "", err
+		}
+		wallet, err := h.CheckUserWalletIfNotCreate(ctx, decision.OwnerID, decision.CurrencyTypeID)
+		if err != nil {
+			return "", err
+		}
+		from, to, transactionType = wallet, systemWallet, enums.TransactionTypeSpend
+	case enums.RiskOperationTransfer:
+		sender, err := h.userRepository.GetUserByID(ctx, decision.OwnerID.String())
+		if err != nil {
+			return "", err
+		}
+		fromWallet, err := h.walletRepository.GetWalletByOwner(ctx, sender.Role, decision.OwnerID.String(), decision.CurrencyTypeID.String())
+		if err != nil {
+			return "", err
+		}
+		toWallet, err := h.CheckUserWalletIfNotCreate(ctx, *decision.ToOwnerID, decision.CurrencyTypeID)
+		if err != nil {
+			return "", err
+		}
+		from, to, transactionType = fromWallet, toWallet, enums.TransactionTypeTransfer
+	default:
+		return "", fmt.Errorf("cannot release a %q operation", decision.Operation)
+	}
+	if err := h.walletRepository.Transfer(ctx, from.ID.String(), to.ID.String(),
+		decision.CurrencyTypeID.String(), decision.IdempotencyKey, decision.Amount, transactionType); err != nil {
+		return "", err
+	}
+	transaction, err := h.walletRepository.GetTransactionByIdempotencyKey(ctx, decision.IdempotencyKey)
+	if err != nil {
+		return "", err
+	}
+	return transaction.ReferenceID, nil
 }
 
-func (h *WalletHandler) CheckUserWalletIfNotCreate(ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error) {
-	owner, err := h.userRepository.GetUserByID(ownerID.String())
+func (h *WalletHandler) CheckUserWalletIfNotCreate(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (_ *repository.Wallet, err error) {
+	ctx, span := tracer.Start(ctx, "WalletHandler.CheckUserWalletIfNotCreate", trace.WithAttributes(
+		attribute.String("wallet.owner_id", ownerID.String()),
+		attribute.String("wallet.currency_type_id", currencyTypeID.String())))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	owner, err := h.userRepository.GetUserByID(ctx, ownerID.String())
 	if err != nil {
 		return nil, err
 	}
 
-	wallet, err := h.walletRepository.GetWalletByOwner(owner.Role, ownerID.String(), currencyTypeID.String())
+	wallet, err := h.walletRepository.GetWalletByOwner(ctx, owner.Role, ownerID.String(), currencyTypeID.String())
 	if status, err := utils.CheckGormError(nil, err); err != nil {
 		if status == http.StatusNotFound {
 			var newWallet = &repository.Wallet{
@@ -204,7 +427,7 @@ func (h *WalletHandler) CheckUserWalletIfNotCreate(ownerID, currencyTypeID uuid.
 				CurrencyTypeID: currencyTypeID,
 				Balance:        0,
 			}
-			if err := h.walletRepository.CreateWallet(newWallet); err != nil {
+			if err := h.walletRepository.CreateWallet(ctx, newWallet); err != nil {
 				return nil, err
 			}
 			return newWallet, nil
@@ -216,18 +439,35 @@ func (h *WalletHandler) CheckUserWalletIfNotCreate(ownerID, currencyTypeID uuid.
 	return wallet, nil
 }
 
+// GetWalletByOwner answers the owner's wallet in a currency. owner_type is
+// optional and defaults to the owner's role, like the gRPC GetBalance.
 func (h *WalletHandler) GetWalletByOwner(c *gin.Context) {
-	ownerType := c.Param("owner_type")
+	ownerType := c.Query("owner_type")
 	ownerID := c.Query("owner_id")
 	currencyTypeID := c.Query("currency_type_id")
 
-	if ownerType == "" || ownerID == "" || currencyTypeID == "" {
+	if ownerID == "" || currencyTypeID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "owner_type, owner_id, and currency_type_id are required in query params",
+			"error": "owner_id and currency_type_id are required in query params",
 		})
 		return
 	}
-	wallet, err := h.walletRepository.GetWalletByOwner(ownerType, ownerID, currencyTypeID)
+	if _, err := uuid.Parse(ownerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner_id must be a valid UUID"})
+		return
+	}
+	if _, err := uuid.Parse(currencyTypeID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency_type_id must be a valid UUID"})
+		return
+	}
+	if ownerType == "" {
+		owner, err := h.userRepository.GetUserByID(c.Request.Context(), ownerID)
+		if utils.ReturnIfGormError(c, err) {
+			return
+		}
+		ownerType = owner.Role
+	}
+	wallet, err := h.walletRepository.GetWalletByOwner(c.Request.Context(), ownerType, ownerID, currencyTypeID)
 	if utils.ReturnIfGormError(c, err) {
 		return
 	}
@@ -0,0 +1,526 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// testEnv is a router wired to in-memory repositories holding two users and
+// a treasury of 1000 in one currency.
+type testEnv struct {
+	router   *gin.Engine
+	users    repository.UserRepository
+	wallets  repository.WalletRepository
+	user     repository.User
+	other    repository.User
+	currency uuid.UUID
+	treasury repository.Wallet
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	ctx := context.Background()
+	env := &testEnv{
+		router:   gin.New(),
+		users:    repository.NewInMemoryUserRepository(),
+		wallets:  repository.NewInMemoryWalletRepository(),
+		user:     repository.User{ID: uuid.New(), Name: "Alice", Role: "user"},
+		other:    repository.User{ID: uuid.New(), Name: "Bob", Role: "user"},
+		currency: uuid.New(),
+	}
+	for _, user := range []*repository.User{&env.user, &env.other} {
+		if err := env.users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.treasury = repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: env.currency, Balance: 1_000}
+	if err := env.wallets.CreateWallet(ctx, &env.treasury); err != nil {
+		t.Fatal(err)
+	}
+
+	apiV1 := env.router.Group("/api/v1")
+	handler.NewUserHandler(env.users).RegisterRoutes(apiV1)
+	handler.NewWalletHandler(env.wallets, env.users).RegisterRoutes(apiV1)
+	return env
+}
+
+func (env *testEnv) do(t *testing.T, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = strings.NewReader(string(encoded))
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (env *testEnv) balance(t *testing.T, ownerID uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := env.wallets.GetWalletByOwner(context.Background(), "user", ownerID.String(), env.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+func (env *testEnv) body(overrides map[string]any) map[string]any {
+	body := map[string]any{
+		"idempotency_key":  uuid.NewString(),
+		"owner_id":         env.user.ID,
+		"currency_type_id": env.currency,
+		"amount":           100,
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(body, k)
+			continue
+		}
+		body[k] = v
+	}
+	return body
+}
+
+type walletCase struct {
+	name string
+	// setup runs before the request, e.g. to fund the user.
+	setup       func(t *testing.T, env *testEnv)
+	body        func(env *testEnv) any
+	wantStatus  int
+	wantMessage string
+	// wantBalance is the user's balance afterwards; -1 skips the check.
+	wantBalance int64
+}
+
+func runWalletCases(t *testing.T, path string, cases []walletCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tc.setup != nil {
+				tc.setup(t, env)
+			}
+			rec := env.do(t, http.MethodPost, path, tc.body(env))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantMessage != "" {
+				var resp struct {
+					Message string `json:"message"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Message != tc.wantMessage {
+					t.Errorf("message = %q, want %q", resp.Message, tc.wantMessage)
+				}
+			}
+			if tc.wantBalance >= 0 {
+				if got := env.balance(t, env.user.ID); got != tc.wantBalance {
+					t.Errorf("balance = %d, want %d", got, tc.wantBalance)
+				}
+			}
+		})
+	}
+}
+
+func topUp(amount int64, key string) func(t *testing.T, env *testEnv) {
+	return func(t *testing.T, env *testEnv) {
+		t.Helper()
+		rec := env.do(t, http.MethodPost, "/api/v1/wallets/top-up",
+			env.body(map[string]any{"amount": amount, "idempotency_key": key}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("setup top-up: status %d; body %s", rec.Code, rec.Body)
+		}
+	}
+}
+
+// invalidBodyCases are rejected by binding on every mutating endpoint.
+func invalidBodyCases() []walletCase {
+	return []walletCase{
+		{
+			name:        "malformed JSON",
+			body:        func(env *testEnv) any { return `{"amount":` },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "missing idempotency key",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"idempotency_key": nil}) },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "zero amount",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"amount": 0}) },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "negative amount",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"amount": -5}) },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "owner id not a UUID",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"owner_id": "alice"}) },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "unknown user",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"owner_id": uuid.New()}) },
+			wantStatus:  http.StatusNotFound,
+			wantBalance: -1,
+		},
+		{
+			name:        "currency without treasury",
+			body:        func(env *testEnv) any { return env.body(map[string]any{"currency_type_id": uuid.New()}) },
+			wantStatus:  http.StatusNotFound,
+			wantBalance: -1,
+		},
+	}
+}
+
+func TestTopUp(t *testing.T) {
+	runWalletCases(t, "/api/v1/wallets/top-up", append([]walletCase{
+		{
+			name:        "creates wallet and credits it",
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Top-up successful",
+			wantBalance: 100,
+		},
+		{
+			name:        "replayed key is not applied twice",
+			setup:       topUp(100, "top-up-1"),
+			body:        func(env *testEnv) any { return env.body(map[string]any{"idempotency_key": "top-up-1"}) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Top-up successful (idempotent)",
+			wantBalance: 100,
+		},
+		{
+			name:        "more than the treasury holds",
+			setup:       topUp(1, "open-wallet"),
+			body:        func(env *testEnv) any { return env.body(map[string]any{"amount": 1_000}) },
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantBalance: 1,
+		},
+	}, invalidBodyCases()...))
+}
+
+func TestSpend(t *testing.T) {
+	runWalletCases(t, "/api/v1/wallets/spend", append([]walletCase{
+		{
+			name:        "debits the wallet",
+			setup:       topUp(150, "fund"),
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Spend successful",
+			wantBalance: 50,
+		},
+		{
+			name:        "spends the whole balance",
+			setup:       topUp(100, "fund"),
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Spend successful",
+			wantBalance: 0,
+		},
+		{
+			name:        "insufficient balance",
+			setup:       topUp(99, "fund"),
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantBalance: 99,
+		},
+		{
+			name: "replayed key is not applied twice",
+			setup: func(t *testing.T, env *testEnv) {
+				topUp(300, "fund")(t, env)
+				rec := env.do(t, http.MethodPost, "/api/v1/wallets/spend", env.body(map[string]any{"idempotency_key": "spend-1"}))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("first spend: status %d", rec.Code)
+				}
+			},
+			body:        func(env *testEnv) any { return env.body(map[string]any{"idempotency_key": "spend-1"}) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Spend successful (idempotent)",
+			wantBalance: 200,
+		},
+		{
+			name:        "empty wallet is created then rejected",
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantBalance: 0,
+		},
+	}, invalidBodyCases()...))
+}
+
+func TestBonus(t *testing.T) {
+	runWalletCases(t, "/api/v1/wallets/bonus", append([]walletCase{
+		{
+			name:        "credits the wallet",
+			body:        func(env *testEnv) any { return env.body(nil) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Bonus added",
+			wantBalance: 100,
+		},
+		{
+			name: "replayed key is not applied twice",
+			setup: func(t *testing.T, env *testEnv) {
+				rec := env.do(t, http.MethodPost, "/api/v1/wallets/bonus", env.body(map[string]any{"idempotency_key": "bonus-1"}))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("first bonus: status %d", rec.Code)
+				}
+			},
+			body:        func(env *testEnv) any { return env.body(map[string]any{"idempotency_key": "bonus-1"}) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Bonus added (idempotent)",
+			wantBalance: 100,
+		},
+		{
+			name: "deprecated path shares the idempotency key",
+			setup: func(t *testing.T, env *testEnv) {
+				rec := env.do(t, http.MethodPost, "/api/v1/wallets/bonus/welcome", env.body(map[string]any{"idempotency_key": "bonus-1"}))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("legacy bonus: status %d", rec.Code)
+				}
+			},
+			body:        func(env *testEnv) any { return env.body(map[string]any{"idempotency_key": "bonus-1"}) },
+			wantStatus:  http.StatusOK,
+			wantMessage: "Bonus added (idempotent)",
+			wantBalance: 100,
+		},
+	}, invalidBodyCases()...))
+}
+
+// TestLedgerRuleViolations checks that an overdraft or a frozen wallet
+// answers 422, as gRPC answers FailedPrecondition, and moves nothing.
+func TestLedgerRuleViolations(t *testing.T) {
+	env := newTestEnv(t)
+	topUp(40, "fund")(t, env)
+	rec := env.do(t, http.MethodPost, "/api/v1/wallets/spend", env.body(map[string]any{"amount": 41}))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "insufficient balance") {
+		t.Errorf("overdraft = %d %s, want 422", rec.Code, rec.Body)
+	}
+	wallet, err := env.wallets.GetWalletByOwner(context.Background(), "user", env.user.ID.String(), env.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.wallets.SetFrozen(context.Background(), wallet.ID.String(), true); err != nil {
+		t.Fatal(err)
+	}
+	rec = env.do(t, http.MethodPost, "/api/v1/wallets/spend", env.body(map[string]any{"amount": 10}))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "wallet is frozen") {
+		t.Errorf("spend from a frozen wallet = %d %s, want 422", rec.Code, rec.Body)
+	}
+	if got := env.balance(t, env.user.ID); got != 40 {
+		t.Errorf("balance = %d, want 40", got)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	transfer := func(overrides map[string]any) func(env *testEnv) any {
+		return func(env *testEnv) any {
+			body := env.body(map[string]any{"owner_id": nil, "from_owner_id": env.user.ID, "to_owner_id": env.other.ID})
+			for k, v := range overrides {
+				if v == nil {
+					delete(body, k)
+					continue
+				}
+				body[k] = v
+			}
+			return body
+		}
+	}
+	runWalletCases(t, "/api/v1/wallets/transfer", []walletCase{
+		{
+			name:        "moves funds to the recipient",
+			setup:       topUp(250, "fund"),
+			body:        transfer(nil),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Transfer successful",
+			wantBalance: 150,
+		},
+		{
+			name: "replayed key is not applied twice",
+			setup: func(t *testing.T, env *testEnv) {
+				topUp(250, "fund")(t, env)
+				rec := env.do(t, http.MethodPost, "/api/v1/wallets/transfer", transfer(map[string]any{"idempotency_key": "transfer-1"})(env))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("first transfer: status %d", rec.Code)
+				}
+			},
+			body:        transfer(map[string]any{"idempotency_key": "transfer-1"}),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Transfer successful (idempotent)",
+			wantBalance: 150,
+		},
+		{
+			name:        "insufficient balance",
+			setup:       topUp(50, "fund"),
+			body:        transfer(nil),
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantBalance: 50,
+		},
+		{
+			name:        "sender has no wallet",
+			body:        transfer(nil),
+			wantStatus:  http.StatusNotFound,
+			wantBalance: -1,
+		},
+		{
+			name:        "unknown recipient",
+			setup:       topUp(250, "fund"),
+			body:        transfer(map[string]any{"to_owner_id": uuid.New()}),
+			wantStatus:  http.StatusNotFound,
+			wantBalance: 250,
+		},
+		{
+			name:        "same sender and recipient",
+			setup:       topUp(250, "fund"),
+			body:        func(env *testEnv) any { return transfer(map[string]any{"to_owner_id": env.user.ID})(env) },
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: 250,
+		},
+		{
+			name:        "missing recipient",
+			body:        transfer(map[string]any{"to_owner_id": nil}),
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+		{
+			name:        "zero amount",
+			body:        transfer(map[string]any{"amount": 0}),
+			wantStatus:  http.StatusBadRequest,
+			wantBalance: -1,
+		},
+	})
+}
+
+func TestGetBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, env *testEnv)
+		query      func(env *testEnv) string
+		wantStatus int
+	}{
+		{
+			name:       "no query params",
+			query:      func(env *testEnv) string { return "" },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing currency",
+			query:      func(env *testEnv) string { return "owner_id=" + env.user.ID.String() },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "all query params without a wallet",
+			query: func(env *testEnv) string {
+				return "owner_type=user&owner_id=" + env.user.ID.String() + "&currency_type_id=" + env.currency.String()
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:  "all query params",
+			setup: topUp(100, "fund"),
+			query: func(env *testEnv) string {
+				return "owner_type=user&owner_id=" + env.user.ID.String() + "&currency_type_id=" + env.currency.String()
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "owner_type defaults to the owner's role",
+			setup: topUp(100, "fund"),
+			query: func(env *testEnv) string {
+				return "owner_id=" + env.user.ID.String() + "&currency_type_id=" + env.currency.String()
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "other owner type",
+			setup: topUp(100, "fund"),
+			query: func(env *testEnv) string {
+				return "owner_type=system&owner_id=" + env.user.ID.String() + "&currency_type_id=" + env.currency.String()
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "unknown owner",
+			query: func(env *testEnv) string {
+				return "owner_id=" + uuid.NewString() + "&currency_type_id=" + env.currency.String()
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.setup != nil {
+				tt.setup(t, env)
+			}
+			rec := env.do(t, http.MethodGet, "/api/v1/wallets/balance?"+tt.query(env), nil)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestWalletOptions(t *testing.T) {
+	env := newTestEnv(t)
+	env.router = gin.New()
+	handler.NewWalletHandler(env.wallets, env.users).
+		WithOptions(handler.WalletOptions{MaxAmount: 50}).
+		RegisterRoutes(env.router.Group("/api/v1"))
+
+	rec := env.do(t, http.MethodPost, "/api/v1/wallets/top-up", env.body(map[string]any{"amount": 51}))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "exceeds the limit of 50") {
+		t.Errorf("top-up over the limit = %d %s, want 400", rec.Code, rec.Body)
+	}
+	if rec := env.do(t, http.MethodPost, "/api/v1/wallets/top-up", env.body(map[string]any{"amount": 50})); rec.Code != http.StatusOK {
+		t.Errorf("top-up at the limit = %d %s, want 200", rec.Code, rec.Body)
+	}
+	// transfers are off unless the options enable them
+	rec = env.do(t, http.MethodPost, "/api/v1/wallets/transfer", map[string]any{
+		"idempotency_key":  uuid.NewString(),
+		"from_owner_id":    env.user.ID,
+		"to_owner_id":      env.other.ID,
+		"currency_type_id": env.currency,
+		"amount":           10,
+	})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("transfer with transfers disabled = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+// DatabaseCheck pings the primary connection pool and reports its stats.
+func DatabaseCheck(sqlDB *sql.DB) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run:      ping(sqlDB),
+	}
+}
+
+// ReplicaCheck pings the pool of the index-th read replica. It does not block
+// readiness: every instance shares the replicas, and writes and
+// read-your-writes requests still work without them.
+func ReplicaCheck(index int, sqlDB *sql.DB) Check {
+	return Check{
+		Name: fmt.Sprintf("replica_%d", index),
+		Run:  ping(sqlDB),
+	}
+}
+
+func ping(sqlDB *sql.DB) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		stats := sqlDB.Stats()
+		details := map[string]int{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		}
+		return details, sqlDB.PingContext(ctx)
+	}
+}
+
+// TreasuryCheck verifies that every currency type has a system treasury wallet.
+// It reads the primary, so a lagging replica cannot fail readiness.
+func TreasuryCheck(currencyTypes repository.CurrencyTypeRepository, wallets repository.WalletRepository) Check {
+	return Check{
+		Name:     "treasury",
+		Critical: true,
+		Run: func(ctx context.Context) (any, error) {
+			ctx = consistency.WithPrimary(ctx)
+			types, err := currencyTypes.ListCurrencyTypes(ctx)
+			if err != nil {
+				return nil, err
+			}
+			systemWallets, err := wallets.ListSystemWallets(ctx)
+			if err != nil {
+				return nil, err
+			}
+			funded := make(map[string]bool, len(systemWallets))
+			for _, wallet := range systemWallets {
+				funded[wallet.CurrencyTypeID.String()] = true
+			}
+			var missing []string
+			for _, ct := range types {
+				if !funded[ct.ID.String()] {
+					missing = append(missing, ct.Name)
+				}
+			}
+			details := map[string]any{"currency_types": len(types)}
+			if len(missing) > 0 {
+				details["missing"] = missing
+				return details, fmt.Errorf("no treasury wallet for %d currency type(s)", len(missing))
+			}
+			return details, nil
+		},
+	}
+}
+
+// WorkersCheck reports background worker status. It never blocks readiness:
+// a stopped worker degrades the instance but requests can still be served.
+func WorkersCheck(lc *lifecycle.Manager) Check {
+	return Check{
+		Name: "workers",
+		Run: func(ctx context.Context) (any, error) {
+			workers := lc.Workers()
+			var failed int
+			for _, worker := range workers {
+				if worker.State == lifecycle.WorkerStateFailed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return workers, fmt.Errorf("%d background worker(s) failed", failed)
+			}
+			return workers, nil
+		},
+	}
+}
+
+// MigrationCheck verifies that every migration known to this binary is applied
+// to the primary.
+func MigrationCheck(migrator *migrations.Migrator) Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: func(ctx context.Context) (any, error) {
+			ctx = consistency.WithPrimary(ctx)
+			current, err := migrator.Current(ctx)
+			if err != nil {
+				return nil, err
+			}
+			details := map[string]int64{"current": current, "expected": migrator.Latest()}
+			return details, migrator.CheckCurrent(ctx)
+		},
+	}
+}
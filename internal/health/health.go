@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDown     Status = "down"
+	StatusDegraded Status = "degraded"
+)
+
+// Check is a single readiness probe. A failing Critical check makes the
+// instance not ready; a failing non-critical check only reports degraded.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) (details any, err error)
+}
+
+type Result struct {
+	Status     Status `json:"status"`
+	Critical   bool   `json:"critical"`
+	DurationMs int64  `json:"duration_ms"`
+	Details    any    `json:"details,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Ready  bool              `json:"-"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run executes every check concurrently, each bounded by the checker timeout.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Ready: true, Checks: make(map[string]Result, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			start := time.Now()
+			details, err := check.Run(ctx)
+			result := Result{
+				Status:     StatusUp,
+				Critical:   check.Critical,
+				DurationMs: time.Since(start).Milliseconds(),
+				Details:    details,
+			}
+			if err != nil {
+				result.Error = err.Error()
+				result.Status = StatusDegraded
+				if check.Critical {
+					result.Status = StatusDown
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if err != nil && check.Critical {
+				report.Ready = false
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
@@ -0,0 +1,22 @@
+package lifecycle
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// InstanceID returns a new identifier of this process among the instances
+// sharing a database, used as the owner of the work leases it takes. It fits
+// in 64 bytes.
+func InstanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	id := fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+	if len(id) > 64 {
+		id = id[len(id)-64:]
+	}
+	return id
+}
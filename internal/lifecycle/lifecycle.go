@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type WorkerState string
+
+const (
+	WorkerStateRunning WorkerState = "running"
+	WorkerStateStopped WorkerState = "stopped"
+	WorkerStateFailed  WorkerState = "failed"
+)
+
+// WorkerStatus is a snapshot of a background worker started with Manager.Go.
+type WorkerStatus struct {
+	Name      string      `json:"name"`
+	State     WorkerState `json:"state"`
+	Error     string      `json:"error,omitempty"`
+	StartedAt time.Time   `json:"started_at"`
+	StoppedAt *time.Time  `json:"stopped_at,omitempty"`
+}
+
+// Manager tracks process readiness and the background workers that must be
+// drained before the process exits.
+type Manager struct {
+	ready  atomic.Bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	workers map[string]*WorkerStatus
+}
+
+func New() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:     ctx,
+		cancel:  cancel,
+		workers: make(map[string]*WorkerStatus),
+	}
+}
+
+func (m *Manager) SetReady(ready bool) {
+	m.ready.Store(ready)
+}
+
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Go runs fn in a goroutine until it returns or the manager shuts down.
+// fn must return promptly once ctx is cancelled.
+func (m *Manager) Go(name string, fn func(ctx context.Context) error) {
+	status := &WorkerStatus{Name: name, State: WorkerStateRunning, StartedAt: time.Now()}
+	m.mu.Lock()
+	m.workers[name] = status
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		err := fn(m.ctx)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		now := time.Now()
+		status.StoppedAt = &now
+		status.State = WorkerStateStopped
+		if err != nil && m.ctx.Err() == nil {
+			status.State = WorkerStateFailed
+			status.Error = err.Error()
+			slog.Error("background worker failed", "worker", name, "error", err)
+		}
+	}()
+}
+
+// Workers returns the status of every worker, sorted by name.
+func (m *Manager) Workers() []WorkerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]WorkerStatus, 0, len(m.workers))
+	for _, status := range m.workers {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Shutdown marks the process not ready, cancels the workers' context and
+// waits for them to return or for ctx to expire.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.SetReady(false)
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,22 @@
+package limits
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor puts the requestTimeout deadline on every call,
+// unless the client asked for a shorter one. Zero disables it. Message sizes
+// are bounded by the server's grpc.MaxRecvMsgSize instead.
+func UnaryServerInterceptor(requestTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if requestTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,36 @@
+// Package limits bounds the size and duration of API requests.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware rejects bodies larger than maxBodyBytes with 413 and puts a
+// requestTimeout deadline on the request context, which the repositories
+// pass on to the database. Zero disables either limit.
+func GinMiddleware(maxBodyBytes int64, requestTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBodyBytes > 0 {
+			if c.Request.ContentLength > maxBodyBytes {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes),
+				})
+				return
+			}
+			// chunked bodies have no length up front; reading past the
+			// limit fails the JSON binding instead
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		}
+		if requestTimeout > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), requestTimeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
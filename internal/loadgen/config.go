@@ -0,0 +1,155 @@
+// Package loadgen drives the wallet HTTP API with a synthetic mix of
+// operations and checks afterwards that the ledger still balances.
+package loadgen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Operation string
+
+const (
+	OpTopUp    Operation = "top-up"
+	OpSpend    Operation = "spend"
+	OpBonus    Operation = "bonus"
+	OpTransfer Operation = "transfer"
+)
+
+var operations = []Operation{OpTopUp, OpSpend, OpBonus, OpTransfer}
+
+// Mix holds the relative weight of each operation.
+type Mix map[Operation]int
+
+// ParseMix parses "top-up=40,spend=40,bonus=10,transfer=10". Operations
+// left out get weight zero.
+func ParseMix(s string) (Mix, error) {
+	mix := make(Mix)
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("mix entry %q must look like operation=weight", part)
+		}
+		op := Operation(strings.TrimSpace(name))
+		if !op.valid() {
+			return nil, fmt.Errorf("unknown operation %q in mix, want one of %s", op, joinOperations())
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight for %s must be a non-negative integer, got %q", op, value)
+		}
+		mix[op] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix %q has no operation with a positive weight", s)
+	}
+	return mix, nil
+}
+
+func (m Mix) String() string {
+	parts := make([]string, 0, len(m))
+	for _, op := range operations {
+		if m[op] > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", op, m[op]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (op Operation) valid() bool {
+	for _, known := range operations {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+func joinOperations() string {
+	names := make([]string, len(operations))
+	for i, op := range operations {
+		names[i] = string(op)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type Config struct {
+	// Target is the base URL of the API, e.g. http://127.0.0.1:8080.
+	Target string
+	// Duration bounds the run; Requests, when positive, stops it earlier.
+	Duration time.Duration
+	Requests int
+	// Concurrency is the number of in-flight requests.
+	Concurrency int
+	// Rate caps requests per second across all workers; zero is unlimited.
+	Rate    float64
+	Timeout time.Duration
+
+	Users      int
+	Currencies int
+	// Supply is minted into each currency's treasury at setup.
+	Supply int64
+	// InitialBalance is transferred from the treasury to every user wallet at setup.
+	InitialBalance int64
+	MaxAmount      int64
+
+	Mix Mix
+	// Skew is the Zipf exponent used to pick users; values above 1 concentrate
+	// traffic on a few hot wallets, zero picks uniformly.
+	Skew float64
+	// DuplicateRate is the probability that a request resends an earlier
+	// request with the same idempotency key.
+	DuplicateRate float64
+	Seed          int64
+}
+
+func (c Config) Validate() error {
+	var problems []string
+	if c.Target == "" {
+		problems = append(problems, "target is required")
+	}
+	if c.Duration <= 0 && c.Requests <= 0 {
+		problems = append(problems, "either duration or requests must be positive")
+	}
+	if c.Concurrency < 1 {
+		problems = append(problems, "concurrency must be at least 1")
+	}
+	if c.Users < 2 && c.Mix[OpTransfer] > 0 {
+		problems = append(problems, "transfers need at least 2 users")
+	}
+	if c.Users < 1 {
+		problems = append(problems, "users must be at least 1")
+	}
+	if c.Currencies < 1 {
+		problems = append(problems, "currencies must be at least 1")
+	}
+	if c.MaxAmount < 1 {
+		problems = append(problems, "max amount must be at least 1")
+	}
+	if c.InitialBalance < 0 || c.Supply < c.InitialBalance*int64(c.Users) {
+		problems = append(problems, "supply must cover the initial balance of every user")
+	}
+	if c.Skew != 0 && c.Skew <= 1 {
+		problems = append(problems, "skew must be 0 (uniform) or greater than 1")
+	}
+	if c.DuplicateRate < 0 || c.DuplicateRate > 1 {
+		problems = append(problems, "duplicate rate must be between 0 and 1")
+	}
+	if c.Rate < 0 {
+		problems = append(problems, "rate must not be negative")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid load generator config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"gorm.io/gorm"
+)
+
+// Fixture is the set of users and currencies created for one run. Every
+// name carries the run ID so runs never share wallets.
+type Fixture struct {
+	RunID      string
+	Supply     int64
+	Users      []uuid.UUID
+	Currencies []Currency
+}
+
+type Currency struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	TreasuryID uuid.UUID `json:"treasury_id"`
+}
+
+// Setup creates cfg.Currencies currencies with a treasury holding
+// cfg.Supply each, and cfg.Users users. Users are funded with
+// cfg.InitialBalance through ordinary ledger transfers from the treasury, so
+// the conservation check covers setup as well.
+func Setup(ctx context.Context, db *gorm.DB, cfg Config) (*Fixture, error) {
+	wallets := repository.NewWalletRepository(db)
+	f := &Fixture{RunID: uuid.NewString()[:8], Supply: cfg.Supply}
+
+	for i := 0; i < cfg.Currencies; i++ {
+		currency := repository.CurrencyType{ID: uuid.New(), Name: fmt.Sprintf("loadgen-%s-%d", f.RunID, i)}
+		if err := db.WithContext(ctx).Create(&currency).Error; err != nil {
+			return nil, fmt.Errorf("create currency: %w", err)
+		}
+		treasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: currency.ID, Balance: cfg.Supply}
+		if err := wallets.CreateWallet(ctx, &treasury); err != nil {
+			return nil, fmt.Errorf("create treasury: %w", err)
+		}
+		f.Currencies = append(f.Currencies, Currency{ID: currency.ID, Name: currency.Name, TreasuryID: treasury.ID})
+	}
+
+	users := make([]repository.User, cfg.Users)
+	for i := range users {
+		users[i] = repository.User{ID: uuid.New(), Name: fmt.Sprintf("loadgen-%s-%d", f.RunID, i), Role: "user"}
+		f.Users = append(f.Users, users[i].ID)
+	}
+	if err := db.WithContext(ctx).CreateInBatches(users, 500).Error; err != nil {
+		return nil, fmt.Errorf("create users: %w", err)
+	}
+
+	// create every user wallet up front so concurrent first requests do not
+	// race to create the same wallet
+	jobs := make(chan [2]int)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := f.openWallet(ctx, wallets, job[0], job[1], cfg.InitialBalance); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}()
+	}
+	for u := range f.Users {
+		for c := range f.Currencies {
+			jobs <- [2]int{u, c}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	return f, nil
+}
+
+func (f *Fixture) openWallet(ctx context.Context, wallets repository.WalletRepository, user, currency int, balance int64) error {
+	c := f.Currencies[currency]
+	wallet := repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: f.Users[user], CurrencyTypeID: c.ID}
+	if err := wallets.CreateWallet(ctx, &wallet); err != nil {
+		return fmt.Errorf("create wallet: %w", err)
+	}
+	if balance == 0 {
+		return nil
+	}
+	key := fmt.Sprintf("loadgen-%s-fund-%d-%d", f.RunID, user, currency)
+	if err := wallets.Transfer(ctx, c.TreasuryID.String(), wallet.ID.String(), c.ID.String(), key, balance, enums.TransactionTypeTopUp); err != nil {
+		return fmt.Errorf("fund wallet: %w", err)
+	}
+	return nil
+}
+
+// Conservation is the result of the post-run ledger check.
+type Conservation struct {
+	OK         bool            `json:"ok"`
+	Currencies []CurrencyCheck `json:"currencies"`
+	// DuplicatedKeys counts idempotency keys with other than exactly two ledger legs.
+	DuplicatedKeys int `json:"duplicated_keys"`
+}
+
+type CurrencyCheck struct {
+	Currency
+	Supply       int64 `json:"supply"`
+	TotalBalance int64 `json:"total_balance"`
+	// LedgerSum is the sum of all ledger amounts; every transfer nets to zero.
+	LedgerSum int64 `json:"ledger_sum"`
+	Wallets   int   `json:"wallets"`
+	// Mismatched wallets have a balance that differs from their ledger history.
+	Mismatched int `json:"mismatched_wallets"`
+	Negative   int `json:"negative_wallets"`
+}
+
+type walletLedger struct {
+	ID      uuid.UUID
+	Balance int64
+	Ledger  int64
+}
+
+// Check verifies, per currency, that total supply is unchanged, that ledger
+// legs net to zero, that every wallet balance equals its starting balance
+// plus its ledger history, and that no idempotency key was applied twice.
+func Check(ctx context.Context, db *gorm.DB, f *Fixture) (*Conservation, error) {
+	db = db.WithContext(ctx)
+	result := &Conservation{OK: true}
+	ids := make([]uuid.UUID, 0, len(f.Currencies))
+	for _, currency := range f.Currencies {
+		var rows []walletLedger
+		err := db.Raw(`SELECT w.id AS id, w.balance AS balance, COALESCE(SUM(t.amount), 0) AS ledger
+			FROM wallets w LEFT JOIN wallet_transactions t ON t.wallet_id = w.id
+			WHERE w.currency_type_id = ?
+			GROUP BY w.id, w.balance`, currency.ID).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		check := CurrencyCheck{Currency: currency, Supply: f.Supply, Wallets: len(rows)}
+		for _, row := range rows {
+			want := row.Ledger
+			if row.ID == currency.TreasuryID {
+				want += f.Supply
+			}
+			if row.Balance != want {
+				check.Mismatched++
+			}
+			if row.Balance < 0 {
+				check.Negative++
+			}
+			check.TotalBalance += row.Balance
+			check.LedgerSum += row.Ledger
+		}
+		if check.TotalBalance != check.Supply || check.LedgerSum != 0 || check.Mismatched > 0 || check.Negative > 0 {
+			result.OK = false
+		}
+		result.Currencies = append(result.Currencies, check)
+		ids = append(ids, currency.ID)
+	}
+
+	var duplicated int64
+	err := db.Raw(`SELECT COUNT(*) FROM (
+			SELECT t.idempotency_key FROM wallet_transactions t JOIN wallets w ON w.id = t.wallet_id
+			WHERE w.currency_type_id IN ?
+			GROUP BY t.idempotency_key HAVING COUNT(*) <> 2
+		) bad`, ids).Scan(&duplicated).Error
+	if err != nil {
+		return nil, err
+	}
+	result.DuplicatedKeys = int(duplicated)
+	if duplicated > 0 {
+		result.OK = false
+	}
+	return result, nil
+}
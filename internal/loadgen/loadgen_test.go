@@ -0,0 +1,132 @@
+package loadgen_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/loadgen"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    loadgen.Mix
+		wantErr string
+	}{
+		{in: "top-up=3,spend=1", want: loadgen.Mix{loadgen.OpTopUp: 3, loadgen.OpSpend: 1}},
+		{in: " transfer = 5 , bonus=0", want: loadgen.Mix{loadgen.OpTransfer: 5, loadgen.OpBonus: 0}},
+		{in: "withdraw=1", wantErr: "unknown operation"},
+		{in: "spend", wantErr: "operation=weight"},
+		{in: "spend=-1", wantErr: "non-negative"},
+		{in: "spend=0", wantErr: "no operation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := loadgen.ParseMix(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for op, weight := range tt.want {
+				if got[op] != weight {
+					t.Errorf("%s = %d, want %d", op, got[op], weight)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConservesLedger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	slog.SetDefault(log)
+	ctx := context.Background()
+
+	database, err := config_db.NewGormDB(config_env.DbConfig{Driver: "sqlite", DSN: ":memory:"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	db := database.GetDB()
+	migrator, err := migrations.New(db, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	wallets, users := repository.NewWalletRepository(db), repository.NewUserRepository(db)
+	handler.NewWalletHandler(wallets, users).RegisterRoutes(router.Group("/api/v1"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	cfg := loadgen.Config{
+		Target:         server.URL,
+		Duration:       time.Minute,
+		Requests:       300,
+		Concurrency:    8,
+		Timeout:        10 * time.Second,
+		Users:          6,
+		Currencies:     2,
+		Supply:         100_000,
+		InitialBalance: 50,
+		MaxAmount:      80,
+		Mix:            loadgen.Mix{loadgen.OpTopUp: 1, loadgen.OpSpend: 1, loadgen.OpBonus: 1, loadgen.OpTransfer: 1},
+		Skew:           1.5,
+		DuplicateRate:  0.2,
+		Seed:           1,
+	}
+	fixture, err := loadgen.Setup(ctx, db, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := loadgen.Run(ctx, cfg, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != cfg.Requests {
+		t.Errorf("sent %d requests, want %d", report.Requests, cfg.Requests)
+	}
+	if report.Duplicates == 0 {
+		t.Error("no duplicate idempotency keys were sent")
+	}
+	for _, kind := range report.ErrorKinds {
+		if !strings.Contains(kind.Error, "insufficient balance") {
+			t.Errorf("unexpected error kind %q (%d)", kind.Error, kind.Count)
+		}
+	}
+
+	conservation, err := loadgen.Check(ctx, db, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conservation.OK {
+		t.Errorf("conservation check failed: %+v", conservation)
+	}
+	for _, c := range conservation.Currencies {
+		if c.Wallets != cfg.Users+1 {
+			t.Errorf("currency %s has %d wallets, want %d", c.Name, c.Wallets, cfg.Users+1)
+		}
+	}
+}
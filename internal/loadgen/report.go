@@ -0,0 +1,212 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder collects outcomes for one worker, so workers never contend on it.
+type recorder struct {
+	latencies  map[Operation][]time.Duration
+	errors     map[Operation]int
+	breakdown  map[string]int
+	duplicates int
+	replays    int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[Operation][]time.Duration),
+		errors:    make(map[Operation]int),
+		breakdown: make(map[string]int),
+	}
+}
+
+func (r *recorder) record(op Operation, duplicate bool, out outcome) {
+	if out.aborted {
+		return
+	}
+	r.latencies[op] = append(r.latencies[op], out.latency)
+	if duplicate {
+		r.duplicates++
+	}
+	if out.replay {
+		r.replays++
+	}
+	if out.err != "" {
+		r.errors[op]++
+		r.breakdown[fmt.Sprintf("%s: %s", op, out.err)]++
+	}
+}
+
+type Report struct {
+	Config struct {
+		Target        string  `json:"target"`
+		Concurrency   int     `json:"concurrency"`
+		Users         int     `json:"users"`
+		Currencies    int     `json:"currencies"`
+		Mix           string  `json:"mix"`
+		Skew          float64 `json:"skew"`
+		DuplicateRate float64 `json:"duplicate_rate"`
+	} `json:"config"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Requests       int     `json:"requests"`
+	Errors         int     `json:"errors"`
+	Throughput     float64 `json:"throughput_rps"`
+	// Duplicates counts requests resent with an earlier idempotency key;
+	// Replays counts responses the API marked as idempotent replays.
+	Duplicates   int                          `json:"duplicates_sent"`
+	Replays      int                          `json:"idempotent_replays"`
+	Latency      Latency                      `json:"latency"`
+	Operations   map[Operation]OperationStats `json:"operations"`
+	ErrorKinds   []ErrorKind                  `json:"errors_by_kind"`
+	Conservation *Conservation                `json:"conservation,omitempty"`
+}
+
+type OperationStats struct {
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	Latency  Latency `json:"latency"`
+}
+
+// Latency percentiles are in milliseconds.
+type Latency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+type ErrorKind struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+func buildReport(cfg Config, elapsed time.Duration, recorders []*recorder) *Report {
+	report := &Report{ElapsedSeconds: elapsed.Seconds(), Operations: make(map[Operation]OperationStats)}
+	report.Config.Target = cfg.Target
+	report.Config.Concurrency = cfg.Concurrency
+	report.Config.Users = cfg.Users
+	report.Config.Currencies = cfg.Currencies
+	report.Config.Mix = cfg.Mix.String()
+	report.Config.Skew = cfg.Skew
+	report.Config.DuplicateRate = cfg.DuplicateRate
+
+	var all []time.Duration
+	breakdown := make(map[string]int)
+	for _, op := range operations {
+		var latencies []time.Duration
+		stats := OperationStats{}
+		for _, r := range recorders {
+			latencies = append(latencies, r.latencies[op]...)
+			stats.Errors += r.errors[op]
+		}
+		if len(latencies) == 0 {
+			continue
+		}
+		stats.Requests = len(latencies)
+		stats.Latency = summarize(latencies)
+		report.Operations[op] = stats
+		report.Requests += stats.Requests
+		report.Errors += stats.Errors
+		all = append(all, latencies...)
+	}
+	for _, r := range recorders {
+		report.Duplicates += r.duplicates
+		report.Replays += r.replays
+		for kind, count := range r.breakdown {
+			breakdown[kind] += count
+		}
+	}
+	for kind, count := range breakdown {
+		report.ErrorKinds = append(report.ErrorKinds, ErrorKind{Error: kind, Count: count})
+	}
+	sort.Slice(report.ErrorKinds, func(i, j int) bool {
+		if report.ErrorKinds[i].Count != report.ErrorKinds[j].Count {
+			return report.ErrorKinds[i].Count > report.ErrorKinds[j].Count
+		}
+		return report.ErrorKinds[i].Error < report.ErrorKinds[j].Error
+	})
+	report.Latency = summarize(all)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report
+}
+
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p float64) float64 {
+		return ms(latencies[int(p*float64(len(latencies)-1))])
+	}
+	return Latency{
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+// WriteText prints the report as human-readable tables.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "target %s, %d workers, %d users, %d currencies, mix %s, skew %g, duplicate rate %g\n\n",
+		r.Config.Target, r.Config.Concurrency, r.Config.Users, r.Config.Currencies, r.Config.Mix, r.Config.Skew, r.Config.DuplicateRate)
+	fmt.Fprintf(w, "%d requests in %.1fs: %.1f req/s, %d errors, %d duplicates sent, %d idempotent replays\n\n",
+		r.Requests, r.ElapsedSeconds, r.Throughput, r.Errors, r.Duplicates, r.Replays)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\tmean ms\tp50 ms\tp90 ms\tp99 ms\tmax ms\t")
+	row := func(name string, requests, errors int, l Latency) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", name, requests, errors, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	}
+	for _, op := range operations {
+		if stats, ok := r.Operations[op]; ok {
+			row(string(op), stats.Requests, stats.Errors, stats.Latency)
+		}
+	}
+	row("all", r.Requests, r.Errors, r.Latency)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.ErrorKinds) > 0 {
+		fmt.Fprintln(w, "\nerrors:")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, kind := range r.ErrorKinds {
+			fmt.Fprintf(tw, "  %d\t%s\n", kind.Count, kind.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if c := r.Conservation; c != nil {
+		verdict := "OK"
+		if !c.OK {
+			verdict = "FAILED"
+		}
+		fmt.Fprintf(w, "\nledger conservation: %s (%d idempotency keys not applied exactly once)\n", verdict, c.DuplicatedKeys)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  currency\tsupply\ttotal balance\tledger sum\twallets\tmismatched\tnegative")
+		for _, cc := range c.Currencies {
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\t%d\t%d\n", cc.Name, cc.Supply, cc.TotalBalance, cc.LedgerSum, cc.Wallets, cc.Mismatched, cc.Negative)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
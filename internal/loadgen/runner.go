@@ -0,0 +1,227 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// request is one prepared API call, kept so it can be resent verbatim as a duplicate.
+type request struct {
+	op   Operation
+	path string
+	body []byte
+}
+
+// history is a bounded pool of sent requests that duplicates are drawn from.
+type history struct {
+	mu       sync.Mutex
+	requests []request
+	next     int
+}
+
+const historySize = 1024
+
+func (h *history) add(r request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.requests) < historySize {
+		h.requests = append(h.requests, r)
+		return
+	}
+	h.requests[h.next] = r
+	h.next = (h.next + 1) % historySize
+}
+
+func (h *history) pick(rng *rand.Rand) (request, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.requests) == 0 {
+		return request{}, false
+	}
+	return h.requests[rng.Intn(len(h.requests))], true
+}
+
+type generator struct {
+	cfg     Config
+	fixture *Fixture
+	rng     *rand.Rand
+	zipf    *rand.Zipf
+	weights []Operation
+}
+
+func newGenerator(cfg Config, f *Fixture, seed int64) *generator {
+	g := &generator{cfg: cfg, fixture: f, rng: rand.New(rand.NewSource(seed))}
+	if cfg.Skew > 1 && len(f.Users) > 1 {
+		g.zipf = rand.NewZipf(g.rng, cfg.Skew, 1, uint64(len(f.Users)-1))
+	}
+	for _, op := range operations {
+		for i := 0; i < cfg.Mix[op]; i++ {
+			g.weights = append(g.weights, op)
+		}
+	}
+	return g
+}
+
+func (g *generator) user() int {
+	if g.zipf != nil {
+		return int(g.zipf.Uint64())
+	}
+	return g.rng.Intn(len(g.fixture.Users))
+}
+
+func (g *generator) next() request {
+	op := g.weights[g.rng.Intn(len(g.weights))]
+	currency := g.fixture.Currencies[g.rng.Intn(len(g.fixture.Currencies))]
+	user := g.user()
+	body := map[string]any{
+		"idempotency_key":  "lg-" + uuid.NewString(),
+		"currency_type_id": currency.ID,
+		"amount":           g.rng.Int63n(g.cfg.MaxAmount) + 1,
+	}
+	path := "/api/v1/wallets/" + string(op)
+	switch op {
+	case OpBonus:
+		path = "/api/v1/wallets/bonus"
+		body["owner_id"] = g.fixture.Users[user]
+	case OpTransfer:
+		to := g.user()
+		if to == user {
+			to = (user + 1) % len(g.fixture.Users)
+		}
+		body["from_owner_id"] = g.fixture.Users[user]
+		body["to_owner_id"] = g.fixture.Users[to]
+	default:
+		body["owner_id"] = g.fixture.Users[user]
+	}
+	encoded, _ := json.Marshal(body)
+	return request{op: op, path: path, body: encoded}
+}
+
+// Run sends requests until cfg.Duration elapses, cfg.Requests have been sent
+// or ctx is cancelled, and summarises what happened.
+func Run(ctx context.Context, cfg Config, f *Fixture) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        cfg.Concurrency,
+			MaxIdleConnsPerHost: cfg.Concurrency,
+		},
+	}
+	defer client.CloseIdleConnections()
+	target := strings.TrimRight(cfg.Target, "/")
+
+	var tokens <-chan time.Time
+	if cfg.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	var sent atomic.Int64
+	var past history
+	results := make([]*recorder, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		rec := newRecorder()
+		results[w] = rec
+		gen := newGenerator(cfg, f, cfg.Seed+int64(w))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if tokens != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tokens:
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if cfg.Requests > 0 && sent.Add(1) > int64(cfg.Requests) {
+					return
+				}
+				req, duplicate := gen.next(), false
+				if cfg.DuplicateRate > 0 && gen.rng.Float64() < cfg.DuplicateRate {
+					req, duplicate = past.pick(gen.rng)
+					if !duplicate {
+						req = gen.next()
+					}
+				}
+				if !duplicate {
+					past.add(req)
+				}
+				rec.record(req.op, duplicate, send(ctx, client, target, req))
+			}
+		}()
+	}
+	wg.Wait()
+	return buildReport(cfg, time.Since(start), results), nil
+}
+
+type outcome struct {
+	latency time.Duration
+	status  int
+	// err describes a failed request; empty on success
+	err string
+	// replay is set when the API reported the request as an idempotent replay
+	replay bool
+	// aborted requests were cut off by the end of the run and are not counted
+	aborted bool
+}
+
+func send(ctx context.Context, client *http.Client, target string, req request) outcome {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target+req.path, bytes.NewReader(req.body))
+	if err != nil {
+		return outcome{err: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	latency := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return outcome{aborted: true}
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return outcome{latency: latency, err: "transport: " + err.Error()}
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Message string `json:"message"`
+		Error   any    `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body)
+	out := outcome{latency: latency, status: resp.StatusCode}
+	if resp.StatusCode >= 400 {
+		out.err = fmt.Sprintf("%d %v", resp.StatusCode, body.Error)
+	}
+	out.replay = strings.Contains(body.Message, "(idempotent)")
+	return out
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor is GinMiddleware and Recovery for gRPC. The request
+// ID comes from the "x-request-id" metadata when present and is sent back in
+// the response header.
+func UnaryServerInterceptor(base *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		start := time.Now()
+
+		var requestID string
+		if values := metadata.ValueFromIncomingContext(ctx, "x-request-id"); len(values) > 0 {
+			requestID = values[0]
+		}
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+		l := base.With("request_id", requestID)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			l = l.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		ctx = WithContext(ctx, l)
+
+		defer func() {
+			if p := recover(); p != nil {
+				l.Error("panic recovered", "panic", p)
+				resp, err = nil, status.Error(codes.Internal, "internal server error")
+			}
+			code := status.Code(err)
+			level := slog.LevelInfo
+			switch code {
+			case codes.OK:
+			case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+				level = slog.LevelError
+			default:
+				level = slog.LevelWarn
+			}
+			l.LogAttrs(ctx, level, "grpc request",
+				slog.String("method", info.FullMethod),
+				slog.String("code", code.String()),
+				slog.Duration("latency", time.Since(start)),
+			)
+		}()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wallet"
+
+// Registry holds every collector exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	grpcRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	transfersTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Committed transfers by transaction type and currency type.",
+	}, []string{"transaction_type", "currency_type_id"})
+
+	transferAmountTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount_total",
+		Help:      "Sum of committed transfer amounts by transaction type and currency type.",
+	}, []string{"transaction_type", "currency_type_id"})
+
+	insufficientBalanceTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_balance_rejections_total",
+		Help:      "Transfers rejected because the source wallet balance was too low.",
+	}, []string{"transaction_type", "currency_type_id"})
+
+	dbTransactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Duration of database transactions by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	dbLockWaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_lock_wait_seconds",
+		Help:      "Time spent acquiring row locks inside database transactions.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation"})
+
+	reconcileProblems = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ledger_reconcile_problems",
+		Help:      "Problems found by the last ledger reconciliation, by kind.",
+	}, []string{"kind"})
+
+	reconcileLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ledger_reconcile_last_run_timestamp_seconds",
+		Help:      "Unix time of the last completed ledger reconciliation.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		grpcRequestsTotal,
+		grpcRequestDuration,
+		transfersTotal,
+		transferAmountTotal,
+		insufficientBalanceTotal,
+		dbTransactionDuration,
+		dbLockWaitDuration,
+		reconcileProblems,
+		reconcileLastRun,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// GinMiddleware records request counts and latencies per matched gin route.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(route, c.Request.Method, status).Inc()
+		httpRequestDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics of sqlDB.
+func RegisterDBStats(sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, namespace))
+}
+
+// RegisterReplicaDBStats exposes the pool statistics of the index-th read
+// replica, labelled db_name="wallet_replica_<index>".
+func RegisterReplicaDBStats(index int, sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, fmt.Sprintf("%s_replica_%d", namespace, index)))
+}
+
+func ObserveTransfer(transactionType, currencyTypeID string, amount int64) {
+	transfersTotal.WithLabelValues(transactionType, currencyTypeID).Inc()
+	transferAmountTotal.WithLabelValues(transactionType, currencyTypeID).Add(float64(amount))
+}
+
+func ObserveInsufficientBalance(transactionType, currencyTypeID string) {
+	insufficientBalanceTotal.WithLabelValues(transactionType, currencyTypeID).Inc()
+}
+
+func ObserveDBTransaction(operation string, err error, d time.Duration) {
+	outcome := "commit"
+	if err != nil {
+		outcome = "rollback"
+	}
+	dbTransactionDuration.WithLabelValues(operation, outcome).Observe(d.Seconds())
+}
+
+func ObserveLockWait(operation string, d time.Duration) {
+	dbLockWaitDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// ObserveReconcile records the outcome of a ledger reconciliation.
+func ObserveReconcile(drifted, unbalanced, reusedKeys int) {
+	reconcileProblems.WithLabelValues("drifted_wallets").Set(float64(drifted))
+	reconcileProblems.WithLabelValues("unbalanced_references").Set(float64(unbalanced))
+	reconcileProblems.WithLabelValues("reused_keys").Set(float64(reusedKeys))
+	reconcileLastRun.SetToCurrentTime()
+}
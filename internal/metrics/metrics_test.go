@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/metrics"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var lookupErr error
+	err := metrics.RegisterTreasury(func() (map[string]int64, error) {
+		return map[string]int64{"gold": 1500}, lookupErr
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(metrics.GinMiddleware())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	scrape := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /metrics = %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+	for range 2 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	}
+	metrics.ObserveTransfer("spend", "gold", 40)
+	metrics.ObserveInsufficientBalance("spend", "gold")
+
+	body := scrape()
+	for _, want := range []string{
+		`wallet_http_requests_total{method="GET",route="/ping",status="204"} 2`,
+		`wallet_transfers_total{currency_type_id="gold",transaction_type="spend"} 1`,
+		`wallet_transfer_amount_total{currency_type_id="gold",transaction_type="spend"} 40`,
+		`wallet_insufficient_balance_rejections_total{currency_type_id="gold",transaction_type="spend"} 1`,
+		`wallet_treasury_balance{currency_type_id="gold"} 1500`,
+		`wallet_treasury_scrape_error 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics lacks %s", want)
+		}
+	}
+
+	// a failed lookup is reported instead of stale balances
+	lookupErr = errors.New("database is down")
+	body = scrape()
+	if !strings.Contains(body, "wallet_treasury_scrape_error 1") || strings.Contains(body, "wallet_treasury_balance{") {
+		t.Errorf("after a failed lookup /metrics reports:\n%s", body)
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// TreasuryBalancesFunc returns the treasury balance keyed by currency type ID.
+type TreasuryBalancesFunc func() (map[string]int64, error)
+
+type treasuryCollector struct {
+	balances TreasuryBalancesFunc
+	desc     *prometheus.Desc
+	errDesc  *prometheus.Desc
+}
+
+// RegisterTreasury exposes a balance gauge per currency, read from balances on every scrape.
+func RegisterTreasury(balances TreasuryBalancesFunc) error {
+	return Registry.Register(&treasuryCollector{
+		balances: balances,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "treasury", "balance"),
+			"Current balance of the system treasury wallet per currency type.",
+			[]string{"currency_type_id"}, nil,
+		),
+		errDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "treasury", "scrape_error"),
+			"1 if the last treasury balance lookup failed.",
+			nil, nil,
+		),
+	})
+}
+
+func (c *treasuryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+	ch <- c.errDesc
+}
+
+func (c *treasuryCollector) Collect(ch chan<- prometheus.Metric) {
+	balances, err := c.balances()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.errDesc, prometheus.GaugeValue, 1)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.errDesc, prometheus.GaugeValue, 0)
+	for currencyTypeID, balance := range balances {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(balance), currencyTypeID)
+	}
+}
@@ -0,0 +1,282 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const lockName = "wallet_schema_migrations"
+
+// lockKey is the postgres advisory lock key; postgres keys locks by integer.
+const lockKey int64 = 0x77616c6c6574 // "wallet"
+
+// ErrSchemaBehind is returned by CheckCurrent when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+const createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+
+// Status describes one known migration and whether it has been applied.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type appliedRow struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator applies the embedded migrations for the dialect of db and records
+// them in the schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+	log        *slog.Logger
+}
+
+func New(db *gorm.DB, log *slog.Logger) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, log: log}, nil
+}
+
+// Latest is the version this binary expects the schema to be at.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current is the highest applied version, 0 for an empty database.
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	for version := range applied {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// CheckCurrent returns ErrSchemaBehind when the binary knows migrations that
+// have not been applied yet.
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []int64
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) %v, expected version %d; run `migrate up`",
+			ErrSchemaBehind, len(pending), pending, m.Latest())
+	}
+	return nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(ctx, migration, false); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To migrates up or down until exactly the migrations up to version are applied.
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version < 0 || version > m.Latest() {
+		return nil, fmt.Errorf("unknown version %d, latest is %d", version, m.Latest())
+	}
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := m.run(ctx, migration, false); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.run(ctx, migration, true); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedRow, error) {
+	if err := m.db.WithContext(ctx).Exec(createTableSQL).Error; err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []appliedRow
+	if err := m.db.WithContext(ctx).Raw("SELECT version, name, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[int64]appliedRow, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// run applies (up) or reverts (down) a single migration and updates
+// schema_migrations. MySQL commits DDL implicitly, so a failure part-way
+// through a MySQL migration must be repaired by hand before retrying.
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	script, direction := migration.Down, "down"
+	if up {
+		script, direction = migration.Up, "up"
+	}
+	start := time.Now()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s: %w", statement, err)
+			}
+		}
+		if up {
+			return tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC()).Error
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	m.log.Info("migration applied",
+		"version", migration.Version,
+		"name", migration.Name,
+		"direction", direction,
+		"duration", time.Since(start))
+	return nil
+}
+
+// withLock holds a database advisory lock around fn so that concurrent
+// migrators, e.g. several replicas starting at once, run one at a time.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if m.dialect == "sqlite" {
+		// SQLite has no advisory locks. Each migration runs in a transaction,
+		// which takes the database file's write lock, and the pool has a
+		// single connection, so pinning one for a lock would deadlock.
+		return fn()
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	// advisory locks are held per session, so pin one connection for the lock
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
+
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	switch m.dialect {
+	case "mysql":
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, 60).Scan(&acquired); err != nil {
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if !acquired.Valid || acquired.Int64 != 1 {
+			return nil, errors.New("acquire migration lock: another migrator is running")
+		}
+		return func() {
+			conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		}, nil
+	case "postgres":
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		return func() {
+			conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+		}, nil
+	}
+	return nil, fmt.Errorf("migration lock is not supported for dialect %q", m.dialect)
+}
@@ -0,0 +1,48 @@
+-- Baseline matching the schema previously created by GORM AutoMigrate.
+-- IF NOT EXISTS lets databases created by AutoMigrate adopt version 1 as-is.
+CREATE TABLE IF NOT EXISTS users (
+    id CHAR(36) NOT NULL,
+    name LONGTEXT NOT NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS currency_types (
+    id CHAR(36) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_currency_types_name (name)
+);
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id CHAR(36) NOT NULL,
+    owner_type VARCHAR(32) NOT NULL,
+    owner_id CHAR(36) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uniq_owner_currency (owner_type, owner_id, currency_type_id),
+    CONSTRAINT chk_wallets_balance CHECK (balance >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    transaction_type VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uniq_wallet_idempotency (wallet_id, idempotency_key),
+    KEY idx_wallet_transactions_reference_id (reference_id)
+);
@@ -0,0 +1 @@
+DROP INDEX idx_wallet_transactions_idempotency_key ON wallet_transactions;
@@ -0,0 +1,3 @@
+-- GetTransactionByIdempotencyKey looks keys up without a wallet ID, which the
+-- (wallet_id, idempotency_key) unique index cannot serve.
+CREATE INDEX idx_wallet_transactions_idempotency_key ON wallet_transactions (idempotency_key);
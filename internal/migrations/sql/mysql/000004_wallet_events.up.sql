@@ -0,0 +1,19 @@
+-- Outbox of committed ledger legs. Rows are written in the transaction that
+-- writes the leg and polled by every API instance to push balance updates to
+-- streaming clients; the id orders them and is the stream's resume point.
+CREATE TABLE IF NOT EXISTS wallet_events (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    wallet_id CHAR(36) NOT NULL,
+    owner_type VARCHAR(32) NOT NULL,
+    owner_id CHAR(36) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    transaction_id CHAR(36) NOT NULL,
+    transaction_type VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_wallet_events_owner (owner_id, id),
+    KEY idx_wallet_events_created_at (created_at)
+);
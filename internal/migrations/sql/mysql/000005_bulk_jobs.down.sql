@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bulk_job_rows;
+DROP TABLE IF EXISTS bulk_jobs;
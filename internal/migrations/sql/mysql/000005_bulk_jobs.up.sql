@@ -0,0 +1,40 @@
+-- Bulk credit jobs and their uploaded rows. A job is claimed by one API
+-- instance at a time through its lease; each row is applied through the
+-- ledger with its own idempotency key, so re-running a row never credits
+-- twice.
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+    id CHAR(36) NOT NULL,
+    transaction_type VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    total_rows INT NOT NULL,
+    pending_rows INT NOT NULL,
+    succeeded_rows INT NOT NULL,
+    failed_rows INT NOT NULL,
+    invalid_rows INT NOT NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    lease_owner VARCHAR(64) NOT NULL DEFAULT '',
+    lease_until DATETIME(3) NULL,
+    started_at DATETIME(3) NULL,
+    finished_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_bulk_jobs_status (status, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS bulk_job_rows (
+    job_id CHAR(36) NOT NULL,
+    line INT NOT NULL,
+    owner_id VARCHAR(36) NOT NULL,
+    currency_type_id VARCHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error TEXT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INT NOT NULL DEFAULT 0,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (job_id, line),
+    KEY idx_bulk_job_rows_status (job_id, status, line)
+);
@@ -0,0 +1,46 @@
+-- Scheduled and recurring transfers between an owner and the currency
+-- treasury. occurrence_at is the nominal time of the occurrence being run and
+-- names its idempotency key, so retries and restarts never fire it twice;
+-- next_run_at is when it is next attempted.
+CREATE TABLE IF NOT EXISTS schedules (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    owner_id CHAR(36) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    direction VARCHAR(16) NOT NULL,
+    amount BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    cron VARCHAR(128) NOT NULL DEFAULT '',
+    interval_seconds BIGINT NOT NULL DEFAULT 0,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    end_at DATETIME(3) NULL,
+    on_failure VARCHAR(16) NOT NULL,
+    max_retries INT NOT NULL DEFAULT 0,
+    retry_delay_seconds BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL,
+    occurrence_at DATETIME(3) NULL,
+    next_run_at DATETIME(3) NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    run_count INT NOT NULL DEFAULT 0,
+    failure_count INT NOT NULL DEFAULT 0,
+    last_run_at DATETIME(3) NULL,
+    last_error TEXT NOT NULL,
+    lease_owner VARCHAR(64) NOT NULL DEFAULT '',
+    lease_until DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    KEY idx_schedules_due (status, next_run_at),
+    KEY idx_schedules_owner (owner_id, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    schedule_id CHAR(36) NOT NULL,
+    occurrence_at DATETIME(3) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL,
+    error TEXT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (schedule_id, occurrence_at)
+);
@@ -0,0 +1,31 @@
+-- Escrowed player-to-player trades. Each leg moves an amount of one currency
+-- from a party to a counterparty through an escrow wallet owned by the trade
+-- (owner_type 'escrow', owner_id the trade ID). reference_id is the ledger
+-- reference of the release or refund that settled the trade.
+CREATE TABLE IF NOT EXISTS trades (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    status VARCHAR(16) NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    settled_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    KEY idx_trades_expiry (status, expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS trade_legs (
+    trade_id CHAR(36) NOT NULL,
+    leg INT NOT NULL,
+    from_owner_id CHAR(36) NOT NULL,
+    to_owner_id CHAR(36) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    from_wallet_id CHAR(36) NOT NULL,
+    to_wallet_id CHAR(36) NOT NULL,
+    escrow_wallet_id CHAR(36) NOT NULL,
+    deposit_reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    deposited_at DATETIME(3) NULL,
+    PRIMARY KEY (trade_id, leg),
+    KEY idx_trade_legs_from (from_owner_id),
+    KEY idx_trade_legs_to (to_owner_id)
+);
@@ -0,0 +1,36 @@
+-- Bonus campaigns. A campaign pays a fixed reward per claim out of its own
+-- budget wallet (owner_type 'campaign', owner_id the campaign ID), which is
+-- funded from the treasury, so claims never debit the treasury. Each row of
+-- campaign_claims is one owner's nth claim, which makes a claim past the
+-- per-owner cap impossible to record twice.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    reward BIGINT NOT NULL,
+    max_claims_per_owner INT NOT NULL,
+    requirement VARCHAR(16) NOT NULL,
+    min_amount BIGINT NOT NULL DEFAULT 0,
+    spend_window_seconds BIGINT NOT NULL DEFAULT 0,
+    starts_at DATETIME(3) NOT NULL,
+    ends_at DATETIME(3) NULL,
+    status VARCHAR(16) NOT NULL,
+    claim_count BIGINT NOT NULL DEFAULT 0,
+    claimed_amount BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    KEY idx_campaigns_status (status, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS campaign_claims (
+    campaign_id CHAR(36) NOT NULL,
+    owner_id CHAR(36) NOT NULL,
+    claim INT NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (campaign_id, owner_id, claim),
+    UNIQUE KEY uniq_campaign_claims_idempotency (campaign_id, idempotency_key)
+);
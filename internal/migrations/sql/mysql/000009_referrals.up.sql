@@ -0,0 +1,23 @@
+-- Referral rewards. users.referred_by links an invitee to the user who
+-- invited them; the referrals row for the invitee tracks whether their first
+-- top-up qualified and is settled exactly once, from pending to rewarded or
+-- disqualified, under a row lock.
+ALTER TABLE users ADD COLUMN referred_by CHAR(36) NULL;
+
+CREATE TABLE IF NOT EXISTS referrals (
+    referee_id CHAR(36) NOT NULL PRIMARY KEY,
+    referrer_id CHAR(36) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    currency_type_id CHAR(36) NULL,
+    qualifying_transaction_id CHAR(36) NULL,
+    qualifying_amount BIGINT NOT NULL DEFAULT 0,
+    referrer_reward BIGINT NOT NULL DEFAULT 0,
+    referee_reward BIGINT NOT NULL DEFAULT 0,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    settled_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    KEY idx_referrals_referrer (referrer_id, created_at),
+    KEY idx_referrals_status (status, created_at),
+    KEY idx_referrals_settled_at (settled_at)
+);
@@ -0,0 +1,25 @@
+-- Risk decisions. Every spend and transfer screened by the risk rules leaves
+-- one row, keyed by its idempotency key so that a retry gets the same
+-- answer. Operations held for review carry what is needed to apply them
+-- once an admin approves.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    operation VARCHAR(16) NOT NULL,
+    owner_id CHAR(36) NOT NULL,
+    to_owner_id CHAR(36) NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    rule VARCHAR(64) NOT NULL DEFAULT '',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    review_status VARCHAR(16) NOT NULL DEFAULT '',
+    reviewed_by VARCHAR(100) NOT NULL DEFAULT '',
+    reviewed_at DATETIME(3) NULL,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    UNIQUE KEY uniq_risk_decisions_idempotency (idempotency_key),
+    KEY idx_risk_decisions_owner (owner_id, created_at),
+    KEY idx_risk_decisions_review (review_status, created_at)
+);
@@ -0,0 +1,45 @@
+-- Pending operations. Admin operations that need a second admin, because
+-- of their kind or amount, wait here from proposal until another admin
+-- approves or rejects them or they expire, keyed by their idempotency key so
+-- that a retried proposal finds the same operation. Executed operations keep
+-- the reference ID of their ledger entry.
+CREATE TABLE IF NOT EXISTS pending_operations (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    currency_type_id CHAR(36) NOT NULL,
+    owner_id CHAR(36) NULL,
+    direction VARCHAR(8) NOT NULL DEFAULT '',
+    from_wallet_id CHAR(36) NULL,
+    to_wallet_id CHAR(36) NULL,
+    amount BIGINT NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    proposed_by VARCHAR(100) NOT NULL,
+    decided_by VARCHAR(100) NOT NULL DEFAULT '',
+    decided_at DATETIME(3) NULL,
+    expires_at DATETIME(3) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    UNIQUE KEY uniq_pending_operations_idempotency (idempotency_key),
+    KEY idx_pending_operations_status (status, expires_at)
+);
+
+-- The audit trail of pending operations: one row per step, never changed
+-- or removed, which the triggers below enforce.
+CREATE TABLE IF NOT EXISTS approval_audit_entries (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    operation_id CHAR(36) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL,
+    KEY idx_approval_audit_entries_operation (operation_id, created_at),
+    KEY idx_approval_audit_entries_created (created_at)
+);
+
+DROP TRIGGER IF EXISTS approval_audit_entries_no_update;
+CREATE TRIGGER approval_audit_entries_no_update BEFORE UPDATE ON approval_audit_entries FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'approval audit entries are append-only';
+DROP TRIGGER IF EXISTS approval_audit_entries_no_delete;
+CREATE TRIGGER approval_audit_entries_no_delete BEFORE DELETE ON approval_audit_entries FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'approval audit entries are append-only';
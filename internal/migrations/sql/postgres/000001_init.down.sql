@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallets;
+DROP TABLE IF EXISTS currency_types;
+DROP TABLE IF EXISTS users;
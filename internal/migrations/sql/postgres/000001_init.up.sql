@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS users (
+    id UUID NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS currency_types (
+    id UUID NOT NULL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT idx_currency_types_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id UUID NOT NULL PRIMARY KEY,
+    owner_type VARCHAR(32) NOT NULL,
+    owner_id UUID NOT NULL,
+    currency_type_id UUID NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uniq_owner_currency UNIQUE (owner_type, owner_id, currency_type_id),
+    CONSTRAINT chk_wallets_balance CHECK (balance >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id UUID NOT NULL PRIMARY KEY,
+    wallet_id UUID NOT NULL,
+    transaction_type VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uniq_wallet_idempotency UNIQUE (wallet_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_reference_id ON wallet_transactions (reference_id);
@@ -0,0 +1 @@
+CREATE INDEX idx_wallet_transactions_idempotency_key ON wallet_transactions (idempotency_key);
@@ -0,0 +1,19 @@
+-- Outbox of committed ledger legs. Rows are written in the transaction that
+-- writes the leg and polled by every API instance to push balance updates to
+-- streaming clients; the id orders them and is the stream's resume point.
+CREATE TABLE IF NOT EXISTS wallet_events (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id UUID NOT NULL,
+    owner_type VARCHAR(32) NOT NULL,
+    owner_id UUID NOT NULL,
+    currency_type_id UUID NOT NULL,
+    transaction_id UUID NOT NULL,
+    transaction_type VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_events_owner ON wallet_events (owner_id, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_events_created_at ON wallet_events (created_at);
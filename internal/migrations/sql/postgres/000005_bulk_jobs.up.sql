@@ -0,0 +1,41 @@
+-- Bulk credit jobs and their uploaded rows. A job is claimed by one API
+-- instance at a time through its lease; each row is applied through the
+-- ledger with its own idempotency key, so re-running a row never credits
+-- twice.
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+    id UUID PRIMARY KEY,
+    transaction_type VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    total_rows INTEGER NOT NULL,
+    pending_rows INTEGER NOT NULL,
+    succeeded_rows INTEGER NOT NULL,
+    failed_rows INTEGER NOT NULL,
+    invalid_rows INTEGER NOT NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    lease_owner VARCHAR(64) NOT NULL DEFAULT '',
+    lease_until TIMESTAMPTZ NULL,
+    started_at TIMESTAMPTZ NULL,
+    finished_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_jobs_status ON bulk_jobs (status, created_at);
+
+CREATE TABLE IF NOT EXISTS bulk_job_rows (
+    job_id UUID NOT NULL,
+    line INTEGER NOT NULL,
+    owner_id VARCHAR(36) NOT NULL,
+    currency_type_id VARCHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error TEXT NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (job_id, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_job_rows_status ON bulk_job_rows (job_id, status, line);
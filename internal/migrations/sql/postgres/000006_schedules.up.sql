@@ -0,0 +1,47 @@
+-- Scheduled and recurring transfers between an owner and the currency
+-- treasury. occurrence_at is the nominal time of the occurrence being run and
+-- names its idempotency key, so retries and restarts never fire it twice;
+-- next_run_at is when it is next attempted.
+CREATE TABLE IF NOT EXISTS schedules (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL,
+    currency_type_id UUID NOT NULL,
+    direction VARCHAR(16) NOT NULL,
+    amount BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    cron VARCHAR(128) NOT NULL DEFAULT '',
+    interval_seconds BIGINT NOT NULL DEFAULT 0,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    end_at TIMESTAMPTZ NULL,
+    on_failure VARCHAR(16) NOT NULL,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    retry_delay_seconds BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL,
+    occurrence_at TIMESTAMPTZ NULL,
+    next_run_at TIMESTAMPTZ NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_count INTEGER NOT NULL DEFAULT 0,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMPTZ NULL,
+    last_error TEXT NOT NULL,
+    lease_owner VARCHAR(64) NOT NULL DEFAULT '',
+    lease_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules (status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_schedules_owner ON schedules (owner_id, created_at);
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    schedule_id UUID NOT NULL,
+    occurrence_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (schedule_id, occurrence_at)
+);
@@ -0,0 +1,38 @@
+-- Bonus campaigns. A campaign pays a fixed reward per claim out of its own
+-- budget wallet (owner_type 'campaign', owner_id the campaign ID), which is
+-- funded from the treasury, so claims never debit the treasury. Each row of
+-- campaign_claims is one owner's nth claim, which makes a claim past the
+-- per-owner cap impossible to record twice.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    currency_type_id UUID NOT NULL,
+    wallet_id UUID NOT NULL,
+    reward BIGINT NOT NULL,
+    max_claims_per_owner INTEGER NOT NULL,
+    requirement VARCHAR(16) NOT NULL,
+    min_amount BIGINT NOT NULL DEFAULT 0,
+    spend_window_seconds BIGINT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NULL,
+    status VARCHAR(16) NOT NULL,
+    claim_count BIGINT NOT NULL DEFAULT 0,
+    claimed_amount BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns (status, created_at);
+
+CREATE TABLE IF NOT EXISTS campaign_claims (
+    campaign_id UUID NOT NULL,
+    owner_id UUID NOT NULL,
+    claim INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    reference_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (campaign_id, owner_id, claim)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_campaign_claims_idempotency ON campaign_claims (campaign_id, idempotency_key);
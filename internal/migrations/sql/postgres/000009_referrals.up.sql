@@ -0,0 +1,24 @@
+-- Referral rewards. users.referred_by links an invitee to the user who
+-- invited them; the referrals row for the invitee tracks whether their first
+-- top-up qualified and is settled exactly once, from pending to rewarded or
+-- disqualified, under a row lock.
+ALTER TABLE users ADD COLUMN referred_by UUID NULL;
+
+CREATE TABLE IF NOT EXISTS referrals (
+    referee_id UUID NOT NULL PRIMARY KEY,
+    referrer_id UUID NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    currency_type_id UUID NULL,
+    qualifying_transaction_id UUID NULL,
+    qualifying_amount BIGINT NOT NULL DEFAULT 0,
+    referrer_reward BIGINT NOT NULL DEFAULT 0,
+    referee_reward BIGINT NOT NULL DEFAULT 0,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    settled_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer ON referrals (referrer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_referrals_status ON referrals (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_referrals_settled_at ON referrals (settled_at);
@@ -0,0 +1,26 @@
+-- Risk decisions. Every spend and transfer screened by the risk rules leaves
+-- one row, keyed by its idempotency key so that a retry gets the same
+-- answer. Operations held for review carry what is needed to apply them
+-- once an admin approves.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    id UUID NOT NULL PRIMARY KEY,
+    operation VARCHAR(16) NOT NULL,
+    owner_id UUID NOT NULL,
+    to_owner_id UUID NULL,
+    currency_type_id UUID NOT NULL,
+    amount BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    rule VARCHAR(64) NOT NULL DEFAULT '',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    review_status VARCHAR(16) NOT NULL DEFAULT '',
+    reviewed_by VARCHAR(100) NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMPTZ NULL,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_risk_decisions_idempotency ON risk_decisions (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_risk_decisions_owner ON risk_decisions (owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_risk_decisions_review ON risk_decisions (review_status, created_at);
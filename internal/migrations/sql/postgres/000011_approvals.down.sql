@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS approval_audit_entries;
+DROP FUNCTION IF EXISTS approval_audit_append_only();
+DROP TABLE IF EXISTS pending_operations;
@@ -0,0 +1,46 @@
+-- Pending operations. Admin operations that need a second admin, because
+-- of their kind or amount, wait here from proposal until another admin
+-- approves or rejects them or they expire, keyed by their idempotency key so
+-- that a retried proposal finds the same operation. Executed operations keep
+-- the reference ID of their ledger entry.
+CREATE TABLE IF NOT EXISTS pending_operations (
+    id UUID NOT NULL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    currency_type_id UUID NOT NULL,
+    owner_id UUID NULL,
+    direction VARCHAR(8) NOT NULL DEFAULT '',
+    from_wallet_id UUID NULL,
+    to_wallet_id UUID NULL,
+    amount BIGINT NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    proposed_by VARCHAR(100) NOT NULL,
+    decided_by VARCHAR(100) NOT NULL DEFAULT '',
+    decided_at TIMESTAMPTZ NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    reference_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_pending_operations_idempotency ON pending_operations (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_pending_operations_status ON pending_operations (status, expires_at);
+
+-- The audit trail of pending operations: one row per step, never changed
+-- or removed, which the triggers below enforce.
+CREATE TABLE IF NOT EXISTS approval_audit_entries (
+    id UUID NOT NULL PRIMARY KEY,
+    operation_id UUID NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_audit_entries_operation ON approval_audit_entries (operation_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_approval_audit_entries_created ON approval_audit_entries (created_at);
+
+CREATE OR REPLACE FUNCTION approval_audit_append_only() RETURNS trigger AS $$ BEGIN RAISE EXCEPTION 'approval audit entries are append-only'; END; $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS approval_audit_entries_append_only ON approval_audit_entries;
+CREATE TRIGGER approval_audit_entries_append_only BEFORE UPDATE OR DELETE ON approval_audit_entries FOR EACH ROW EXECUTE PROCEDURE approval_audit_append_only();
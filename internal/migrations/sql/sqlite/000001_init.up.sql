@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'user',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS currency_types (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_types_name ON currency_types (name);
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id TEXT NOT NULL PRIMARY KEY,
+    owner_type TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    balance INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    CONSTRAINT chk_wallets_balance CHECK (balance >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_owner_currency ON wallets (owner_type, owner_id, currency_type_id);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id TEXT NOT NULL PRIMARY KEY,
+    wallet_id TEXT NOT NULL,
+    transaction_type TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL,
+    reference_id TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_wallet_idempotency ON wallet_transactions (wallet_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_reference_id ON wallet_transactions (reference_id);
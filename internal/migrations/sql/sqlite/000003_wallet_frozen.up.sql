@@ -0,0 +1,2 @@
+-- Frozen wallets can neither send nor receive funds until unfrozen.
+ALTER TABLE wallets ADD COLUMN frozen BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,20 @@
+-- Outbox of committed ledger legs. Rows are written in the transaction that
+-- writes the leg and polled by every API instance to push balance updates to
+-- streaming clients; the id orders them and is the stream's resume point.
+-- AUTOINCREMENT keeps ids from being reused once old events are pruned.
+CREATE TABLE IF NOT EXISTS wallet_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    wallet_id TEXT NOT NULL,
+    owner_type TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    transaction_type TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL,
+    reference_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_events_owner ON wallet_events (owner_id, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_events_created_at ON wallet_events (created_at);
@@ -0,0 +1,41 @@
+-- Bulk credit jobs and their uploaded rows. A job is claimed by one API
+-- instance at a time through its lease; each row is applied through the
+-- ledger with its own idempotency key, so re-running a row never credits
+-- twice.
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+    id TEXT PRIMARY KEY,
+    transaction_type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    total_rows INTEGER NOT NULL,
+    pending_rows INTEGER NOT NULL,
+    succeeded_rows INTEGER NOT NULL,
+    failed_rows INTEGER NOT NULL,
+    invalid_rows INTEGER NOT NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    lease_owner TEXT NOT NULL DEFAULT '',
+    lease_until DATETIME NULL,
+    started_at DATETIME NULL,
+    finished_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_jobs_status ON bulk_jobs (status, created_at);
+
+CREATE TABLE IF NOT EXISTS bulk_job_rows (
+    job_id TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    owner_id TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL,
+    reference_id TEXT NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (job_id, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_job_rows_status ON bulk_job_rows (job_id, status, line);
@@ -0,0 +1,47 @@
+-- Scheduled and recurring transfers between an owner and the currency
+-- treasury. occurrence_at is the nominal time of the occurrence being run and
+-- names its idempotency key, so retries and restarts never fire it twice;
+-- next_run_at is when it is next attempted.
+CREATE TABLE IF NOT EXISTS schedules (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    direction TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    cron TEXT NOT NULL DEFAULT '',
+    interval_seconds INTEGER NOT NULL DEFAULT 0,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    end_at DATETIME NULL,
+    on_failure TEXT NOT NULL,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    retry_delay_seconds INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    occurrence_at DATETIME NULL,
+    next_run_at DATETIME NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_count INTEGER NOT NULL DEFAULT 0,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    last_run_at DATETIME NULL,
+    last_error TEXT NOT NULL,
+    lease_owner TEXT NOT NULL DEFAULT '',
+    lease_until DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules (status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_schedules_owner ON schedules (owner_id, created_at);
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    schedule_id TEXT NOT NULL,
+    occurrence_at DATETIME NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    reference_id TEXT NOT NULL,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (schedule_id, occurrence_at)
+);
@@ -0,0 +1,33 @@
+-- Escrowed player-to-player trades. Each leg moves an amount of one currency
+-- from a party to a counterparty through an escrow wallet owned by the trade
+-- (owner_type 'escrow', owner_id the trade ID). reference_id is the ledger
+-- reference of the release or refund that settled the trade.
+CREATE TABLE IF NOT EXISTS trades (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    reference_id TEXT NOT NULL DEFAULT '',
+    settled_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_trades_expiry ON trades (status, expires_at);
+
+CREATE TABLE IF NOT EXISTS trade_legs (
+    trade_id TEXT NOT NULL,
+    leg INTEGER NOT NULL,
+    from_owner_id TEXT NOT NULL,
+    to_owner_id TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    from_wallet_id TEXT NOT NULL,
+    to_wallet_id TEXT NOT NULL,
+    escrow_wallet_id TEXT NOT NULL,
+    deposit_reference_id TEXT NOT NULL DEFAULT '',
+    deposited_at DATETIME NULL,
+    PRIMARY KEY (trade_id, leg)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_legs_from ON trade_legs (from_owner_id);
+CREATE INDEX IF NOT EXISTS idx_trade_legs_to ON trade_legs (to_owner_id);
@@ -0,0 +1,38 @@
+-- Bonus campaigns. A campaign pays a fixed reward per claim out of its own
+-- budget wallet (owner_type 'campaign', owner_id the campaign ID), which is
+-- funded from the treasury, so claims never debit the treasury. Each row of
+-- campaign_claims is one owner's nth claim, which makes a claim past the
+-- per-owner cap impossible to record twice.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    wallet_id TEXT NOT NULL,
+    reward INTEGER NOT NULL,
+    max_claims_per_owner INTEGER NOT NULL,
+    requirement TEXT NOT NULL,
+    min_amount INTEGER NOT NULL DEFAULT 0,
+    spend_window_seconds INTEGER NOT NULL DEFAULT 0,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NULL,
+    status TEXT NOT NULL,
+    claim_count INTEGER NOT NULL DEFAULT 0,
+    claimed_amount INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns (status, created_at);
+
+CREATE TABLE IF NOT EXISTS campaign_claims (
+    campaign_id TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    claim INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    reference_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (campaign_id, owner_id, claim)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_campaign_claims_idempotency ON campaign_claims (campaign_id, idempotency_key);
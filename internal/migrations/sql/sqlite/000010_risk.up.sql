@@ -0,0 +1,26 @@
+-- Risk decisions. Every spend and transfer screened by the risk rules leaves
+-- one row, keyed by its idempotency key so that a retry gets the same
+-- answer. Operations held for review carry what is needed to apply them
+-- once an admin approves.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    id TEXT NOT NULL PRIMARY KEY,
+    operation TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    to_owner_id TEXT NULL,
+    currency_type_id TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    rule TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    review_status TEXT NOT NULL DEFAULT '',
+    reviewed_by TEXT NOT NULL DEFAULT '',
+    reviewed_at DATETIME NULL,
+    reference_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_risk_decisions_idempotency ON risk_decisions (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_risk_decisions_owner ON risk_decisions (owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_risk_decisions_review ON risk_decisions (review_status, created_at);
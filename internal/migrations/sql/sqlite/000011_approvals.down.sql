@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS approval_audit_entries;
+DROP TABLE IF EXISTS pending_operations;
@@ -0,0 +1,45 @@
+-- Pending operations. Admin operations that need a second admin, because
+-- of their kind or amount, wait here from proposal until another admin
+-- approves or rejects them or they expire, keyed by their idempotency key so
+-- that a retried proposal finds the same operation. Executed operations keep
+-- the reference ID of their ledger entry.
+CREATE TABLE IF NOT EXISTS pending_operations (
+    id TEXT NOT NULL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    currency_type_id TEXT NOT NULL,
+    owner_id TEXT NULL,
+    direction TEXT NOT NULL DEFAULT '',
+    from_wallet_id TEXT NULL,
+    to_wallet_id TEXT NULL,
+    amount INTEGER NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    proposed_by TEXT NOT NULL,
+    decided_by TEXT NOT NULL DEFAULT '',
+    decided_at DATETIME NULL,
+    expires_at DATETIME NOT NULL,
+    reference_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_pending_operations_idempotency ON pending_operations (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_pending_operations_status ON pending_operations (status, expires_at);
+
+-- The audit trail of pending operations: one row per step, never changed
+-- or removed, which the triggers below enforce.
+CREATE TABLE IF NOT EXISTS approval_audit_entries (
+    id TEXT NOT NULL PRIMARY KEY,
+    operation_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_audit_entries_operation ON approval_audit_entries (operation_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_approval_audit_entries_created ON approval_audit_entries (created_at);
+
+CREATE TRIGGER IF NOT EXISTS approval_audit_entries_no_update BEFORE UPDATE ON approval_audit_entries BEGIN SELECT RAISE(ABORT, 'approval audit entries are append-only'); END;
+CREATE TRIGGER IF NOT EXISTS approval_audit_entries_no_delete BEFORE DELETE ON approval_audit_entries BEGIN SELECT RAISE(ABORT, 'approval audit entries are append-only'); END;
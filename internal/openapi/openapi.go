@@ -0,0 +1,169 @@
+// Package openapi embeds the OpenAPI 3 description of the REST API, serves it
+// with a Swagger UI, and validates requests against it.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// swaggerInitializer replaces the Swagger UI's default, which points at the
+// petstore example.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+func init() {
+	// accept every UUID the handlers accept, not only RFC 4122 versions 1-5
+	openapi3.DefineStringFormatCallback("uuid", func(value string) error {
+		_, err := uuid.Parse(value)
+		return err
+	})
+	// keep validation errors to one line instead of dumping the schema
+	openapi3.SchemaErrorDetailsDisabled = true
+}
+
+// Load parses and validates the embedded document.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+type Handler struct {
+	document []byte
+}
+
+func NewHandler(doc *openapi3.T) (*Handler, error) {
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{document: document}, nil
+}
+
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/openapi.json", h.Document)
+	r.GET("/docs/*filepath", h.SwaggerUI)
+}
+
+func (h *Handler) Document(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.document)
+}
+
+// SwaggerUI serves the bundled Swagger UI, pointed at /openapi.json.
+func (h *Handler) SwaggerUI(c *gin.Context) {
+	switch file := c.Param("filepath"); file {
+	case "/", "/index.html":
+		index, err := swaggerFiles.ReadFile("index.html")
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	case "/swagger-initializer.js":
+		c.Data(http.StatusOK, "text/javascript; charset=utf-8", []byte(swaggerInitializer))
+	default:
+		c.FileFromFS(file, swaggerFiles.HTTP)
+	}
+}
+
+// GinMiddleware rejects requests that do not match the document with 400
+// before they reach a handler. Requests for paths the document does not
+// describe pass through untouched, and API keys are left to the auth
+// middleware.
+func GinMiddleware(doc *openapi3.T) (gin.HandlerFunc, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	options := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	uploadOptions := *options
+	uploadOptions.ExcludeRequestBody = true
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+		validateOptions := options
+		if isUpload(route.Operation) {
+			validateOptions = &uploadOptions
+		}
+		err = openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    validateOptions,
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+// isUpload reports whether operation takes a file, which its handler parses
+// as it reads; validating it here would hold the whole file in memory first.
+func isUpload(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+	for _, media := range operation.RequestBody.Value.Content {
+		if media.Schema == nil || media.Schema.Value == nil || media.Schema.Value.Format != "binary" {
+			return false
+		}
+	}
+	return true
+}
+
+// validationMessage names the offending parameter or body field.
+func validationMessage(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return err.Error()
+	}
+	where := "request body"
+	if requestErr.Parameter != nil {
+		where = requestErr.Parameter.In + " parameter " + requestErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			where += " field " + strings.Join(pointer, ".")
+		}
+		return where + ": " + schemaErr.Reason
+	}
+	if requestErr.Err != nil {
+		return where + ": " + requestErr.Err.Error()
+	}
+	return where + ": " + requestErr.Reason
+}
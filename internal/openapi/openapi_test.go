@@ -0,0 +1,600 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/approval"
+	"github.com/jay6909/dino-internal-wallet-service/internal/auth"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/events"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/health"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+	"github.com/jay6909/dino-internal-wallet-service/internal/openapi"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// undocumented are routes registered on purpose without an operation.
+var undocumented = map[string]bool{
+	"GET /docs/*filepath": true,
+}
+
+// testEnv is every RegisterRoutes of the service, with transfers and streams
+// on, wired to in-memory repositories holding two users and a treasury of
+// 1000. Bulk jobs, schedules, trades, campaigns, referrals, risk
+// decisions and admin operations are kept in SQLite, with the same users and
+// a treasury of its own. The risk rules hold amounts over 100 for review, as
+// do the approvals mints and anything over 100. The admin routes take the
+// keys of admins ops-a and ops-b, and requests carry ops-a's key.
+type testEnv struct {
+	router   *gin.Engine
+	doc      *openapi3.T
+	user     repository.User
+	peer     repository.User
+	currency uuid.UUID
+	adminKey string
+}
+
+var adminKeys = map[string]string{"ops-a": "ops-a-key-0123456789", "ops-b": "ops-b-key-0123456789"}
+
+// as returns env sending the key of admin instead.
+func (env *testEnv) as(admin string) *testEnv {
+	other := *env
+	other.adminKey = adminKeys[admin]
+	return &other
+}
+
+func newTestEnv(t *testing.T, validate bool) *testEnv {
+	t.Helper()
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	ctx := context.Background()
+	users := repository.NewInMemoryUserRepository()
+	wallets := repository.NewInMemoryWalletRepository()
+	env := &testEnv{
+		router:   gin.New(),
+		doc:      doc,
+		user:     repository.User{ID: uuid.New(), Name: "Alice", Role: "user"},
+		peer:     repository.User{ID: uuid.New(), Name: "Bob", Role: "user"},
+		currency: uuid.New(),
+		adminKey: adminKeys["ops-a"],
+	}
+	for _, user := range []*repository.User{&env.user, &env.peer} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	treasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: env.currency, Balance: 1_000}
+	if err := wallets.CreateWallet(ctx, &treasury); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := openapi.NewHandler(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs.RegisterRoutes(env.router)
+	handler.NewHealthHandler(lifecycle.New(), health.NewChecker(time.Second)).RegisterRoutes(env.router)
+	apiV1 := env.router.Group("/api/v1")
+	adminV1 := env.router.Group("/api/v1/admin", auth.AdminGinMiddleware(auth.NewAdminKeys(adminKeys)))
+	if validate {
+		middleware, err := openapi.GinMiddleware(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		apiV1.Use(middleware)
+		adminV1.Use(middleware)
+	}
+	db := dbtest.Open(t)
+	riskDecisions := repository.NewRiskRepository(db)
+	handler.NewUserHandler(users).RegisterRoutes(apiV1)
+	walletHandler := handler.NewWalletHandler(wallets, users).WithOptions(handler.WalletOptions{
+		Transfers: true,
+		Risk:      risk.NewEngine(riskDecisions, risk.NewAccount(time.Hour, 100)),
+	})
+	walletHandler.RegisterRoutes(apiV1)
+	handler.NewRiskHandler(riskDecisions, walletHandler.Release).RegisterRoutes(adminV1)
+	handler.NewStreamHandler(events.NewBroker(1), wallets, users, nil).RegisterRoutes(apiV1)
+	handler.NewBulkJobHandler(repository.NewBulkJobRepository(db)).RegisterRoutes(apiV1)
+	handler.NewScheduleHandler(repository.NewScheduleRepository(db), users).RegisterRoutes(apiV1)
+	for _, user := range []repository.User{env.user, env.peer} {
+		if err := repository.NewUserRepository(db).CreateUser(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sqlWallets := repository.NewWalletRepository(db)
+	sqlTreasury := repository.Wallet{ID: uuid.New(), OwnerType: "system", OwnerID: uuid.New(), CurrencyTypeID: env.currency}
+	if err := sqlWallets.CreateWallet(ctx, &sqlTreasury); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlWallets.Mint(ctx, env.currency.String(), "mint", 1_000); err != nil {
+		t.Fatal(err)
+	}
+	escrowWallets := handler.NewWalletHandler(sqlWallets, users)
+	handler.NewTradeHandler(repository.NewTradeRepository(db), escrowWallets.CheckUserWalletIfNotCreate).RegisterRoutes(apiV1)
+	handler.NewCampaignHandler(repository.NewCampaignRepository(db), escrowWallets.CheckUserWalletIfNotCreate).RegisterRoutes(apiV1)
+	handler.NewReferralHandler(repository.NewReferralRepository(db)).RegisterRoutes(apiV1)
+	handler.NewApprovalHandler(repository.NewApprovalRepository(db), sqlWallets, escrowWallets.CheckUserWalletIfNotCreate,
+		approval.Policy{Threshold: 100, Privileged: []string{enums.ApprovalKindMint}, Expiry: time.Hour}).RegisterRoutes(adminV1)
+	return env
+}
+
+// do sends body as JSON unless it is an upload, which starts with "csv:" or
+// "jsonl:".
+func (env *testEnv) do(method, path, body string) (*http.Request, *httptest.ResponseRecorder) {
+	contentType := "application/json"
+	for prefix, upload := range map[string]string{"csv:": "text/csv", "jsonl:": "application/x-ndjson"} {
+		if strings.HasPrefix(body, prefix) {
+			body, contentType = strings.TrimPrefix(body, prefix), upload
+		}
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if env.adminKey != "" {
+		req.Header.Set("X-API-Key", env.adminKey)
+	}
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	validated := httptest.NewRequest(method, path, strings.NewReader(body))
+	validated.Header = req.Header.Clone()
+	return validated, rec
+}
+
+// createJob uploads a one-row bulk job and returns its ID.
+func (env *testEnv) createJob(t *testing.T) string {
+	t.Helper()
+	_, rec := env.do(http.MethodPost, "/api/v1/bulk-jobs", "csv:owner_id,currency_type_id,amount,idempotency_key\n"+
+		env.user.ID.String()+","+env.currency.String()+",5,bulk-1\n")
+	var job struct{ ID string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil || job.ID == "" {
+		t.Fatalf("upload answered %d: %s", rec.Code, rec.Body)
+	}
+	return job.ID
+}
+
+var ginParam = regexp.MustCompile(`:([^/]+)`)
+
+// TestRoutesMatchSpec fails when a route is registered without being
+// documented, or documented without being registered.
+func TestRoutesMatchSpec(t *testing.T) {
+	env := newTestEnv(t, false)
+	registered := map[string]bool{}
+	for _, route := range env.router.Routes() {
+		key := route.Method + " " + ginParam.ReplaceAllString(route.Path, "{$1}")
+		if !undocumented[key] {
+			registered[key] = true
+		}
+	}
+	documented := map[string]bool{}
+	for path, item := range env.doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for key := range registered {
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	for _, key := range missing {
+		t.Errorf("%s is registered but not in openapi.yaml", key)
+	}
+	for _, key := range stale {
+		t.Errorf("%s is in openapi.yaml but no RegisterRoutes serves it", key)
+	}
+}
+
+// TestResponsesMatchSpec checks real handler responses against the documented
+// status codes and schemas.
+func TestResponsesMatchSpec(t *testing.T) {
+	env := newTestEnv(t, false)
+	router, err := legacy.NewRouter(env.doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation := func(key string) string {
+		return `{"idempotency_key":"` + key + `","owner_id":"` + env.user.ID.String() +
+			`","currency_type_id":"` + env.currency.String() + `","amount":10}`
+	}
+	balance := "/api/v1/wallets/balance?owner_id=" + env.user.ID.String() + "&currency_type_id=" + env.currency.String()
+	overdraft := `{"idempotency_key":"overdraft","owner_id":"` + env.user.ID.String() +
+		`","currency_type_id":"` + env.currency.String() + `","amount":1000000}`
+	job := "/api/v1/bulk-jobs/" + env.createJob(t)
+	definition := func(owner, kind, extra string) string {
+		return `{"owner_id":"` + owner + `","currency_type_id":"` + env.currency.String() +
+			`","direction":"debit","amount":10,"kind":"` + kind + `"` + extra + `}`
+	}
+	_, rec := env.do(http.MethodPost, "/api/v1/schedules", definition(env.user.ID.String(), "cron", `,"cron":"@weekly"`))
+	var created struct{ ID string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("create schedule answered %d: %s", rec.Code, rec.Body)
+	}
+	schedule := "/api/v1/schedules/" + created.ID
+	tradeID := uuid.NewString()
+	trade := "/api/v1/trades/" + tradeID
+	offer := func(to string) string {
+		return `{"trade_id":"` + tradeID + `","legs":[{"from_owner_id":"` + env.user.ID.String() + `","to_owner_id":"` + to +
+			`","currency_type_id":"` + env.currency.String() + `","amount":10}]}`
+	}
+	deposit := func(owner repository.User) string { return `{"owner_id":"` + owner.ID.String() + `"}` }
+	_, rec = env.do(http.MethodPost, "/api/v1/campaigns", `{"name":"welcome","currency_type_id":"`+env.currency.String()+`","reward":10}`)
+	created.ID = ""
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("create campaign answered %d: %s", rec.Code, rec.Body)
+	}
+	campaign := "/api/v1/campaigns/" + created.ID
+	claim := func(key string) string {
+		return `{"idempotency_key":"` + key + `","owner_id":"` + env.user.ID.String() + `"}`
+	}
+	refer := func(referee, referrer string) string {
+		return `{"referee_id":"` + referee + `","referrer_id":"` + referrer + `"}`
+	}
+	large := func(key string) string {
+		return `{"idempotency_key":"` + key + `","from_owner_id":"` + env.user.ID.String() + `","to_owner_id":"` + env.peer.ID.String() +
+			`","owner_id":"` + env.user.ID.String() + `","currency_type_id":"` + env.currency.String() + `","amount":200}`
+	}
+	heldDecision := func(path, key string) string {
+		_, rec := env.do(http.MethodPost, path, large(key))
+		var held struct{ Decision struct{ ID string } }
+		if err := json.Unmarshal(rec.Body.Bytes(), &held); err != nil || held.Decision.ID == "" {
+			t.Fatalf("large operation answered %d: %s", rec.Code, rec.Body)
+		}
+		return "/api/v1/admin/risk/decisions/" + held.Decision.ID
+	}
+	rejected := heldDecision("/api/v1/wallets/spend", "held-spend")
+	approved := heldDecision("/api/v1/wallets/transfer", "held-gift")
+	proposal := func(kind, key, fields string) string {
+		return `{"kind":"` + kind + `","idempotency_key":"` + key + `","amount":` + fields + `}`
+	}
+	mint := proposal("mint", "mint-q3", `500,"currency_type_id":"`+env.currency.String()+`","note":"q3 supply"`)
+	credit := func(key, amount string) string {
+		return proposal("adjustment", key, amount+`,"currency_type_id":"`+env.currency.String()+`","owner_id":"`+env.user.ID.String()+`","direction":"credit"`)
+	}
+	pendingOperation := func(body string) string {
+		_, rec := env.do(http.MethodPost, "/api/v1/admin/approvals", body)
+		var pending struct{ ID string }
+		if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil || pending.ID == "" {
+			t.Fatalf("proposal answered %d: %s", rec.Code, rec.Body)
+		}
+		return "/api/v1/admin/approvals/" + pending.ID
+	}
+	minted := pendingOperation(mint)
+	withdrawn := pendingOperation(credit("credit-large", "200"))
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodGet, "/readyz", ""},
+		{http.MethodGet, "/openapi.json", ""},
+		{http.MethodGet, "/api/v1/users/" + env.user.ID.String(), ""},
+		{http.MethodGet, "/api/v1/users/" + uuid.NewString(), ""},
+		{http.MethodGet, balance, ""},
+		{http.MethodPost, "/api/v1/wallets/top-up", operation("fund")},
+		{http.MethodPost, "/api/v1/wallets/top-up", operation("fund")},
+		{http.MethodPost, "/api/v1/wallets/bonus", operation("bonus")},
+		{http.MethodPost, "/api/v1/wallets/bonus/legacy", operation("bonus")},
+		{http.MethodPost, "/api/v1/wallets/spend", operation("spend")},
+		{http.MethodPost, "/api/v1/wallets/spend", operation("spend")},
+		{http.MethodPost, "/api/v1/wallets/spend", overdraft},
+		{http.MethodGet, balance, ""},
+		{http.MethodPost, "/api/v1/wallets/transfer", `{"idempotency_key":"gift"}`},
+		{http.MethodPost, "/api/v1/bulk-jobs", "jsonl:" + operation("bulk-2")},
+		{http.MethodPost, "/api/v1/bulk-jobs", "csv:owner_id\n"},
+		{http.MethodPost, "/api/v1/bulk-jobs", `{"rows":[]}`},
+		{http.MethodGet, "/api/v1/bulk-jobs", ""},
+		{http.MethodGet, job, ""},
+		{http.MethodGet, "/api/v1/bulk-jobs/" + uuid.NewString(), ""},
+		{http.MethodGet, job + "/rows?limit=1", ""},
+		{http.MethodPost, job + "/retry", ""},
+		{http.MethodPost, job + "/cancel", ""},
+		{http.MethodPost, job + "/cancel", ""},
+		{http.MethodPost, job + "/retry", ""},
+		{http.MethodPost, "/api/v1/schedules", definition(env.user.ID.String(), "interval", `,"interval":"24h","end_at":"2030-01-01T00:00:00Z"`)},
+		{http.MethodPost, "/api/v1/schedules", definition(env.user.ID.String(), "once", `,"cron":"@daily"`)},
+		{http.MethodPost, "/api/v1/schedules", definition(uuid.NewString(), "once", "")},
+		{http.MethodGet, "/api/v1/schedules?owner_id=" + env.user.ID.String(), ""},
+		{http.MethodGet, schedule, ""},
+		{http.MethodGet, "/api/v1/schedules/" + uuid.NewString(), ""},
+		{http.MethodGet, schedule + "/runs", ""},
+		{http.MethodPost, schedule + "/pause", ""},
+		{http.MethodPost, schedule + "/resume", ""},
+		{http.MethodPost, schedule + "/cancel", ""},
+		{http.MethodPost, schedule + "/pause", ""},
+		{http.MethodPost, "/api/v1/trades", offer(uuid.NewString())},
+		{http.MethodPost, "/api/v1/trades", offer(env.peer.ID.String())},
+		{http.MethodPost, "/api/v1/trades", offer(env.peer.ID.String())},
+		{http.MethodGet, "/api/v1/trades?owner_id=" + env.peer.ID.String(), ""},
+		{http.MethodGet, trade, ""},
+		{http.MethodGet, "/api/v1/trades/" + uuid.NewString(), ""},
+		{http.MethodPost, trade + "/deposit", deposit(env.peer)},
+		{http.MethodPost, trade + "/deposit", deposit(env.user)},
+		{http.MethodPost, trade + "/cancel", ""},
+		{http.MethodPost, trade + "/cancel", ""},
+		{http.MethodPost, trade + "/deposit", deposit(env.user)},
+		{http.MethodPost, "/api/v1/campaigns", `{"name":"welcome","currency_type_id":"` + env.currency.String() + `","reward":10,"min_amount":5}`},
+		{http.MethodGet, "/api/v1/campaigns?status=active", ""},
+		{http.MethodGet, campaign, ""},
+		{http.MethodGet, "/api/v1/campaigns/" + uuid.NewString(), ""},
+		{http.MethodPost, campaign + "/claim", claim("claim-1")},
+		{http.MethodPost, campaign + "/fund", `{"idempotency_key":"campaign-fund","amount":100}`},
+		{http.MethodPost, "/api/v1/campaigns/" + uuid.NewString() + "/fund", `{"idempotency_key":"campaign-fund","amount":100}`},
+		{http.MethodPost, campaign + "/claim", claim("claim-1")},
+		{http.MethodPost, campaign + "/claim", claim("claim-1")},
+		{http.MethodPost, campaign + "/claim", claim("claim-2")},
+		{http.MethodGet, campaign + "/claims?owner_id=" + env.user.ID.String(), ""},
+		{http.MethodPost, campaign + "/pause", ""},
+		{http.MethodPost, campaign + "/resume", ""},
+		{http.MethodPost, campaign + "/end", ""},
+		{http.MethodPost, campaign + "/resume", ""},
+		{http.MethodPost, campaign + "/fund", `{"idempotency_key":"campaign-refund","amount":100}`},
+		{http.MethodPost, "/api/v1/referrals", refer(env.peer.ID.String(), env.user.ID.String())},
+		{http.MethodPost, "/api/v1/referrals", refer(env.peer.ID.String(), env.user.ID.String())},
+		{http.MethodPost, "/api/v1/referrals", refer(env.user.ID.String(), env.peer.ID.String())},
+		{http.MethodPost, "/api/v1/referrals", refer(env.user.ID.String(), env.user.ID.String())},
+		{http.MethodPost, "/api/v1/referrals", refer(uuid.NewString(), env.user.ID.String())},
+		{http.MethodGet, "/api/v1/referrals?status=pending&referrer_id=" + env.user.ID.String(), ""},
+		{http.MethodGet, "/api/v1/referrals/" + env.peer.ID.String(), ""},
+		{http.MethodGet, "/api/v1/referrals/" + uuid.NewString(), ""},
+		{http.MethodGet, "/api/v1/referrals/report?from=2020-01-01T00:00:00Z&limit=5", ""},
+		{http.MethodGet, "/api/v1/referrals/report?from=2030-01-01T00:00:00Z&to=2020-01-01T00:00:00Z", ""},
+		{http.MethodPost, "/api/v1/wallets/spend", large("held-spend")},
+		{http.MethodGet, "/api/v1/admin/risk/decisions?review_status=pending&owner_id=" + env.user.ID.String(), ""},
+		{http.MethodGet, rejected, ""},
+		{http.MethodGet, "/api/v1/admin/risk/decisions/" + uuid.NewString(), ""},
+		{http.MethodPost, rejected + "/reject", ""},
+		{http.MethodPost, rejected + "/approve", ""},
+		{http.MethodPost, "/api/v1/wallets/spend", large("held-spend")},
+		{http.MethodPost, approved + "/approve", ""},
+		{http.MethodPost, "/api/v1/wallets/top-up", large("fund-gift")},
+		{http.MethodPost, approved + "/approve", ""},
+		{http.MethodPost, "/api/v1/wallets/transfer", large("held-gift")},
+		{http.MethodPost, "/api/v1/admin/risk/decisions/" + uuid.NewString() + "/reject", ""},
+	}
+	approvals := "/api/v1/admin/approvals"
+	adminTests := []struct {
+		admin, method, path, body string
+	}{
+		{"ops-a", http.MethodPost, approvals, mint},
+		{"ops-a", http.MethodPost, approvals, proposal("mint", "mint-q3", `501,"currency_type_id":"`+env.currency.String()+`"`)},
+		{"ops-a", http.MethodPost, approvals, credit("credit-small", "10")},
+		{"ops-a", http.MethodPost, approvals, proposal("adjustment", "credit-nobody", `10,"currency_type_id":"`+env.currency.String()+
+			`","owner_id":"`+uuid.NewString()+`","direction":"credit"`)},
+		{"ops-a", http.MethodPost, approvals, proposal("transfer", "transfer-nowhere", "10")},
+		{"", http.MethodPost, approvals, mint},
+		{"ops-b", http.MethodGet, approvals + "?status=pending&kind=mint", ""},
+		{"ops-b", http.MethodGet, approvals + "?status=maybe", ""},
+		{"ops-b", http.MethodGet, minted, ""},
+		{"ops-b", http.MethodGet, approvals + "/" + uuid.NewString(), ""},
+		{"ops-a", http.MethodPost, minted + "/approve", ""},
+		{"ops-b", http.MethodPost, minted + "/approve", `{"note":"checked"}`},
+		{"ops-b", http.MethodPost, minted + "/reject", ""},
+		{"ops-a", http.MethodPost, withdrawn + "/reject", `{"note":"wrong amount"}`},
+		{"ops-b", http.MethodPost, approvals + "/" + uuid.NewString() + "/approve", ""},
+		{"ops-b", http.MethodGet, minted + "/audit", ""},
+		{"ops-b", http.MethodGet, approvals + "/" + uuid.NewString() + "/audit", ""},
+		{"ops-b", http.MethodGet, approvals + "/audit?limit=5", ""},
+	}
+	check := func(env *testEnv, method, path, body string) {
+		t.Helper()
+		req, rec := env.do(method, path, body)
+		route, pathParams, err := router.FindRoute(req)
+		if err != nil {
+			t.Errorf("%s %s: %v", method, path, err)
+			return
+		}
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: route},
+			Status:                 rec.Code,
+			Header:                 rec.Header(),
+			Body:                   io.NopCloser(rec.Body),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		})
+		if err != nil {
+			t.Errorf("%s %s answered %d: %v", method, path, rec.Code, err)
+		}
+	}
+	for _, tt := range tests {
+		check(env, tt.method, tt.path, tt.body)
+	}
+	for _, tt := range adminTests {
+		check(env.as(tt.admin), tt.method, tt.path, tt.body)
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	env := newTestEnv(t, true)
+	user := env.user.ID.String()
+	currency := env.currency.String()
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "valid top-up",
+			method:     http.MethodPost,
+			path:       "/api/v1/wallets/top-up",
+			body:       `{"idempotency_key":"k1","owner_id":"` + user + `","currency_type_id":"` + currency + `","amount":5}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "amount below minimum",
+			method:     http.MethodPost,
+			path:       "/api/v1/wallets/top-up",
+			body:       `{"idempotency_key":"k2","owner_id":"` + user + `","currency_type_id":"` + currency + `","amount":0}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field amount",
+		},
+		{
+			name:       "owner is not a uuid",
+			method:     http.MethodPost,
+			path:       "/api/v1/wallets/spend",
+			body:       `{"idempotency_key":"k3","owner_id":"alice","currency_type_id":"` + currency + `","amount":5}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field owner_id",
+		},
+		{
+			name:       "missing idempotency key",
+			method:     http.MethodPost,
+			path:       "/api/v1/wallets/bonus",
+			body:       `{"owner_id":"` + user + `","currency_type_id":"` + currency + `","amount":5}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "idempotency_key",
+		},
+		{
+			name:       "balance without currency",
+			method:     http.MethodGet,
+			path:       "/api/v1/wallets/balance?owner_id=" + user,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "query parameter currency_type_id",
+		},
+		{
+			name:       "user id is not a uuid",
+			method:     http.MethodGet,
+			path:       "/api/v1/users/alice",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "path parameter id",
+		},
+		{
+			name:       "uploads are left to the handler to parse",
+			method:     http.MethodPost,
+			path:       "/api/v1/bulk-jobs",
+			body:       "csv:owner_id,currency_type_id,amount,idempotency_key\n" + user + "," + currency + ",5,k4\n",
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "upload in another format",
+			method:     http.MethodPost,
+			path:       "/api/v1/bulk-jobs",
+			body:       `{"rows":[]}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantError:  "text/csv",
+		},
+		{
+			name:       "unknown row status",
+			method:     http.MethodGet,
+			path:       "/api/v1/bulk-jobs/" + uuid.NewString() + "/rows?status=done",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "query parameter status",
+		},
+		{
+			name:       "schedule of an unknown kind",
+			method:     http.MethodPost,
+			path:       "/api/v1/schedules",
+			body:       `{"owner_id":"` + user + `","currency_type_id":"` + currency + `","direction":"credit","amount":5,"kind":"hourly"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field kind",
+		},
+		{
+			name:       "trade without legs",
+			method:     http.MethodPost,
+			path:       "/api/v1/trades",
+			body:       `{"trade_id":"` + uuid.NewString() + `","legs":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field legs",
+		},
+		{
+			name:       "campaign with an unknown requirement",
+			method:     http.MethodPost,
+			path:       "/api/v1/campaigns",
+			body:       `{"name":"welcome","currency_type_id":"` + currency + `","reward":5,"requirement":"vip"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field requirement",
+		},
+		{
+			name:       "referral without a referee",
+			method:     http.MethodPost,
+			path:       "/api/v1/referrals",
+			body:       `{"referrer_id":"` + user + `"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field referee_id",
+		},
+		{
+			name:       "risk decisions of an unknown outcome",
+			method:     http.MethodGet,
+			path:       "/api/v1/admin/risk/decisions?outcome=maybe",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "query parameter outcome",
+		},
+		{
+			name:       "approval with an unknown kind",
+			method:     http.MethodPost,
+			path:       "/api/v1/admin/approvals",
+			body:       `{"kind":"gift","idempotency_key":"k5","amount":5}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body field kind",
+		},
+		{
+			name:       "undocumented path is left to the router",
+			method:     http.MethodGet,
+			path:       "/api/v1/nope",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rec := env.do(tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body %s, want an error mentioning %q", rec.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	env := newTestEnv(t, false)
+	for path, want := range map[string]string{
+		"/docs/":                       "swagger-ui",
+		"/docs/swagger-initializer.js": `url: "/openapi.json"`,
+		"/docs/swagger-ui.css":         ".swagger-ui",
+		"/openapi.json":                `"openapi":"3.0.3"`,
+	} {
+		_, rec := env.do(http.MethodGet, path, "")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET %s = %d, want 200 containing %q", path, rec.Code, want)
+		}
+	}
+}
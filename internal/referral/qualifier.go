@@ -0,0 +1,83 @@
+// Package referral rewards referrals once the invitee's first top-up
+// qualifies, as the wallet events of their top-ups come in.
+package referral
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+)
+
+const batchSize = 100
+
+// Qualifier returns a background worker that settles the pending referrals
+// whose invitee has topped up in terms.CurrencyTypeID, every interval and
+// whenever this process commits wallet events. openWallet finds or opens
+// the wallets rewards are paid into, such as
+// WalletHandler.CheckUserWalletIfNotCreate. A referral that cannot be paid,
+// say because the treasury is short, is logged and tried again on the next
+// tick.
+func Qualifier(referrals repository.ReferralRepository, openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error),
+	terms repository.ReferralTerms, interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx = consistency.WithPrimary(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			committed := repository.WalletEventsCommitted()
+			settled, err := Qualify(ctx, referrals, openWallet, terms)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "qualifying referrals failed", "error", err)
+			}
+			if settled == batchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			case <-committed:
+			}
+		}
+	}
+}
+
+// Qualify settles one batch of qualifying referrals and returns how many it
+// settled.
+func Qualify(ctx context.Context, referrals repository.ReferralRepository, openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error),
+	terms repository.ReferralTerms) (int, error) {
+	pending, err := referrals.ListQualifying(ctx, terms.CurrencyTypeID, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	settled := 0
+	for _, referral := range pending {
+		if err := qualify(ctx, referrals, openWallet, terms, &referral); err != nil {
+			if ctx.Err() != nil {
+				return settled, ctx.Err()
+			}
+			slog.ErrorContext(ctx, "qualifying referral failed", "referee_id", referral.RefereeID.String(), "error", err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+func qualify(ctx context.Context, referrals repository.ReferralRepository, openWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error),
+	terms repository.ReferralTerms, referral *repository.Referral) error {
+	referrerWallet, err := openWallet(ctx, referral.ReferrerID, terms.CurrencyTypeID)
+	if err != nil {
+		return err
+	}
+	refereeWallet, err := openWallet(ctx, referral.RefereeID, terms.CurrencyTypeID)
+	if err != nil {
+		return err
+	}
+	_, err = referrals.Qualify(ctx, referral.RefereeID, terms, referrerWallet.ID, refereeWallet.ID)
+	return err
+}
@@ -0,0 +1,196 @@
+package referral_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/referral"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fixture is a migrated SQLite database with four users and a gold
+// treasury of 1000. Referrals pay 30 to the referrer and 20 to the invitee
+// for a first top-up of at least 100.
+type fixture struct {
+	db        *gorm.DB
+	referrals repository.ReferralRepository
+	wallets   repository.WalletRepository
+	open      func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	terms     repository.ReferralTerms
+	treasury  uuid.UUID
+	users     [4]uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	f := &fixture{
+		db:        db,
+		referrals: repository.NewReferralRepository(db),
+		wallets:   repository.NewWalletRepository(db),
+		terms:     repository.ReferralTerms{MinTopUp: 100, ReferrerReward: 30, RefereeReward: 20},
+	}
+	f.open = handler.NewWalletHandler(f.wallets, repository.NewUserRepository(db)).CheckUserWalletIfNotCreate
+	for i := range f.users {
+		f.users[i] = uuid.New()
+	}
+	dbtest.Users(t, db, f.users[:]...)
+	var treasury repository.Wallet
+	f.terms.CurrencyTypeID, treasury = dbtest.Currency(t, db, "gold", 1000)
+	f.treasury = treasury.ID
+	return f
+}
+
+func (f *fixture) refer(t *testing.T, referee, referrer uuid.UUID) {
+	t.Helper()
+	if _, created, err := f.referrals.Refer(context.Background(), referee, referrer); err != nil || !created {
+		t.Fatalf("Refer = %v, %v; want a new referral", created, err)
+	}
+}
+
+func (f *fixture) topUp(t *testing.T, owner uuid.UUID, amount int64) {
+	t.Helper()
+	ctx := context.Background()
+	wallet, err := f.open(ctx, owner, f.terms.CurrencyTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, f.treasury.String(), wallet.ID.String(), f.terms.CurrencyTypeID.String(),
+		uuid.NewString(), amount, enums.TransactionTypeTopUp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) balance(t *testing.T, owner uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := f.wallets.GetWalletByOwner(context.Background(), "user", owner.String(), f.terms.CurrencyTypeID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+func (f *fixture) status(t *testing.T, referee uuid.UUID) *repository.Referral {
+	t.Helper()
+	got, err := f.referrals.GetReferral(context.Background(), referee.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestReferralPaysOnceForAQualifyingFirstTopUp(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	alice, bob, carol, dave := f.users[0], f.users[1], f.users[2], f.users[3]
+	f.refer(t, bob, alice)
+	f.refer(t, carol, alice)
+	f.refer(t, dave, bob)
+
+	if settled, err := referral.Qualify(ctx, f.referrals, f.open, f.terms); err != nil || settled != 0 {
+		t.Fatalf("Qualify before any top-up = %d, %v; want nothing settled", settled, err)
+	}
+	f.topUp(t, bob, 150)
+	f.topUp(t, bob, 150)
+	f.topUp(t, carol, 50)
+	f.topUp(t, carol, 500)
+
+	// workers on several instances race for the same referrals
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := referral.Qualify(ctx, f.referrals, f.open, f.terms); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	rewarded := f.status(t, bob)
+	if rewarded.Status != enums.ReferralStatusRewarded || rewarded.QualifyingAmount != 150 || rewarded.ReferenceID == "" {
+		t.Errorf("bob's referral %+v, want rewarded for his first top-up of 150", rewarded)
+	}
+	if got := f.status(t, carol); got.Status != enums.ReferralStatusDisqualified || got.QualifyingAmount != 50 {
+		t.Errorf("carol's referral %+v, want disqualified by her first top-up of 50", got)
+	}
+	if got := f.status(t, dave); got.Status != enums.ReferralStatusPending {
+		t.Errorf("dave's referral %+v, want pending until he tops up", got)
+	}
+	for owner, want := range map[uuid.UUID]int64{alice: 30, bob: 320, carol: 550} {
+		if got := f.balance(t, owner); got != want {
+			t.Errorf("balance %d, want %d", got, want)
+		}
+	}
+	legs, err := f.wallets.GetTransactionByIdempotencyKey(ctx, "referral:"+bob.String())
+	if err != nil || legs.ReferenceID != rewarded.ReferenceID {
+		t.Errorf("payout leg %+v, %v; want it under the referral's reference", legs, err)
+	}
+
+	report, err := f.referrals.Report(ctx, nil, time.Now().UTC().Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Payouts) != 1 || report.Payouts[0].Referrals != 1 || report.Payouts[0].ReferrerRewards != 30 ||
+		report.Payouts[0].RefereeRewards != 20 || report.Disqualified != 1 || report.Pending != 1 ||
+		len(report.TopReferrers) != 1 || report.TopReferrers[0].ReferrerID != alice {
+		t.Errorf("report %+v, want alice's one payout, one disqualified and one pending", report)
+	}
+	audit, err := admin.Reconcile(ctx, f.db)
+	if err != nil || !audit.OK {
+		t.Errorf("Reconcile = %+v, %v; want the ledger to add up", audit, err)
+	}
+}
+
+func TestRefer(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	alice, bob, carol, dave := f.users[0], f.users[1], f.users[2], f.users[3]
+	f.refer(t, bob, alice)
+	f.topUp(t, dave, 10)
+
+	if got, created, err := f.referrals.Refer(ctx, bob, alice); err != nil || created || got.ReferrerID != alice {
+		t.Errorf("Refer of the same pair = %+v, %v, %v; want the referral back", got, created, err)
+	}
+	for name, tt := range map[string]struct {
+		referee, referrer uuid.UUID
+		want              error
+	}{
+		"self":             {carol, carol, repository.ErrSelfReferral},
+		"someone else's":   {bob, carol, repository.ErrAlreadyReferred},
+		"cycle":            {alice, bob, repository.ErrReferralCycle},
+		"after a top-up":   {dave, alice, repository.ErrAlreadyToppedUp},
+		"unknown referrer": {carol, uuid.New(), gorm.ErrRecordNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := f.referrals.Refer(ctx, tt.referee, tt.referrer); !errors.Is(err, tt.want) {
+				t.Errorf("Refer = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	var user repository.User
+	if err := f.db.Where("id = ?", bob).First(&user).Error; err != nil || user.ReferredBy == nil || *user.ReferredBy != alice {
+		t.Errorf("bob's user %+v, %v; want him referred by alice", user, err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package risk screens spends and transfers before they move funds. Rules
+// look at the operation and its owner's history and allow it, deny it or
+// hold it for an admin's review; every decision is recorded.
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+var (
+	ErrDenied = errors.New("operation denied by risk rules")
+	ErrHeld   = errors.New("operation held for review")
+)
+
+// Operation is a spend or transfer about to be applied.
+type Operation struct {
+	// Kind is enums.RiskOperationSpend or enums.RiskOperationTransfer.
+	Kind    string
+	OwnerID uuid.UUID
+	// ToOwnerID is the recipient of a transfer.
+	ToOwnerID      *uuid.UUID
+	CurrencyTypeID uuid.UUID
+	Amount         int64
+	IdempotencyKey string
+}
+
+// Verdict is a rule's objection to an operation.
+type Verdict struct {
+	// Outcome is enums.RiskOutcomeDeny or enums.RiskOutcomeReview.
+	Outcome string
+	Reason  string
+}
+
+// Rule is one check of an operation. Check returns nil when the rule has no
+// objection.
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, op Operation, history repository.RiskHistory) (*Verdict, error)
+}
+
+// Evaluator decides whether an operation may go ahead. The same idempotency
+// key always gets the same decision, including once a review settled it.
+type Evaluator interface {
+	Evaluate(ctx context.Context, op Operation) (*repository.RiskDecision, error)
+}
+
+// Engine is the Evaluator that runs rules in order and records the
+// decision: the first denial wins, otherwise the first review, otherwise the
+// operation is allowed.
+type Engine struct {
+	decisions repository.RiskRepository
+	rules     []Rule
+}
+
+func NewEngine(decisions repository.RiskRepository, rules ...Rule) *Engine {
+	return &Engine{decisions: decisions, rules: rules}
+}
+
+// Evaluate implements Evaluator.
+func (e *Engine) Evaluate(ctx context.Context, op Operation) (*repository.RiskDecision, error) {
+	proposed := &repository.RiskDecision{
+		Operation:      op.Kind,
+		OwnerID:        op.OwnerID,
+		ToOwnerID:      op.ToOwnerID,
+		CurrencyTypeID: op.CurrencyTypeID,
+		Amount:         op.Amount,
+		IdempotencyKey: op.IdempotencyKey,
+		Outcome:        enums.RiskOutcomeAllow,
+	}
+	decision, _, err := e.decisions.Decide(ctx, proposed, func(history repository.RiskHistory) error {
+		for _, rule := range e.rules {
+			verdict, err := rule.Check(ctx, op, history)
+			if err != nil {
+				return fmt.Errorf("risk rule %s: %w", rule.Name(), err)
+			}
+			if verdict == nil || proposed.Outcome == enums.RiskOutcomeReview && verdict.Outcome == enums.RiskOutcomeReview {
+				continue
+			}
+			proposed.Outcome, proposed.Rule, proposed.Reason = verdict.Outcome, rule.Name(), verdict.Reason
+			if verdict.Outcome == enums.RiskOutcomeDeny {
+				break
+			}
+		}
+		if proposed.Outcome == enums.RiskOutcomeReview {
+			proposed.ReviewStatus = enums.RiskReviewPending
+		}
+		return nil
+	})
+	return decision, err
+}
+
+// Err returns ErrDenied for a decision that stops the operation for good and
+// ErrHeld for one awaiting review, with the rule's reason; nil lets the
+// operation go ahead.
+func Err(decision *repository.RiskDecision) error {
+	switch {
+	case decision.Outcome == enums.RiskOutcomeDeny:
+		return fmt.Errorf("%w: %s", ErrDenied, decision.Reason)
+	case decision.ReviewStatus == enums.RiskReviewRejected:
+		return fmt.Errorf("%w: rejected on review", ErrDenied)
+	case decision.ReviewStatus == enums.RiskReviewPending:
+		return fmt.Errorf("%w as decision %s: %s", ErrHeld, decision.ID, decision.Reason)
+	}
+	return nil
+}
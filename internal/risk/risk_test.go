@@ -0,0 +1,241 @@
+package risk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/risk"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fixture is a migrated SQLite database with a gold treasury of 1000, a
+// user who signed up a year ago and one who just did.
+type fixture struct {
+	decisions repository.RiskRepository
+	wallets   repository.WalletRepository
+	open      func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+	currency  uuid.UUID
+	treasury  uuid.UUID
+	veteran   uuid.UUID
+	newcomer  uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	users := repository.NewUserRepository(db)
+	f := &fixture{
+		decisions: repository.NewRiskRepository(db),
+		wallets:   repository.NewWalletRepository(db),
+		veteran:   uuid.New(),
+		newcomer:  uuid.New(),
+	}
+	f.open = handler.NewWalletHandler(f.wallets, users).CheckUserWalletIfNotCreate
+	yearAgo := time.Now().UTC().AddDate(-1, 0, 0)
+	if err := users.CreateUser(context.Background(), &repository.User{ID: f.veteran, Name: "veteran", Role: "user",
+		BaseTimeStamps: repository.BaseTimeStamps{CreatedAt: yearAgo, UpdatedAt: yearAgo}}); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Users(t, db, f.newcomer)
+	var treasury repository.Wallet
+	f.currency, treasury = dbtest.Currency(t, db, "gold", 1000)
+	f.treasury = treasury.ID
+	return f
+}
+
+// spend debits the owner's wallet as a spend would, after topping it up.
+func (f *fixture) spend(t *testing.T, owner uuid.UUID, amount int64) {
+	t.Helper()
+	ctx := context.Background()
+	wallet, err := f.open(ctx, owner, f.currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, f.treasury.String(), wallet.ID.String(), f.currency.String(),
+		uuid.NewString(), amount, enums.TransactionTypeTopUp); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, wallet.ID.String(), f.treasury.String(), f.currency.String(),
+		uuid.NewString(), amount, enums.TransactionTypeSpend); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) operation(owner uuid.UUID, amount int64) risk.Operation {
+	return risk.Operation{Kind: enums.RiskOperationSpend, OwnerID: owner, CurrencyTypeID: f.currency,
+		Amount: amount, IdempotencyKey: uuid.NewString()}
+}
+
+func TestRules(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	blocked := uuid.New()
+	for range 5 {
+		f.spend(t, f.veteran, 10)
+	}
+	f.spend(t, f.newcomer, 10)
+	engine := risk.NewEngine(f.decisions, risk.Rules(config_env.RiskConfig{
+		BlockedOwners:           []string{blocked.String()},
+		NewAccountAge:           time.Hour,
+		NewAccountMaxAmount:     50,
+		UnusualAmountFactor:     3,
+		UnusualAmountMinHistory: 5,
+		HistoryWindow:           time.Hour,
+	})...)
+	transferTo := func(op risk.Operation, recipient uuid.UUID) risk.Operation {
+		op.Kind, op.ToOwnerID = enums.RiskOperationTransfer, &recipient
+		return op
+	}
+
+	for name, tt := range map[string]struct {
+		op          risk.Operation
+		wantOutcome string
+		wantRule    string
+	}{
+		"usual amount":             {f.operation(f.veteran, 30), enums.RiskOutcomeAllow, ""},
+		"unusual amount":           {f.operation(f.veteran, 31), enums.RiskOutcomeReview, "unusual_amount"},
+		"too little history":       {f.operation(f.newcomer, 40), enums.RiskOutcomeAllow, ""},
+		"new account over the cap": {f.operation(f.newcomer, 60), enums.RiskOutcomeReview, "new_account"},
+		"to a blocked recipient":   {transferTo(f.operation(f.veteran, 1), blocked), enums.RiskOutcomeDeny, "blocked_owner"},
+		"deny beats review":        {transferTo(f.operation(f.newcomer, 60), blocked), enums.RiskOutcomeDeny, "blocked_owner"},
+		"old account over the cap": {f.operation(f.veteran, 25), enums.RiskOutcomeAllow, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			decision, err := engine.Evaluate(ctx, tt.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Outcome != tt.wantOutcome || decision.Rule != tt.wantRule {
+				t.Errorf("decision %+v, want %s by %q", decision, tt.wantOutcome, tt.wantRule)
+			}
+			wantHeld := tt.wantOutcome == enums.RiskOutcomeReview
+			if held := decision.ReviewStatus == enums.RiskReviewPending; held != wantHeld {
+				t.Errorf("review status %q, want held %v", decision.ReviewStatus, wantHeld)
+			}
+			again, err := engine.Evaluate(ctx, tt.op)
+			if err != nil || again.ID != decision.ID {
+				t.Errorf("Evaluate of the same key = %+v, %v; want decision %s again", again, err, decision.ID)
+			}
+		})
+	}
+	decisions, err := f.decisions.ListDecisions(ctx, repository.RiskDecisionFilter{}, 100)
+	if err != nil || len(decisions) != 7 {
+		t.Errorf("ListDecisions = %d decisions, %v; want every one recorded once", len(decisions), err)
+	}
+}
+
+func TestVelocityHoldsUnderConcurrency(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	engine := risk.NewEngine(f.decisions, risk.Velocity(3, time.Minute))
+
+	// a script fires ten spends at once
+	ops := make([]risk.Operation, 10)
+	for i := range ops {
+		ops[i] = f.operation(f.veteran, 1)
+	}
+	outcomes := make(chan string, len(ops))
+	var wg sync.WaitGroup
+	for _, op := range ops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := engine.Evaluate(ctx, op)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			outcomes <- decision.Outcome
+		}()
+	}
+	wg.Wait()
+	close(outcomes)
+	counts := map[string]int{}
+	for outcome := range outcomes {
+		counts[outcome]++
+	}
+	if counts[enums.RiskOutcomeAllow] != 3 || counts[enums.RiskOutcomeDeny] != 7 {
+		t.Errorf("outcomes %v, want 3 allowed and 7 denied", counts)
+	}
+	decision, err := engine.Evaluate(ctx, f.operation(f.newcomer, 1))
+	if err != nil || decision.Outcome != enums.RiskOutcomeAllow {
+		t.Errorf("another owner's spend = %+v, %v; want it allowed", decision, err)
+	}
+}
+
+func TestReview(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	engine := risk.NewEngine(f.decisions, risk.NewAccount(time.Hour, 50))
+	wallet, err := f.open(ctx, f.newcomer, f.currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, f.treasury.String(), wallet.ID.String(), f.currency.String(),
+		"top-up", 100, enums.TransactionTypeTopUp); err != nil {
+		t.Fatal(err)
+	}
+	release := func(ctx context.Context, decision *repository.RiskDecision) (string, error) {
+		if err := f.wallets.Transfer(ctx, wallet.ID.String(), f.treasury.String(), f.currency.String(),
+			decision.IdempotencyKey, decision.Amount, enums.TransactionTypeSpend); err != nil {
+			return "", err
+		}
+		transaction, err := f.wallets.GetTransactionByIdempotencyKey(ctx, decision.IdempotencyKey)
+		if err != nil {
+			return "", err
+		}
+		return transaction.ReferenceID, nil
+	}
+
+	tooMuch, err := engine.Evaluate(ctx, f.operation(f.newcomer, 150))
+	if err != nil || !errors.Is(risk.Err(tooMuch), risk.ErrHeld) {
+		t.Fatalf("Evaluate = %+v, %v; want it held", tooMuch, err)
+	}
+	if _, err := f.decisions.Approve(ctx, tooMuch.ID.String(), "ops", release); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Errorf("Approve beyond the balance = %v, want ErrInsufficientBalance", err)
+	}
+	if got, err := f.decisions.GetDecision(ctx, tooMuch.ID.String()); err != nil || got.ReviewStatus != enums.RiskReviewPending || got.ReviewedBy != "" {
+		t.Errorf("decision after a failed approval %+v, %v; want it pending again", got, err)
+	}
+	rejected, err := f.decisions.Reject(ctx, tooMuch.ID.String(), "ops")
+	if err != nil || rejected.ReviewStatus != enums.RiskReviewRejected || !errors.Is(risk.Err(rejected), risk.ErrDenied) {
+		t.Errorf("Reject = %+v, %v; want it rejected for good", rejected, err)
+	}
+
+	held, err := engine.Evaluate(ctx, f.operation(f.newcomer, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	approved, err := f.decisions.Approve(ctx, held.ID.String(), "ops", release)
+	if err != nil || approved.ReviewStatus != enums.RiskReviewApproved || approved.ReviewedBy != "ops" || approved.ReferenceID == "" {
+		t.Fatalf("Approve = %+v, %v; want it approved and applied", approved, err)
+	}
+	if err := risk.Err(approved); err != nil {
+		t.Errorf("Err of an approved decision = %v, want nil", err)
+	}
+	for _, id := range []uuid.UUID{held.ID, tooMuch.ID} {
+		if _, err := f.decisions.Approve(ctx, id.String(), "ops", release); !errors.Is(err, repository.ErrNotUnderReview) {
+			t.Errorf("second review = %v, want ErrNotUnderReview", err)
+		}
+	}
+	balance, err := f.wallets.GetWalletByID(ctx, wallet.ID.String())
+	if err != nil || balance.Balance != 20 {
+		t.Errorf("wallet %+v, %v; want 20 left after the approved spend", balance, err)
+	}
+}
@@ -0,0 +1,135 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+)
+
+// Rules returns the built-in rules cfg turns on, cheapest first.
+func Rules(cfg config_env.RiskConfig) []Rule {
+	var rules []Rule
+	if len(cfg.BlockedOwners) > 0 {
+		blocked := make([]uuid.UUID, 0, len(cfg.BlockedOwners))
+		for _, owner := range cfg.BlockedOwners {
+			blocked = append(blocked, uuid.MustParse(owner))
+		}
+		rules = append(rules, BlockedOwners(blocked...))
+	}
+	if cfg.NewAccountAge > 0 {
+		rules = append(rules, NewAccount(cfg.NewAccountAge, cfg.NewAccountMaxAmount))
+	}
+	if cfg.VelocityLimit > 0 {
+		rules = append(rules, Velocity(cfg.VelocityLimit, cfg.VelocityWindow))
+	}
+	if cfg.UnusualAmountFactor > 0 {
+		rules = append(rules, UnusualAmount(cfg.UnusualAmountFactor, cfg.UnusualAmountMinHistory, cfg.HistoryWindow))
+	}
+	return rules
+}
+
+type blockedOwners map[uuid.UUID]bool
+
+// BlockedOwners denies every operation sent or received by the owners.
+func BlockedOwners(owners ...uuid.UUID) Rule {
+	blocked := blockedOwners{}
+	for _, owner := range owners {
+		blocked[owner] = true
+	}
+	return blocked
+}
+
+func (blockedOwners) Name() string { return "blocked_owner" }
+
+func (b blockedOwners) Check(_ context.Context, op Operation, _ repository.RiskHistory) (*Verdict, error) {
+	if b[op.OwnerID] {
+		return &Verdict{Outcome: enums.RiskOutcomeDeny, Reason: "owner is blocked"}, nil
+	}
+	if op.ToOwnerID != nil && b[*op.ToOwnerID] {
+		return &Verdict{Outcome: enums.RiskOutcomeDeny, Reason: "recipient is blocked"}, nil
+	}
+	return nil, nil
+}
+
+type newAccount struct {
+	age       time.Duration
+	maxAmount int64
+}
+
+// NewAccount holds for review amounts over maxAmount from owners whose user
+// was created less than age ago.
+func NewAccount(age time.Duration, maxAmount int64) Rule {
+	return newAccount{age: age, maxAmount: maxAmount}
+}
+
+func (newAccount) Name() string { return "new_account" }
+
+func (n newAccount) Check(_ context.Context, op Operation, history repository.RiskHistory) (*Verdict, error) {
+	if op.Amount <= n.maxAmount || time.Since(history.OwnerSince()) >= n.age {
+		return nil, nil
+	}
+	return &Verdict{Outcome: enums.RiskOutcomeReview,
+		Reason: fmt.Sprintf("accounts younger than %s may move at most %d without review", n.age, n.maxAmount)}, nil
+}
+
+type velocity struct {
+	limit  int64
+	window time.Duration
+}
+
+// Velocity denies an owner's operation once they attempted limit operations
+// within window. Denied attempts count, so a script that keeps trying stays
+// denied until it pauses.
+func Velocity(limit int64, window time.Duration) Rule {
+	return velocity{limit: limit, window: window}
+}
+
+func (velocity) Name() string { return "velocity" }
+
+func (v velocity) Check(_ context.Context, _ Operation, history repository.RiskHistory) (*Verdict, error) {
+	attempts, err := history.Attempts(time.Now().UTC().Add(-v.window))
+	if err != nil {
+		return nil, err
+	}
+	if attempts < v.limit {
+		return nil, nil
+	}
+	return &Verdict{Outcome: enums.RiskOutcomeDeny,
+		Reason: fmt.Sprintf("at most %d operations are allowed within %s", v.limit, v.window)}, nil
+}
+
+type unusualAmount struct {
+	factor     float64
+	minHistory int64
+	window     time.Duration
+}
+
+// UnusualAmount holds for review amounts over factor times the owner's
+// average debit in the currency within window. Owners with fewer than
+// minHistory debits have no usual amount yet.
+func UnusualAmount(factor float64, minHistory int64, window time.Duration) Rule {
+	return unusualAmount{factor: factor, minHistory: minHistory, window: window}
+}
+
+func (unusualAmount) Name() string { return "unusual_amount" }
+
+func (u unusualAmount) Check(_ context.Context, op Operation, history repository.RiskHistory) (*Verdict, error) {
+	count, total, err := history.Debits(op.CurrencyTypeID, time.Now().UTC().Add(-u.window))
+	if err != nil {
+		return nil, err
+	}
+	if count < u.minHistory {
+		return nil, nil
+	}
+	average := float64(total) / float64(count)
+	if float64(op.Amount) <= u.factor*average {
+		return nil, nil
+	}
+	return &Verdict{Outcome: enums.RiskOutcomeReview,
+		Reason: fmt.Sprintf("amount is over %g times the average debit of %.0f", u.factor, average)}, nil
+}
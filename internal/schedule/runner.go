@@ -0,0 +1,218 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/consistency"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/lifecycle"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultLease     = time.Minute
+	releaseTimeout   = 5 * time.Second
+)
+
+// OpenWallet returns the owner's wallet in a currency, opening it if needed.
+type OpenWallet func(ctx context.Context, ownerID, currencyTypeID uuid.UUID) (*repository.Wallet, error)
+
+type RunnerOptions struct {
+	// Interval between looks for due schedules.
+	Interval time.Duration
+	// BatchSize is how many due schedules are claimed at a time; 0 is 100.
+	BatchSize int
+	// Lease is how long a claimed schedule is held before another instance
+	// may take it over; 0 is one minute.
+	Lease time.Duration
+}
+
+// Runner executes due schedules. An occurrence that fails is attempted again
+// after the schedule's retry delay, up to its retries; after the last
+// attempt its failure policy skips to the next occurrence, pauses the
+// schedule or cancels it. An occurrence missed while no Runner was running
+// fires once when one is back, and any others missed meanwhile are skipped.
+type Runner struct {
+	schedules  repository.ScheduleRepository
+	wallets    repository.WalletRepository
+	openWallet OpenWallet
+	options    RunnerOptions
+	owner      string
+}
+
+func NewRunner(schedules repository.ScheduleRepository, wallets repository.WalletRepository, openWallet OpenWallet, options RunnerOptions) *Runner {
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if options.Lease <= 0 {
+		options.Lease = defaultLease
+	}
+	return &Runner{schedules: schedules, wallets: wallets, openWallet: openWallet, options: options, owner: lifecycle.InstanceID()}
+}
+
+// Run executes schedules as they fall due until ctx is cancelled; it is
+// meant for lifecycle.Manager.Go.
+func (r *Runner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+	for {
+		ran, err := r.RunDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "running schedules failed", "error", err)
+		}
+		if ran == r.options.BatchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims a batch of due schedules and attempts the occurrence each
+// is due for. It returns how many it claimed.
+func (r *Runner) RunDue(ctx context.Context) (int, error) {
+	// schedules are read back right after they are advanced
+	ctx = consistency.WithPrimary(ctx)
+	due, err := r.schedules.ClaimDue(ctx, r.owner, r.options.Lease, r.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for i := range due {
+		if ctx.Err() != nil {
+			// hand back the rest at once rather than when their leases expire
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+			for _, s := range due[i:] {
+				errs = append(errs, r.schedules.Release(releaseCtx, s.ID.String(), r.owner))
+			}
+			cancel()
+			break
+		}
+		errs = append(errs, r.run(ctx, &due[i]))
+	}
+	return len(due), errors.Join(errs...)
+}
+
+// run attempts the schedule's current occurrence and advances it according
+// to the outcome.
+func (r *Runner) run(ctx context.Context, s *repository.Schedule) error {
+	log := slog.With("schedule_id", s.ID.String())
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	if s.OccurrenceAt == nil {
+		// resumed after it ran out of occurrences
+		s.Status, s.NextRunAt = enums.ScheduleStatusCompleted, nil
+		return r.schedules.Advance(saveCtx, s, r.owner, nil)
+	}
+
+	occurrence := *s.OccurrenceAt
+	run := &repository.ScheduleRun{OccurrenceAt: occurrence, IdempotencyKey: IdempotencyKey(s.ID, occurrence)}
+	err := r.execute(ctx, s, run)
+	if ctx.Err() != nil {
+		// the outcome is unknown; the next attempt finds it by its key
+		return r.schedules.Release(saveCtx, s.ID.String(), r.owner)
+	}
+	now := time.Now().UTC()
+	s.LastRunAt = &now
+	run.Attempts = s.Attempts + 1
+	if err == nil {
+		run.Status = enums.ScheduleRunStatusSucceeded
+		s.RunCount++
+		s.LastError = ""
+		advance(s, now)
+		log.InfoContext(ctx, "schedule ran", "occurrence_at", occurrence, "reference_id", run.ReferenceID, "replayed", run.Replayed)
+		return r.schedules.Advance(saveCtx, s, r.owner, run)
+	}
+
+	s.Attempts++
+	s.LastError = err.Error()
+	if s.Attempts <= s.MaxRetries {
+		retryAt := now.Add(time.Duration(s.RetryDelaySeconds) * time.Second)
+		s.NextRunAt = &retryAt
+		log.WarnContext(ctx, "schedule attempt failed", "occurrence_at", occurrence, "attempts", s.Attempts, "error", err)
+		return r.schedules.Advance(saveCtx, s, r.owner, nil)
+	}
+	run.Status, run.Error = enums.ScheduleRunStatusFailed, err.Error()
+	s.FailureCount++
+	switch s.OnFailure {
+	case enums.ScheduleFailurePause:
+		// the failed occurrence is attempted again when resumed
+		s.Status = enums.ScheduleStatusPaused
+	case enums.ScheduleFailureCancel:
+		s.Status, s.NextRunAt = enums.ScheduleStatusCancelled, nil
+	default:
+		advance(s, now)
+	}
+	log.WarnContext(ctx, "schedule occurrence failed", "occurrence_at", occurrence, "attempts", run.Attempts,
+		"on_failure", s.OnFailure, "error", err)
+	return r.schedules.Advance(saveCtx, s, r.owner, run)
+}
+
+// advance moves the schedule on to its first occurrence after now, or
+// completes it when it has none left.
+func advance(s *repository.Schedule, now time.Time) {
+	s.Attempts = 0
+	after := now
+	if s.OccurrenceAt.After(after) {
+		after = *s.OccurrenceAt
+	}
+	next, ok := Next(s, after)
+	if !ok {
+		s.Status, s.OccurrenceAt, s.NextRunAt = enums.ScheduleStatusCompleted, nil, nil
+		return
+	}
+	s.OccurrenceAt, s.NextRunAt = &next, &next
+}
+
+// execute moves the occurrence's amount through the ledger, or finds it
+// already moved, and records the transaction on run.
+func (r *Runner) execute(ctx context.Context, s *repository.Schedule, run *repository.ScheduleRun) error {
+	transaction, err := r.wallets.GetTransactionByIdempotencyKey(ctx, run.IdempotencyKey)
+	if err == nil {
+		run.ReferenceID, run.Replayed = transaction.ReferenceID, true
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := r.transfer(ctx, s, run.IdempotencyKey); err != nil {
+		return err
+	}
+	transaction, err = r.wallets.GetTransactionByIdempotencyKey(ctx, run.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+	run.ReferenceID = transaction.ReferenceID
+	return nil
+}
+
+func (r *Runner) transfer(ctx context.Context, s *repository.Schedule, idempotencyKey string) error {
+	treasury, err := r.wallets.GetSystemWalletByCurrencyType(ctx, s.CurrencyTypeID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("currency has no treasury wallet")
+	}
+	if err != nil {
+		return err
+	}
+	wallet, err := r.openWallet(ctx, s.OwnerID, s.CurrencyTypeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("owner not found")
+	}
+	if err != nil {
+		return err
+	}
+	from, to, transactionType := treasury.ID.String(), wallet.ID.String(), enums.TransactionTypeBonus
+	if s.Direction == enums.ScheduleDirectionDebit {
+		from, to, transactionType = to, from, enums.TransactionTypeSpend
+	}
+	return r.wallets.Transfer(ctx, from, to, s.CurrencyTypeID.String(), idempotencyKey, s.Amount, enums.TransactionType(transactionType))
+}
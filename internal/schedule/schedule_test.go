@@ -0,0 +1,399 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/dbtest"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/jay6909/dino-internal-wallet-service/internal/handler"
+	"github.com/jay6909/dino-internal-wallet-service/internal/schedule"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPrepareAndNext(t *testing.T) {
+	end := date("2026-01-20T00:00:00Z")
+	tests := []struct {
+		name      string
+		schedule  repository.Schedule
+		start     string
+		wantFirst string
+		// after and wantNext check Next; an empty wantNext means none left
+		after    string
+		wantNext string
+		wantErr  string
+	}{
+		{
+			name:      "once",
+			schedule:  repository.Schedule{Kind: enums.ScheduleKindOnce, Timezone: "UTC"},
+			start:     "2026-01-01T10:00:00.5Z",
+			wantFirst: "2026-01-01T10:00:00Z",
+			after:     "2026-01-01T10:00:00Z",
+		},
+		{
+			name:      "interval skips missed occurrences",
+			schedule:  repository.Schedule{Kind: enums.ScheduleKindInterval, IntervalSeconds: 86400, Timezone: "UTC"},
+			start:     "2026-01-01T10:00:00Z",
+			wantFirst: "2026-01-01T10:00:00Z",
+			after:     "2026-01-03T12:00:00Z",
+			wantNext:  "2026-01-04T10:00:00Z",
+		},
+		{
+			name:      "interval until end_at",
+			schedule:  repository.Schedule{Kind: enums.ScheduleKindInterval, IntervalSeconds: 7 * 86400, Timezone: "UTC", EndAt: &end},
+			start:     "2026-01-01T10:00:00Z",
+			wantFirst: "2026-01-01T10:00:00Z",
+			after:     "2026-01-15T10:00:00Z",
+		},
+		{
+			name:      "cron in a timezone",
+			schedule:  repository.Schedule{Kind: enums.ScheduleKindCron, Cron: "0 9 * * 1", Timezone: "Europe/Berlin"},
+			start:     "2026-01-01T00:00:00Z",
+			wantFirst: "2026-01-05T08:00:00Z",
+			after:     "2026-06-23T00:00:00Z",
+			wantNext:  "2026-06-29T07:00:00Z",
+		},
+		{
+			name:      "cron starting on an occurrence",
+			schedule:  repository.Schedule{Kind: enums.ScheduleKindCron, Cron: "@daily", Timezone: "UTC"},
+			start:     "2026-01-02T00:00:00Z",
+			wantFirst: "2026-01-02T00:00:00Z",
+			after:     "2026-01-02T00:00:00Z",
+			wantNext:  "2026-01-03T00:00:00Z",
+		},
+		{
+			name:     "interval too short",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindInterval, IntervalSeconds: 30, Timezone: "UTC"},
+			wantErr:  "at least 1m0s",
+		},
+		{
+			name:     "cron with @every",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindCron, Cron: "@every 1s", Timezone: "UTC"},
+			wantErr:  "use interval",
+		},
+		{
+			name:     "malformed cron",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindCron, Cron: "0 9 * *", Timezone: "UTC"},
+			wantErr:  "invalid cron",
+		},
+		{
+			name:     "once with a cron",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindOnce, Cron: "@daily", Timezone: "UTC"},
+			wantErr:  "neither cron nor interval",
+		},
+		{
+			name:     "unknown timezone",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindOnce, Timezone: "Mars/Olympus"},
+			wantErr:  "unknown timezone",
+		},
+		{
+			name:     "ends before it starts",
+			schedule: repository.Schedule{Kind: enums.ScheduleKindOnce, Timezone: "UTC", EndAt: &end},
+			start:    "2026-02-01T00:00:00Z",
+			wantErr:  "end_at",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.schedule
+			start := time.Now()
+			if tt.start != "" {
+				start = date(tt.start)
+			}
+			err := schedule.Prepare(&s, start)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Status != enums.ScheduleStatusActive || !s.OccurrenceAt.Equal(date(tt.wantFirst)) || !s.NextRunAt.Equal(*s.OccurrenceAt) {
+				t.Fatalf("prepared %s at %v, next run %v; want active at %s", s.Status, s.OccurrenceAt, s.NextRunAt, tt.wantFirst)
+			}
+			next, ok := schedule.Next(&s, date(tt.after))
+			if tt.wantNext == "" {
+				if ok {
+					t.Errorf("Next = %v, want none left", next)
+				}
+				return
+			}
+			if !ok || !next.Equal(date(tt.wantNext)) {
+				t.Errorf("Next = %v, %v; want %s", next, ok, tt.wantNext)
+			}
+		})
+	}
+}
+
+// fixture is a migrated SQLite database with two users, a currency with a
+// treasury of 1000 and a runner.
+type fixture struct {
+	schedules repository.ScheduleRepository
+	wallets   repository.WalletRepository
+	users     repository.UserRepository
+	currency  uuid.UUID
+	alice     uuid.UUID
+	bob       uuid.UUID
+	runner    *schedule.Runner
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	f := &fixture{
+		schedules: repository.NewScheduleRepository(db),
+		wallets:   repository.NewWalletRepository(db),
+		users:     repository.NewUserRepository(db),
+		alice:     uuid.New(),
+		bob:       uuid.New(),
+	}
+	f.currency, _ = dbtest.Currency(t, db, "diamonds", 1000)
+	dbtest.Users(t, db, f.alice, f.bob)
+	f.runner = schedule.NewRunner(f.schedules, f.wallets, handler.NewWalletHandler(f.wallets, f.users).CheckUserWalletIfNotCreate,
+		schedule.RunnerOptions{Interval: time.Hour})
+	return f
+}
+
+// create stores a schedule for owner prepared to start at start.
+func (f *fixture) create(t *testing.T, s repository.Schedule, start time.Time) *repository.Schedule {
+	t.Helper()
+	s.ID, s.CurrencyTypeID, s.Timezone = uuid.New(), f.currency, "UTC"
+	if s.OnFailure == "" {
+		s.OnFailure = enums.ScheduleFailureSkip
+	}
+	if err := schedule.Prepare(&s, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.schedules.CreateSchedule(context.Background(), &s); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+// grant credits owner from the treasury under key.
+func (f *fixture) grant(t *testing.T, owner uuid.UUID, amount int64, key string) {
+	t.Helper()
+	ctx := context.Background()
+	wallet, err := handler.NewWalletHandler(f.wallets, f.users).CheckUserWalletIfNotCreate(ctx, owner, f.currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury, err := f.wallets.GetSystemWalletByCurrencyType(ctx, f.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(), f.currency.String(), key, amount, enums.TransactionTypeBonus); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) runDue(t *testing.T, want int) {
+	t.Helper()
+	ran, err := f.runner.RunDue(context.Background())
+	if err != nil || ran != want {
+		t.Fatalf("RunDue = %d, %v; want %d schedules run", ran, err, want)
+	}
+}
+
+func (f *fixture) get(t *testing.T, id uuid.UUID) *repository.Schedule {
+	t.Helper()
+	s, err := f.schedules.GetSchedule(context.Background(), id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (f *fixture) runs(t *testing.T, id uuid.UUID) []repository.ScheduleRun {
+	t.Helper()
+	runs, err := f.schedules.ListRuns(context.Background(), id.String(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func (f *fixture) balance(t *testing.T, owner uuid.UUID) int64 {
+	t.Helper()
+	wallet, err := f.wallets.GetWalletByOwner(context.Background(), "user", owner.String(), f.currency.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+func TestRunnerFiresEachOccurrenceOnce(t *testing.T) {
+	f := newFixture(t)
+	now := time.Now().UTC()
+	f.grant(t, f.alice, 250, "starter")
+	pass := f.create(t, repository.Schedule{OwnerID: f.alice, Direction: enums.ScheduleDirectionDebit, Amount: 100,
+		Kind: enums.ScheduleKindInterval, IntervalSeconds: 7 * 86400}, now.Add(-time.Minute))
+	// two days of rewards were missed while no runner was up
+	reward := f.create(t, repository.Schedule{OwnerID: f.bob, Direction: enums.ScheduleDirectionCredit, Amount: 10,
+		Kind: enums.ScheduleKindCron, Cron: "@daily"}, now.Add(-48*time.Hour))
+	// a worker that crashed before recording the charge had already applied it
+	f.grant(t, f.bob, 1, "bob")
+	occurrence := *pass.OccurrenceAt
+	if got := schedule.IdempotencyKey(pass.ID, occurrence); len(got) > 64 || !strings.HasPrefix(got, "sched:"+pass.ID.String()+":") {
+		t.Fatalf("key %q, want sched:<id>:<unix> within 64 bytes", got)
+	}
+
+	f.runDue(t, 2)
+	f.runDue(t, 0)
+	got := f.get(t, pass.ID)
+	if got.Status != enums.ScheduleStatusActive || got.RunCount != 1 || !got.OccurrenceAt.Equal(occurrence.Add(7*24*time.Hour)) {
+		t.Errorf("pass after a run %+v, want active with the next week due", got)
+	}
+	if got := f.balance(t, f.alice); got != 150 {
+		t.Errorf("alice's balance %d, want 150 after one charge", got)
+	}
+	runs := f.runs(t, pass.ID)
+	if len(runs) != 1 || runs[0].Status != enums.ScheduleRunStatusSucceeded || runs[0].ReferenceID == "" ||
+		runs[0].IdempotencyKey != schedule.IdempotencyKey(pass.ID, occurrence) {
+		t.Errorf("runs %+v, want one succeeded under the occurrence's key", runs)
+	}
+	got = f.get(t, reward.ID)
+	if got.RunCount != 1 || !got.OccurrenceAt.After(now) {
+		t.Errorf("reward %+v, want one run and the missed day skipped", got)
+	}
+	if got := f.balance(t, f.bob); got != 11 {
+		t.Errorf("bob's balance %d, want 11 after one reward", got)
+	}
+}
+
+func TestRunnerReplaysAnAppliedOccurrence(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	s := f.create(t, repository.Schedule{OwnerID: f.alice, Direction: enums.ScheduleDirectionCredit, Amount: 10,
+		Kind: enums.ScheduleKindOnce}, time.Now())
+	// applied by a worker that crashed before recording it
+	f.grant(t, f.alice, 10, schedule.IdempotencyKey(s.ID, *s.OccurrenceAt))
+
+	f.runDue(t, 1)
+	if got := f.get(t, s.ID); got.Status != enums.ScheduleStatusCompleted || got.OccurrenceAt != nil || got.NextRunAt != nil {
+		t.Errorf("schedule %+v, want completed with nothing due", got)
+	}
+	if runs := f.runs(t, s.ID); len(runs) != 1 || !runs[0].Replayed {
+		t.Errorf("runs %+v, want one replay", runs)
+	}
+	if got := f.balance(t, f.alice); got != 10 {
+		t.Errorf("balance %d, want 10", got)
+	}
+	if _, err := f.schedules.Resume(ctx, s.ID.String()); !errors.Is(err, repository.ErrScheduleFinished) {
+		t.Errorf("Resume of a completed schedule = %v, want ErrScheduleFinished", err)
+	}
+}
+
+func TestRunnerFailurePolicies(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	debit := func(kind, onFailure string, retries int) *repository.Schedule {
+		s := repository.Schedule{OwnerID: f.alice, Direction: enums.ScheduleDirectionDebit, Amount: 100,
+			Kind: kind, OnFailure: onFailure, MaxRetries: retries}
+		if kind == enums.ScheduleKindInterval {
+			s.IntervalSeconds = 86400
+		}
+		return f.create(t, s, time.Now())
+	}
+	paused := debit(enums.ScheduleKindInterval, enums.ScheduleFailurePause, 1)
+	occurrence := *paused.OccurrenceAt
+	skipped := debit(enums.ScheduleKindOnce, enums.ScheduleFailureSkip, 0)
+	cancelled := debit(enums.ScheduleKindInterval, enums.ScheduleFailureCancel, 0)
+
+	// alice has nothing, so every first attempt fails
+	f.runDue(t, 3)
+	if got := f.get(t, paused.ID); got.Status != enums.ScheduleStatusActive || got.Attempts != 1 ||
+		got.LastError != repository.ErrInsufficientBalance.Error() || len(f.runs(t, paused.ID)) != 0 {
+		t.Fatalf("retrying schedule %+v, want active with one failed attempt and no run yet", got)
+	}
+	if got := f.get(t, skipped.ID); got.Status != enums.ScheduleStatusCompleted || got.FailureCount != 1 {
+		t.Errorf("skipping once schedule %+v, want completed with a failure", got)
+	}
+	if got := f.get(t, cancelled.ID); got.Status != enums.ScheduleStatusCancelled || got.NextRunAt != nil {
+		t.Errorf("cancelling schedule %+v, want cancelled", got)
+	}
+
+	// the retry delay is 0, so the retry is due at once
+	f.runDue(t, 1)
+	got := f.get(t, paused.ID)
+	if got.Status != enums.ScheduleStatusPaused || !got.OccurrenceAt.Equal(occurrence) || got.FailureCount != 1 {
+		t.Fatalf("schedule after its last attempt %+v, want paused on the failed occurrence", got)
+	}
+	if runs := f.runs(t, paused.ID); len(runs) != 1 || runs[0].Status != enums.ScheduleRunStatusFailed || runs[0].Attempts != 2 {
+		t.Errorf("runs %+v, want one failed after 2 attempts", runs)
+	}
+	f.runDue(t, 0)
+
+	// topped up and resumed, the failed occurrence is charged
+	f.grant(t, f.alice, 100, "top-up")
+	resumed, err := f.schedules.Resume(ctx, paused.ID.String())
+	if err != nil || resumed.Status != enums.ScheduleStatusActive || resumed.Attempts != 0 {
+		t.Fatalf("Resume = %+v, %v; want active again", resumed, err)
+	}
+	f.runDue(t, 1)
+	if runs := f.runs(t, paused.ID); len(runs) != 1 || runs[0].Status != enums.ScheduleRunStatusSucceeded {
+		t.Errorf("runs %+v, want the occurrence succeeded", runs)
+	}
+	if got := f.balance(t, f.alice); got != 0 {
+		t.Errorf("balance %d, want 0 after the charge", got)
+	}
+}
+
+func TestClaimDueHonoursLeases(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	s := f.create(t, repository.Schedule{OwnerID: f.alice, Direction: enums.ScheduleDirectionCredit, Amount: 1,
+		Kind: enums.ScheduleKindInterval, IntervalSeconds: 3600}, time.Now())
+
+	claimed, err := f.schedules.ClaimDue(ctx, "first", time.Minute, 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDue = %v, %v; want the schedule", claimed, err)
+	}
+	if again, err := f.schedules.ClaimDue(ctx, "second", time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimDue = %v, %v; want nothing while leased", again, err)
+	}
+	if err := f.schedules.Advance(ctx, &claimed[0], "second", nil); !errors.Is(err, repository.ErrScheduleLeaseLost) {
+		t.Errorf("Advance by another worker = %v, want ErrScheduleLeaseLost", err)
+	}
+
+	// paused while the first worker holds it: its outcome is kept, its
+	// status is not
+	if _, err := f.schedules.Pause(ctx, s.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	claimed[0].RunCount = 1
+	if err := f.schedules.Advance(ctx, &claimed[0], "first", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.get(t, s.ID); got.Status != enums.ScheduleStatusPaused || got.RunCount != 1 {
+		t.Errorf("schedule %+v, want paused with the run counted", got)
+	}
+	if _, err := f.schedules.Resume(ctx, s.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := f.schedules.ClaimDue(ctx, "second", time.Minute, 10); err != nil || len(again) != 1 {
+		t.Errorf("ClaimDue after release = %v, %v; want the schedule", again, err)
+	}
+}
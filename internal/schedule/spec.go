@@ -0,0 +1,117 @@
+// Package schedule runs transfers on a schedule, such as daily login
+// rewards and weekly subscription charges. A Runner on every API instance
+// claims due schedules and moves each occurrence through the ledger under a
+// key derived from the schedule and the occurrence's time, so retrying an
+// occurrence or running it again after a restart is safe; see
+// repository.WalletRepository.Transfer.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
+	"github.com/robfig/cron/v3"
+)
+
+// MinInterval is the shortest interval a recurring schedule may have.
+const MinInterval = time.Minute
+
+// parser accepts standard five-field expressions and descriptors such as
+// @daily, but not @every, which is what interval schedules are for.
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// IdempotencyKey is the ledger key of a schedule's occurrence.
+func IdempotencyKey(scheduleID uuid.UUID, occurrence time.Time) string {
+	return fmt.Sprintf("sched:%s:%d", scheduleID, occurrence.Unix())
+}
+
+// Prepare checks a new schedule's definition and makes it active with its
+// first occurrence, the first at or after start.
+func Prepare(s *repository.Schedule, start time.Time) error {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil || s.Timezone == "" || s.Timezone == "Local" {
+		return fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	start = start.UTC().Truncate(time.Second)
+	first := start
+	switch s.Kind {
+	case enums.ScheduleKindOnce:
+		if s.Cron != "" || s.IntervalSeconds != 0 {
+			return errors.New("a once schedule takes neither cron nor interval")
+		}
+	case enums.ScheduleKindInterval:
+		if s.Cron != "" {
+			return errors.New("an interval schedule does not take cron")
+		}
+		if time.Duration(s.IntervalSeconds)*time.Second < MinInterval {
+			return fmt.Errorf("interval must be at least %s", MinInterval)
+		}
+	case enums.ScheduleKindCron:
+		if s.IntervalSeconds != 0 {
+			return errors.New("a cron schedule does not take interval")
+		}
+		spec, err := parseCron(s.Cron)
+		if err != nil {
+			return err
+		}
+		first = spec.Next(start.Add(-time.Second).In(location)).UTC()
+		if first.IsZero() {
+			return errors.New("cron never fires")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", s.Kind)
+	}
+	if s.EndAt != nil && first.After(*s.EndAt) {
+		return errors.New("end_at is before the first occurrence")
+	}
+	s.Status = enums.ScheduleStatusActive
+	s.OccurrenceAt, s.NextRunAt = &first, &first
+	return nil
+}
+
+func parseCron(expression string) (cron.Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@every") || strings.HasPrefix(expression, "CRON_TZ=") || strings.HasPrefix(expression, "TZ=") {
+		return nil, errors.New("cron must be a five-field expression or a descriptor such as @daily; use interval or timezone instead")
+	}
+	spec, err := parser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron: %w", err)
+	}
+	return spec, nil
+}
+
+// Next returns the schedule's first occurrence after after, which must not
+// be before its current one, and false when it has none left.
+func Next(s *repository.Schedule, after time.Time) (time.Time, bool) {
+	var next time.Time
+	switch s.Kind {
+	case enums.ScheduleKindInterval:
+		interval := time.Duration(s.IntervalSeconds) * time.Second
+		current := *s.OccurrenceAt
+		next = current.Add((after.Sub(current)/interval + 1) * interval)
+	case enums.ScheduleKindCron:
+		spec, err := parseCron(s.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		location, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return time.Time{}, false
+		}
+		next = spec.Next(after.In(location))
+	default:
+		return time.Time{}, false
+	}
+	next = next.UTC()
+	if next.IsZero() || (s.EndAt != nil && next.After(*s.EndAt)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
@@ -1,183 +1,296 @@
+// Package seed loads fixtures into the database. Every balance a fixture
+// declares is minted into a treasury and transferred out of it, so a seeded
+// database reconciles like one built up by real traffic.
 package seed
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
-	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
-	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
 	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
+	"github.com/jay6909/dino-internal-wallet-service/internal/enums"
 	"gorm.io/gorm"
 )
 
-func SeedDb() {
-	appEnv, err := config_env.LoadAppEnv()
-	if err != nil {
-		panic(err)
+// Options tunes a seed run.
+type Options struct {
+	// Concurrency is the number of wallets opened and funded in parallel.
+	Concurrency int
+	// BatchSize is the number of users inserted per statement.
+	BatchSize int
+}
+
+// Result counts what a seed run created. Rerunning a fixture reports zeros.
+type Result struct {
+	Currencies int   `json:"currencies_created"`
+	Minted     int64 `json:"minted"`
+	Users      int   `json:"users_created"`
+	Wallets    int64 `json:"wallets_created"`
+	Funded     int64 `json:"wallets_funded"`
+}
+
+type seeder struct {
+	db         *gorm.DB
+	wallets    repository.WalletRepository
+	users      repository.UserRepository
+	currencies map[string]repository.CurrencyType
+	treasuries map[string]*repository.Wallet
+	result     Result
+}
+
+type walletJob struct {
+	user     uuid.UUID
+	currency string
+	balance  int64
+}
+
+// Apply makes sure everything in f exists. It is idempotent: currencies are
+// matched by name, users by ID, and every mint and transfer carries an
+// idempotency key derived from what it funds, so a rerun (or a run resumed
+// after a failure) creates and moves only what is still missing. Supplies and
+// balances are applied once; changing them in the fixture later has no effect
+// on data that was already seeded.
+func Apply(ctx context.Context, db *gorm.DB, f *Fixture, opts Options) (*Result, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
 	}
-	database, err := config_db.NewGormDB(appEnv.DatabaseConfig.DSN)
-	if err != nil {
-		panic(err)
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 8
 	}
-	systemUser := SeedSystemUser(database)
-	currencyTypes := SeedCurrencyTypes(database)
-	SeedUsers(database, currencyTypes)
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 500
+	}
+	s := &seeder{
+		db:         db,
+		wallets:    repository.NewWalletRepository(db),
+		users:      repository.NewUserRepository(db),
+		currencies: make(map[string]repository.CurrencyType),
+		treasuries: make(map[string]*repository.Wallet),
+	}
+	if err := s.seedCurrencies(ctx, f.Currencies); err != nil {
+		return nil, err
+	}
+	if err := s.seedUsers(ctx, f.Users, opts.BatchSize); err != nil {
+		return nil, err
+	}
+	if err := s.seedWallets(ctx, f.Users, opts.Concurrency); err != nil {
+		return nil, err
+	}
+	return &s.result, nil
+}
 
-	SeedTreasury(database, *systemUser, currencyTypes)
+// SystemUser returns the user that owns the treasuries, creating it when the
+// database has none yet.
+func SystemUser(ctx context.Context, users repository.UserRepository) (*repository.User, error) {
+	existing, err := users.ListUsers(ctx, "system", 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return &existing[0], nil
+	}
+	user := repository.User{ID: uuid.New(), Name: "system", Role: "system"}
+	if err := users.CreateUser(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-func SeedUsers(db config_db.DB, currencyTypes []repository.CurrencyType) {
-	uuid1, err := uuid.Parse("d3f57c3b-3a35-4b6a-9c22-3f8f9e3c1111")
+func (s *seeder) seedCurrencies(ctx context.Context, currencies []Currency) error {
+	system, err := SystemUser(ctx, s.users)
 	if err != nil {
-		panic("failed to parse uuid for user 1")
+		return fmt.Errorf("system user: %w", err)
 	}
-	uuid2, err := uuid.Parse("d3f57c3b-3a35-4b6a-9c22-3f8f9e3c2222")
+	currencyTypes := repository.NewCurrencyTypeRepository(s.db)
+	existing, err := currencyTypes.ListCurrencyTypes(ctx)
 	if err != nil {
-		panic("failed to parse uuid for user 2")
+		return err
 	}
-
-	users := []repository.User{
-		{ID: uuid1, Name: "Alice", Role: "user"},
-		{ID: uuid2, Name: "Bob", Role: "user"},
+	for _, currencyType := range existing {
+		s.currencies[currencyType.Name] = currencyType
 	}
 
-	// 1️⃣ Seed users (idempotent)
-	for _, user := range users {
-		var existing repository.User
-		err := db.GetDB().
-			Where("id = ?", user.ID).
-			First(&existing).Error
-
-		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-			panic(err)
+	for _, currency := range currencies {
+		currencyType, ok := s.currencies[currency.Name]
+		if !ok {
+			currencyType = repository.CurrencyType{ID: uuid.New(), Name: currency.Name}
+			if err := currencyTypes.CreateCurrencyType(ctx, &currencyType); err != nil {
+				return fmt.Errorf("create currency %s: %w", currency.Name, err)
+			}
+			s.currencies[currency.Name] = currencyType
+			s.result.Currencies++
 		}
 
+		treasury, err := s.wallets.GetSystemWalletByCurrencyType(ctx, currencyType.ID.String())
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if err := db.GetDB().Create(&user).Error; err != nil {
-				panic(err)
+			treasury = &repository.Wallet{
+				ID:             uuid.New(),
+				OwnerType:      "system",
+				OwnerID:        system.ID,
+				CurrencyTypeID: currencyType.ID,
 			}
+			err = s.wallets.CreateWallet(ctx, treasury)
+		}
+		if err != nil {
+			return fmt.Errorf("treasury for %s: %w", currency.Name, err)
+		}
+		s.treasuries[currency.Name] = treasury
+
+		if currency.Supply == 0 {
+			continue
+		}
+		key := "seed-supply-" + currencyType.ID.String()
+		applied, err := s.applied(ctx, key)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
 		}
+		if err := s.wallets.Mint(ctx, currencyType.ID.String(), key, currency.Supply); err != nil {
+			return fmt.Errorf("mint %s: %w", currency.Name, err)
+		}
+		s.result.Minted += currency.Supply
 	}
+	return nil
+}
 
-	// 2️⃣ Seed wallets (3 per user)
-	for _, user := range users {
-		for _, ct := range currencyTypes {
-
-			var wallet repository.Wallet
-			err := db.GetDB().
-				Where(
-					"owner_type = ? AND owner_id = ? AND currency_type_id = ?",
-					user.Role,
-					user.ID,
-					ct.ID,
-				).
-				First(&wallet).Error
-
-			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-				panic(err)
+func (s *seeder) seedUsers(ctx context.Context, users []User, batchSize int) error {
+	for start := 0; start < len(users); start += batchSize {
+		batch := users[start:min(start+batchSize, len(users))]
+		ids := make([]uuid.UUID, len(batch))
+		for i, user := range batch {
+			ids[i], _ = user.userID()
+		}
+
+		var existing []uuid.UUID
+		if err := s.db.WithContext(ctx).Model(&repository.User{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		found := make(map[uuid.UUID]bool, len(existing))
+		for _, id := range existing {
+			found[id] = true
+		}
+		var missing []repository.User
+		for i, user := range batch {
+			if !found[ids[i]] {
+				missing = append(missing, repository.User{ID: ids[i], Name: user.Name, Role: "user"})
 			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		if err := s.db.WithContext(ctx).Create(&missing).Error; err != nil {
+			return fmt.Errorf("create users: %w", err)
+		}
+		s.result.Users += len(missing)
+		slog.DebugContext(ctx, "seeding users", "created", s.result.Users, "of", len(users))
+	}
+	return nil
+}
 
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				newWallet := repository.Wallet{
-					ID:             uuid.New(),
-					OwnerType:      user.Role,
-					OwnerID:        user.ID,
-					CurrencyTypeID: ct.ID,
-					Balance:        1000,
-				}
+// seedWallets opens every declared wallet and funds it from its treasury with
+// the same concurrent worker pattern loadgen uses to set up its users.
+func (s *seeder) seedWallets(ctx context.Context, users []User, concurrency int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-				if err := db.GetDB().Create(&newWallet).Error; err != nil {
-					panic(err)
+	jobs := make(chan walletJob)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := s.seedWallet(ctx, job); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
 				}
 			}
-		}
+		}()
 	}
-}
 
-func SeedSystemUser(db config_db.DB) *repository.User {
-	systemUser := &repository.User{}
-	err := db.GetDB().Where("name = ? AND role = ?",
-		"system",
-		"system").First(&systemUser).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		panic(err)
+send:
+	for _, user := range users {
+		id, _ := user.userID()
+		currencies := make([]string, 0, len(user.Balances))
+		for currency := range user.Balances {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		for _, currency := range currencies {
+			select {
+			case jobs <- walletJob{user: id, currency: currency, balance: user.Balances[currency]}:
+			case <-ctx.Done():
+				break send
+			}
+		}
 	}
-	if err == nil {
-		return systemUser
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
 	}
-	systemUser = &repository.User{
-		ID:   uuid.New(),
-		Name: "system",
-		Role: "system",
+	return ctx.Err()
+}
+
+func (s *seeder) seedWallet(ctx context.Context, job walletJob) error {
+	currency := s.currencies[job.currency]
+	created := false
+	wallet, err := s.wallets.GetWalletByOwner(ctx, "user", job.user.String(), currency.ID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		wallet = &repository.Wallet{ID: uuid.New(), OwnerType: "user", OwnerID: job.user, CurrencyTypeID: currency.ID}
+		if err = s.wallets.CreateWallet(ctx, wallet); err == nil {
+			created = true
+			atomic.AddInt64(&s.result.Wallets, 1)
+		}
 	}
-	err = db.GetDB().Create(systemUser).Error
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("wallet of user %s in %s: %w", job.user, job.currency, err)
+	}
+	if job.balance == 0 {
+		return nil
 	}
 
-	return systemUser
-
-}
-func SeedCurrencyTypes(db config_db.DB) []repository.CurrencyType {
-	names := []string{"gold", "diamond", "loyalty_points"}
-	var result []repository.CurrencyType
-
-	for _, name := range names {
-		ct := &repository.CurrencyType{}
-		err := db.GetDB().
-			Where("name = ?", name).
-			First(&ct).Error
-		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-			panic(err)
-		}
-		if err == nil {
-			result = append(result, *ct)
-			continue
-		}
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ct = &repository.CurrencyType{
-				ID:   uuid.New(),
-				Name: name,
-			}
-			err = db.GetDB().Create(ct).Error
-			if err != nil {
-				panic(err)
-			}
-			result = append(result, *ct)
-
+	key := "seed-balance-" + wallet.ID.String()
+	if !created {
+		// a wallet created by an earlier run may already be funded
+		applied, err := s.applied(ctx, key)
+		if err != nil || applied {
+			return err
 		}
-
 	}
-	return result
-
+	treasury := s.treasuries[job.currency]
+	err = s.wallets.Transfer(ctx, treasury.ID.String(), wallet.ID.String(), currency.ID.String(), key, job.balance, enums.TransactionTypeTopUp)
+	if err != nil {
+		return fmt.Errorf("fund wallet of user %s in %s: %w", job.user, job.currency, err)
+	}
+	if funded := atomic.AddInt64(&s.result.Funded, 1); funded%10_000 == 0 {
+		slog.InfoContext(ctx, "seeding balances", "funded", funded)
+	}
+	return nil
 }
-func SeedTreasury(
-	db config_db.DB,
-	systemUser repository.User,
-	currencyTypes []repository.CurrencyType,
-) {
-	for _, ct := range currencyTypes {
-		wallet := repository.Wallet{
-			ID:             uuid.New(),
-			OwnerType:      "system",
-			OwnerID:        systemUser.ID,
-			CurrencyTypeID: ct.ID,
-			Balance:        1_000_000,
-		}
-
-		err := db.GetDB().
-			Where(
-				"owner_type = ? AND owner_id = ? AND currency_type_id = ?",
-				"system", systemUser.ID, ct.ID,
-			).
-			First(&wallet).Error
-		if err == nil && wallet.ID != uuid.Nil {
-			continue
-		}
-		err = db.GetDB().Create(&wallet).Error
 
-		if err != nil {
-			panic("failed to seed treasury wallet")
-		}
+// applied reports whether a ledger entry with the key exists. Mint and
+// Transfer would skip it anyway; checking first keeps reruns of large
+// fixtures cheap.
+func (s *seeder) applied(ctx context.Context, key string) (bool, error) {
+	_, err := s.wallets.GetTransactionByIdempotencyKey(ctx, key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return false, nil
 	}
+	return false, err
 }
@@ -0,0 +1,186 @@
+package seed_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jay6909/dino-internal-wallet-service/internal/admin"
+	config_db "github.com/jay6909/dino-internal-wallet-service/internal/config/db"
+	config_env "github.com/jay6909/dino-internal-wallet-service/internal/config/env"
+	"github.com/jay6909/dino-internal-wallet-service/internal/migrations"
+	"github.com/jay6909/dino-internal-wallet-service/internal/seed"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	database, err := config_db.NewGormDB(config_env.DbConfig{Driver: "sqlite", DSN: ":memory:"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	migrator, err := migrations.New(database.GetDB(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return database.GetDB()
+}
+
+func reconcile(t *testing.T, db *gorm.DB) *admin.ReconcileReport {
+	t.Helper()
+	report, err := admin.Reconcile(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Fatalf("seeded ledger does not reconcile: %+v", report)
+	}
+	return report
+}
+
+func TestApplyDemoIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	result, err := seed.Apply(ctx, db, seed.Demo(), seed.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := seed.Result{Currencies: 3, Minted: 3_000_000, Users: 2, Wallets: 6, Funded: 6}
+	if *result != want {
+		t.Errorf("first run = %+v, want %+v", *result, want)
+	}
+	report := reconcile(t, db)
+	for _, c := range report.Currencies {
+		if c.Minted != 1_000_000 || c.TotalBalance != 1_000_000 || c.Wallets != 3 {
+			t.Errorf("currency %s = %+v, want 3 wallets sharing the 1000000 minted", c.Name, c)
+		}
+	}
+
+	result, err = seed.Apply(ctx, db, seed.Demo(), seed.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (seed.Result{}) {
+		t.Errorf("second run = %+v, want nothing created", *result)
+	}
+	var transactions int64
+	if err := db.Table("wallet_transactions").Count(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	// one mint per currency and two legs per funded wallet
+	if transactions != 3+2*6 {
+		t.Errorf("%d ledger entries after rerun, want 15", transactions)
+	}
+}
+
+func TestApplySyntheticExtendsEarlierRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	if _, err := seed.Apply(ctx, db, seed.Synthetic(20, 2, 50), seed.Options{Concurrency: 4, BatchSize: 7}); err != nil {
+		t.Fatal(err)
+	}
+	// the treasury of the larger run was already minted for the smaller one,
+	// so it can only fund users it has room for
+	result, err := seed.Apply(ctx, db, seed.Synthetic(30, 2, 50), seed.Options{Concurrency: 4, BatchSize: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := seed.Result{Users: 10, Wallets: 20, Funded: 20}
+	if *result != want {
+		t.Errorf("second run = %+v, want %+v", *result, want)
+	}
+	for _, c := range reconcile(t, db).Currencies {
+		if c.Wallets != 31 || c.TotalBalance != 2_000 {
+			t.Errorf("currency %s = %+v, want 31 wallets holding the 2000 minted", c.Name, c)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "fixture.yaml",
+			content: `currencies:
+  - {name: gems, supply: 100}
+users:
+  - name: Carol
+    balances: {gems: 60}
+  - name: Dave
+    balances: {gems: 40}
+`,
+		},
+		{
+			name:    "json",
+			file:    "fixture.json",
+			content: `{"currencies": [{"name": "gems", "supply": 10}], "users": [{"name": "Carol", "balances": {"gems": 10}}]}`,
+		},
+		{
+			name:    "overdrawn treasury",
+			file:    "fixture.yaml",
+			content: "currencies: [{name: gems, supply: 10}]\nusers: [{name: Carol, balances: {gems: 11}}]\n",
+			wantErr: "exceed the supply of 10 by 1",
+		},
+		{
+			name:    "undeclared currency",
+			file:    "fixture.yaml",
+			content: "users: [{name: Carol, balances: {gems: 1}}]\n",
+			wantErr: `undeclared currency "gems"`,
+		},
+		{
+			name:    "duplicate user",
+			file:    "fixture.json",
+			content: `{"users": [{"name": "Carol"}, {"name": "Carol"}]}`,
+			wantErr: "same id",
+		},
+		{
+			name:    "unknown field",
+			file:    "fixture.yaml",
+			content: "currencies: [{name: gems, suply: 10}]\n",
+			wantErr: "suply",
+		},
+		{
+			name:    "unsupported extension",
+			file:    "fixture.toml",
+			content: "",
+			wantErr: ".yaml, .yml or .json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			f, err := seed.Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Currencies) != 1 || len(f.Users) == 0 {
+				t.Errorf("loaded %+v", f)
+			}
+		})
+	}
+}
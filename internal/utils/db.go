@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jay6909/dino-internal-wallet-service/internal/data/repository"
 	"gorm.io/gorm"
 )
 
@@ -39,6 +40,13 @@ func CheckGormError(c *gin.Context, err error) (int, error) {
 	if errors.Is(err, gorm.ErrEmptySlice) {
 		return http.StatusBadRequest, fmt.Errorf("empty slice")
 	}
+	// the request is valid but the ledger's rules do not allow it
+	if errors.Is(err, repository.ErrInsufficientBalance) || errors.Is(err, repository.ErrWalletFrozen) {
+		return http.StatusUnprocessableEntity, err
+	}
+	if errors.Is(err, repository.ErrIdempotencyKeyReused) {
+		return http.StatusConflict, err
+	}
 
 	return http.StatusInternalServerError, err
 }